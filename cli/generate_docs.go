@@ -13,6 +13,12 @@ type GenerateDocsRequest struct {
 	templateOption
 	// InputFileName is the service definition to parse/process (the "--service" option)
 	InputFileName string
+	// AsyncAPITemplate is the path to a custom template used to generate the AsyncAPI
+	// document (the "--asyncapi-template" option). Leave blank to use the standard one.
+	AsyncAPITemplate string
+	// GraphQLTemplate is the path to a custom template used to generate the GraphQL
+	// schema (the "--graphql-template" option). Leave blank to use the standard one.
+	GraphQLTemplate string
 }
 
 // GenerateDocs handles the registration and execution of the 'abide docs' CLI subcommand.
@@ -23,7 +29,7 @@ func (c GenerateDocs) Command() *cobra.Command {
 	request := &GenerateDocsRequest{}
 	cmd := &cobra.Command{
 		Use:   "docs [flags] FILENAME",
-		Short: "Generates the OpenAPI documentation for your service that can be distributed to users.",
+		Short: "Generates the OpenAPI, AsyncAPI, and GraphQL documentation for your service that can be distributed to users.",
 		Args:  cobra.ExactArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			request.InputFileName = args[0]
@@ -31,6 +37,8 @@ func (c GenerateDocs) Command() *cobra.Command {
 		},
 	}
 	cmd.Flags().StringVar(&request.Template, "template", "", "Path to a custom OpenAPI/Swagger/docs template file used to generate this artifact.")
+	cmd.Flags().StringVar(&request.AsyncAPITemplate, "asyncapi-template", "", "Path to a custom AsyncAPI template file used to generate the event documentation.")
+	cmd.Flags().StringVar(&request.GraphQLTemplate, "graphql-template", "", "Path to a custom GraphQL template file used to generate the GraphQL schema.")
 	return cmd
 }
 
@@ -44,5 +52,26 @@ func (c GenerateDocs) Exec(request *GenerateDocsRequest) error {
 
 	artifact := request.ToFileTemplate("openapi.yml")
 	log.Printf("Generating artifact '%s'", artifact.Name)
-	return generate.File(ctx, artifact)
+	if err = generate.File(ctx, artifact); err != nil {
+		return err
+	}
+
+	// Every function publishes an event when it completes (even HTTP OMIT ones), so we always
+	// describe the service's event channels for the teams that consume them.
+	asyncArtifact := generate.NewStandardTemplate("asyncapi.yml", "templates/asyncapi.yml.tmpl")
+	if request.AsyncAPITemplate != "" {
+		asyncArtifact = generate.NewCustomTemplate("asyncapi.yml", request.AsyncAPITemplate)
+	}
+	log.Printf("Generating artifact '%s'", asyncArtifact.Name)
+	if err = generate.File(ctx, asyncArtifact); err != nil {
+		return err
+	}
+
+	// The schema that your frontend tooling needs to query this service via the GraphQL gateway.
+	graphqlArtifact := generate.NewStandardTemplate("schema.graphql", "templates/schema.graphql.tmpl")
+	if request.GraphQLTemplate != "" {
+		graphqlArtifact = generate.NewCustomTemplate("schema.graphql", request.GraphQLTemplate)
+	}
+	log.Printf("Generating artifact '%s'", graphqlArtifact.Name)
+	return generate.File(ctx, graphqlArtifact)
 }
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/monadicstack/abide/generate"
+	"github.com/monadicstack/abide/parser"
+	"github.com/spf13/cobra"
+)
+
+// GenerateGraphRequest contains all of the CLI options used in the "abide graph" command.
+type GenerateGraphRequest struct {
+	templateOption
+	// InputFileNames are all of the service definitions to parse/process.
+	InputFileNames []string
+	// Format is the type of diagram to output: "mermaid" or "dot" (the "--format" option).
+	Format string
+	// Output is the path of the file to write the diagram to. When blank, we write it to stdout.
+	Output string
+}
+
+// GenerateGraph handles the registration and execution of the 'abide graph' CLI subcommand.
+type GenerateGraph struct{}
+
+// Command creates the Cobra struct describing this CLI command and its options.
+func (c GenerateGraph) Command() *cobra.Command {
+	request := &GenerateGraphRequest{}
+	cmd := &cobra.Command{
+		Use:   "graph [flags] FILENAME...",
+		Short: "Generates a Mermaid or Graphviz diagram of the event flows between your services.",
+		Args:  cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			request.InputFileNames = args
+			crapPants(c.Exec(request))
+		},
+	}
+	cmd.Flags().StringVar(&request.Format, "format", "mermaid", "The type of diagram to generate: 'mermaid' or 'dot'.")
+	cmd.Flags().StringVar(&request.Output, "output", "", "Path of the file to write the diagram to. Writes to stdout when omitted.")
+	cmd.Flags().StringVar(&request.Template, "template", "", "Path to a custom Mermaid/DOT template file used to generate this artifact.")
+	return cmd
+}
+
+// Exec takes all of the parsed CLI flags and generates the event flow diagram for all of the services.
+func (c GenerateGraph) Exec(request *GenerateGraphRequest) error {
+	var artifact generate.FileTemplate
+	switch request.Format {
+	case "mermaid", "mmd", "":
+		artifact = request.ToFileTemplate("graph.mmd")
+	case "dot", "graphviz":
+		artifact = request.ToFileTemplate("graph.dot")
+	default:
+		return fmt.Errorf("invalid graph format: '%s' (must be 'mermaid' or 'dot')", request.Format)
+	}
+
+	var contexts []*parser.Context
+	for _, inputFileName := range request.InputFileNames {
+		log.Printf("Parsing service definitions: %s", inputFileName)
+		ctx, err := parser.ParseFile(inputFileName)
+		if err != nil {
+			return err
+		}
+		contexts = append(contexts, ctx)
+	}
+
+	graph := generate.NewGraph(contexts...)
+	for _, warning := range graph.Warnings {
+		log.Printf("Warning: %s", warning)
+	}
+
+	log.Printf("Generating artifact '%s'", artifact.Name)
+	diagram, err := artifact.Eval(graph)
+	if err != nil {
+		return fmt.Errorf("template eval error: %s: %v", artifact.Name, err)
+	}
+
+	// Logging goes to stderr, so you can safely pipe stdout right into another tool.
+	if request.Output == "" {
+		_, err = os.Stdout.Write(diagram)
+		return err
+	}
+	if err = os.WriteFile(request.Output, diagram, 0644); err != nil {
+		return fmt.Errorf("unable to write graph: %s: %w", request.Output, err)
+	}
+	return nil
+}
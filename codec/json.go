@@ -10,6 +10,7 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/monadicstack/abide/fail"
 	"github.com/monadicstack/abide/internal/reflection"
 )
 
@@ -113,7 +114,17 @@ func (encoder JSONEncoder) encodeValues(prefix string, rawValue any, out url.Val
 // After generating each value, the decoder will feed the massaged JSON to a 'json.Decoder' and standard
 // JSON marshaling rules will overlay each one onto your 'out' value.
 type JSONDecoder struct {
+	// Loose ignores values that fail to decode in DecodeValues rather than failing the whole operation.
 	Loose bool
+	// Strict makes Decode fail w/ a 400 error when the JSON contains fields that don't exist on
+	// the 'out' value or when there's more data after the JSON value.
+	Strict bool
+	// MaxValues is the maximum number of keys that DecodeValues will accept before failing
+	// w/ a 400 error. The default of 0 means that there's no limit.
+	MaxValues int
+	// MaxDepth is the maximum number of segments allowed in a key passed to DecodeValues before failing
+	// w/ a 400 error (e.g. "User.Address.City" has a depth of 3). The default of 0 means that there's no limit.
+	MaxDepth int
 }
 
 // Decode simply uses standard encoding/json to populate your 'out' value w/ JSON from the reader.
@@ -121,10 +132,47 @@ func (decoder JSONDecoder) Decode(data io.Reader, out any) error {
 	if data == nil || data == http.NoBody {
 		return nil
 	}
-	if err := json.NewDecoder(data).Decode(out); err != nil {
-		return fmt.Errorf("json decoder: reader error: %w", err)
+
+	if !decoder.Strict {
+		if err := json.NewDecoder(data).Decode(out); err != nil {
+			return fmt.Errorf("json decoder: reader error: %w", err)
+		}
+		return nil
 	}
-	return nil
+
+	// In strict mode, bad JSON is the caller's fault, so it's a 400. We still need to know when the
+	// failure came from the reader itself (e.g. the body was too big or the connection dropped) so we
+	// don't blame the caller for those.
+	reader := &errorTrackingReader{Reader: data}
+	jsonDecoder := json.NewDecoder(reader)
+	jsonDecoder.DisallowUnknownFields()
+
+	err := jsonDecoder.Decode(out)
+	if err == nil {
+		// Whitespace is fine, but there should be nothing else after the value (e.g. `{"ID":"1"} {"ID":"2"}`).
+		if _, err = jsonDecoder.Token(); err == io.EOF {
+			return nil
+		}
+		err = fmt.Errorf("unexpected data after JSON value")
+	}
+	if reader.err != nil {
+		return fmt.Errorf("json decoder: reader error: %w", reader.err)
+	}
+	return fail.BadRequest("json decoder: reader error: %v", err)
+}
+
+// errorTrackingReader remembers the last non-EOF error returned by the underlying reader.
+type errorTrackingReader struct {
+	io.Reader
+	err error
+}
+
+func (r *errorTrackingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err != nil && err != io.EOF {
+		r.err = err
+	}
+	return n, err
 }
 
 // DecodeValues accepts key/value mappings like "User.ID":"123" and uses JSON-style
@@ -133,6 +181,9 @@ func (decoder JSONDecoder) DecodeValues(values url.Values, out any) error {
 	if len(values) == 0 {
 		return nil
 	}
+	if decoder.MaxValues > 0 && len(values) > decoder.MaxValues {
+		return fail.BadRequest("json decoder: too many values: %d (max %d)", len(values), decoder.MaxValues)
+	}
 
 	// To keep the logic more simple (but fast enough for most use cases), we will generate a separate
 	// JSON representation of each value and run it through the JSON decoder. To make things a bit more
@@ -165,6 +216,9 @@ func (decoder JSONDecoder) DecodeValues(values url.Values, out any) error {
 
 	for key, value := range values {
 		keySegments := strings.Split(key, ".")
+		if decoder.MaxDepth > 0 && len(keySegments) > decoder.MaxDepth {
+			return fail.BadRequest("json decoder: value key too deep: '%s' (max depth %d)", key, decoder.MaxDepth)
+		}
 
 		// Follow the segments of the key and determine the JSON type of the last segment. So if you
 		// are binding the key "foo.bar.baz", we'll look at the Go data type of the "baz" field once
@@ -5,12 +5,16 @@ package codec_test
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strings"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/monadicstack/abide/codec"
+	"github.com/monadicstack/abide/fail"
 	"github.com/monadicstack/abide/internal/testext"
 	"github.com/stretchr/testify/suite"
 )
@@ -282,6 +286,56 @@ func (suite *JSONSuite) TestDecodeValues_loose() {
 	suite.Equal(false, value.RemappedUser.AuditTrail.Deleted)
 }
 
+// Ensures that MaxValues and MaxDepth reject values w/ a 400 before we decode any of them.
+func (suite *JSONSuite) TestDecodeValues_limits() {
+	decoder := codec.JSONDecoder{MaxValues: 2, MaxDepth: 2}
+
+	var value testStruct
+	suite.NoError(decoder.DecodeValues(map[string][]string{"String": {"Hello"}, "User.ID": {"123"}}, &value))
+	suite.Equal("Hello", value.String)
+	suite.Equal("123", value.User.ID)
+
+	err := decoder.DecodeValues(map[string][]string{"String": {"A"}, "Int": {"1"}, "Bool": {"true"}}, &value)
+	suite.Require().Error(err, "Should fail when there are too many values")
+	suite.True(fail.IsBadRequest(err))
+
+	err = decoder.DecodeValues(map[string][]string{"User.AuditTrail.Deleted": {"true"}}, &value)
+	suite.Require().Error(err, "Should fail when a key is nested too deeply")
+	suite.True(fail.IsBadRequest(err))
+	suite.False(value.User.AuditTrail.Deleted)
+}
+
+// Ensures that strict mode rejects unknown fields and trailing data w/ a 400, but still accepts valid JSON.
+func (suite *JSONSuite) TestDecode_strict() {
+	decoder := codec.JSONDecoder{Strict: true}
+
+	var value testStruct
+	suite.NoError(decoder.Decode(strings.NewReader(`{"String":"Hello"}  `+"\n"), &value))
+	suite.Equal("Hello", value.String)
+
+	assertBadRequest := func(data string) {
+		err := decoder.Decode(strings.NewReader(data), &testStruct{})
+		suite.Require().Error(err, data)
+		suite.True(fail.IsBadRequest(err), "Should be a 400: %s", data)
+	}
+	assertBadRequest(`{"String":"Hello","Nope":true}`)
+	assertBadRequest(`{"String":"Hello"} {"String":"Goodbye"}`)
+	assertBadRequest(`{"String":"Hello"}}`)
+	assertBadRequest(`{"String":`)
+	assertBadRequest(`{"Int":"Hello"}`)
+
+	// Failures reading the data aren't the caller's fault, so they shouldn't be 400s.
+	err := decoder.Decode(io.MultiReader(strings.NewReader(`{"String":`), iotest.ErrReader(errors.New("dropped"))), &value)
+	suite.Require().Error(err)
+	suite.False(fail.IsBadRequest(err))
+	suite.Contains(err.Error(), "dropped")
+
+	// Without strict mode, these are all fine.
+	decoder = codec.JSONDecoder{}
+	suite.NoError(decoder.Decode(strings.NewReader(`{"String":"Hello","Nope":true}`), &value))
+	suite.NoError(decoder.Decode(strings.NewReader(`{"String":"Hello"} {"String":"Goodbye"}`), &value))
+}
+
 func (suite *JSONSuite) TestDecodeEncodeValues() {
 	inTime := time.Date(2010, time.November, 11, 12, 0, 0, 0, time.UTC)
 	inTimePtr := time.Date(2020, time.November, 11, 12, 0, 0, 0, time.UTC)
@@ -21,6 +21,15 @@ type Publisher interface {
 	// is no error returned. Errors should only describe an inability to give the message
 	// to the broker.
 	Publish(ctx context.Context, key string, payload []byte) error
+
+	// PublishAt behaves just like Publish except that subscribers should not receive the
+	// event until the given time. This is how you schedule work like "send a reminder email
+	// an hour after the user abandoned their shopping cart". If the time has already passed,
+	// the event should be delivered as soon as possible just like Publish().
+	//
+	// Just like Publish, a nil error only means that the broker accepted the event. It is not
+	// a guarantee that anyone will actually be listening once the time comes.
+	PublishAt(ctx context.Context, key string, payload []byte, at time.Time) error
 }
 
 // Subscriber is a broker client/connection that lets you subscribe to asynchronous events
@@ -52,7 +61,8 @@ type EventHandlerFunc func(ctx context.Context, evt *EventMessage) error
 
 // EventMessage is the message/envelope that brokers use to deliver events to subscribers.
 type EventMessage struct {
-	// Timestamp indicates when the event was fired/published.
+	// Timestamp indicates when the event was fired/published. For events published
+	// using PublishAt(), this is the time that the event was scheduled to be delivered.
 	Timestamp time.Time
 	// Key is the identifier of the event (e.g. "UserService.Created").
 	Key string
@@ -1,6 +1,7 @@
 package local
 
 import (
+	"container/heap"
 	"context"
 	"fmt"
 	"log"
@@ -15,7 +16,9 @@ import (
 )
 
 // Broker creates a new local/in-memory broker that dispatches events to subscribers
-// running just within this Go process.
+// running just within this Go process. Events scheduled via PublishAt() are only held in
+// memory, so any that haven't been delivered yet are lost when the process exits or you
+// Close() the broker. Use a durable broker such as NATS JetStream if they must survive restarts.
 func Broker(options ...BrokerOption) eventsource.Broker {
 	b := broker{
 		groups: map[string]*subscriptionGroup{},
@@ -28,12 +31,14 @@ func Broker(options ...BrokerOption) eventsource.Broker {
 	for _, option := range options {
 		option(&b)
 	}
+	b.schedule = &schedule{now: b.now, publish: b.publishScheduled}
 	return &b
 }
 
 type broker struct {
 	mutex        *sync.Mutex
 	groups       map[string]*subscriptionGroup
+	schedule     *schedule
 	now          func() time.Time
 	errorHandler fail.ErrorHandler
 }
@@ -42,7 +47,46 @@ func (b *broker) Publish(ctx context.Context, key string, payload []byte) error
 	if err := ctx.Err(); err != nil {
 		return fmt.Errorf("local broker publish: %w", err)
 	}
+	b.dispatch(key, payload, b.now())
+	return nil
+}
+
+func (b *broker) PublishAt(ctx context.Context, key string, payload []byte, at time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("local broker publish at: %w", err)
+	}
+
+	// You're scheduling something for "right now" (or the past), so don't bother making
+	// a round trip through the schedule. Just deliver it like any other event.
+	if !at.After(b.now()) {
+		b.dispatch(key, payload, b.now())
+		return nil
+	}
 
+	if !b.schedule.push(scheduledEvent{key: key, payload: payload, at: at}) {
+		return fmt.Errorf("local broker publish at: broker closed")
+	}
+	return nil
+}
+
+// Close stops the timer that delivers scheduled events and discards any that haven't been delivered
+// yet; this broker never persists them anywhere. Subsequent calls to PublishAt() fail, but you can
+// still Publish() events for immediate delivery. It is safe to call this more than once.
+func (b *broker) Close() error {
+	b.schedule.stop()
+	return nil
+}
+
+// publishScheduled is the callback the schedule fires once a delayed event's time has come.
+func (b *broker) publishScheduled(evt scheduledEvent) {
+	b.dispatch(evt.key, evt.payload, evt.at)
+}
+
+// dispatch delivers the event to one subscriber in each matching group. The handlers run asynchronously,
+// so they get a fresh context rather than the publisher's, which is likely canceled (or past its deadline)
+// by the time they run. That's especially true for scheduled events whose publisher finished long ago.
+// This is how a real broker behaves, too; anything handlers need (e.g. metadata) travels in the payload.
+func (b *broker) dispatch(key string, payload []byte, timestamp time.Time) {
 	keyTokens := b.tokenizeKey(key)
 
 	b.mutex.Lock()
@@ -67,13 +111,12 @@ func (b *broker) Publish(ctx context.Context, key string, payload []byte) error
 			continue
 		}
 
-		go b.publishMessage(ctx, sub, eventsource.EventMessage{
-			Timestamp: b.now(),
+		go b.publishMessage(context.Background(), sub, eventsource.EventMessage{
+			Timestamp: timestamp,
 			Key:       key,
 			Payload:   payload,
 		})
 	}
-	return nil
 }
 
 func (b *broker) publishMessage(ctx context.Context, sub *subscription, msg eventsource.EventMessage) {
@@ -239,6 +282,128 @@ func (robin *subscriptionRoundRobin) remove(sub *subscription) {
 	}
 }
 
+// ---------------------------------
+// SCHEDULED DELIVERY
+// ---------------------------------
+
+// schedule holds onto events published via PublishAt() until their delivery time arrives. The
+// pending events are kept in a min-heap ordered by delivery time, and we only ever keep a single
+// timer running for whichever event is due next. When it fires, we deliver everything that's
+// due and re-arm the timer for the next one in line.
+type schedule struct {
+	mutex    sync.Mutex
+	events   scheduledEvents
+	timer    *time.Timer
+	sequence uint64
+	stopped  bool
+	now      func() time.Time
+	publish  func(scheduledEvent)
+}
+
+// push adds the event to the schedule. It returns false if the schedule has already been stopped.
+func (s *schedule) push(evt scheduledEvent) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped {
+		return false
+	}
+
+	// The sequence number guarantees FIFO delivery for events scheduled at the exact same time.
+	s.sequence++
+	evt.sequence = s.sequence
+
+	heap.Push(&s.events, evt)
+	s.arm()
+	return true
+}
+
+// stop halts the timer and throws away any pending events, so nothing fires after the broker is closed.
+func (s *schedule) stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.stopped = true
+	s.events = nil
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+}
+
+// arm (re)starts the timer so that it fires when the next event in the heap is due. You
+// must already hold the mutex when calling this.
+func (s *schedule) arm() {
+	if len(s.events) == 0 {
+		return
+	}
+
+	delay := s.events[0].at.Sub(s.now())
+	if s.timer == nil {
+		s.timer = time.AfterFunc(delay, s.fire)
+		return
+	}
+	s.timer.Reset(delay)
+}
+
+func (s *schedule) fire() {
+	s.mutex.Lock()
+	if s.stopped {
+		s.mutex.Unlock()
+		return
+	}
+
+	var due []scheduledEvent
+	now := s.now()
+	for len(s.events) > 0 && !s.events[0].at.After(now) {
+		due = append(due, heap.Pop(&s.events).(scheduledEvent))
+	}
+	s.arm()
+	s.mutex.Unlock()
+
+	// Deliver outside the lock so that handlers are free to schedule more events.
+	for _, evt := range due {
+		s.publish(evt)
+	}
+}
+
+type scheduledEvent struct {
+	key      string
+	payload  []byte
+	at       time.Time
+	sequence uint64
+}
+
+// scheduledEvents implements heap.Interface so that the event with the earliest
+// delivery time is always at index 0.
+type scheduledEvents []scheduledEvent
+
+func (events scheduledEvents) Len() int {
+	return len(events)
+}
+
+func (events scheduledEvents) Less(i, j int) bool {
+	if events[i].at.Equal(events[j].at) {
+		return events[i].sequence < events[j].sequence
+	}
+	return events[i].at.Before(events[j].at)
+}
+
+func (events scheduledEvents) Swap(i, j int) {
+	events[i], events[j] = events[j], events[i]
+}
+
+func (events *scheduledEvents) Push(value any) {
+	*events = append(*events, value.(scheduledEvent))
+}
+
+func (events *scheduledEvents) Pop() any {
+	old := *events
+	n := len(old)
+	evt := old[n-1]
+	*events = old[:n-1]
+	return evt
+}
+
 // ---------------------------------
 // OPTIONS
 // ---------------------------------
@@ -5,6 +5,7 @@ package local_test
 import (
 	"context"
 	"fmt"
+	"io"
 	"testing"
 	"time"
 
@@ -429,3 +430,136 @@ func (suite *LocalBrokerSuite) TestUnsubscribe() {
 		"Foo:I'm Back!",
 	})
 }
+
+func (suite *LocalBrokerSuite) TestPublishAt_canceledContext() {
+	broker := local.Broker()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	suite.Error(broker.PublishAt(ctx, "Foo", []byte("Hello"), time.Now().Add(time.Minute)))
+}
+
+// Events scheduled in the past (or right now) should be delivered just like Publish().
+func (suite *LocalBrokerSuite) TestPublishAt_past() {
+	results := &testext.Sequence{}
+	broker := local.Broker()
+	suite.subscribe(broker, results, "Foo")
+
+	results.ResetWithWorkers(2)
+	suite.NoError(broker.PublishAt(context.Background(), "Foo", []byte("A"), time.Now().Add(-time.Hour)))
+	suite.NoError(broker.PublishAt(context.Background(), "Foo", []byte("B"), time.Time{}))
+	suite.assertFired(results, []string{
+		"Foo:A",
+		"Foo:B",
+	})
+}
+
+// Events should not fire until their scheduled time, and they should fire in scheduled
+// order regardless of the order that they were published.
+func (suite *LocalBrokerSuite) TestPublishAt_future() {
+	broker := local.Broker()
+	payloads := make(chan string, 3)
+	_, err := broker.Subscribe("Foo", func(ctx context.Context, evt *eventsource.EventMessage) error {
+		payloads <- string(evt.Payload)
+		return nil
+	})
+	suite.Require().NoError(err)
+
+	now := time.Now()
+	suite.NoError(broker.PublishAt(context.Background(), "Foo", []byte("C"), now.Add(150*time.Millisecond)))
+	suite.NoError(broker.PublishAt(context.Background(), "Foo", []byte("A"), now.Add(50*time.Millisecond)))
+	suite.NoError(broker.PublishAt(context.Background(), "Foo", []byte("B"), now.Add(100*time.Millisecond)))
+
+	time.Sleep(10 * time.Millisecond)
+	suite.Len(payloads, 0, "None of the event handlers should have fired yet")
+
+	for _, expected := range []string{"A", "B", "C"} {
+		select {
+		case payload := <-payloads:
+			suite.Equal(expected, payload)
+		case <-time.After(5 * time.Second):
+			suite.FailNow("Scheduled event was never delivered", expected)
+		}
+	}
+}
+
+// Closing the broker should stop the schedule's timer, so pending events are never delivered.
+func (suite *LocalBrokerSuite) TestPublishAt_close() {
+	broker := local.Broker()
+	payloads := make(chan string, 1)
+	_, err := broker.Subscribe("Foo", func(ctx context.Context, evt *eventsource.EventMessage) error {
+		payloads <- string(evt.Payload)
+		return nil
+	})
+	suite.Require().NoError(err)
+	suite.Require().NoError(broker.PublishAt(context.Background(), "Foo", []byte("A"), time.Now().Add(20*time.Millisecond)))
+
+	closer, ok := broker.(io.Closer)
+	suite.Require().True(ok, "Local broker should be closable")
+	suite.NoError(closer.Close())
+	suite.NoError(closer.Close(), "Closing more than once should be harmless")
+
+	suite.Error(broker.PublishAt(context.Background(), "Foo", []byte("B"), time.Now().Add(20*time.Millisecond)))
+	select {
+	case payload := <-payloads:
+		suite.Fail("Scheduled event should not be delivered after closing", payload)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// Immediate delivery still works.
+	suite.NoError(broker.Publish(context.Background(), "Foo", []byte("C")))
+	select {
+	case payload := <-payloads:
+		suite.Equal("C", payload)
+	case <-time.After(5 * time.Second):
+		suite.Fail("Event was never delivered")
+	}
+}
+
+// Handlers run asynchronously, so they shouldn't be at the mercy of the publisher's context being canceled.
+func (suite *LocalBrokerSuite) TestPublish_detachedContext() {
+	broker := local.Broker()
+	ctxErrs := make(chan error, 2)
+	_, err := broker.Subscribe("Foo", func(ctx context.Context, evt *eventsource.EventMessage) error {
+		time.Sleep(10 * time.Millisecond)
+		ctxErrs <- ctx.Err()
+		return nil
+	})
+	suite.Require().NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	suite.Require().NoError(broker.Publish(ctx, "Foo", []byte("A")))
+	suite.Require().NoError(broker.PublishAt(ctx, "Foo", []byte("B"), time.Now().Add(20*time.Millisecond)))
+	cancel()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err = <-ctxErrs:
+			suite.NoError(err, "Handler context should not be canceled w/ the publisher's")
+		case <-time.After(5 * time.Second):
+			suite.FailNow("Event was never delivered")
+		}
+	}
+}
+
+// The timestamp of a scheduled event should be the time it was scheduled for, not the time it was published.
+func (suite *LocalBrokerSuite) TestPublishAt_timestamp() {
+	broker := local.Broker()
+	at := time.Now().Add(20 * time.Millisecond)
+	timestamps := make(chan time.Time, 1)
+
+	_, err := broker.Subscribe("Foo", func(ctx context.Context, evt *eventsource.EventMessage) error {
+		timestamps <- evt.Timestamp
+		return nil
+	})
+	suite.Require().NoError(err)
+	suite.Require().NoError(broker.PublishAt(context.Background(), "Foo", []byte("A"), at))
+
+	select {
+	case timestamp := <-timestamps:
+		suite.True(at.Equal(timestamp))
+		suite.False(time.Now().Before(at), "Event should not be delivered before its scheduled time")
+	case <-time.After(5 * time.Second):
+		suite.Fail("Scheduled event was never delivered")
+	}
+}
@@ -14,6 +14,11 @@ import (
 var ErrNotConnected = fmt.Errorf("not connected")
 var ErrInvalidNamespace = fmt.Errorf("key does not have valid namespace: e.g. 'usercreated' instead of 'user.created'")
 
+// headerDeliverAt is the NATS message header we use to carry the delivery time of events
+// published via PublishAt(). JetStream doesn't support scheduled delivery natively, so we
+// publish right away and have subscribers bounce the message back to the server until it's due.
+const headerDeliverAt = "Abide-Deliver-At"
+
 func Broker(options ...Option) eventsource.Broker {
 	c := client{
 		uri:               "nats://127.0.0.1:4222",
@@ -65,6 +70,21 @@ func (c *client) Publish(ctx context.Context, key string, payload []byte) error
 	return nil
 }
 
+func (c *client) PublishAt(ctx context.Context, key string, payload []byte, at time.Time) error {
+	if err := c.loadStream(key); err != nil {
+		return fmt.Errorf("nats publish at: %w", err)
+	}
+
+	msg := nats.NewMsg(key)
+	msg.Data = payload
+	msg.Header.Set(headerDeliverAt, at.UTC().Format(time.RFC3339Nano))
+
+	if _, err := c.jetstream.PublishMsg(msg, nats.Context(ctx)); err != nil {
+		return fmt.Errorf("nats publish at: %w", err)
+	}
+	return nil
+}
+
 func (c *client) Subscribe(key string, handlerFunc eventsource.EventHandlerFunc) (eventsource.Subscription, error) {
 	if err := c.loadStream(key); err != nil {
 		return nil, fmt.Errorf("nats subscribe: %w", err)
@@ -94,8 +114,21 @@ func (c *client) SubscribeGroup(key string, group string, handlerFunc eventsourc
 
 func (c *client) toMsgHandler(handlerFunc eventsource.EventHandlerFunc) nats.MsgHandler {
 	return func(m *nats.Msg) {
+		timestamp := time.Now()
+
+		// This was published using PublishAt(), so we need to make sure that its time has come. If
+		// not, negatively acknowledge it so JetStream redelivers it once it's actually due. The auto-ack
+		// that NATS performs after this handler returns is a no-op once we've already NAK'd.
+		if deliverAt, ok := c.deliverAt(m); ok {
+			if delay := time.Until(deliverAt); delay > 0 {
+				_ = m.NakWithDelay(delay)
+				return
+			}
+			timestamp = deliverAt
+		}
+
 		err := handlerFunc(context.Background(), &eventsource.EventMessage{
-			Timestamp: time.Now(),
+			Timestamp: timestamp,
 			Key:       m.Subject,
 			Payload:   m.Data,
 		})
@@ -105,6 +138,23 @@ func (c *client) toMsgHandler(handlerFunc eventsource.EventHandlerFunc) nats.Msg
 	}
 }
 
+// deliverAt extracts the scheduled delivery time from a message published via PublishAt(). The
+// 'ok' value is false for messages published normally (or with a garbage header value).
+func (c *client) deliverAt(m *nats.Msg) (time.Time, bool) {
+	if m.Header == nil {
+		return time.Time{}, false
+	}
+	value := m.Header.Get(headerDeliverAt)
+	if value == "" {
+		return time.Time{}, false
+	}
+	deliverAt, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return deliverAt, true
+}
+
 func (c *client) loadStream(key string) error {
 	if c.err != nil {
 		return c.err
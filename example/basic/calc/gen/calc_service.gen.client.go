@@ -41,7 +41,7 @@ func (client *calculatorServiceClient) Add(ctx context.Context, request *calc.Ad
 	}
 
 	response := &calc.AddResponse{}
-	err := client.Invoke(ctx, "GET", "/add/{A}/{B}", request, response)
+	err := client.InvokeFunction(ctx, "Add", "GET", "/add/{A}/{B}", request, response)
 	return response, err
 
 }
@@ -57,7 +57,7 @@ func (client *calculatorServiceClient) Double(ctx context.Context, request *calc
 	}
 
 	response := &calc.DoubleResponse{}
-	err := client.Invoke(ctx, "POST", "/double/{Value}", request, response)
+	err := client.InvokeFunction(ctx, "Double", "POST", "/double/{Value}", request, response)
 	return response, err
 
 }
@@ -73,7 +73,7 @@ func (client *calculatorServiceClient) Mul(ctx context.Context, request *calc.Mu
 	}
 
 	response := &calc.MulResponse{}
-	err := client.Invoke(ctx, "GET", "/multiply/{A}/{B}", request, response)
+	err := client.InvokeFunction(ctx, "Mul", "GET", "/multiply/{A}/{B}", request, response)
 	return response, err
 
 }
@@ -89,7 +89,7 @@ func (client *calculatorServiceClient) Sub(ctx context.Context, request *calc.Su
 	}
 
 	response := &calc.SubResponse{}
-	err := client.Invoke(ctx, "GET", "/sub/{A}/{B}", request, response)
+	err := client.InvokeFunction(ctx, "Sub", "GET", "/sub/{A}/{B}", request, response)
 	return response, err
 
 }
@@ -198,6 +198,115 @@ function buildRequestPath(method, path, serviceRequest) {
         : resolvedPath + '?' + values.format();
 }
 
+/**
+ * Performs the same `validate` checks that the server does so that you can fail fast without
+ * making a round trip. Every invalid field is reported in a single 400 GatewayError whose message
+ * matches the one the server would have sent back.
+ *
+ * @param {Object} serviceRequest The input struct for the service call
+ * @param {Object[]} rules The constraints for each field, keyed by the field's binding path (e.g. "User.Name")
+ */
+function validateRequest(serviceRequest, rules) {
+    const failures = [];
+    const failedPaths = [];
+    for (const rule of rules) {
+        // Just like the server, once a field fails we don't bother checking the fields inside of it.
+        if (failedPaths.some(path => rule.path.startsWith(path + '.'))) {
+            continue;
+        }
+        const [found, value] = lookupValue(serviceRequest, rule.path);
+        if (!found) {
+            continue;
+        }
+        const [code, message] = validateValue(value, rule);
+        if (message) {
+            failures.push({Field: rule.path, Code: code, Message: message});
+            failedPaths.push(rule.path);
+        }
+    }
+    if (failures.length > 0) {
+        const message = 'validation failed: ' + failures.map(f => f.Field + ' ' + f.Message).join('; ');
+        throw new GatewayError(400, message, {code: 'VALIDATION_FAILED', details: failures});
+    }
+}
+
+/**
+ * Finds the value at the dot-delimited path (e.g. "User.Name"). The first element indicates whether
+ * every parent along the way was present; there's nothing to validate inside a null/missing parent.
+ *
+ * @param {Object} value The object to look inside
+ * @param {string} path The dot-delimited path to the value
+ * @returns {[boolean, *]}
+ */
+function lookupValue(value, path) {
+    const keys = path.split('.');
+    for (let i = 0; i < keys.length; i++) {
+        if (value === null || typeof value !== 'object') {
+            return [false, undefined];
+        }
+        value = value[keys[i]];
+    }
+    return [true, value];
+}
+
+/**
+ * Checks a single value against its constraints, returning the rule that failed and a message
+ * like ['required', 'is required'] when it's invalid or empty strings when it's valid.
+ *
+ * @param {*} value The field value to check
+ * @param {Object} rule The field's constraints
+ * @returns {[string, string]}
+ */
+function validateValue(value, rule) {
+    const isObject = value !== null && typeof value === 'object';
+    const empty = value === null || typeof value === 'undefined' || value === '' || value === 0 || value === false ||
+        (Array.isArray(value) && value.length === 0) ||
+        (isObject && !Array.isArray(value) && Object.keys(value).length === 0);
+    if (empty) {
+        return rule.required ? ['required', 'is required'] : ['', ''];
+    }
+
+    let size, units;
+    if (typeof value === 'string') {
+        [size, units] = [[...value].length, ' characters'];
+    } else if (Array.isArray(value)) {
+        [size, units] = [value.length, ' items'];
+    } else if (typeof value === 'number') {
+        [size, units] = [value, ''];
+    } else if (isObject) {
+        [size, units] = [Object.keys(value).length, ' items'];
+    }
+    if (typeof size !== 'undefined') {
+        const sizeMessage = (comparison, limit) => units === ' items'
+            ? 'must have ' + comparison + ' ' + limit + units
+            : 'must be ' + comparison + ' ' + limit + units;
+
+        if (typeof rule.len !== 'undefined' && size !== rule.len) {
+            return ['len', sizeMessage('exactly', rule.len)];
+        }
+        if (typeof rule.min !== 'undefined' && size < rule.min) {
+            return ['min', sizeMessage('at least', rule.min)];
+        }
+        if (typeof rule.max !== 'undefined' && size > rule.max) {
+            return ['max', sizeMessage('at most', rule.max)];
+        }
+    }
+
+    if (rule.oneOf && !rule.oneOf.includes(String(value))) {
+        return ['oneof', 'must be one of [' + rule.oneOf.join(' ') + ']'];
+    }
+    if (typeof value !== 'string') {
+        return ['', ''];
+    }
+    if (rule.regex && !new RegExp(rule.regex).test(value)) {
+        return ['regex', 'must match the pattern ' + rule.regex];
+    }
+    if (rule.email && !/^[^\s@]+@[^\s@]+$/.test(value)) {
+        return ['email', 'must be a valid email address'];
+    }
+    return ['', ''];
+}
+
 /**
  * URLValues helps convert a single request object into a map of individual attributes that can
  * be easily added to a path or query string.
@@ -354,10 +463,27 @@ async function newError(response) {
         ? await response.json()
         : await response.text();
 
-    // One of the framework's standard status/message errors, already.
-    throw (body['Status'] && body['Message'])
-        ? new GatewayError(body['Status'], body['Message'])
-        : new GatewayError(response.status, parseErrorMessage(body));
+    // One of the framework's standard status/message errors, already. Hold onto the code, details,
+    // and retry hints so that callers can make decisions without parsing the message.
+    if (body['Status'] && body['Message']) {
+        throw new GatewayError(body['Status'], body['Message'], {
+            code: body['Code'],
+            details: body['Details'],
+            retryable: body['Retryable'],
+            retryAfter: body['RetryAfter'] || toInt(response.headers.get('Retry-After')) || 0,
+        });
+    }
+
+    // The gateway uses WithProblemDetails(), so this is an RFC 7807 problem document.
+    if (body['status'] && (body['detail'] || body['title'])) {
+        throw new GatewayError(body['status'], body['detail'] || body['title'], {
+            code: body['code'],
+            details: body['details'],
+            retryable: body['retryable'],
+            retryAfter: body['retryAfter'] || toInt(response.headers.get('Retry-After')) || 0,
+        });
+    }
+    throw new GatewayError(response.status, parseErrorMessage(body));
 }
 
 /**
@@ -385,11 +511,14 @@ function dispositionFileName(contentDisposition = '') {
 }
 
 /**
- * Determines whether or not the response has a content type of JSON.
+ * Determines whether or not the response has a content type of JSON (including problem+json errors).
  */
 function isJSON(response) {
     const contentType = response.headers.get('content-type');
-    return contentType && contentType.toLowerCase().startsWith('application/json');
+    return contentType && (
+        contentType.toLowerCase().startsWith('application/json') ||
+        contentType.toLowerCase().startsWith('application/problem+json')
+    );
 }
 
 /**
@@ -492,9 +621,42 @@ class GatewayError {
     */
     message;
 
-    constructor(status, message) {
+    /**
+    * The optional, machine-readable code that identifies this failure (e.g. "USER_NOT_FOUND").
+    *
+    * @type {string}
+    */
+    code;
+
+    /**
+    * Finer-grained descriptions of the failure such as field violations. Each one has
+    * a 'Message' as well as an optional 'Field' and 'Code'.
+    *
+    * @type {Object[]}
+    */
+    details;
+
+    /**
+    * Indicates that the same request might succeed if you try again later.
+    *
+    * @type {boolean}
+    */
+    retryable;
+
+    /**
+    * The number of seconds that the server suggested you wait before retrying (0 if no suggestion).
+    *
+    * @type {number}
+    */
+    retryAfter;
+
+    constructor(status, message, {code = '', details = [], retryable = false, retryAfter = 0} = {}) {
         this.Status = this.status = status || 500;
         this.Message = this.message = message;
+        this.Code = this.code = code || '';
+        this.Details = this.details = details || [];
+        this.Retryable = this.retryable = !!retryable || retryAfter > 0;
+        this.RetryAfter = this.retryAfter = retryAfter || 0;
     }
 
     toString() {
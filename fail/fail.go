@@ -3,18 +3,45 @@ package fail
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"time"
 )
 
 // StatusError is an error that maintains not just an error message but an HTTP
 // compatible status code indicating the type/class of error. It's useful for helping
 // you figure out downstream if an occurred because the user didn't have rights or
 // if some record did not exist.
+//
+// Beyond the status and message, you can also include a machine-readable Code, any
+// Details that describe the failure more precisely (e.g. which fields were invalid), and
+// hints about whether the caller should retry. All of these round trip through the
+// gateways and generated clients, so callers can use errors.As() to get at them.
 type StatusError struct {
 	// Status is the HTTP status code that most closely describes this error.
 	Status int `json:"Status"`
 	// Message is the human-readable error message.
 	Message string `json:"Message"`
+	// Code is an optional, stable, machine-readable identifier for this failure (e.g. "USER_NOT_FOUND")
+	// that callers can rely on rather than parsing the English message.
+	Code string `json:"Code,omitempty"`
+	// Details are optional, finer-grained descriptions of the failure such as field violations.
+	Details []ErrorDetail `json:"Details,omitempty"`
+	// Retryable indicates that the same request might succeed if the caller tries again later.
+	Retryable bool `json:"Retryable,omitempty"`
+	// RetryAfter is the number of seconds that the caller should wait before retrying. This
+	// is also sent as the "Retry-After" header in API responses.
+	RetryAfter int `json:"RetryAfter,omitempty"`
+}
+
+// ErrorDetail describes one specific aspect of a failure, such as a single invalid field.
+type ErrorDetail struct {
+	// Field is the name/path of the request field that this detail refers to, if any (e.g. "User.Name").
+	Field string `json:"Field,omitempty"`
+	// Code is an optional machine-readable identifier for this specific problem (e.g. "required").
+	Code string `json:"Code,omitempty"`
+	// Message is the human-readable description of the problem.
+	Message string `json:"Message"`
 }
 
 // StatusCode returns the most relevant HTTP-style status code describing this type of error.
@@ -27,6 +54,29 @@ func (r StatusError) Error() string {
 	return r.Message
 }
 
+// WithCode returns a copy of this error that has the given machine-readable code.
+//
+//	return nil, fail.NotFound("user not found: %s", req.ID).WithCode("USER_NOT_FOUND")
+func (r StatusError) WithCode(code string) StatusError {
+	r.Code = code
+	return r
+}
+
+// WithDetails returns a copy of this error that includes the given details in addition
+// to any details the error already had.
+func (r StatusError) WithDetails(details ...ErrorDetail) StatusError {
+	r.Details = append(append([]ErrorDetail{}, r.Details...), details...)
+	return r
+}
+
+// WithRetry returns a copy of this error that indicates the caller can retry the request after
+// the given delay. A delay of zero indicates that it's retryable, but there is no suggested delay.
+func (r StatusError) WithRetry(after time.Duration) StatusError {
+	r.Retryable = true
+	r.RetryAfter = int(math.Ceil(after.Seconds()))
+	return r
+}
+
 // New creates an error that maps directly to an HTTP status so if your method results in
 // this error, it will result in the same 'status' in your HTTP response. While you can do this
 // for more obscure HTTP failure statuses like "payment required", it's typically a better idea
@@ -61,6 +111,59 @@ func Status(err error) int {
 	return http.StatusInternalServerError
 }
 
+// From converts any error into a StatusError w/ the error's status and message. If there is a StatusError
+// somewhere in the chain of wrapped errors, its code, details, and retry hints are preserved, too.
+func From(err error) StatusError {
+	if err == nil {
+		return StatusError{}
+	}
+
+	result := StatusError{}
+	_ = errors.As(err, &result)
+	result.Status = Status(err)
+	result.Message = err.Error()
+	return result
+}
+
+// Code returns the machine-readable code of the StatusError in the chain of wrapped errors. It
+// returns an empty string if the error doesn't have one.
+func Code(err error) string {
+	var statusErr StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code
+	}
+	return ""
+}
+
+// Details returns the details of the StatusError in the chain of wrapped errors, if any.
+func Details(err error) []ErrorDetail {
+	var statusErr StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Details
+	}
+	return nil
+}
+
+// IsRetryable returns true if the StatusError in the chain of wrapped errors indicates that the
+// caller could retry the request. This will be true for any error you created using WithRetry().
+func IsRetryable(err error) bool {
+	var statusErr StatusError
+	return errors.As(err, &statusErr) && statusErr.Retryable
+}
+
+// Matches returns true if the error is the failure identified by the status and code. When the error
+// has a code, that's all we compare since it's more specific. Otherwise, we compare the statuses. This
+// is what the generated helpers for the "ERRORS" doc option (e.g. IsUserNotFound) use under the hood.
+func Matches(err error, status int, code string) bool {
+	if err == nil {
+		return false
+	}
+	if errCode := Code(err); errCode != "" {
+		return errCode == code
+	}
+	return Status(err) == status
+}
+
 // Unexpected is a generic 500-style catch-all error for failures you don't know what to do with. This is
 // exactly the same as calling InternalServerError(), just more concise in your code.
 func Unexpected(messageFormat string, args ...any) StatusError {
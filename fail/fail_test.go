@@ -5,6 +5,7 @@ package fail_test
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/monadicstack/abide/fail"
 	"github.com/stretchr/testify/suite"
@@ -52,6 +53,95 @@ func (suite *FailSuite) TestStatus() {
 	suite.Equal(404, fail.Status(errWithStatusCode{statusCode: 404}))
 }
 
+// The fluent helpers should return copies w/ the extra info, leaving the original alone.
+func (suite *FailSuite) TestWithCodeDetailsRetry() {
+	original := fail.NotFound("user not found")
+	err := original.
+		WithCode("USER_NOT_FOUND").
+		WithDetails(fail.ErrorDetail{Field: "ID", Message: "unknown"}).
+		WithDetails(fail.ErrorDetail{Field: "Org", Code: "gone", Message: "deleted"}).
+		WithRetry(1500 * time.Millisecond)
+
+	suite.assertError(err, 404, "user not found")
+	suite.Equal("USER_NOT_FOUND", err.Code)
+	suite.Equal([]fail.ErrorDetail{
+		{Field: "ID", Message: "unknown"},
+		{Field: "Org", Code: "gone", Message: "deleted"},
+	}, err.Details)
+	suite.True(err.Retryable)
+	suite.Equal(2, err.RetryAfter)
+
+	suite.Equal("", original.Code)
+	suite.Nil(original.Details)
+	suite.False(original.Retryable)
+}
+
+// The extra info should be available through wrapped errors, too.
+func (suite *FailSuite) TestCodeDetailsRetryable() {
+	err := fmt.Errorf("wrapped: %w", fail.Throttled("slow down").WithCode("SLOW_DOWN").WithRetry(0))
+	suite.Equal("SLOW_DOWN", fail.Code(err))
+	suite.True(fail.IsRetryable(err))
+	suite.Nil(fail.Details(err))
+
+	err = fmt.Errorf("plain")
+	suite.Equal("", fail.Code(err))
+	suite.False(fail.IsRetryable(err))
+	suite.Nil(fail.Details(err))
+}
+
+func (suite *FailSuite) TestMatches() {
+	suite.True(fail.Matches(fail.NotFound("nope"), 404, "UserNotFound"))
+	suite.True(fail.Matches(fail.NotFound("nope").WithCode("UserNotFound"), 404, "UserNotFound"))
+	suite.True(fail.Matches(fmt.Errorf("wrapped: %w", fail.NotFound("nope").WithCode("UserNotFound")), 404, "UserNotFound"))
+	suite.True(fail.Matches(errWithCode{code: 404}, 404, "UserNotFound"))
+
+	// A different code is a different failure, even if the status is the same.
+	suite.False(fail.Matches(fail.NotFound("nope").WithCode("GroupNotFound"), 404, "UserNotFound"))
+	suite.False(fail.Matches(fail.BadRequest("nope"), 404, "UserNotFound"))
+	suite.False(fail.Matches(nil, 404, "UserNotFound"))
+}
+
+func (suite *FailSuite) TestFrom() {
+	suite.Equal(fail.StatusError{}, fail.From(nil))
+	suite.Equal(fail.StatusError{Status: 500, Message: "plain"}, fail.From(fmt.Errorf("plain")))
+	suite.Equal(fail.StatusError{Status: 503, Message: ""}, fail.From(errWithCode{code: 503}))
+
+	err := fmt.Errorf("wrapped: %w", fail.AlreadyExists("dupe").WithCode("DUPE").WithRetry(time.Second))
+	suite.Equal(fail.StatusError{
+		Status:     409,
+		Message:    "wrapped: dupe",
+		Code:       "DUPE",
+		Retryable:  true,
+		RetryAfter: 1,
+	}, fail.From(err))
+}
+
+// Problem documents should carry everything from the error and convert back to the same error.
+func (suite *FailSuite) TestProblem() {
+	err := fail.Throttled("slow down").
+		WithCode("SLOW_DOWN").
+		WithDetails(fail.ErrorDetail{Code: "quota", Message: "100/min"}).
+		WithRetry(time.Minute)
+
+	problem := fail.NewProblem(err, "trace-123")
+	suite.Equal(fail.Problem{
+		Type:       "about:blank",
+		Title:      "Too Many Requests",
+		Status:     429,
+		Detail:     "slow down",
+		Instance:   "trace-123",
+		Code:       "SLOW_DOWN",
+		Details:    []fail.ErrorDetail{{Code: "quota", Message: "100/min"}},
+		Retryable:  true,
+		RetryAfter: 60,
+	}, problem)
+	suite.Equal(err, problem.StatusError())
+
+	// No detail? Use the title as the message.
+	problem = fail.Problem{Type: "about:blank", Title: "Not Found", Status: 404}
+	suite.Equal(fail.StatusError{Status: 404, Message: "Not Found"}, problem.StatusError())
+}
+
 func (suite *FailSuite) TestUnexpected() {
 	expectedStatus := 500
 	suite.assertError(fail.Unexpected("foo"), expectedStatus, "foo")
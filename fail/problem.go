@@ -0,0 +1,76 @@
+package fail
+
+import (
+	"net/http"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem documents.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 "problem details" document that describes a failed request. The standard
+// members come from the error's status and message while the code, details, and retry hints are
+// included as extension members.
+//
+//	{
+//	    "type": "about:blank",
+//	    "title": "Not Found",
+//	    "status": 404,
+//	    "detail": "user not found: 123",
+//	    "instance": "6c0bb44a-...",
+//	    "code": "USER_NOT_FOUND"
+//	}
+type Problem struct {
+	// Type is a URI that identifies the type of problem. We always use "about:blank", which
+	// indicates that the problem has no more semantics than the HTTP status itself.
+	Type string `json:"type"`
+	// Title is the short summary of the problem type; the standard text for the HTTP status.
+	Title string `json:"title"`
+	// Status is the HTTP status code for this occurrence of the problem.
+	Status int `json:"status"`
+	// Detail is the human-readable explanation specific to this occurrence of the problem.
+	Detail string `json:"detail,omitempty"`
+	// Instance identifies this specific occurrence of the problem (e.g. the request's trace ID).
+	Instance string `json:"instance,omitempty"`
+	// Code is the machine-readable code of the original error, if any.
+	Code string `json:"code,omitempty"`
+	// Details are the finer-grained descriptions of the original error, if any.
+	Details []ErrorDetail `json:"details,omitempty"`
+	// Retryable indicates that the same request might succeed if the caller tries again later.
+	Retryable bool `json:"retryable,omitempty"`
+	// RetryAfter is the number of seconds that the caller should wait before retrying.
+	RetryAfter int `json:"retryAfter,omitempty"`
+}
+
+// NewProblem creates the problem document that describes the given error. The instance is
+// typically the trace ID of the request that failed.
+func NewProblem(err error, instance string) Problem {
+	statusErr := From(err)
+	return Problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(statusErr.Status),
+		Status:     statusErr.Status,
+		Detail:     statusErr.Message,
+		Instance:   instance,
+		Code:       statusErr.Code,
+		Details:    statusErr.Details,
+		Retryable:  statusErr.Retryable,
+		RetryAfter: statusErr.RetryAfter,
+	}
+}
+
+// StatusError converts the problem document back into the equivalent StatusError. When the problem
+// doesn't have a detail message, the title is used instead.
+func (p Problem) StatusError() StatusError {
+	message := p.Detail
+	if message == "" {
+		message = p.Title
+	}
+	return StatusError{
+		Status:     p.Status,
+		Message:    message,
+		Code:       p.Code,
+		Details:    p.Details,
+		Retryable:  p.Retryable,
+		RetryAfter: p.RetryAfter,
+	}
+}
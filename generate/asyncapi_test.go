@@ -0,0 +1,143 @@
+//go:build unit
+
+package generate_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/generate"
+	"github.com/monadicstack/abide/parser"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v3"
+)
+
+type AsyncAPISuite struct {
+	suite.Suite
+}
+
+// eval runs the standard AsyncAPI template for a service where Create is a plain API function, Notify
+// listens for Create (and GroupService.Delete an hour later), and Hidden is "HTTP OMIT".
+func (suite *AsyncAPISuite) eval() map[string]any {
+	request := &parser.TypeDeclaration{Name: "UserRequest", Kind: reflect.Struct}
+	response := &parser.TypeDeclaration{Name: "UserResponse", Kind: reflect.Struct}
+
+	ctx := &parser.Context{
+		Path:      "user_service.go",
+		Timestamp: time.Now(),
+		Service: &parser.ServiceDeclaration{
+			Name:    "UserService",
+			Version: "1.2.3",
+			Gateway: &parser.GatewayServiceOptions{},
+			Functions: parser.ServiceFunctionDeclarations{
+				{
+					Name:          "Create",
+					Request:       request,
+					Response:      response,
+					Documentation: parser.DocumentationLines{"Create makes a new user."},
+					Routes: parser.GatewayRoutes{
+						{GatewayType: "API", Method: "POST", Path: "/user", Status: 200},
+					},
+				},
+				{
+					Name:     "Notify",
+					Request:  request,
+					Response: response,
+					Routes: parser.GatewayRoutes{
+						{GatewayType: "EVENTS", Method: "ON", Path: "UserService.Create"},
+						{GatewayType: "EVENTS", Method: "ON", Path: "GroupService.Delete", Delay: time.Hour},
+						{GatewayType: "EVENTS", Method: "COMPENSATE", Path: "UserService.Create"},
+					},
+				},
+				{
+					Name:     "Hidden",
+					Request:  request,
+					Response: response,
+				},
+			},
+		},
+		Types: parser.TypeRegistry{
+			"UserRequest":  request,
+			"UserResponse": response,
+		},
+	}
+
+	output, err := generate.NewStandardTemplate("asyncapi.yml", "templates/asyncapi.yml.tmpl").Eval(ctx)
+	suite.Require().NoError(err)
+
+	doc := map[string]any{}
+	suite.Require().NoError(yaml.Unmarshal(output, &doc), "Output should be valid YAML:\n%s", output)
+	return doc
+}
+
+func (suite *AsyncAPISuite) lookup(value any, keys ...string) any {
+	for _, key := range keys {
+		values, ok := value.(map[string]any)
+		suite.Require().True(ok, "Unable to find '%s' in %v", key, keys)
+		value = values[key]
+	}
+	return value
+}
+
+func (suite *AsyncAPISuite) TestInfo() {
+	doc := suite.eval()
+	suite.Equal("3.0.0", doc["asyncapi"])
+	suite.Equal("UserService", suite.lookup(doc, "info", "title"))
+	suite.Equal("1.2.3", suite.lookup(doc, "info", "version"))
+}
+
+// Every function publishes an event, even if it doesn't have an API route. We should also include
+// channels from other services that we listen to.
+func (suite *AsyncAPISuite) TestChannels() {
+	doc := suite.eval()
+
+	channels := suite.lookup(doc, "channels").(map[string]any)
+	suite.Len(channels, 4)
+	suite.Equal("UserService.Create", suite.lookup(channels, "UserService.Create", "address"))
+	suite.Equal("UserService.Notify", suite.lookup(channels, "UserService.Notify", "address"))
+	suite.Equal("UserService.Hidden", suite.lookup(channels, "UserService.Hidden", "address"))
+	suite.Equal("GroupService.Delete", suite.lookup(channels, "GroupService.Delete", "address"))
+
+	suite.Equal("#/components/schemas/AbideEvent", suite.lookup(channels, "UserService.Create", "messages", "event", "payload", "$ref"))
+	suite.Equal("#/components/schemas/UserResponse", suite.lookup(channels, "UserService.Create", "messages", "event", "x-abide-values", "$ref"))
+	suite.Nil(suite.lookup(channels, "GroupService.Delete", "messages", "event", "x-abide-values"))
+}
+
+func (suite *AsyncAPISuite) TestOperations() {
+	doc := suite.eval()
+
+	operations := suite.lookup(doc, "operations").(map[string]any)
+	suite.Len(operations, 5)
+
+	suite.Equal("send", suite.lookup(operations, "UserService.Create.publish", "action"))
+	suite.Equal("#/channels/UserService.Create", suite.lookup(operations, "UserService.Create.publish", "channel", "$ref"))
+	suite.NotNil(operations["UserService.Notify.publish"])
+	suite.NotNil(operations["UserService.Hidden.publish"])
+
+	// Subscribers should include the consumer group that the events gateway actually uses.
+	subscriber := operations["UserService.Notify.on.UserService.Create"]
+	suite.Equal("receive", suite.lookup(subscriber, "action"))
+	suite.Equal("#/channels/UserService.Create", suite.lookup(subscriber, "channel", "$ref"))
+	suite.Equal("UserService.Notify", suite.lookup(subscriber, "bindings", "nats", "queue"))
+	suite.Equal("#/components/schemas/UserRequest", suite.lookup(subscriber, "x-abide-request", "$ref"))
+	suite.Nil(suite.lookup(subscriber, "x-abide-delay"))
+
+	delayed := operations["UserService.Notify.Delay1h0m0s.on.GroupService.Delete"]
+	suite.Equal("receive", suite.lookup(delayed, "action"))
+	suite.Equal("UserService.Notify.Delay1h0m0s", suite.lookup(delayed, "bindings", "nats", "queue"))
+	suite.Equal("1h0m0s", suite.lookup(delayed, "x-abide-delay"))
+}
+
+func (suite *AsyncAPISuite) TestSchemas() {
+	doc := suite.eval()
+
+	schemas := suite.lookup(doc, "components", "schemas").(map[string]any)
+	suite.Contains(schemas, "AbideEvent")
+	suite.Contains(schemas, "UserRequest")
+	suite.Contains(schemas, "UserResponse")
+}
+
+func TestAsyncAPISuite(t *testing.T) {
+	suite.Run(t, new(AsyncAPISuite))
+}
@@ -5,6 +5,7 @@ package generate_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
@@ -168,6 +169,13 @@ func (suite *GoClientSuite) TestFail4XX() {
 	ctx, client := suite.init(address)
 	_, err := client.Fail4XX(ctx, &testext.SampleRequest{})
 	suite.ErrorMatches(err, 409, "always a conflict")
+
+	// The code and details should survive the round trip, too.
+	statusErr := fail.StatusError{}
+	suite.Require().True(errors.As(err, &statusErr))
+	suite.Equal("ALWAYS_CONFLICT", statusErr.Code)
+	suite.Equal([]fail.ErrorDetail{{Field: "ID", Code: "duplicate", Message: "already exists"}}, statusErr.Details)
+	suite.False(fail.IsRetryable(err))
 }
 
 // Ensures that the client reports back 5XX style errors when they're returned.
@@ -178,6 +186,12 @@ func (suite *GoClientSuite) TestFail5XX() {
 	ctx, client := suite.init(address)
 	_, err := client.Fail5XX(ctx, &testext.SampleRequest{})
 	suite.ErrorMatches(err, 502, "always a bad gateway")
+
+	statusErr := fail.StatusError{}
+	suite.Require().True(errors.As(err, &statusErr))
+	suite.True(statusErr.Retryable)
+	suite.Equal(5, statusErr.RetryAfter)
+	suite.Equal("", fail.Code(err))
 }
 
 // Ensures that we can define a custom method/path and still send data properly.
@@ -263,6 +277,78 @@ func (suite *GoClientSuite) TestDownloadResumable() {
 	suite.Equal("<h1>The Dude Abides</h1>", string(content))
 }
 
+// Ensures that the client can receive a live stream of values using server-sent events.
+func (suite *GoClientSuite) TestProgress() {
+	address, shutdown := suite.startServer()
+	defer shutdown()
+
+	ctx, client := suite.init(address)
+	res, err := client.Progress(ctx, &testext.SampleRequest{Text: "Abide"})
+	suite.Require().NoError(err)
+
+	var events []testext.SampleResponse
+	for event := range res.Events() {
+		events = append(events, event)
+	}
+	suite.NoError(res.Err())
+	suite.Equal([]testext.SampleResponse{
+		{ID: "1", Text: "Abide"},
+		{ID: "2", Text: "Abide"},
+		{ID: "3", Text: "Abide"},
+	}, events)
+}
+
+// Ensures that canceling the context stops the stream early without hanging the client or server.
+func (suite *GoClientSuite) TestProgress_cancel() {
+	address, shutdown := suite.startServer()
+	defer shutdown()
+
+	ctx, client := suite.init(address)
+	ctx, cancel := context.WithCancel(ctx)
+	res, err := client.Progress(ctx, &testext.SampleRequest{Text: "Abide"})
+	suite.Require().NoError(err)
+
+	event := <-res.Events()
+	suite.Equal("1", event.ID)
+	cancel()
+
+	for range res.Events() {
+		// Drain anything that was already on its way so that the stream can close.
+	}
+	suite.ErrorIs(res.Err(), context.Canceled)
+}
+
+// Ensures that the client can upload a raw stream of data rather than auto-encoding the request.
+func (suite *GoClientSuite) TestUpload() {
+	address, shutdown := suite.startServer()
+	defer shutdown()
+
+	ctx, client := suite.init(address)
+	req := &testext.SampleUploadRequest{ID: "123", Text: "Abide"}
+	req.SetContent(io.NopCloser(strings.NewReader("The Dude abides")))
+	req.SetContentType("text/plain")
+	req.SetContentFileName("dude.txt")
+
+	res, err := client.Upload(ctx, req)
+	suite.Require().NoError(err)
+	suite.Equal("123", res.ID)
+	suite.Equal("Abide:dude.txt:text/plain:The Dude abides", res.Text)
+}
+
+// Ensures that uploads still work when you don't supply any of the optional content attributes.
+func (suite *GoClientSuite) TestUpload_defaults() {
+	address, shutdown := suite.startServer()
+	defer shutdown()
+
+	ctx, client := suite.init(address)
+	req := &testext.SampleUploadRequest{ID: "123", Text: "Abide"}
+	req.SetContent(io.NopCloser(strings.NewReader("The Dude abides")))
+
+	res, err := client.Upload(ctx, req)
+	suite.Require().NoError(err)
+	suite.Equal("Abide::application/octet-stream:The Dude abides", res.Text)
+}
+
 // Ensures that the client can handle receiving a redirect and reads it in as a raw stream.
 func (suite *GoClientSuite) TestRedirect() {
 	address, shutdown := suite.startServer()
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/monadicstack/abide/fail"
 	"github.com/monadicstack/abide/internal/testext"
 	"github.com/stretchr/testify/suite"
 )
@@ -116,7 +117,9 @@ func (suite *JavaScriptClientSuite) TestFail4XX() {
 	defer shutdown()
 
 	output := suite.Run("Fail4XX", address, 1)
-	suite.ExpectFail(output[0], 409, "always a conflict")
+	err := suite.ExpectFail(output[0], 409, "always a conflict")
+	suite.Equal("ALWAYS_CONFLICT", err.Code)
+	suite.Equal([]fail.ErrorDetail{{Field: "ID", Code: "duplicate", Message: "already exists"}}, err.Details)
 }
 
 // Ensures that the client reports back 5XX style errors when they're returned.
@@ -125,7 +128,9 @@ func (suite *JavaScriptClientSuite) TestFail5XX() {
 	defer shutdown()
 
 	output := suite.Run("Fail5XX", address, 1)
-	suite.ExpectFail(output[0], 502, "always a bad gateway")
+	err := suite.ExpectFail(output[0], 502, "always a bad gateway")
+	suite.True(err.Retryable)
+	suite.Equal(5, err.RetryAfter)
 }
 
 // Ensures that we can define a custom method/path and still send data properly.
@@ -211,6 +216,35 @@ func (suite *JavaScriptClientSuite) TestDownloadResumable() {
 	})
 }
 
+// Ensures that the client can receive a live stream of values using server-sent events.
+func (suite *JavaScriptClientSuite) TestProgress() {
+	address, shutdown := suite.startServer()
+	defer shutdown()
+
+	output := suite.Run("Progress", address, 1)
+	var res []testext.SampleResponse
+	suite.ExpectPass(output[0], &res, func() {
+		suite.Equal([]testext.SampleResponse{
+			{ID: "1", Text: "Abide"},
+			{ID: "2", Text: "Abide"},
+			{ID: "3", Text: "Abide"},
+		}, res)
+	})
+}
+
+// Ensures that the client can upload a raw stream of data rather than auto-encoding the request.
+func (suite *JavaScriptClientSuite) TestUpload() {
+	address, shutdown := suite.startServer()
+	defer shutdown()
+
+	output := suite.Run("Upload", address, 1)
+	res := testext.SampleResponse{}
+	suite.ExpectPass(output[0], &res, func() {
+		suite.Equal("123", res.ID)
+		suite.Equal("Abide:dude.txt:text/plain:The Dude abides", res.Text)
+	})
+}
+
 // Ensures that the client can handle receiving a redirect and reads it in as a raw stream.
 func (suite *JavaScriptClientSuite) TestRedirect() {
 	address, shutdown := suite.startServer()
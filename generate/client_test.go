@@ -76,7 +76,9 @@ func (suite *GeneratedClientSuite) ExpectPass(result ClientTestResult, out any,
 //	suite.ExpectFail(output[0], 403, "forbidden")
 //	suite.ExpectFail(output[1], 502, "bad gateway")
 //	suite.ExpectFail(output[2], 500, "wtf")
-func (suite *GeneratedClientSuite) ExpectFail(result ClientTestResult, status int, msg string) {
+//
+// It returns the decoded error, so you can make additional assertions about its code, details, etc.
+func (suite *GeneratedClientSuite) ExpectFail(result ClientTestResult, status int, msg string) fail.StatusError {
 	err := fail.StatusError{}
 
 	// If the failure string matches our {"Status":404, "Message":"Not Found"} format, decode it
@@ -88,6 +90,7 @@ func (suite *GeneratedClientSuite) ExpectFail(result ClientTestResult, status in
 	suite.Require().Equal(false, result.Pass, "Line %d: Passed when it should have failed: %s", result.Index, result.String())
 	suite.Equal(status, err.StatusCode())
 	suite.Contains(strings.ToLower(err.Error()), strings.ToLower(msg))
+	return err
 }
 
 // RunExternalTest executes the language-specific runner to execute a single test case in that language. The result
@@ -0,0 +1,55 @@
+//go:build unit
+
+package generate_test
+
+import (
+	"testing"
+
+	"github.com/monadicstack/abide/generate"
+	"github.com/stretchr/testify/suite"
+)
+
+type ClientErrorsSuite struct {
+	suite.Suite
+}
+
+func (suite *ClientErrorsSuite) eval(name string, path string) string {
+	output, err := generate.NewStandardTemplate(name, path).Eval(userServiceContext())
+	suite.Require().NoError(err)
+	return string(output)
+}
+
+// The Go client should have an "IsXxx" helper for each error declared using the ERRORS doc option.
+func (suite *ClientErrorsSuite) TestGo() {
+	output := suite.eval("client.go", "templates/client.go.tmpl")
+
+	suite.Contains(output, `func IsUserNotFound(err error) bool {
+	return fail.Matches(err, 404, "NotFound")
+}`)
+	suite.Contains(output, `func IsUserConflict(err error) bool {
+	return fail.Matches(err, 409, "Conflict")
+}`)
+	suite.Contains(output, `func IsUserGroupNotFound(err error) bool {
+	return fail.Matches(err, 404, "GroupNotFound")
+}`)
+}
+
+// The JS client should export an "isXxx" helper for each error declared using the ERRORS doc option.
+func (suite *ClientErrorsSuite) TestJavaScript() {
+	output := suite.eval("client.js", "templates/client.js.tmpl")
+
+	suite.Contains(output, `function isUserNotFound(err) {
+    // When the error has a code, it's more specific than the status, so that's all we compare.
+    return !!err && (err.code ? err.code === 'NotFound' : err.status === 404);
+}`)
+	suite.Contains(output, `module.exports = {
+    UserServiceClient,
+    isUserNotFound,
+    isUserConflict,
+    isUserGroupNotFound,
+};`)
+}
+
+func TestClientErrorsSuite(t *testing.T) {
+	suite.Run(t, new(ClientErrorsSuite))
+}
@@ -3,17 +3,23 @@ package generate
 import (
 	"bytes"
 	"embed"
+	"encoding/json"
 	"fmt"
 	"go/format"
 	"io/fs"
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/monadicstack/abide/internal/naming"
 	"github.com/monadicstack/abide/internal/quiet"
+	"github.com/monadicstack/abide/internal/slices"
 	"github.com/monadicstack/abide/parser"
 )
 
@@ -174,6 +180,30 @@ var templateFuncs = template.FuncMap{
 	"JavaType":       javaFunctions{}.convertType,
 	"DartType":       dartFunctions{}.convertType,
 	"OpenAPIPath":    openapiFunctions{}.convertPath,
+
+	// Validation helpers for client-side checks
+	"ValidatedFields":    validationFunctions{}.fields,
+	"JSValidationRule":   validationFunctions{}.jsRule,
+	"DartValidationRule": validationFunctions{}.dartRule,
+
+	// OpenAPI-specific helpers
+	"OpenAPIRequired":    openapiFunctions{}.required,
+	"OpenAPIConstraints": openapiFunctions{}.constraints,
+	"OpenAPIErrors":      openapiFunctions{}.errors,
+
+	// AsyncAPI-specific helpers
+	"AsyncAPIChannels": asyncapiFunctions{}.channels,
+
+	// GraphQL-specific helpers
+	"GraphQLQueries":     graphqlFunctions{}.queries,
+	"GraphQLMutations":   graphqlFunctions{}.mutations,
+	"GraphQLObjects":     graphqlFunctions{}.objects,
+	"GraphQLFieldName":   graphqlFunctions{}.fieldName,
+	"GraphQLTypeName":    graphqlFunctions{}.typeName,
+	"GraphQLResultType":  graphqlFunctions{}.resultType,
+	"GraphQLOutputType":  graphqlFunctions{}.outputType,
+	"GraphQLInputType":   graphqlFunctions{}.inputType,
+	"GraphQLDescription": graphqlFunctions{}.description,
 }
 
 type jsFunctions struct{}
@@ -336,3 +366,396 @@ func (funcs openapiFunctions) convertPath(path string) string {
 		return "/" + path
 	*/
 }
+
+// errors groups the function's declared "ERRORS" by status, since each status can only appear once in
+// an OpenAPI operation's responses. Descriptions for the same status are combined.
+func (funcs openapiFunctions) errors(function *parser.ServiceFunctionDeclaration) parser.ErrorResponses {
+	var results parser.ErrorResponses
+	byStatus := map[int]*parser.ErrorResponse{}
+	for _, errorResponse := range function.Errors {
+		if existing, ok := byStatus[errorResponse.Status]; ok {
+			existing.Description += "; " + errorResponse.Description
+			continue
+		}
+		grouped := *errorResponse
+		byStatus[grouped.Status] = &grouped
+		results = append(results, &grouped)
+	}
+	return results
+}
+
+// required returns the binding names of all of the type's fields that have the "required" validation rule.
+func (funcs openapiFunctions) required(t *parser.TypeDeclaration) []string {
+	var names []string
+	for _, field := range t.NonOmittedFields() {
+		if field.Binding.Validation != nil && field.Binding.Validation.Required {
+			names = append(names, field.Binding.Name)
+		}
+	}
+	return names
+}
+
+// constraints converts the field's `validate` rules into the equivalent JSON schema keywords, one YAML
+// line per keyword (e.g. "minLength: 3" or "format: email"). Whether "min" becomes minLength, minItems, or
+// minimum depends on the type of the field.
+func (funcs openapiFunctions) constraints(field *parser.FieldDeclaration) []string {
+	rules := field.Binding.Validation
+	if rules == nil || !field.Type.Basic {
+		return nil
+	}
+
+	minKey, maxKey := "minimum", "maximum"
+	switch field.Type.Kind {
+	case reflect.String:
+		minKey, maxKey = "minLength", "maxLength"
+	case reflect.Array, reflect.Slice:
+		minKey, maxKey = "minItems", "maxItems"
+	case reflect.Map:
+		minKey, maxKey = "minProperties", "maxProperties"
+	}
+
+	number := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	quote := func(value string) string {
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+
+	var lines []string
+	switch {
+	case rules.Len != nil:
+		lines = append(lines, minKey+": "+number(*rules.Len), maxKey+": "+number(*rules.Len))
+	default:
+		if rules.Min != nil {
+			lines = append(lines, minKey+": "+number(*rules.Min))
+		}
+		if rules.Max != nil {
+			lines = append(lines, maxKey+": "+number(*rules.Max))
+		}
+	}
+	if rules.Regex != "" && field.Type.Kind == reflect.String {
+		lines = append(lines, "pattern: "+quote(rules.Regex))
+	}
+	if rules.Email && field.Type.Kind == reflect.String {
+		lines = append(lines, "format: email")
+	}
+	if len(rules.OneOf) > 0 {
+		values := rules.OneOf
+		if field.Type.Kind == reflect.String {
+			values = slices.Map(values, quote)
+		}
+		lines = append(lines, "enum: ["+strings.Join(values, ", ")+"]")
+	}
+	return lines
+}
+
+type validationFunctions struct{}
+
+// validatedField is a field on a request (or one of its nested structs) that has `validate` constraints. The
+// Path is the dot-delimited binding path to the field (e.g. "User.Name") just like the server reports it.
+type validatedField struct {
+	Path  string
+	Rules *parser.FieldValidationOptions
+}
+
+// fields returns every field in the type that has `validate` constraints, including the fields of nested
+// structs. They're in the same order that the server checks them, so client-side failures have the same
+// message as the server's.
+func (funcs validationFunctions) fields(t *parser.TypeDeclaration) []validatedField {
+	return funcs.collectFields(t, "", map[*parser.TypeDeclaration]bool{})
+}
+
+func (funcs validationFunctions) collectFields(t *parser.TypeDeclaration, prefix string, visited map[*parser.TypeDeclaration]bool) []validatedField {
+	if t == nil || visited[t] {
+		return nil
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	var results []validatedField
+	for _, field := range t.NonOmittedFields() {
+		path := prefix + field.Binding.Name
+		if field.Binding.Validation != nil {
+			results = append(results, validatedField{Path: path, Rules: field.Binding.Validation})
+		}
+		if field.Type.Kind == reflect.Struct && !field.Type.Implements.MarshalJSON {
+			results = append(results, funcs.collectFields(field.Type, path+".", visited)...)
+		}
+	}
+	return results
+}
+
+// jsRule converts the field's constraints to a JS object literal like "{"path":"Name","required":true,"max":50}".
+func (funcs validationFunctions) jsRule(field validatedField) (string, error) {
+	rule := struct {
+		Path     string   `json:"path"`
+		Required bool     `json:"required,omitempty"`
+		Min      *float64 `json:"min,omitempty"`
+		Max      *float64 `json:"max,omitempty"`
+		Len      *float64 `json:"len,omitempty"`
+		Regex    string   `json:"regex,omitempty"`
+		OneOf    []string `json:"oneOf,omitempty"`
+		Email    bool     `json:"email,omitempty"`
+	}{
+		Path:     field.Path,
+		Required: field.Rules.Required,
+		Min:      field.Rules.Min,
+		Max:      field.Rules.Max,
+		Len:      field.Rules.Len,
+		Regex:    field.Rules.Regex,
+		OneOf:    field.Rules.OneOf,
+		Email:    field.Rules.Email,
+	}
+	ruleJSON, err := json.Marshal(rule)
+	return string(ruleJSON), err
+}
+
+// dartRule converts the field's constraints to a Dart constructor call like "_ValidationRule('Name', required: true, max: 50)".
+func (funcs validationFunctions) dartRule(field validatedField) string {
+	quote := func(value string) string {
+		value = strings.ReplaceAll(value, `\`, `\\`)
+		value = strings.ReplaceAll(value, `'`, `\'`)
+		value = strings.ReplaceAll(value, `$`, `\$`)
+		return "'" + value + "'"
+	}
+	number := func(value float64) string {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	args := []string{quote(field.Path)}
+	if field.Rules.Required {
+		args = append(args, "required: true")
+	}
+	if field.Rules.Min != nil {
+		args = append(args, "min: "+number(*field.Rules.Min))
+	}
+	if field.Rules.Max != nil {
+		args = append(args, "max: "+number(*field.Rules.Max))
+	}
+	if field.Rules.Len != nil {
+		args = append(args, "len: "+number(*field.Rules.Len))
+	}
+	if field.Rules.Regex != "" {
+		args = append(args, "regex: "+quote(field.Rules.Regex))
+	}
+	if len(field.Rules.OneOf) > 0 {
+		args = append(args, "oneOf: ["+strings.Join(slices.Map(field.Rules.OneOf, quote), ", ")+"]")
+	}
+	if field.Rules.Email {
+		args = append(args, "email: true")
+	}
+	return "_ValidationRule(" + strings.Join(args, ", ") + ")"
+}
+
+type asyncapiFunctions struct{}
+
+// asyncapiChannel describes a single event key on the broker that this service publishes to
+// and/or subscribes to.
+type asyncapiChannel struct {
+	// Key is the event key/subject on the broker (e.g. "UserService.Create").
+	Key string
+	// Publisher is the function that publishes this event. It's nil when the event is published by
+	// some other service, and this service only subscribes to it.
+	Publisher *parser.ServiceFunctionDeclaration
+	// Subscribers are all of this service's functions that listen for this event.
+	Subscribers []asyncapiSubscriber
+}
+
+// asyncapiSubscriber describes one of the "ON Service.Method" routes for a service function.
+type asyncapiSubscriber struct {
+	// Function is the service function that handles the event.
+	Function *parser.ServiceFunctionDeclaration
+	// Group is the consumer group the events gateway uses for this subscription.
+	Group string
+	// Delay is how long after the event the function runs (e.g. "ON UserService.Create DELAY 1h").
+	Delay time.Duration
+}
+
+// channels builds the list of every event channel that the service interacts with. Every function
+// publishes an event when it completes successfully (even "HTTP OMIT" ones), so every function gets
+// a channel. We add channels for other services' events that our functions listen for after that.
+// Compensation routes are omitted since those are private to the saga machinery.
+func (funcs asyncapiFunctions) channels(ctx *parser.Context) []*asyncapiChannel {
+	var channels []*asyncapiChannel
+	channelsByKey := map[string]*asyncapiChannel{}
+
+	for _, function := range ctx.Service.Functions {
+		channel := &asyncapiChannel{Key: ctx.Service.Name + "." + function.Name, Publisher: function}
+		channels = append(channels, channel)
+		channelsByKey[channel.Key] = channel
+	}
+
+	var external []*asyncapiChannel
+	for _, function := range ctx.Service.Functions {
+		for _, route := range function.Routes.Events() {
+			if route.Method != "ON" {
+				continue
+			}
+
+			channel, ok := channelsByKey[route.Path]
+			if !ok {
+				channel = &asyncapiChannel{Key: route.Path}
+				channelsByKey[route.Path] = channel
+				external = append(external, channel)
+			}
+
+			// This needs to match the consumer groups that the events gateway actually uses.
+			group := ctx.Service.Name + "." + function.Name
+			if route.Delay > 0 {
+				group += ".Delay" + route.Delay.String()
+			}
+			channel.Subscribers = append(channel.Subscribers, asyncapiSubscriber{
+				Function: function,
+				Group:    group,
+				Delay:    route.Delay,
+			})
+		}
+	}
+
+	sort.SliceStable(external, func(i, j int) bool {
+		return external[i].Key < external[j].Key
+	})
+	return append(channels, external...)
+}
+
+type graphqlFunctions struct{}
+
+// queries returns the functions that the GraphQL gateway exposes on the "Query" type; the ones whose API route
+// uses GET. Everything else that has an API route is a mutation.
+func (funcs graphqlFunctions) queries(ctx *parser.Context) parser.ServiceFunctionDeclarations {
+	var results parser.ServiceFunctionDeclarations
+	for _, function := range funcs.functions(ctx) {
+		if function.Routes.API().MethodMatches(http.MethodGet) {
+			results = append(results, function)
+		}
+	}
+	return results
+}
+
+// mutations returns the functions that the GraphQL gateway exposes on the "Mutation" type; every function
+// with an API route that doesn't use GET.
+func (funcs graphqlFunctions) mutations(ctx *parser.Context) parser.ServiceFunctionDeclarations {
+	var results parser.ServiceFunctionDeclarations
+	for _, function := range funcs.functions(ctx) {
+		if !function.Routes.API().MethodMatches(http.MethodGet) {
+			results = append(results, function)
+		}
+	}
+	return results
+}
+
+// functions returns every function that the GraphQL gateway can serve. That's the same set of functions
+// as the API gateway minus the ones that accept/return raw content or event streams.
+func (funcs graphqlFunctions) functions(ctx *parser.Context) parser.ServiceFunctionDeclarations {
+	var results parser.ServiceFunctionDeclarations
+	for _, function := range ctx.Service.Functions {
+		switch {
+		case function.Routes.API() == nil:
+			continue
+		case function.Request.Implements.ContentSetter:
+			continue
+		case function.Response.Implements.ContentGetter, function.Response.Implements.EventStreamer:
+			continue
+		}
+		results = append(results, function)
+	}
+	return results
+}
+
+// objects returns all of the struct types that we describe using both an object type and an input type,
+// sorted by name so that the schema doesn't change every time you regenerate it. Types with custom JSON
+// marshaling use the JSON scalar instead, since we have no idea what shape they'll take.
+func (funcs graphqlFunctions) objects(ctx *parser.Context) []*parser.TypeDeclaration {
+	var results []*parser.TypeDeclaration
+	for _, t := range ctx.Types.NonBasicTypes() {
+		if t.Kind == reflect.Struct && !t.Implements.MarshalJSON {
+			results = append(results, t)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool {
+		return funcs.typeName(results[i]) < funcs.typeName(results[j])
+	})
+	return results
+}
+
+// fieldName returns the name of the Query/Mutation field for the function (e.g. "UserService_GetByID"). We
+// include the service name so that you can combine the schemas for multiple services into one.
+func (funcs graphqlFunctions) fieldName(function *parser.ServiceFunctionDeclaration) string {
+	return function.Service.Name + "_" + function.Name
+}
+
+// typeName returns the GraphQL type name for a struct; just the type's name without the package. The gateway
+// uses the same rule when it resolves "__typename", so the two always line up.
+func (funcs graphqlFunctions) typeName(t *parser.TypeDeclaration) string {
+	return naming.NoPackage(naming.NoPointer(naming.CleanPrefix(t.Name)))
+}
+
+// resultType returns the GraphQL type for the value that a function returns. It's nullable, so that a failure
+// in one function doesn't wipe out the results of the others in the same query.
+func (funcs graphqlFunctions) resultType(t *parser.TypeDeclaration) string {
+	return funcs.convertType(t, "")
+}
+
+// outputType returns the GraphQL type we use when a field shows up in a response (e.g. "String!" or "[User!]").
+// Go values are never null, so only pointers, slices, maps, and custom types can be.
+func (funcs graphqlFunctions) outputType(field *parser.FieldDeclaration) string {
+	t := funcs.convertType(field.Type, "")
+	if field.Pointer || strings.HasPrefix(field.Type.Name, "*") || t == "JSON" || strings.HasPrefix(t, "[") {
+		return t
+	}
+	return t + "!"
+}
+
+// inputType returns the GraphQL type we use when a field shows up in a request (e.g. "String" or "[UserInput]").
+// Every argument is optional, just like it is in the API gateway.
+func (funcs graphqlFunctions) inputType(field *parser.FieldDeclaration) string {
+	return funcs.convertType(field.Type, "Input")
+}
+
+func (funcs graphqlFunctions) convertType(t *parser.TypeDeclaration, objectSuffix string) string {
+	if t.Implements.MarshalJSON {
+		return "JSON"
+	}
+	switch t.Kind {
+	case reflect.String:
+		return "String"
+	case reflect.Bool:
+		return "Boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "Int"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "Int"
+	case reflect.Float32, reflect.Float64:
+		return "Float"
+	case reflect.Array, reflect.Slice:
+		// The JSON encoder sends byte slices as base64 strings, not arrays of numbers.
+		if t.Kind == reflect.Slice && (t.Elem.Name == "byte" || t.Elem.Name == "uint8") {
+			return "String"
+		}
+		elemType := funcs.convertType(t.Elem, objectSuffix)
+		if objectSuffix == "" && elemType != "JSON" && !strings.HasPrefix(t.Elem.Name, "*") {
+			elemType += "!"
+		}
+		return "[" + elemType + "]"
+	case reflect.Struct:
+		return funcs.typeName(t) + objectSuffix
+	default:
+		return "JSON"
+	}
+}
+
+// description formats doc comments as a GraphQL block string where every line starts with the given indentation.
+// It's blank when there aren't any comments.
+func (funcs graphqlFunctions) description(indent string, docs parser.DocumentationLines) string {
+	docs = docs.Trim()
+	if docs.Empty() {
+		return ""
+	}
+
+	lines := []string{indent + `"""`}
+	for _, line := range docs {
+		lines = append(lines, indent+strings.ReplaceAll(line, `"""`, `\"""`))
+	}
+	lines = append(lines, indent+`"""`)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,205 @@
+package generate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/monadicstack/abide/parser"
+)
+
+// NewGraph builds the event flow graph for all of the given services. Every service function becomes a
+// node, and every "ON Service.Method" (or "COMPENSATE WITH") doc option becomes an edge from the function
+// that publishes the event to the function that handles it. If a function listens for an event from a
+// function that isn't in any of the services, we still add a node for it, but mark it as missing.
+func NewGraph(contexts ...*parser.Context) *Graph {
+	graph := &Graph{nodes: map[string]*GraphNode{}}
+
+	// Add all of the "real" nodes first, so that we can tell which event keys point at functions
+	// that don't exist once we start connecting them.
+	for _, ctx := range contexts {
+		if ctx == nil || ctx.Service == nil {
+			continue
+		}
+		for _, function := range ctx.Service.Functions {
+			graph.node(ctx.Service.Name, function.Name, false)
+		}
+	}
+
+	for _, ctx := range contexts {
+		if ctx == nil || ctx.Service == nil {
+			continue
+		}
+		for _, function := range ctx.Service.Functions {
+			to := graph.node(ctx.Service.Name, function.Name, false)
+			for _, route := range function.Routes.Events() {
+				graph.connect(route, to)
+			}
+		}
+	}
+
+	graph.sort()
+	return graph
+}
+
+// Graph describes the event-driven workflows between service functions. It's the root value fed to
+// the "graph.mmd" and "graph.dot" templates.
+type Graph struct {
+	// Services contains all of the nodes in the graph grouped by the service they belong to.
+	Services []*GraphService
+	// Edges contains all of the event subscriptions between the functions in the graph.
+	Edges []*GraphEdge
+	// Warnings describe problems we found while building the graph, such as functions that
+	// listen for events from functions that don't exist.
+	Warnings []string
+
+	nodes map[string]*GraphNode
+}
+
+// GraphService groups all of the nodes in the graph that belong to the same service.
+type GraphService struct {
+	// Name is the name of the service (e.g. "UserService").
+	Name string
+	// Nodes are all of the service's functions that appear in the graph.
+	Nodes []*GraphNode
+}
+
+// GraphNode is a single service function in the event flow graph.
+type GraphNode struct {
+	// ID is the identifier for this node that is safe to use in Mermaid/DOT (e.g. "UserService_Create").
+	ID string
+	// ServiceName is the name of the service that this function belongs to (e.g. "UserService").
+	ServiceName string
+	// Name is the name of the function (e.g. "Create").
+	Name string
+	// Missing is true when some function listens for this one, but it isn't defined in any of the services.
+	Missing bool
+}
+
+// QualifiedName returns the "ServiceName.Name" identifier for this node (e.g. "UserService.Create").
+func (node GraphNode) QualifiedName() string {
+	return node.ServiceName + "." + node.Name
+}
+
+// GraphEdge indicates that the 'To' function is invoked when the 'From' function publishes its event.
+type GraphEdge struct {
+	// From is the function that publishes the event (e.g. the "UserService.Create" in "ON UserService.Create").
+	From *GraphNode
+	// To is the function that handles the event.
+	To *GraphNode
+	// Method is the type of subscription: "ON" for normal listeners or "COMPENSATE" when the 'To' function
+	// undoes the 'From' function when a saga fails.
+	Method string
+	// Delay is how long after the event the 'To' function runs (e.g. "ON UserService.Create DELAY 1h").
+	Delay time.Duration
+}
+
+// Label returns a short description of the edge for display purposes. It's blank for plain "ON" edges.
+func (edge GraphEdge) Label() string {
+	switch {
+	case edge.Method == "COMPENSATE":
+		return "compensate"
+	case edge.Delay > 0:
+		return "delay " + edge.Delay.String()
+	default:
+		return ""
+	}
+}
+
+// Compensate returns true if this edge is for a "COMPENSATE WITH" doc option rather than a normal listener.
+func (edge GraphEdge) Compensate() bool {
+	return edge.Method == "COMPENSATE"
+}
+
+// MissingNodes returns all of the nodes that are referenced by a listener, but don't exist in any service.
+func (graph *Graph) MissingNodes() []*GraphNode {
+	var missing []*GraphNode
+	for _, service := range graph.Services {
+		for _, node := range service.Nodes {
+			if node.Missing {
+				missing = append(missing, node)
+			}
+		}
+	}
+	return missing
+}
+
+// connect adds the edge for the given event route, which belongs to the 'to' function.
+func (graph *Graph) connect(route *parser.GatewayRoute, to *GraphNode) {
+	// Event keys are always "ServiceName.MethodName", so anything else can't possibly be a function.
+	serviceName, name, ok := strings.Cut(route.Path, ".")
+	if !ok || serviceName == "" || name == "" {
+		graph.Warnings = append(graph.Warnings, fmt.Sprintf("%s listens for '%s', which is not a valid ServiceName.MethodName event",
+			to.QualifiedName(), route.Path))
+		return
+	}
+
+	from, exists := graph.nodes[route.Path]
+	if !exists {
+		from = graph.node(serviceName, name, true)
+	}
+	if from.Missing {
+		graph.Warnings = append(graph.Warnings, fmt.Sprintf("%s listens for %s, but that function does not exist",
+			to.QualifiedName(), route.Path))
+	}
+
+	graph.Edges = append(graph.Edges, &GraphEdge{
+		From:   from,
+		To:     to,
+		Method: route.Method,
+		Delay:  route.Delay,
+	})
+}
+
+// node fetches the node for the given function, adding it to the graph if it's not already there.
+func (graph *Graph) node(serviceName string, name string, missing bool) *GraphNode {
+	qualifiedName := serviceName + "." + name
+	if node, ok := graph.nodes[qualifiedName]; ok {
+		return node
+	}
+
+	node := &GraphNode{
+		ID:          graphNodeID(serviceName, name),
+		ServiceName: serviceName,
+		Name:        name,
+		Missing:     missing,
+	}
+	graph.nodes[qualifiedName] = node
+
+	for _, service := range graph.Services {
+		if service.Name == serviceName {
+			service.Nodes = append(service.Nodes, node)
+			return node
+		}
+	}
+	graph.Services = append(graph.Services, &GraphService{Name: serviceName, Nodes: []*GraphNode{node}})
+	return node
+}
+
+// sort puts the services/nodes in alphabetical order, so the output is the same no matter what
+// order you supply the service files in. Edges stay in the order they were declared.
+func (graph *Graph) sort() {
+	sort.SliceStable(graph.Services, func(i, j int) bool {
+		return graph.Services[i].Name < graph.Services[j].Name
+	})
+	for _, service := range graph.Services {
+		nodes := service.Nodes
+		sort.SliceStable(nodes, func(i, j int) bool {
+			return nodes[i].Name < nodes[j].Name
+		})
+	}
+}
+
+// graphNodeID creates an identifier that both Mermaid and DOT are happy with (no dots, spaces, etc).
+func graphNodeID(serviceName string, name string) string {
+	id := []rune(serviceName + "_" + name)
+	for i, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+		default:
+			id[i] = '_'
+		}
+	}
+	return string(id)
+}
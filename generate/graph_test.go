@@ -0,0 +1,158 @@
+//go:build unit
+
+package generate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/generate"
+	"github.com/monadicstack/abide/parser"
+	"github.com/stretchr/testify/suite"
+)
+
+type GraphSuite struct {
+	suite.Suite
+}
+
+func (suite *GraphSuite) service(name string, functions ...*parser.ServiceFunctionDeclaration) *parser.Context {
+	return &parser.Context{
+		Service: &parser.ServiceDeclaration{Name: name, Functions: functions},
+	}
+}
+
+func (suite *GraphSuite) function(name string, routes ...*parser.GatewayRoute) *parser.ServiceFunctionDeclaration {
+	return &parser.ServiceFunctionDeclaration{Name: name, Routes: routes}
+}
+
+func (suite *GraphSuite) on(key string, delay time.Duration) *parser.GatewayRoute {
+	return &parser.GatewayRoute{GatewayType: "EVENTS", Method: "ON", Path: key, Delay: delay}
+}
+
+// contexts creates a set of services where the Dude rolls, Walter listens (after a delay), Donny listens
+// for a function that doesn't exist, and Walter's rolling is undone by the Dude.
+func (suite *GraphSuite) contexts() []*parser.Context {
+	return []*parser.Context{
+		suite.service("WalterService",
+			suite.function("Roll", suite.on("DudeService.Roll", 90*time.Minute)),
+			suite.function("Shomer", &parser.GatewayRoute{GatewayType: "API", Method: "POST", Path: "/shomer"}),
+		),
+		suite.service("DudeService",
+			suite.function("Roll"),
+			suite.function("Abide",
+				suite.on("DonnyService.Bowl", 0),
+				&parser.GatewayRoute{GatewayType: "EVENTS", Method: "COMPENSATE", Path: "WalterService.Roll"},
+			),
+		),
+		nil,
+	}
+}
+
+func (suite *GraphSuite) TestNewGraph_empty() {
+	graph := generate.NewGraph()
+	suite.Empty(graph.Services)
+	suite.Empty(graph.Edges)
+	suite.Empty(graph.Warnings)
+	suite.Empty(graph.MissingNodes())
+}
+
+// Services and nodes should be alphabetical no matter which order the files were given in.
+func (suite *GraphSuite) TestNewGraph_nodes() {
+	graph := generate.NewGraph(suite.contexts()...)
+	suite.Require().Len(graph.Services, 3)
+
+	suite.Equal("DonnyService", graph.Services[0].Name)
+	suite.Require().Len(graph.Services[0].Nodes, 1)
+	suite.Equal("DonnyService_Bowl", graph.Services[0].Nodes[0].ID)
+	suite.True(graph.Services[0].Nodes[0].Missing)
+
+	suite.Equal("DudeService", graph.Services[1].Name)
+	suite.Require().Len(graph.Services[1].Nodes, 2)
+	suite.Equal("DudeService.Abide", graph.Services[1].Nodes[0].QualifiedName())
+	suite.Equal("DudeService.Roll", graph.Services[1].Nodes[1].QualifiedName())
+	suite.False(graph.Services[1].Nodes[0].Missing)
+	suite.False(graph.Services[1].Nodes[1].Missing)
+
+	suite.Equal("WalterService", graph.Services[2].Name)
+	suite.Require().Len(graph.Services[2].Nodes, 2)
+	suite.Equal("WalterService.Roll", graph.Services[2].Nodes[0].QualifiedName())
+	suite.Equal("WalterService.Shomer", graph.Services[2].Nodes[1].QualifiedName())
+}
+
+func (suite *GraphSuite) TestNewGraph_edges() {
+	graph := generate.NewGraph(suite.contexts()...)
+	suite.Require().Len(graph.Edges, 3)
+
+	suite.Equal("DudeService.Roll", graph.Edges[0].From.QualifiedName())
+	suite.Equal("WalterService.Roll", graph.Edges[0].To.QualifiedName())
+	suite.Equal("delay 1h30m0s", graph.Edges[0].Label())
+	suite.False(graph.Edges[0].Compensate())
+
+	suite.Equal("DonnyService.Bowl", graph.Edges[1].From.QualifiedName())
+	suite.Equal("DudeService.Abide", graph.Edges[1].To.QualifiedName())
+	suite.Equal("", graph.Edges[1].Label())
+
+	suite.Equal("WalterService.Roll", graph.Edges[2].From.QualifiedName())
+	suite.Equal("DudeService.Abide", graph.Edges[2].To.QualifiedName())
+	suite.Equal("compensate", graph.Edges[2].Label())
+	suite.True(graph.Edges[2].Compensate())
+}
+
+// Listeners for functions that don't exist should be flagged.
+func (suite *GraphSuite) TestNewGraph_missing() {
+	graph := generate.NewGraph(suite.contexts()...)
+
+	missing := graph.MissingNodes()
+	suite.Require().Len(missing, 1)
+	suite.Equal("DonnyService.Bowl", missing[0].QualifiedName())
+
+	suite.Require().Len(graph.Warnings, 1)
+	suite.Contains(graph.Warnings[0], "DudeService.Abide")
+	suite.Contains(graph.Warnings[0], "DonnyService.Bowl")
+}
+
+// Event keys that can't possibly be functions should be reported, but not added to the graph.
+func (suite *GraphSuite) TestNewGraph_invalidKey() {
+	graph := generate.NewGraph(suite.service("DudeService",
+		suite.function("Abide", suite.on("Bowling", 0)),
+	))
+	suite.Require().Len(graph.Services, 1)
+	suite.Empty(graph.Edges)
+	suite.Require().Len(graph.Warnings, 1)
+	suite.Contains(graph.Warnings[0], "Bowling")
+}
+
+func (suite *GraphSuite) TestEval_mermaid() {
+	graph := generate.NewGraph(suite.contexts()...)
+	output, err := generate.NewStandardTemplate("graph.mmd", "templates/graph.mmd.tmpl").Eval(graph)
+	suite.Require().NoError(err)
+
+	text := string(output)
+	suite.Contains(text, "flowchart LR")
+	suite.Contains(text, "subgraph DudeService")
+	suite.Contains(text, `DudeService_Abide["Abide"]`)
+	suite.Contains(text, `DonnyService_Bowl["Bowl (missing)"]:::missing`)
+	suite.Contains(text, "DudeService_Roll -->|delay 1h30m0s| WalterService_Roll")
+	suite.Contains(text, "DonnyService_Bowl --> DudeService_Abide")
+	suite.Contains(text, "WalterService_Roll -.->|compensate| DudeService_Abide")
+	suite.Contains(text, "classDef missing")
+}
+
+func (suite *GraphSuite) TestEval_dot() {
+	graph := generate.NewGraph(suite.contexts()...)
+	output, err := generate.NewStandardTemplate("graph.dot", "templates/graph.dot.tmpl").Eval(graph)
+	suite.Require().NoError(err)
+
+	text := string(output)
+	suite.Contains(text, "digraph abide {")
+	suite.Contains(text, "subgraph cluster_DudeService {")
+	suite.Contains(text, `DudeService_Abide [label="Abide"];`)
+	suite.Contains(text, `DonnyService_Bowl [label="Bowl", xlabel="missing", color=red`)
+	suite.Contains(text, `DudeService_Roll -> WalterService_Roll [label="delay 1h30m0s"];`)
+	suite.Contains(text, "DonnyService_Bowl -> DudeService_Abide;")
+	suite.Contains(text, `WalterService_Roll -> DudeService_Abide [label="compensate", style=dashed];`)
+}
+
+func TestGraphSuite(t *testing.T) {
+	suite.Run(t, new(GraphSuite))
+}
@@ -0,0 +1,164 @@
+//go:build unit
+
+package generate_test
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/generate"
+	"github.com/monadicstack/abide/parser"
+	"github.com/stretchr/testify/suite"
+)
+
+type GraphQLSuite struct {
+	suite.Suite
+}
+
+// eval runs the standard GraphQL schema template for a service where GetByID is a query, Create is
+// a mutation, Download returns raw content, and Hidden is "HTTP OMIT".
+func (suite *GraphQLSuite) eval() string {
+	str := &parser.TypeDeclaration{Name: "string", Kind: reflect.String, Basic: true}
+	integer := &parser.TypeDeclaration{Name: "int", Kind: reflect.Int, Basic: true}
+	timestamp := &parser.TypeDeclaration{Name: "time.Time", Kind: reflect.String, Basic: true}
+	custom := &parser.TypeDeclaration{Name: "Custom", Kind: reflect.Struct}
+	custom.Implements.MarshalJSON = true
+
+	contact := &parser.TypeDeclaration{Name: "ContactInfo", Kind: reflect.Struct}
+	contact.Fields = parser.FieldDeclarations{
+		{Name: "Email", Type: str, ParentType: contact, Binding: &parser.FieldBindingOptions{Name: "email"}},
+	}
+	contacts := &parser.TypeDeclaration{Name: "[]ContactInfo", Kind: reflect.Slice, Elem: contact, Basic: true}
+	tags := &parser.TypeDeclaration{Name: "[]string", Kind: reflect.Slice, Elem: str, Basic: true}
+	labels := &parser.TypeDeclaration{Name: "map[string]string", Kind: reflect.Map, Key: str, Elem: str, Basic: true}
+
+	request := &parser.TypeDeclaration{Name: "UserRequest", Kind: reflect.Struct}
+	request.Fields = parser.FieldDeclarations{
+		{Name: "ID", Type: str, ParentType: request, Binding: &parser.FieldBindingOptions{Name: "ID"}},
+		{Name: "Contact", Type: contact, ParentType: request, Binding: &parser.FieldBindingOptions{Name: "Contact"}},
+		{Name: "Secret", Type: str, ParentType: request, Binding: &parser.FieldBindingOptions{Name: "Secret", Omit: true}},
+	}
+
+	response := &parser.TypeDeclaration{Name: "UserResponse", Kind: reflect.Struct}
+	response.Documentation = parser.DocumentationLines{"UserResponse is a user."}
+	response.Fields = parser.FieldDeclarations{
+		{Name: "ID", Type: str, ParentType: response, Binding: &parser.FieldBindingOptions{Name: "ID"}, Documentation: parser.DocumentationLines{"ID is unique."}},
+		{Name: "Age", Type: integer, ParentType: response, Binding: &parser.FieldBindingOptions{Name: "Age"}},
+		{Name: "Created", Type: timestamp, ParentType: response, Binding: &parser.FieldBindingOptions{Name: "Created"}, Pointer: true},
+		{Name: "Contacts", Type: contacts, ParentType: response, Binding: &parser.FieldBindingOptions{Name: "Contacts"}},
+		{Name: "Tags", Type: tags, ParentType: response, Binding: &parser.FieldBindingOptions{Name: "Tags"}},
+		{Name: "Labels", Type: labels, ParentType: response, Binding: &parser.FieldBindingOptions{Name: "Labels"}},
+		{Name: "Custom", Type: custom, ParentType: response, Binding: &parser.FieldBindingOptions{Name: "Custom"}},
+	}
+
+	empty := &parser.TypeDeclaration{Name: "EmptyResponse", Kind: reflect.Struct}
+	download := &parser.TypeDeclaration{Name: "DownloadResponse", Kind: reflect.Struct}
+	download.Implements.ContentGetter = true
+
+	service := &parser.ServiceDeclaration{
+		Name:          "UserService",
+		Version:       "1.2.3",
+		Gateway:       &parser.GatewayServiceOptions{},
+		Documentation: parser.DocumentationLines{"UserService manages users."},
+	}
+	service.Functions = parser.ServiceFunctionDeclarations{
+		{
+			Name:          "GetByID",
+			Service:       service,
+			Request:       request,
+			Response:      response,
+			Documentation: parser.DocumentationLines{"GetByID looks up a user."},
+			Routes:        parser.GatewayRoutes{{GatewayType: "API", Method: "GET", Path: "/user/{ID}", Status: 200}},
+		},
+		{
+			Name:     "Create",
+			Service:  service,
+			Request:  request,
+			Response: empty,
+			Routes:   parser.GatewayRoutes{{GatewayType: "API", Method: "POST", Path: "/user", Status: 201}},
+		},
+		{
+			Name:     "Download",
+			Service:  service,
+			Request:  request,
+			Response: download,
+			Routes:   parser.GatewayRoutes{{GatewayType: "API", Method: "GET", Path: "/user/download", Status: 200}},
+		},
+		{
+			Name:     "Hidden",
+			Service:  service,
+			Request:  request,
+			Response: response,
+		},
+	}
+
+	ctx := &parser.Context{
+		Path:      "user_service.go",
+		Timestamp: time.Now(),
+		Service:   service,
+		Types: parser.TypeRegistry{
+			"string":        str,
+			"int":           integer,
+			"time.time":     timestamp,
+			"custom":        custom,
+			"contactinfo":   contact,
+			"userrequest":   request,
+			"userresponse":  response,
+			"emptyresponse": empty,
+		},
+	}
+
+	output, err := generate.NewStandardTemplate("schema.graphql", "templates/schema.graphql.tmpl").Eval(ctx)
+	suite.Require().NoError(err)
+	return string(output)
+}
+
+// definition finds the "type Foo { ... }" or "input Foo { ... }" block in the schema and returns
+// its fields w/ all whitespace collapsed, so we don't need to worry about formatting.
+func (suite *GraphQLSuite) definition(schema string, kind string, name string) string {
+	matches := regexp.MustCompile(`(?s)\n` + kind + ` ` + name + ` \{(.*?)\n\}`).FindStringSubmatch(schema)
+	suite.Require().Len(matches, 2, "Unable to find '%s %s' in schema:\n%s", kind, name, schema)
+	return strings.Join(strings.Fields(matches[1]), " ")
+}
+
+// GET functions are queries and everything else is a mutation. Functions without an API route and
+// ones that deal in raw content are left out.
+func (suite *GraphQLSuite) TestQueriesAndMutations() {
+	schema := suite.eval()
+	suite.Contains(schema, "scalar JSON")
+
+	query := suite.definition(schema, "type", "Query")
+	suite.Equal(`""" GetByID looks up a user. """ UserService_GetByID(ID: String, Contact: ContactInfoInput): UserResponse`, query)
+
+	mutation := suite.definition(schema, "type", "Mutation")
+	suite.Equal(`UserService_Create(ID: String, Contact: ContactInfoInput): EmptyResponse`, mutation)
+
+	suite.NotContains(schema, "UserService_Download")
+	suite.NotContains(schema, "UserService_Hidden")
+	suite.NotContains(schema, "Secret")
+}
+
+// Every struct should have both an object type for responses and an input type for requests.
+func (suite *GraphQLSuite) TestTypes() {
+	schema := suite.eval()
+
+	suite.Equal(`""" ID is unique. """ ID: String! Age: Int! Created: String Contacts: [ContactInfo!] Tags: [String!] Labels: JSON Custom: JSON`,
+		suite.definition(schema, "type", "UserResponse"))
+	suite.Contains(schema, "\"\"\"\nUserResponse is a user.\n\"\"\"\ntype UserResponse {")
+	suite.Equal(`ID: String Contact: ContactInfoInput`, suite.definition(schema, "input", "UserRequestInput"))
+	suite.Equal(`email: String!`, suite.definition(schema, "type", "ContactInfo"))
+	suite.Equal(`email: String`, suite.definition(schema, "input", "ContactInfoInput"))
+
+	// GraphQL doesn't allow empty types, so there should be a placeholder field.
+	suite.Contains(suite.definition(schema, "type", "EmptyResponse"), "_: Boolean")
+
+	// Types w/ custom JSON marshaling are just JSON scalars.
+	suite.NotContains(schema, "type Custom")
+}
+
+func TestGraphQLSuite(t *testing.T) {
+	suite.Run(t, new(GraphQLSuite))
+}
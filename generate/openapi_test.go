@@ -0,0 +1,196 @@
+//go:build unit
+
+package generate_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/generate"
+	"github.com/monadicstack/abide/parser"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v3"
+)
+
+type OpenAPISuite struct {
+	suite.Suite
+}
+
+// userServiceContext creates a service w/ a "GET /user" function whose request has a bunch of
+// `validate` constraints and that declares a couple of errors, so we can make sure that they show
+// up in the docs/clients we generate. It also has a timeout, rate limit, max body size, and cache
+// control, so we can check the generated server.
+func userServiceContext() *parser.Context {
+	float := func(value float64) *float64 { return &value }
+	stringType := &parser.TypeDeclaration{Name: "string", Kind: reflect.String, Basic: true}
+	intType := &parser.TypeDeclaration{Name: "int", Kind: reflect.Int, Basic: true}
+	sliceType := &parser.TypeDeclaration{Name: "[]string", Kind: reflect.Slice, Basic: true, Elem: stringType}
+
+	request := &parser.TypeDeclaration{Name: "UserRequest", Kind: reflect.Struct}
+	request.Fields = parser.FieldDeclarations{
+		{Name: "Name", ParentType: request, Type: stringType, Binding: &parser.FieldBindingOptions{
+			Name:       "Name",
+			Validation: &parser.FieldValidationOptions{Required: true, Min: float(2), Max: float(50), Regex: "^[A-Z]'s$"},
+		}},
+		{Name: "Email", ParentType: request, Type: stringType, Binding: &parser.FieldBindingOptions{
+			Name:       "email_address",
+			Validation: &parser.FieldValidationOptions{Email: true},
+		}},
+		{Name: "Age", ParentType: request, Type: intType, Binding: &parser.FieldBindingOptions{
+			Name:       "Age",
+			Validation: &parser.FieldValidationOptions{Min: float(18)},
+		}},
+		{Name: "Role", ParentType: request, Type: stringType, Binding: &parser.FieldBindingOptions{
+			Name:       "Role",
+			Validation: &parser.FieldValidationOptions{OneOf: []string{"admin", "member"}},
+		}},
+		{Name: "Tags", ParentType: request, Type: sliceType, Binding: &parser.FieldBindingOptions{
+			Name:       "Tags",
+			Validation: &parser.FieldValidationOptions{Len: float(3)},
+		}},
+		{Name: "Notes", ParentType: request, Type: stringType, Binding: &parser.FieldBindingOptions{
+			Name: "Notes",
+		}},
+	}
+	response := &parser.TypeDeclaration{Name: "UserResponse", Kind: reflect.Struct}
+
+	function := &parser.ServiceFunctionDeclaration{
+		Name:     "Search",
+		Request:  request,
+		Response: response,
+		Errors: parser.ErrorResponses{
+			{Status: 404, Name: "NotFound", Description: `user "does" not exist`},
+			{Status: 409, Name: "Conflict", Description: "Conflict"},
+			{Status: 404, Name: "GroupNotFound", Description: "group does not exist"},
+		},
+		Timeout:      2500 * time.Millisecond,
+		RateLimit:    &parser.RateLimitOptions{Limit: 100, Period: time.Minute, By: "Authorization"},
+		MaxBodySize:  5 * 1024 * 1024,
+		CacheControl: "private, max-age=60",
+	}
+	function.Routes = parser.GatewayRoutes{
+		{Function: function, GatewayType: "API", Method: "GET", Path: "/user", Status: 200},
+	}
+	ctx := &parser.Context{
+		Path:          "user_service.go",
+		Timestamp:     time.Now(),
+		InputPackage:  &parser.PackageDeclaration{Name: "users", Import: "github.com/example/users"},
+		OutputPackage: &parser.PackageDeclaration{Name: "gen", Import: "github.com/example/users/gen"},
+		Service: &parser.ServiceDeclaration{
+			Name:      "UserService",
+			Version:   "1.2.3",
+			Gateway:   &parser.GatewayServiceOptions{},
+			Functions: parser.ServiceFunctionDeclarations{function},
+		},
+		Types: parser.TypeRegistry{
+			"UserRequest":  request,
+			"UserResponse": response,
+		},
+	}
+	function.Service = ctx.Service
+	return ctx
+}
+
+// eval runs the standard OpenAPI template for the user service so that we can check the schemas.
+func (suite *OpenAPISuite) eval() map[string]any {
+	output, err := generate.NewStandardTemplate("openapi.yml", "templates/openapi.yml.tmpl").Eval(userServiceContext())
+	suite.Require().NoError(err)
+
+	doc := map[string]any{}
+	suite.Require().NoError(yaml.Unmarshal(output, &doc), "Output should be valid YAML:\n%s", output)
+	return doc
+}
+
+func (suite *OpenAPISuite) lookup(value any, keys ...string) any {
+	for _, key := range keys {
+		values, ok := value.(map[string]any)
+		suite.Require().True(ok, "Unable to find '%s' in %v", key, keys)
+		value = values[key]
+	}
+	return value
+}
+
+// The `validate` constraints should become the equivalent JSON schema keywords.
+func (suite *OpenAPISuite) TestValidationSchema() {
+	doc := suite.eval()
+
+	schema := suite.lookup(doc, "components", "schemas", "UserRequest")
+	suite.Equal([]any{"Name"}, suite.lookup(schema, "required"))
+
+	properties := suite.lookup(schema, "properties")
+	suite.Equal(map[string]any{
+		"type":      "string",
+		"minLength": 2,
+		"maxLength": 50,
+		"pattern":   "^[A-Z]'s$",
+	}, suite.lookup(properties, "Name"))
+	suite.Equal(map[string]any{"type": "string", "format": "email"}, suite.lookup(properties, "email_address"))
+	suite.Equal(map[string]any{"type": "number", "minimum": 18}, suite.lookup(properties, "Age"))
+	suite.Equal(map[string]any{"type": "string", "enum": []any{"admin", "member"}}, suite.lookup(properties, "Role"))
+	suite.Equal(map[string]any{
+		"type":     "array",
+		"items":    map[string]any{"type": "string"},
+		"minItems": 3,
+		"maxItems": 3,
+	}, suite.lookup(properties, "Tags"))
+	suite.Equal(map[string]any{"type": "string"}, suite.lookup(properties, "Notes"))
+
+	// The response doesn't have any constraints, so it shouldn't have a required list.
+	suite.Nil(suite.lookup(doc, "components", "schemas", "UserResponse", "required"))
+}
+
+// GET requests send their values in the query string, so the constraints should be on the parameters, too.
+func (suite *OpenAPISuite) TestValidationParameters() {
+	doc := suite.eval()
+
+	parameters := suite.lookup(doc, "paths", "/user", "get", "parameters").([]any)
+	suite.Require().Len(parameters, 6)
+
+	suite.Equal(map[string]any{
+		"in":       "query",
+		"name":     "Name",
+		"required": true,
+		"schema": map[string]any{
+			"type":      "string",
+			"minLength": 2,
+			"maxLength": 50,
+			"pattern":   "^[A-Z]'s$",
+		},
+	}, parameters[0])
+	suite.Equal(map[string]any{
+		"in":     "query",
+		"name":   "Notes",
+		"schema": map[string]any{"type": "string"},
+	}, parameters[5])
+}
+
+// Declared errors should be documented responses; errors w/ the same status are combined.
+func (suite *OpenAPISuite) TestErrorResponses() {
+	doc := suite.eval()
+
+	// The status codes are numbers, so YAML doesn't give us a map[string]any for these.
+	responses, ok := suite.lookup(doc, "paths", "/user", "get", "responses").(map[any]any)
+	suite.Require().True(ok)
+	errorContent := map[string]any{
+		"application/json": map[string]any{
+			"schema": map[string]any{"$ref": "#/components/schemas/StatusError"},
+		},
+	}
+	suite.Equal(map[string]any{
+		"description": `user "does" not exist; group does not exist`,
+		"content":     errorContent,
+	}, responses[404])
+	suite.Equal(map[string]any{
+		"description": "Conflict",
+		"content":     errorContent,
+	}, responses[409])
+
+	schema := suite.lookup(doc, "components", "schemas", "StatusError")
+	suite.Equal("object", suite.lookup(schema, "type"))
+	suite.Equal(map[string]any{"type": "string"}, suite.lookup(schema, "properties", "Code"))
+}
+
+func TestOpenAPISuite(t *testing.T) {
+	suite.Run(t, new(OpenAPISuite))
+}
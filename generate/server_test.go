@@ -0,0 +1,70 @@
+//go:build unit
+
+package generate_test
+
+import (
+	"testing"
+
+	"github.com/monadicstack/abide/generate"
+	"github.com/monadicstack/abide/parser"
+	"github.com/stretchr/testify/suite"
+)
+
+type ServerSuite struct {
+	suite.Suite
+}
+
+func (suite *ServerSuite) eval() string {
+	return suite.evalContext(userServiceContext())
+}
+
+func (suite *ServerSuite) evalContext(ctx *parser.Context) string {
+	output, err := generate.NewStandardTemplate("server.go", "templates/server.go.tmpl").Eval(ctx)
+	suite.Require().NoError(err)
+	return string(output)
+}
+
+// The "TIMEOUT xxx" doc option should carry over to the endpoint in the generated server.
+func (suite *ServerSuite) TestTimeout() {
+	suite.Contains(suite.eval(), "Timeout: 2500000000, // 2.5s")
+}
+
+// The "RATE xxx" doc option should carry over to the endpoint in the generated server.
+func (suite *ServerSuite) TestRateLimit() {
+	suite.Contains(suite.eval(), `RateLimit: &services.RateLimit{
+					Limit:  100,
+					Period: 60000000000, // 1m0s
+					By:     "Authorization",
+				},`)
+}
+
+// Rate limit keys come straight from your doc comments, so they need to be escaped to keep the generated code valid.
+func (suite *ServerSuite) TestRateLimit_escaped() {
+	ctx := userServiceContext()
+	ctx.Service.Functions[0].RateLimit.By = `X-"Key"\`
+	suite.Contains(suite.evalContext(ctx), `By:     "X-\"Key\"\\",`)
+}
+
+// The "GATEWAY xxx" doc option should carry over to the route, escaped so it can't break the generated code.
+func (suite *ServerSuite) TestGateway() {
+	ctx := userServiceContext()
+	ctx.Service.Functions[0].Routes[0].Gateway = "internal"
+	suite.Contains(suite.evalContext(ctx), `Gateway:     "internal",`)
+
+	ctx.Service.Functions[0].Routes[0].Gateway = `in"ternal`
+	suite.Contains(suite.evalContext(ctx), `Gateway:     "in\"ternal",`)
+}
+
+// The "MAX BODY xxx" doc option should carry over to the endpoint in the generated server.
+func (suite *ServerSuite) TestMaxBodySize() {
+	suite.Contains(suite.eval(), "MaxBodySize: 5242880,")
+}
+
+// The "CACHE xxx" doc option should carry over to the endpoint in the generated server.
+func (suite *ServerSuite) TestCacheControl() {
+	suite.Contains(suite.eval(), `CacheControl: "private, max-age=60",`)
+}
+
+func TestServerSuite(t *testing.T) {
+	suite.Run(t, new(ServerSuite))
+}
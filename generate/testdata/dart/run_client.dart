@@ -111,6 +111,22 @@ runTestCase(String hostPort, String name) async {
       var client = new SampleServiceClient(baseURI);
       return outputRaw(client.Redirect(SampleRedirectRequest()));
 
+    case 'Progress':
+      var client = new SampleServiceClient(baseURI);
+      return output(client.Progress(SampleRequest(text: 'Abide')).then((events) => events.toList()));
+
+    case 'Upload':
+      var client = new SampleServiceClient(baseURI);
+      var content = utf8.encode('The Dude abides');
+      return output(client.Upload(SampleUploadRequest(
+        id: '123',
+        text: 'Abide',
+        content: Stream.value(content),
+        contentLength: content.length,
+        contentType: 'text/plain',
+        contentFileName: 'dude.txt',
+      )));
+
     case 'Authorization':
       var client = new SampleServiceClient(baseURI);
       return output(client.Authorization(SampleRequest(), authorization: 'Abide'));
@@ -129,14 +145,13 @@ runTestCase(String hostPort, String name) async {
   }
 }
 
-output(Future<ModelJSON> model) async {
+output(Future<Object> model) async {
   try {
     var jsonString = jsonEncode(await model);
     print('OK ${jsonString}');
   }
   on SampleServiceException catch (err) {
-    var message = err.message.replaceAll('"', '\'').trim();
-    print('FAIL {"status":${err.status}, "message": "${message}"}');
+    print('FAIL ${jsonEncode(_failure(err))}');
   }
   catch (err) {
     print('FAIL {"message": "$err"}');
@@ -153,10 +168,20 @@ outputRaw(Future<ModelStream> modelFuture) async {
     print('OK ${jsonEncode(modelJson)}');
   }
   on SampleServiceException catch (err) {
-    var message = err.message.replaceAll('"', '\'');
-    print('FAIL {"status":${err.status}, "message": "${message}"}');
+    print('FAIL ${jsonEncode(_failure(err))}');
   }
   catch (err) {
     print('FAIL {"message": "$err"}');
   }
 }
+
+Map<String, dynamic> _failure(SampleServiceException err) {
+  return {
+    'Status': err.status,
+    'Message': err.message.trim(),
+    'Code': err.code,
+    'Details': err.details,
+    'Retryable': err.retryable,
+    'RetryAfter': err.retryAfter,
+  };
+}
@@ -358,6 +358,30 @@ class SampleServiceClient {
     }
   }
   
+  /// Progress sends a live stream of updates using server-sent events rather than
+  /// responding with a single value.
+  Future<Stream<SampleResponse>> Progress(SampleRequest serviceRequest, {String authorization = ''}) async {
+  
+    var requestJson = serviceRequest.toJson();
+    var method = 'GET';
+    var route = '/v2/progress';
+    var uri = _joinUrl([baseURL, _buildRequestPath(method, route, requestJson)]);
+
+    try {
+      final request = http.Request(method, Uri.parse(uri));
+      request.headers['Accept'] = 'text/event-stream';
+      request.headers['Authorization'] = _authorize(authorization);
+      request.headers['Content-Type'] = 'application/json';
+
+      final response = await httpClient.send(request);
+      return _handleResponseEvents(response, (json) => SampleResponse.fromJson(json));
+    } on SampleServiceException catch (e) {
+      throw e; // already has status information
+    } catch (e) {
+      throw SampleServiceException(500, e.toString());
+    }
+  }
+  
   /// Redirect results in a 307-style redirect to the Download endpoint.
   Future<SampleRedirectResponse> Redirect(SampleRedirectRequest serviceRequest, {String authorization = ''}) async {
   
@@ -507,8 +531,43 @@ class SampleServiceClient {
     }
   }
   
+  /// Upload accepts a raw stream of data rather than relying on auto-decoding
+  /// the request body.
+  Future<SampleResponse> Upload(SampleUploadRequest serviceRequest, {String authorization = ''}) async {
+  
+    var requestJson = serviceRequest.valuesJson();
+    var method = 'POST';
+    var route = '/v2/upload/{ID}';
+    var uri = _joinUrl([baseURL, _buildRequestPath(method, route, requestJson, upload: true)]);
+
+    try {
+      final request = http.StreamedRequest(method, Uri.parse(uri));
+      request.headers['Accept'] = 'application/json';
+      request.headers['Authorization'] = _authorize(authorization);
+      request.headers['Content-Type'] = serviceRequest.contentType ?? 'application/octet-stream';
+      if ((serviceRequest.contentFileName ?? '') != '') {
+        request.headers['Content-Disposition'] = 'attachment; filename="${serviceRequest.contentFileName}"';
+      }
+      if ((serviceRequest.contentLength ?? 0) > 0) {
+        request.contentLength = serviceRequest.contentLength;
+      }
+      (serviceRequest.content ?? Stream<List<int>>.empty()).listen(
+        request.sink.add,
+        onError: request.sink.addError,
+        onDone: request.sink.close,
+      );
+
+      final response = await httpClient.send(request);
+      return _handleResponseJson(response, (json) => SampleResponse.fromJson(json));
+    } on SampleServiceException catch (e) {
+      throw e; // already has status information
+    } catch (e) {
+      throw SampleServiceException(500, e.toString());
+    }
+  }
+  
 
-  String _buildRequestPath(String method, String route, Map<String, dynamic> requestJson) {
+  String _buildRequestPath(String method, String route, Map<String, dynamic> requestJson, {bool upload = false}) {
     String stringify(Map<String, dynamic> json, String key) {
       return Uri.encodeComponent(json[key]?.toString() ?? '');
     }
@@ -526,8 +585,9 @@ class SampleServiceClient {
       .map((s) => _isParameterSegment(s) ? stringifyAndRemove(requestJson, s.substring(1, s.length - 1)) : s)
       .join('/');
 
-    // These encode the data in the body, so no need to shove it in the query string.
-    if (method == 'POST' || method == 'PUT' || method == 'PATCH') {
+    // These encode the data in the body, so no need to shove it in the query string. Uploads are
+    // the exception since the body is the raw content, so the other values go in the query string.
+    if (!upload && (method == 'POST' || method == 'PUT' || method == 'PATCH')) {
       return resolvedPath;
     }
 
@@ -543,6 +603,40 @@ class SampleServiceClient {
     return segment.startsWith('{') && segment.endsWith('}');
   }
 
+  /// Performs the same `validate` checks that the server does so that we can fail fast without making
+  /// a round trip. Every invalid field is reported in a single 400 error w/ the same message the server uses.
+  void _validateRequest(Map<String, dynamic> requestJson, List<_ValidationRule> rules) {
+    var failures = <Map<String, dynamic>>[];
+    var failedPaths = <String>[];
+    for (var rule in rules) {
+      // Just like the server, once a field fails we don't bother checking the fields inside of it.
+      if (failedPaths.any((path) => rule.path.startsWith(path + '.'))) {
+        continue;
+      }
+      dynamic value = requestJson;
+      var found = true;
+      for (var key in rule.path.split('.')) {
+        if (value is! Map) {
+          found = false;
+          break;
+        }
+        value = value[key];
+      }
+      if (!found) {
+        continue;
+      }
+      var failure = rule.check(value);
+      if (failure[1] != '') {
+        failures.add({'Field': rule.path, 'Code': failure[0], 'Message': failure[1]});
+        failedPaths.add(rule.path);
+      }
+    }
+    if (failures.isNotEmpty) {
+      var message = 'validation failed: ' + failures.map((f) => '${f['Field']} ${f['Message']}').join('; ');
+      throw new SampleServiceException(400, message, code: 'VALIDATION_FAILED', details: failures);
+    }
+  }
+
   Future<T> _handleResponseJson<T>(http.StreamedResponse response, T Function(Map<String, dynamic>) factory) async {
     if (response.statusCode >= 400) {
       throw await SampleServiceException.fromResponse(response);
@@ -567,6 +661,46 @@ class SampleServiceClient {
     );
   }
 
+  Future<Stream<T>> _handleResponseEvents<T>(http.StreamedResponse response, T Function(dynamic) factory) async {
+    if (response.statusCode >= 400) {
+      throw await SampleServiceException.fromResponse(response);
+    }
+
+    return _readEvents(response.stream).map((data) => factory(jsonDecode(data)));
+  }
+
+  /// Parses the raw "text/event-stream" body, emitting the 'data' of each event as soon as it arrives. An 'error'
+  /// event means that the server was unable to continue the stream, so we'll fail the stream with an exception.
+  Stream<String> _readEvents(Stream<List<int>> body) async* {
+    var eventName = '';
+    var data = <String>[];
+
+    await for (var line in body.transform(utf8.decoder).transform(const LineSplitter())) {
+      // A blank line dispatches the event that we've been building up.
+      if (line == '') {
+        if (data.isNotEmpty && eventName == 'error') {
+          throw SampleServiceException(500, data.join('\n'));
+        }
+        if (data.isNotEmpty) {
+          yield data.join('\n');
+        }
+        eventName = '';
+        data = [];
+        continue;
+      }
+
+      var colon = line.indexOf(':');
+      var field = colon < 0 ? line : line.substring(0, colon);
+      var value = colon < 0 ? '' : line.substring(colon + 1);
+      value = value.startsWith(' ') ? value.substring(1) : value;
+      if (field == 'event') {
+        eventName = value;
+      } else if (field == 'data') {
+        data.add(value);
+      }
+    }
+  }
+
   String _authorize(String callAuthorization) {
     return callAuthorization.trim().isNotEmpty
       ? callAuthorization
@@ -640,19 +774,51 @@ class SampleServiceException implements Exception {
   int status;
   String message;
 
-  SampleServiceException(this.status, this.message);
+  /// The optional, machine-readable code that identifies this failure (e.g. "USER_NOT_FOUND").
+  String code;
+
+  /// Finer-grained descriptions of the failure such as field violations. Each one has
+  /// a 'Message' as well as an optional 'Field' and 'Code'.
+  List<Map<String, dynamic>> details;
+
+  /// Indicates that the same request might succeed if you try again later.
+  bool retryable;
+
+  /// The number of seconds that the server suggested you wait before retrying (0 if no suggestion).
+  int retryAfter;
+
+  SampleServiceException(this.status, this.message, {
+    this.code = '',
+    this.details = const [],
+    this.retryable = false,
+    this.retryAfter = 0,
+  });
 
   static Future<SampleServiceException> fromResponse(http.StreamedResponse response) async {
     var body = await _streamToString(response.stream);
     var message = '';
+    var code = '';
+    var details = <Map<String, dynamic>>[];
+    var retryAfter = int.tryParse(response.headers['retry-after'] ?? '') ?? 0;
+    var retryable = retryAfter > 0;
     try {
       Map<String, dynamic> json = jsonDecode(body);
-      message = json['message'] ?? json['error'] ?? json['Message'] ?? json['Error'] ?? body;
+      // Problem documents (see WithProblemDetails) use lowercase members and call the message the "detail".
+      message = json['message'] ?? json['error'] ?? json['Message'] ?? json['Error'] ?? json['detail'] ?? json['title'] ?? body;
+      code = json['Code'] ?? json['code'] ?? '';
+      details = _map(json['Details'] ?? json['details'], (x) => Map<String, dynamic>.from(x)) ?? details;
+      retryAfter = json['RetryAfter'] ?? json['retryAfter'] ?? retryAfter;
+      retryable = json['Retryable'] ?? json['retryable'] ?? retryable;
     }
     catch (_) {
       message = body;
     }
-    throw new SampleServiceException(response.statusCode, message);
+    throw new SampleServiceException(response.statusCode, message,
+      code: code,
+      details: details,
+      retryable: retryable,
+      retryAfter: retryAfter,
+    );
   }
 }
 
@@ -735,14 +901,23 @@ class SampleServiceException implements Exception {
   typedef TimeTime = dynamic;
 
   
-  class SampleRedirectResponse extends ModelStream {
-    SampleRedirectResponse({
+  class SampleRedirectResponse extends ModelStream { 
+    String? uri;
+
+    SampleRedirectResponse({ 
+      this.uri,
       Stream<List<int>>? content,
       int? contentLength,
       String? contentType,
       String? contentFileName,
       ModelStreamContentRange? contentRange,
     }) : super(content: content, contentType: contentType, contentFileName: contentFileName, contentLength: contentLength, contentRange: contentRange);
+
+    Map<String, dynamic> valuesJson() {
+      return { 
+        'URI': uri,
+      };
+    }
   }
 
   
@@ -848,6 +1023,21 @@ class SampleServiceException implements Exception {
 
 
   
+  class SampleProgressResponse implements ModelJSON { 
+
+    SampleProgressResponse();
+
+    SampleProgressResponse.fromJson(Map<String, dynamic> json) { 
+    }
+
+    Map<String, dynamic> toJson() {
+      return { 
+      };
+    }
+  }
+
+
+  
   class SampleRedirectRequest implements ModelJSON { 
 
     SampleRedirectRequest();
@@ -985,14 +1175,43 @@ class SampleServiceException implements Exception {
 
 
   
-  class SampleDownloadResponse extends ModelStream {
-    SampleDownloadResponse({
+  class SampleUploadRequest extends ModelStream { 
+    String? id;
+    String? text;
+
+    SampleUploadRequest({ 
+      this.id,
+      this.text,
       Stream<List<int>>? content,
       int? contentLength,
       String? contentType,
       String? contentFileName,
       ModelStreamContentRange? contentRange,
     }) : super(content: content, contentType: contentType, contentFileName: contentFileName, contentLength: contentLength, contentRange: contentRange);
+
+    Map<String, dynamic> valuesJson() {
+      return { 
+        'ID': id,
+        'Text': text,
+      };
+    }
+  }
+
+  
+  class SampleDownloadResponse extends ModelStream { 
+
+    SampleDownloadResponse({ 
+      Stream<List<int>>? content,
+      int? contentLength,
+      String? contentType,
+      String? contentFileName,
+      ModelStreamContentRange? contentRange,
+    }) : super(content: content, contentType: contentType, contentFileName: contentFileName, contentLength: contentLength, contentRange: contentRange);
+
+    Map<String, dynamic> valuesJson() {
+      return { 
+      };
+    }
   }
 
 
@@ -1013,6 +1232,11 @@ class ModelStream implements ModelJSON {
     this.content, this.contentLength, this.contentType, this.contentFileName, this.contentRange,
   });
 
+  /// Returns the model's values other than the stream itself (e.g. to send in the query string of an upload).
+  Map<String, dynamic> valuesJson() {
+    return {};
+  }
+
   Map<String, dynamic> toJson() {
     return {
       'Content': _streamToString(content),
@@ -1075,6 +1299,74 @@ Future<String> _streamToString(Stream<List<int>>? stream) async {
   return bodyCompleter.future;
 }
 
+/// The constraints from a single field's `validate` tag, keyed by the field's binding path (e.g. "User.Name").
+class _ValidationRule {
+  final String path;
+  final bool required;
+  final num? min;
+  final num? max;
+  final num? len;
+  final String? regex;
+  final List<String>? oneOf;
+  final bool email;
+
+  const _ValidationRule(this.path, {this.required = false, this.min, this.max, this.len, this.regex, this.oneOf, this.email = false});
+
+  /// Returns the rule that failed and a message like ['required', 'is required'] when the value is
+  /// invalid or empty strings when it's valid.
+  List<String> check(dynamic value) {
+    var empty = value == null || value == '' || value == 0 || value == false ||
+      (value is List && value.isEmpty) || (value is Map && value.isEmpty);
+    if (empty) {
+      return required ? ['required', 'is required'] : ['', ''];
+    }
+
+    num? size;
+    var units = '';
+    if (value is String) {
+      size = value.runes.length;
+      units = ' characters';
+    } else if (value is List) {
+      size = value.length;
+      units = ' items';
+    } else if (value is Map) {
+      size = value.length;
+      units = ' items';
+    } else if (value is num) {
+      size = value;
+    }
+    if (size != null) {
+      String sizeMessage(String comparison, num limit) => units == ' items'
+        ? 'must have $comparison $limit$units'
+        : 'must be $comparison $limit$units';
+
+      if (len != null && size != len) {
+        return ['len', sizeMessage('exactly', len!)];
+      }
+      if (min != null && size < min!) {
+        return ['min', sizeMessage('at least', min!)];
+      }
+      if (max != null && size > max!) {
+        return ['max', sizeMessage('at most', max!)];
+      }
+    }
+
+    if (oneOf != null && !oneOf!.contains(value.toString())) {
+      return ['oneof', 'must be one of [' + oneOf!.join(' ') + ']'];
+    }
+    if (value is! String) {
+      return ['', ''];
+    }
+    if (regex != null && !RegExp(regex!).hasMatch(value)) {
+      return ['regex', 'must match the pattern ' + regex!];
+    }
+    if (email && !RegExp(r'^[^\s@]+@[^\s@]+$').hasMatch(value)) {
+      return ['email', 'must be a valid email address'];
+    }
+    return ['', ''];
+  }
+}
+
 
 
 
@@ -106,6 +106,19 @@ async function main() {
             const client = new SampleServiceClient(baseURI);
             return output(client.Redirect({}));
         }
+        case 'Progress': {
+            const client = new SampleServiceClient(baseURI);
+            return output(collect(client.Progress({Text: 'Abide'})));
+        }
+        case 'Upload': {
+            const client = new SampleServiceClient(baseURI);
+            return output(client.Upload({
+                ID: '123',
+                Text: 'Abide',
+                Content: new Blob(['The Dude abides'], {type: 'text/plain'}),
+                ContentFileName: 'dude.txt',
+            }));
+        }
         case 'Authorization': {
             const client = new SampleServiceClient(baseURI);
             return output(client.Authorization({}, {authorization: 'Abide'}));
@@ -121,6 +134,17 @@ async function main() {
     }
 }
 
+/**
+ * Reads all of the values from an event stream, so that we can output them all at once.
+ */
+async function collect(eventsFuture) {
+    const values = [];
+    for await (const value of await eventsFuture) {
+        values.push(value);
+    }
+    return values;
+}
+
 async function output(responseFuture) {
     try {
         const value = await responseFuture;
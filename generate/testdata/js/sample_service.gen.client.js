@@ -527,6 +527,41 @@ class SampleServiceClient {
     }
     
     
+    /**
+     * Progress sends a live stream of updates using server-sent events rather than 
+     * responding with a single value. 
+     *
+     * @param { SampleRequest } serviceRequest The input parameters
+     * @param {object} [options]
+     * @param { string } [options.authorization] The HTTP Authorization header value to include
+     *     in the request. This will override any authorization you might have applied when
+     *     constructing this client. Use this in multi-tenant situations where multiple users
+     *     might utilize this service.
+     * @returns {Promise<AsyncIterable<SampleResponse>> } The values that the server sends as it sends them.
+     */
+    async Progress(serviceRequest, {authorization} = {}) {
+        if (!serviceRequest) {
+            throw new GatewayError(400, 'precondition failed: empty request');
+        }
+
+        const method = 'GET';
+        const route = '/v2/progress';
+        const url = this._baseURL + '/' + buildRequestPath(method, route, serviceRequest);
+        const fetchOptions = {
+            method: method,
+            headers: {
+                'Authorization': authorization || this._authorization,
+                'Accept': 'text/event-stream',
+                'Content-Type': 'application/json; charset=utf-8',
+            },
+        };
+
+        const response = await doFetch(this._fetch, url, fetchOptions);
+        return handleResponseEvents(response);
+    
+    }
+    
+    
     /**
      * Redirect results in a 307-style redirect to the Download endpoint. 
      *
@@ -736,6 +771,42 @@ class SampleServiceClient {
     
     }
     
+    
+    /**
+     * Upload accepts a raw stream of data rather than relying on auto-decoding 
+     * the request body. 
+     *
+     * @param { SampleUploadRequest & StreamedRequest } serviceRequest The input parameters
+     * @param {object} [options]
+     * @param { string } [options.authorization] The HTTP Authorization header value to include
+     *     in the request. This will override any authorization you might have applied when
+     *     constructing this client. Use this in multi-tenant situations where multiple users
+     *     might utilize this service.
+     * @returns {Promise<SampleResponse> } The JSON-encoded return value of the operation.
+     */
+    async Upload(serviceRequest, {authorization} = {}) {
+        if (!serviceRequest) {
+            throw new GatewayError(400, 'precondition failed: empty request');
+        }
+
+        const method = 'POST';
+        const route = '/v2/upload/{ID}';
+        const url = this._baseURL + '/' + buildRequestPath(method, route, uploadValues(serviceRequest), true);
+        const fetchOptions = {
+            method: method,
+            headers: uploadHeaders(serviceRequest, {
+                'Authorization': authorization || this._authorization,
+                'Accept': 'application/json,*/*',
+            }),
+            body: serviceRequest.Content,
+            duplex: 'half',
+        };
+
+        const response = await doFetch(this._fetch, url, fetchOptions);
+        return handleResponseJSON(response);
+    
+    }
+    
 }
 
 /**
@@ -745,9 +816,10 @@ class SampleServiceClient {
  * @param {string} method The HTTP method for this request (determines if we include a query string)
  * @param {string} path The path pattern to populate w/ runtime values (e.g. "/user/{id}")
  * @param {Object} serviceRequest The input struct for the service call
+ * @param {boolean} [upload] Is the body a raw upload? If so, non-path values go in the query string.
  * @returns {string} The fully-populate URL path (e.g. "/user/aCx31s")
  */
-function buildRequestPath(method, path, serviceRequest) {
+function buildRequestPath(method, path, serviceRequest, upload = false) {
     const values = new URLValues(serviceRequest);
 
     const pathSegments = path.split('/').map(segment => {
@@ -759,11 +831,157 @@ function buildRequestPath(method, path, serviceRequest) {
 
     // PUT/POST/PATCH:  encode the data in the body, so no need to shove it in the query string.
     // GET/DELETE/HEAD: will pass all values through the query string.
-    return supportsBody(method)
+    // Uploads:         the body is the raw content, so values must go in the query string.
+    return supportsBody(method) && !upload
         ? resolvedPath
         : resolvedPath + '?' + values.format();
 }
 
+/**
+ * Creates a copy of an upload request without the raw content and its attributes, so that
+ * only the request's "normal" values are sent in the path/query string.
+ *
+ * @param {StreamedRequest} serviceRequest The upload request for the service call
+ * @returns {Object}
+ */
+function uploadValues(serviceRequest) {
+    const values = Object.assign({}, serviceRequest);
+    delete values.Content;
+    delete values.ContentType;
+    delete values.ContentFileName;
+    return values;
+}
+
+/**
+ * Adds the Content-Type and Content-Disposition headers that describe the raw content you
+ * are uploading. If you don't specify them explicitly, we'll use the type/name of the
+ * Blob/File you're uploading when possible.
+ *
+ * @param {StreamedRequest} serviceRequest The upload request for the service call
+ * @param {Object} headers The rest of the headers for the request
+ * @returns {Object}
+ */
+function uploadHeaders(serviceRequest, headers) {
+    const content = serviceRequest.Content || {};
+    const contentType = serviceRequest.ContentType || content.type || 'application/octet-stream';
+    const contentFileName = serviceRequest.ContentFileName || content.name || '';
+
+    headers['Content-Type'] = contentType;
+    if (contentFileName) {
+        headers['Content-Disposition'] = 'attachment; filename="' + contentFileName.replace(/"/g, '\\"') + '"';
+    }
+    return headers;
+}
+
+/**
+ * Performs the same `validate` checks that the server does so that you can fail fast without
+ * making a round trip. Every invalid field is reported in a single 400 GatewayError whose message
+ * matches the one the server would have sent back.
+ *
+ * @param {Object} serviceRequest The input struct for the service call
+ * @param {Object[]} rules The constraints for each field, keyed by the field's binding path (e.g. "User.Name")
+ */
+function validateRequest(serviceRequest, rules) {
+    const failures = [];
+    const failedPaths = [];
+    for (const rule of rules) {
+        // Just like the server, once a field fails we don't bother checking the fields inside of it.
+        if (failedPaths.some(path => rule.path.startsWith(path + '.'))) {
+            continue;
+        }
+        const [found, value] = lookupValue(serviceRequest, rule.path);
+        if (!found) {
+            continue;
+        }
+        const [code, message] = validateValue(value, rule);
+        if (message) {
+            failures.push({Field: rule.path, Code: code, Message: message});
+            failedPaths.push(rule.path);
+        }
+    }
+    if (failures.length > 0) {
+        const message = 'validation failed: ' + failures.map(f => f.Field + ' ' + f.Message).join('; ');
+        throw new GatewayError(400, message, {code: 'VALIDATION_FAILED', details: failures});
+    }
+}
+
+/**
+ * Finds the value at the dot-delimited path (e.g. "User.Name"). The first element indicates whether
+ * every parent along the way was present; there's nothing to validate inside a null/missing parent.
+ *
+ * @param {Object} value The object to look inside
+ * @param {string} path The dot-delimited path to the value
+ * @returns {[boolean, *]}
+ */
+function lookupValue(value, path) {
+    const keys = path.split('.');
+    for (let i = 0; i < keys.length; i++) {
+        if (value === null || typeof value !== 'object') {
+            return [false, undefined];
+        }
+        value = value[keys[i]];
+    }
+    return [true, value];
+}
+
+/**
+ * Checks a single value against its constraints, returning the rule that failed and a message
+ * like ['required', 'is required'] when it's invalid or empty strings when it's valid.
+ *
+ * @param {*} value The field value to check
+ * @param {Object} rule The field's constraints
+ * @returns {[string, string]}
+ */
+function validateValue(value, rule) {
+    const isObject = value !== null && typeof value === 'object';
+    const empty = value === null || typeof value === 'undefined' || value === '' || value === 0 || value === false ||
+        (Array.isArray(value) && value.length === 0) ||
+        (isObject && !Array.isArray(value) && Object.keys(value).length === 0);
+    if (empty) {
+        return rule.required ? ['required', 'is required'] : ['', ''];
+    }
+
+    let size, units;
+    if (typeof value === 'string') {
+        [size, units] = [[...value].length, ' characters'];
+    } else if (Array.isArray(value)) {
+        [size, units] = [value.length, ' items'];
+    } else if (typeof value === 'number') {
+        [size, units] = [value, ''];
+    } else if (isObject) {
+        [size, units] = [Object.keys(value).length, ' items'];
+    }
+    if (typeof size !== 'undefined') {
+        const sizeMessage = (comparison, limit) => units === ' items'
+            ? 'must have ' + comparison + ' ' + limit + units
+            : 'must be ' + comparison + ' ' + limit + units;
+
+        if (typeof rule.len !== 'undefined' && size !== rule.len) {
+            return ['len', sizeMessage('exactly', rule.len)];
+        }
+        if (typeof rule.min !== 'undefined' && size < rule.min) {
+            return ['min', sizeMessage('at least', rule.min)];
+        }
+        if (typeof rule.max !== 'undefined' && size > rule.max) {
+            return ['max', sizeMessage('at most', rule.max)];
+        }
+    }
+
+    if (rule.oneOf && !rule.oneOf.includes(String(value))) {
+        return ['oneof', 'must be one of [' + rule.oneOf.join(' ') + ']'];
+    }
+    if (typeof value !== 'string') {
+        return ['', ''];
+    }
+    if (rule.regex && !new RegExp(rule.regex).test(value)) {
+        return ['regex', 'must match the pattern ' + rule.regex];
+    }
+    if (rule.email && !/^[^\s@]+@[^\s@]+$/.test(value)) {
+        return ['email', 'must be a valid email address'];
+    }
+    return ['', ''];
+}
+
 /**
  * URLValues helps convert a single request object into a map of individual attributes that can
  * be easily added to a path or query string.
@@ -873,6 +1091,81 @@ async function handleResponseStream(response) {
     }
 }
 
+/**
+ * Accepts the response from an endpoint that sends server-sent events ("text/event-stream") and
+ * returns an async iterator that yields each JSON-decoded value as soon as the server sends it.
+ * If you stop iterating early (e.g. 'break' out of your 'for await' loop), we'll close the connection.
+ *
+ * @returns {Promise<AsyncIterable<Object>>}
+ */
+async function handleResponseEvents(response) {
+    if (response.status >= 400) {
+        throw await newError(response);
+    }
+    return readEvents(response.body);
+}
+
+/**
+ * Reads the raw "text/event-stream" body, yielding the decoded 'data' of each event. An 'error' event
+ * means that the server was unable to continue the stream, so we'll throw a GatewayError.
+ *
+ * @param {ReadableStream} body The body of the event stream response
+ * @returns {AsyncIterable<Object>}
+ */
+async function* readEvents(body) {
+    if (!body) {
+        return;
+    }
+
+    const reader = body.getReader();
+    const decoder = new TextDecoder();
+    let buffer = '';
+    let eventName = '';
+    let data = [];
+
+    try {
+        while (true) {
+            const {done, value} = await reader.read();
+            if (done) {
+                return;
+            }
+            buffer += decoder.decode(value, {stream: true});
+
+            let newline;
+            while ((newline = buffer.indexOf('\n')) >= 0) {
+                const line = buffer.substring(0, newline).replace(/\r$/, '');
+                buffer = buffer.substring(newline + 1);
+
+                // A blank line dispatches the event that we've been building up.
+                if (line === '') {
+                    if (data.length > 0 && eventName === 'error') {
+                        throw new GatewayError(500, data.join('\n'));
+                    }
+                    if (data.length > 0) {
+                        yield JSON.parse(data.join('\n'));
+                    }
+                    eventName = '';
+                    data = [];
+                    continue;
+                }
+
+                const colon = line.indexOf(':');
+                const field = colon < 0 ? line : line.substring(0, colon);
+                const fieldValue = colon < 0 ? '' : line.substring(colon + 1).replace(/^ /, '');
+                if (field === 'event') {
+                    eventName = fieldValue;
+                }
+                else if (field === 'data') {
+                    data.push(fieldValue);
+                }
+            }
+        }
+    }
+    finally {
+        reader.cancel().catch(() => {});
+    }
+}
+
 /**
  * Accepts the 'Content-Range' header value from a response and parses out all 4 components
  * of the value; the unit, start, end, and size. You'll get back a single object containing
@@ -920,10 +1213,27 @@ async function newError(response) {
         ? await response.json()
         : await response.text();
 
-    // One of the framework's standard status/message errors, already.
-    throw (body['Status'] && body['Message'])
-        ? new GatewayError(body['Status'], body['Message'])
-        : new GatewayError(response.status, parseErrorMessage(body));
+    // One of the framework's standard status/message errors, already. Hold onto the code, details,
+    // and retry hints so that callers can make decisions without parsing the message.
+    if (body['Status'] && body['Message']) {
+        throw new GatewayError(body['Status'], body['Message'], {
+            code: body['Code'],
+            details: body['Details'],
+            retryable: body['Retryable'],
+            retryAfter: body['RetryAfter'] || toInt(response.headers.get('Retry-After')) || 0,
+        });
+    }
+
+    // The gateway uses WithProblemDetails(), so this is an RFC 7807 problem document.
+    if (body['status'] && (body['detail'] || body['title'])) {
+        throw new GatewayError(body['status'], body['detail'] || body['title'], {
+            code: body['code'],
+            details: body['details'],
+            retryable: body['retryable'],
+            retryAfter: body['retryAfter'] || toInt(response.headers.get('Retry-After')) || 0,
+        });
+    }
+    throw new GatewayError(response.status, parseErrorMessage(body));
 }
 
 /**
@@ -951,11 +1261,14 @@ function dispositionFileName(contentDisposition = '') {
 }
 
 /**
- * Determines whether or not the response has a content type of JSON.
+ * Determines whether or not the response has a content type of JSON (including problem+json errors).
  */
 function isJSON(response) {
     const contentType = response.headers.get('content-type');
-    return contentType && contentType.toLowerCase().startsWith('application/json');
+    return contentType && (
+        contentType.toLowerCase().startsWith('application/json') ||
+        contentType.toLowerCase().startsWith('application/problem+json')
+    );
 }
 
 /**
@@ -1058,9 +1371,42 @@ class GatewayError {
     */
     message;
 
-    constructor(status, message) {
+    /**
+    * The optional, machine-readable code that identifies this failure (e.g. "USER_NOT_FOUND").
+    *
+    * @type {string}
+    */
+    code;
+
+    /**
+    * Finer-grained descriptions of the failure such as field violations. Each one has
+    * a 'Message' as well as an optional 'Field' and 'Code'.
+    *
+    * @type {Object[]}
+    */
+    details;
+
+    /**
+    * Indicates that the same request might succeed if you try again later.
+    *
+    * @type {boolean}
+    */
+    retryable;
+
+    /**
+    * The number of seconds that the server suggested you wait before retrying (0 if no suggestion).
+    *
+    * @type {number}
+    */
+    retryAfter;
+
+    constructor(status, message, {code = '', details = [], retryable = false, retryAfter = 0} = {}) {
         this.Status = this.status = status || 500;
         this.Message = this.message = message;
+        this.Code = this.code = code || '';
+        this.Details = this.details = details || [];
+        this.Retryable = this.retryable = !!retryable || retryAfter > 0;
+        this.RetryAfter = this.retryAfter = retryAfter || 0;
     }
 
     toString() {
@@ -1130,9 +1476,17 @@ class GatewayError {
 /**
  * @typedef { number } timeDuration
 */
+/**
+ * @typedef { object } SampleProgressResponse
+*/
 /**
  * @typedef { object } SampleRedirectRequest
 */
+/**
+ * @typedef { object } SampleUploadRequest
+ * @property { string|* } [ID]
+ * @property { string|* } [Text]
+*/
 /**
  * @typedef { object } SampleUser
  * @property { string|* } [ID]
@@ -1150,6 +1504,13 @@ class GatewayError {
  * @property { string|* } [Work]
 */
 
+/**
+ * @typedef StreamedRequest
+ * @property { Blob|File|ArrayBuffer|ReadableStream|string } Content
+ * @property { string } [ContentType]
+ * @property { string } [ContentFileName]
+*/
+
 /**
  * @typedef StreamedResponse
  * @property { Blob } Content
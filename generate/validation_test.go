@@ -0,0 +1,52 @@
+//go:build unit
+
+package generate_test
+
+import (
+	"testing"
+
+	"github.com/monadicstack/abide/generate"
+	"github.com/stretchr/testify/suite"
+)
+
+type ValidationClientSuite struct {
+	suite.Suite
+}
+
+func (suite *ValidationClientSuite) eval(name string, path string) string {
+	output, err := generate.NewStandardTemplate(name, path).Eval(userServiceContext())
+	suite.Require().NoError(err)
+	return string(output)
+}
+
+// The JS client should check the same constraints as the server before making the request.
+func (suite *ValidationClientSuite) TestJavaScript() {
+	output := suite.eval("client.js", "templates/client.js.tmpl")
+
+	suite.Contains(output, `validateRequest(serviceRequest, [
+            {"path":"Name","required":true,"min":2,"max":50,"regex":"^[A-Z]'s$"},
+            {"path":"email_address","email":true},
+            {"path":"Age","min":18},
+            {"path":"Role","oneOf":["admin","member"]},
+            {"path":"Tags","len":3},
+        ]);`)
+	suite.Contains(output, "function validateRequest(serviceRequest, rules) {")
+}
+
+// The Dart client should check the same constraints as the server before making the request.
+func (suite *ValidationClientSuite) TestDart() {
+	output := suite.eval("client.dart", "templates/client.dart.tmpl")
+
+	suite.Contains(output, `_validateRequest(requestJson, [
+      _ValidationRule('Name', required: true, min: 2, max: 50, regex: '^[A-Z]\'s\$'),
+      _ValidationRule('email_address', email: true),
+      _ValidationRule('Age', min: 18),
+      _ValidationRule('Role', oneOf: ['admin', 'member']),
+      _ValidationRule('Tags', len: 3),
+    ]);`)
+	suite.Contains(output, "class _ValidationRule {")
+}
+
+func TestValidationClientSuite(t *testing.T) {
+	suite.Run(t, new(ValidationClientSuite))
+}
@@ -4,11 +4,13 @@ go 1.19
 
 require (
 	github.com/dimfeld/httptreemux/v5 v5.4.0
+	github.com/gorilla/websocket v1.5.0
 	github.com/nats-io/nats.go v1.19.0
 	github.com/spf13/cobra v1.6.1
 	github.com/stretchr/testify v1.8.1
 	golang.org/x/mod v0.6.0
 	golang.org/x/tools v0.1.12
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -23,5 +25,4 @@ require (
 	golang.org/x/crypto v0.1.0 // indirect
 	golang.org/x/sys v0.1.0 // indirect
 	google.golang.org/protobuf v1.28.1 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
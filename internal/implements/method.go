@@ -42,6 +42,36 @@ func methodOnNamed(namedType *types.Named, name string, paramTypes []string, ret
 	return false
 }
 
+// MethodResult finds the method with the given name on the type (or any of its embedded types) and returns
+// the type of its first return value. This lets you figure out things like the element type of a generic
+// type's method (e.g. "Events() <-chan T"). This returns nil if there is no such method.
+func MethodResult(t types.Type, name string) types.Type {
+	switch tt := t.(type) {
+	case *types.Struct:
+		for i := 0; i < tt.NumFields(); i++ {
+			field := tt.Field(i)
+			if !field.Embedded() {
+				continue
+			}
+			if result := MethodResult(field.Type(), name); result != nil {
+				return result
+			}
+		}
+	case *types.Named:
+		for i := 0; i < tt.NumMethods(); i++ {
+			method := tt.Method(i)
+			signature, ok := method.Type().(*types.Signature)
+			if ok && method.Name() == name && signature.Results().Len() > 0 {
+				return signature.Results().At(0).Type()
+			}
+		}
+		if underlying := tt.Underlying(); tt != underlying {
+			return MethodResult(underlying, name)
+		}
+	}
+	return nil
+}
+
 // Signature accepts a single method and determines whether or not it has the same name, parameter types, and return
 // types as what you provide. Since you might be referencing types that are hard to look up in the AST packages info,
 // you can just supply the qualified names of the types for your params and return values (e.g. "io.Reader"
@@ -42,7 +42,7 @@ func (client *otherServiceClient) ChainFail(ctx context.Context, request *testex
 	}
 
 	response := &testext.OtherResponse{}
-	err := client.Invoke(ctx, "POST", "/OtherService.ChainFail", request, response)
+	err := client.InvokeFunction(ctx, "ChainFail", "POST", "/OtherService.ChainFail", request, response)
 	return response, err
 
 }
@@ -58,7 +58,7 @@ func (client *otherServiceClient) ChainFailAfter(ctx context.Context, request *t
 	}
 
 	response := &testext.OtherResponse{}
-	err := client.Invoke(ctx, "POST", "/OtherService.ChainFailAfter", request, response)
+	err := client.InvokeFunction(ctx, "ChainFailAfter", "POST", "/OtherService.ChainFailAfter", request, response)
 	return response, err
 
 }
@@ -74,7 +74,7 @@ func (client *otherServiceClient) ChainFour(ctx context.Context, request *testex
 	}
 
 	response := &testext.OtherResponse{}
-	err := client.Invoke(ctx, "POST", "/OtherService.ChainFour", request, response)
+	err := client.InvokeFunction(ctx, "ChainFour", "POST", "/OtherService.ChainFour", request, response)
 	return response, err
 
 }
@@ -91,7 +91,7 @@ func (client *otherServiceClient) ChainOne(ctx context.Context, request *testext
 	}
 
 	response := &testext.OtherResponse{}
-	err := client.Invoke(ctx, "POST", "/OtherService.ChainOne", request, response)
+	err := client.InvokeFunction(ctx, "ChainOne", "POST", "/OtherService.ChainOne", request, response)
 	return response, err
 
 }
@@ -107,7 +107,7 @@ func (client *otherServiceClient) ChainThree(ctx context.Context, request *teste
 	}
 
 	response := &testext.OtherResponse{}
-	err := client.Invoke(ctx, "POST", "/OtherService.ChainThree", request, response)
+	err := client.InvokeFunction(ctx, "ChainThree", "POST", "/OtherService.ChainThree", request, response)
 	return response, err
 
 }
@@ -123,7 +123,7 @@ func (client *otherServiceClient) ChainTwo(ctx context.Context, request *testext
 	}
 
 	response := &testext.OtherResponse{}
-	err := client.Invoke(ctx, "POST", "/OtherService.ChainTwo", request, response)
+	err := client.InvokeFunction(ctx, "ChainTwo", "POST", "/OtherService.ChainTwo", request, response)
 	return response, err
 
 }
@@ -139,7 +139,7 @@ func (client *otherServiceClient) ListenWell(ctx context.Context, request *teste
 	}
 
 	response := &testext.OtherResponse{}
-	err := client.Invoke(ctx, "POST", "/OtherService.ListenWell", request, response)
+	err := client.InvokeFunction(ctx, "ListenWell", "POST", "/OtherService.ListenWell", request, response)
 	return response, err
 
 }
@@ -156,11 +156,75 @@ func (client *otherServiceClient) RPCExample(ctx context.Context, request *teste
 	}
 
 	response := &testext.OtherResponse{}
-	err := client.Invoke(ctx, "POST", "/OtherService.RPCExample", request, response)
+	err := client.InvokeFunction(ctx, "RPCExample", "POST", "/OtherService.RPCExample", request, response)
 	return response, err
 
 }
 
+// SagaFail always fails, so the saga should be unwound by running SagaStepUndo then SagaStartUndo.
+func (client *otherServiceClient) SagaFail(ctx context.Context, request *testext.OtherRequest) (*testext.OtherResponse, error) {
+
+	if ctx == nil {
+		return nil, fail.Unexpected("precondition failed: nil context")
+	}
+	if request == nil {
+		return nil, fail.Unexpected("precondition failed: nil request")
+	}
+
+	response := &testext.OtherResponse{}
+	err := client.InvokeFunction(ctx, "SagaFail", "POST", "/OtherService.SagaFail", request, response)
+	return response, err
+
+}
+
+// SagaStart is the first step of a saga. When it succeeds, it triggers SagaStep.
+func (client *otherServiceClient) SagaStart(ctx context.Context, request *testext.OtherRequest) (*testext.OtherResponse, error) {
+
+	if ctx == nil {
+		return nil, fail.Unexpected("precondition failed: nil context")
+	}
+	if request == nil {
+		return nil, fail.Unexpected("precondition failed: nil request")
+	}
+
+	response := &testext.OtherResponse{}
+	err := client.InvokeFunction(ctx, "SagaStart", "POST", "/OtherService.SagaStart", request, response)
+	return response, err
+
+}
+
+// SagaStartUndo compensates for SagaStart when a later step in the saga fails.
+func (client *otherServiceClient) SagaStartUndo(ctx context.Context, request *testext.OtherRequest) (*testext.OtherResponse, error) {
+
+	// Not exposed, so don't bother with a round trip to the server just to get a "not found" error anyway.
+	return nil, fail.NotImplemented("SagaStartUndo is not supported in the API gateway")
+
+}
+
+// SagaStep is the second step of the saga. When it succeeds, it triggers SagaFail.
+func (client *otherServiceClient) SagaStep(ctx context.Context, request *testext.OtherRequest) (*testext.OtherResponse, error) {
+
+	if ctx == nil {
+		return nil, fail.Unexpected("precondition failed: nil context")
+	}
+	if request == nil {
+		return nil, fail.Unexpected("precondition failed: nil request")
+	}
+
+	response := &testext.OtherResponse{}
+	err := client.InvokeFunction(ctx, "SagaStep", "POST", "/OtherService.SagaStep", request, response)
+	return response, err
+
+}
+
+// SagaStepUndo compensates for SagaStep when a later step in the saga fails.
+func (client *otherServiceClient) SagaStepUndo(ctx context.Context, request *testext.OtherRequest) (*testext.OtherResponse, error) {
+
+	// Not exposed, so don't bother with a round trip to the server just to get a "not found" error anyway.
+	return nil, fail.NotImplemented("SagaStepUndo is not supported in the API gateway")
+
+}
+
 // SpaceOut takes your input text and puts spaces in between all the letters.
 func (client *otherServiceClient) SpaceOut(ctx context.Context, request *testext.OtherRequest) (*testext.OtherResponse, error) {
 
@@ -172,7 +236,7 @@ func (client *otherServiceClient) SpaceOut(ctx context.Context, request *testext
 	}
 
 	response := &testext.OtherResponse{}
-	err := client.Invoke(ctx, "POST", "/OtherService.SpaceOut", request, response)
+	err := client.InvokeFunction(ctx, "SpaceOut", "POST", "/OtherService.SpaceOut", request, response)
 	return response, err
 
 }
@@ -261,6 +261,130 @@ func OtherServiceServer(handler testext.OtherService, middleware ...services.Mid
 				},
 			},
 
+			{
+				ServiceName: "OtherService",
+				Name:        "SagaFail",
+				NewInput:    func() services.StructPointer { return &testext.OtherRequest{} },
+				Handler: middlewareFuncs.Then(func(ctx context.Context, req any) (any, error) {
+					typedReq, ok := req.(*testext.OtherRequest)
+					if !ok {
+						return nil, fail.Unexpected("invalid request argument type")
+					}
+					return handler.SagaFail(ctx, typedReq)
+				}),
+				Roles: []string{},
+				Routes: []services.EndpointRoute{
+					{
+						GatewayType: "API",
+						Method:      "POST",
+						Path:        "/OtherService.SagaFail",
+						Status:      200,
+					},
+
+					{
+						GatewayType: "EVENTS",
+						Method:      "ON",
+						Path:        "OtherService.SagaStep",
+						Status:      0,
+					},
+				},
+			},
+
+			{
+				ServiceName: "OtherService",
+				Name:        "SagaStart",
+				NewInput:    func() services.StructPointer { return &testext.OtherRequest{} },
+				Handler: middlewareFuncs.Then(func(ctx context.Context, req any) (any, error) {
+					typedReq, ok := req.(*testext.OtherRequest)
+					if !ok {
+						return nil, fail.Unexpected("invalid request argument type")
+					}
+					return handler.SagaStart(ctx, typedReq)
+				}),
+				Roles: []string{},
+				Routes: []services.EndpointRoute{
+					{
+						GatewayType: "API",
+						Method:      "POST",
+						Path:        "/OtherService.SagaStart",
+						Status:      200,
+					},
+				},
+			},
+
+			{
+				ServiceName: "OtherService",
+				Name:        "SagaStartUndo",
+				NewInput:    func() services.StructPointer { return &testext.OtherRequest{} },
+				Handler: middlewareFuncs.Then(func(ctx context.Context, req any) (any, error) {
+					typedReq, ok := req.(*testext.OtherRequest)
+					if !ok {
+						return nil, fail.Unexpected("invalid request argument type")
+					}
+					return handler.SagaStartUndo(ctx, typedReq)
+				}),
+				Roles: []string{},
+				Routes: []services.EndpointRoute{
+					{
+						GatewayType: "EVENTS",
+						Method:      "COMPENSATE",
+						Path:        "OtherService.SagaStart",
+						Status:      0,
+					},
+				},
+			},
+
+			{
+				ServiceName: "OtherService",
+				Name:        "SagaStep",
+				NewInput:    func() services.StructPointer { return &testext.OtherRequest{} },
+				Handler: middlewareFuncs.Then(func(ctx context.Context, req any) (any, error) {
+					typedReq, ok := req.(*testext.OtherRequest)
+					if !ok {
+						return nil, fail.Unexpected("invalid request argument type")
+					}
+					return handler.SagaStep(ctx, typedReq)
+				}),
+				Roles: []string{},
+				Routes: []services.EndpointRoute{
+					{
+						GatewayType: "API",
+						Method:      "POST",
+						Path:        "/OtherService.SagaStep",
+						Status:      200,
+					},
+
+					{
+						GatewayType: "EVENTS",
+						Method:      "ON",
+						Path:        "OtherService.SagaStart",
+						Status:      0,
+					},
+				},
+			},
+
+			{
+				ServiceName: "OtherService",
+				Name:        "SagaStepUndo",
+				NewInput:    func() services.StructPointer { return &testext.OtherRequest{} },
+				Handler: middlewareFuncs.Then(func(ctx context.Context, req any) (any, error) {
+					typedReq, ok := req.(*testext.OtherRequest)
+					if !ok {
+						return nil, fail.Unexpected("invalid request argument type")
+					}
+					return handler.SagaStepUndo(ctx, typedReq)
+				}),
+				Roles: []string{},
+				Routes: []services.EndpointRoute{
+					{
+						GatewayType: "EVENTS",
+						Method:      "COMPENSATE",
+						Path:        "OtherService.SagaStep",
+						Status:      0,
+					},
+				},
+			},
+
 			{
 				ServiceName: "OtherService",
 				Name:        "SpaceOut",
@@ -43,7 +43,7 @@ func (client *sampleServiceClient) Authorization(ctx context.Context, request *t
 	}
 
 	response := &testext.SampleResponse{}
-	err := client.Invoke(ctx, "POST", "/v2/SampleService.Authorization", request, response)
+	err := client.InvokeFunction(ctx, "Authorization", "POST", "/v2/SampleService.Authorization", request, response)
 	return response, err
 
 }
@@ -59,7 +59,7 @@ func (client *sampleServiceClient) ComplexValues(ctx context.Context, request *t
 	}
 
 	response := &testext.SampleComplexResponse{}
-	err := client.Invoke(ctx, "POST", "/v2/SampleService.ComplexValues", request, response)
+	err := client.InvokeFunction(ctx, "ComplexValues", "POST", "/v2/SampleService.ComplexValues", request, response)
 	return response, err
 
 }
@@ -76,7 +76,7 @@ func (client *sampleServiceClient) ComplexValuesPath(ctx context.Context, reques
 	}
 
 	response := &testext.SampleComplexResponse{}
-	err := client.Invoke(ctx, "GET", "/v2/complex/values/{InUser.ID}/{InUser.Name}/woot", request, response)
+	err := client.InvokeFunction(ctx, "ComplexValuesPath", "GET", "/v2/complex/values/{InUser.ID}/{InUser.Name}/woot", request, response)
 	return response, err
 
 }
@@ -93,7 +93,7 @@ func (client *sampleServiceClient) CustomRoute(ctx context.Context, request *tes
 	}
 
 	response := &testext.SampleResponse{}
-	err := client.Invoke(ctx, "GET", "/v2/custom/route/1/{ID}/{Text}", request, response)
+	err := client.InvokeFunction(ctx, "CustomRoute", "GET", "/v2/custom/route/1/{ID}/{Text}", request, response)
 	return response, err
 
 }
@@ -110,7 +110,7 @@ func (client *sampleServiceClient) CustomRouteBody(ctx context.Context, request
 	}
 
 	response := &testext.SampleResponse{}
-	err := client.Invoke(ctx, "PUT", "/v2/custom/route/3/{ID}", request, response)
+	err := client.InvokeFunction(ctx, "CustomRouteBody", "PUT", "/v2/custom/route/3/{ID}", request, response)
 	return response, err
 
 }
@@ -127,7 +127,7 @@ func (client *sampleServiceClient) CustomRouteQuery(ctx context.Context, request
 	}
 
 	response := &testext.SampleResponse{}
-	err := client.Invoke(ctx, "GET", "/v2/custom/route/2/{ID}", request, response)
+	err := client.InvokeFunction(ctx, "CustomRouteQuery", "GET", "/v2/custom/route/2/{ID}", request, response)
 	return response, err
 
 }
@@ -143,7 +143,7 @@ func (client *sampleServiceClient) Defaults(ctx context.Context, request *testex
 	}
 
 	response := &testext.SampleResponse{}
-	err := client.Invoke(ctx, "POST", "/v2/SampleService.Defaults", request, response)
+	err := client.InvokeFunction(ctx, "Defaults", "POST", "/v2/SampleService.Defaults", request, response)
 	return response, err
 
 }
@@ -160,7 +160,7 @@ func (client *sampleServiceClient) Download(ctx context.Context, request *testex
 	}
 
 	response := &testext.SampleDownloadResponse{}
-	err := client.Invoke(ctx, "GET", "/v2/download", request, response)
+	err := client.InvokeFunction(ctx, "Download", "GET", "/v2/download", request, response)
 	return response, err
 
 }
@@ -178,7 +178,7 @@ func (client *sampleServiceClient) DownloadResumable(ctx context.Context, reques
 	}
 
 	response := &testext.SampleDownloadResponse{}
-	err := client.Invoke(ctx, "GET", "/v2/download/resumable", request, response)
+	err := client.InvokeFunction(ctx, "DownloadResumable", "GET", "/v2/download/resumable", request, response)
 	return response, err
 
 }
@@ -194,7 +194,7 @@ func (client *sampleServiceClient) Fail4XX(ctx context.Context, request *testext
 	}
 
 	response := &testext.SampleResponse{}
-	err := client.Invoke(ctx, "POST", "/v2/SampleService.Fail4XX", request, response)
+	err := client.InvokeFunction(ctx, "Fail4XX", "POST", "/v2/SampleService.Fail4XX", request, response)
 	return response, err
 
 }
@@ -210,7 +210,7 @@ func (client *sampleServiceClient) Fail5XX(ctx context.Context, request *testext
 	}
 
 	response := &testext.SampleResponse{}
-	err := client.Invoke(ctx, "POST", "/v2/SampleService.Fail5XX", request, response)
+	err := client.InvokeFunction(ctx, "Fail5XX", "POST", "/v2/SampleService.Fail5XX", request, response)
 	return response, err
 
 }
@@ -226,7 +226,7 @@ func (client *sampleServiceClient) ListenerA(ctx context.Context, request *teste
 	}
 
 	response := &testext.SampleResponse{}
-	err := client.Invoke(ctx, "GET", "/v2/ListenerA/Woot", request, response)
+	err := client.InvokeFunction(ctx, "ListenerA", "GET", "/v2/ListenerA/Woot", request, response)
 	return response, err
 
 }
@@ -259,7 +259,24 @@ func (client *sampleServiceClient) Panic(ctx context.Context, request *testext.S
 	}
 
 	response := &testext.SampleResponse{}
-	err := client.Invoke(ctx, "POST", "/v2/SampleService.Panic", request, response)
+	err := client.InvokeFunction(ctx, "Panic", "POST", "/v2/SampleService.Panic", request, response)
+	return response, err
+
+}
+
+// Progress sends a live stream of updates using server-sent events rather than
+// responding with a single value.
+func (client *sampleServiceClient) Progress(ctx context.Context, request *testext.SampleRequest) (*testext.SampleProgressResponse, error) {
+
+	if ctx == nil {
+		return nil, fail.Unexpected("precondition failed: nil context")
+	}
+	if request == nil {
+		return nil, fail.Unexpected("precondition failed: nil request")
+	}
+
+	response := &testext.SampleProgressResponse{}
+	err := client.InvokeFunction(ctx, "Progress", "GET", "/v2/progress", request, response)
 	return response, err
 
 }
@@ -275,7 +292,7 @@ func (client *sampleServiceClient) Redirect(ctx context.Context, request *testex
 	}
 
 	response := &testext.SampleRedirectResponse{}
-	err := client.Invoke(ctx, "GET", "/v2/redirect", request, response)
+	err := client.InvokeFunction(ctx, "Redirect", "GET", "/v2/redirect", request, response)
 	return response, err
 
 }
@@ -291,7 +308,7 @@ func (client *sampleServiceClient) SecureWithRoles(ctx context.Context, request
 	}
 
 	response := &testext.SampleSecurityResponse{}
-	err := client.Invoke(ctx, "POST", "/v2/SampleService.SecureWithRoles", request, response)
+	err := client.InvokeFunction(ctx, "SecureWithRoles", "POST", "/v2/SampleService.SecureWithRoles", request, response)
 	return response, err
 
 }
@@ -308,7 +325,7 @@ func (client *sampleServiceClient) Sleep(ctx context.Context, request *testext.S
 	}
 
 	response := &testext.SampleResponse{}
-	err := client.Invoke(ctx, "POST", "/v2/SampleService.Sleep", request, response)
+	err := client.InvokeFunction(ctx, "Sleep", "POST", "/v2/SampleService.Sleep", request, response)
 	return response, err
 
 }
@@ -323,7 +340,7 @@ func (client *sampleServiceClient) TriggerFailure(ctx context.Context, request *
 	}
 
 	response := &testext.SampleResponse{}
-	err := client.Invoke(ctx, "POST", "/v2/SampleService.TriggerFailure", request, response)
+	err := client.InvokeFunction(ctx, "TriggerFailure", "POST", "/v2/SampleService.TriggerFailure", request, response)
 	return response, err
 
 }
@@ -338,7 +355,7 @@ func (client *sampleServiceClient) TriggerLowerCase(ctx context.Context, request
 	}
 
 	response := &testext.SampleResponse{}
-	err := client.Invoke(ctx, "POST", "/v2/SampleService.TriggerLowerCase", request, response)
+	err := client.InvokeFunction(ctx, "TriggerLowerCase", "POST", "/v2/SampleService.TriggerLowerCase", request, response)
 	return response, err
 
 }
@@ -355,7 +372,24 @@ func (client *sampleServiceClient) TriggerUpperCase(ctx context.Context, request
 	}
 
 	response := &testext.SampleResponse{}
-	err := client.Invoke(ctx, "GET", "/v2/Upper/Case/WootyAndTheBlowfish", request, response)
+	err := client.InvokeFunction(ctx, "TriggerUpperCase", "GET", "/v2/Upper/Case/WootyAndTheBlowfish", request, response)
+	return response, err
+
+}
+
+// Upload accepts a raw stream of data rather than relying on auto-decoding
+// the request body.
+func (client *sampleServiceClient) Upload(ctx context.Context, request *testext.SampleUploadRequest) (*testext.SampleResponse, error) {
+
+	if ctx == nil {
+		return nil, fail.Unexpected("precondition failed: nil context")
+	}
+	if request == nil {
+		return nil, fail.Unexpected("precondition failed: nil request")
+	}
+
+	response := &testext.SampleResponse{}
+	err := client.InvokeFunction(ctx, "Upload", "POST", "/v2/upload/{ID}", request, response)
 	return response, err
 
 }
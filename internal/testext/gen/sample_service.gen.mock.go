@@ -36,12 +36,14 @@ type MockSampleService struct {
 	ListenerBFunc         func(context.Context, *testext.SampleRequest) (*testext.SampleResponse, error)
 	OmitMeFunc            func(context.Context, *testext.SampleRequest) (*testext.SampleResponse, error)
 	PanicFunc             func(context.Context, *testext.SampleRequest) (*testext.SampleResponse, error)
+	ProgressFunc          func(context.Context, *testext.SampleRequest) (*testext.SampleProgressResponse, error)
 	RedirectFunc          func(context.Context, *testext.SampleRedirectRequest) (*testext.SampleRedirectResponse, error)
 	SecureWithRolesFunc   func(context.Context, *testext.SampleSecurityRequest) (*testext.SampleSecurityResponse, error)
 	SleepFunc             func(context.Context, *testext.SampleRequest) (*testext.SampleResponse, error)
 	TriggerFailureFunc    func(context.Context, *testext.SampleRequest) (*testext.SampleResponse, error)
 	TriggerLowerCaseFunc  func(context.Context, *testext.SampleRequest) (*testext.SampleResponse, error)
 	TriggerUpperCaseFunc  func(context.Context, *testext.SampleRequest) (*testext.SampleResponse, error)
+	UploadFunc            func(context.Context, *testext.SampleUploadRequest) (*testext.SampleResponse, error)
 
 	Calls struct {
 		Authorization     callsSampleServiceAuthorization
@@ -59,12 +61,14 @@ type MockSampleService struct {
 		ListenerB         callsSampleServiceListenerB
 		OmitMe            callsSampleServiceOmitMe
 		Panic             callsSampleServicePanic
+		Progress          callsSampleServiceProgress
 		Redirect          callsSampleServiceRedirect
 		SecureWithRoles   callsSampleServiceSecureWithRoles
 		Sleep             callsSampleServiceSleep
 		TriggerFailure    callsSampleServiceTriggerFailure
 		TriggerLowerCase  callsSampleServiceTriggerLowerCase
 		TriggerUpperCase  callsSampleServiceTriggerUpperCase
+		Upload            callsSampleServiceUpload
 	}
 }
 
@@ -803,6 +807,55 @@ func (calls callsSampleServicePanic) TimesMatching(pred func(testext.SampleReque
 	return count
 }
 
+/* ---- SampleService.Progress Mock Support For  ---- */
+
+func (mock *MockSampleService) Progress(ctx context.Context, request *testext.SampleRequest) (*testext.SampleProgressResponse, error) {
+	mock.Calls.Progress = mock.Calls.Progress.invoked(*request)
+	if mock.ProgressFunc == nil {
+		return nil, fmt.Errorf("SampleService.Progress not implemented")
+	}
+	response, err := mock.ProgressFunc(ctx, request)
+	return response, err
+}
+
+type callSampleServiceProgress struct {
+	Time    time.Time
+	Request testext.SampleRequest
+}
+
+type callsSampleServiceProgress []callSampleServiceProgress
+
+func (calls callsSampleServiceProgress) invoked(request testext.SampleRequest) callsSampleServiceProgress {
+	return append(calls, callSampleServiceProgress{Time: time.Now(), Request: request})
+}
+
+// Times return the total number of times that Progress was invoked with any request arguments.
+func (calls callsSampleServiceProgress) Times() int {
+	return len(calls)
+}
+
+// TimesFor return the total number of times that Progress was invoked with the specific input. Equality
+// is determined using == on this 'request' param and the de-referenced one used in the invocation, so
+// we'll only county times for those with structural equality.
+func (calls callsSampleServiceProgress) TimesFor(request testext.SampleRequest) int {
+	return calls.TimesMatching(func(actual testext.SampleRequest) bool {
+		return actual == request
+	})
+}
+
+// TimesMatching return the total number of times that Progress was invoked with any
+// input that returns true when fed to your predicate function. It's a way to filter by
+// requests that meet some requirement more complex than equality (like TimesFor uses).
+func (calls callsSampleServiceProgress) TimesMatching(pred func(testext.SampleRequest) bool) int {
+	count := 0
+	for _, call := range calls {
+		if pred(call.Request) {
+			count++
+		}
+	}
+	return count
+}
+
 /* ---- SampleService.Redirect Mock Support For  ---- */
 
 func (mock *MockSampleService) Redirect(ctx context.Context, request *testext.SampleRedirectRequest) (*testext.SampleRedirectResponse, error) {
@@ -1096,3 +1149,52 @@ func (calls callsSampleServiceTriggerUpperCase) TimesMatching(pred func(testext.
 	}
 	return count
 }
+
+/* ---- SampleService.Upload Mock Support For  ---- */
+
+func (mock *MockSampleService) Upload(ctx context.Context, request *testext.SampleUploadRequest) (*testext.SampleResponse, error) {
+	mock.Calls.Upload = mock.Calls.Upload.invoked(*request)
+	if mock.UploadFunc == nil {
+		return nil, fmt.Errorf("SampleService.Upload not implemented")
+	}
+	response, err := mock.UploadFunc(ctx, request)
+	return response, err
+}
+
+type callSampleServiceUpload struct {
+	Time    time.Time
+	Request testext.SampleUploadRequest
+}
+
+type callsSampleServiceUpload []callSampleServiceUpload
+
+func (calls callsSampleServiceUpload) invoked(request testext.SampleUploadRequest) callsSampleServiceUpload {
+	return append(calls, callSampleServiceUpload{Time: time.Now(), Request: request})
+}
+
+// Times return the total number of times that Upload was invoked with any request arguments.
+func (calls callsSampleServiceUpload) Times() int {
+	return len(calls)
+}
+
+// TimesFor return the total number of times that Upload was invoked with the specific input. Equality
+// is determined using == on this 'request' param and the de-referenced one used in the invocation, so
+// we'll only county times for those with structural equality.
+func (calls callsSampleServiceUpload) TimesFor(request testext.SampleUploadRequest) int {
+	return calls.TimesMatching(func(actual testext.SampleUploadRequest) bool {
+		return actual == request
+	})
+}
+
+// TimesMatching return the total number of times that Upload was invoked with any
+// input that returns true when fed to your predicate function. It's a way to filter by
+// requests that meet some requirement more complex than equality (like TimesFor uses).
+func (calls callsSampleServiceUpload) TimesMatching(pred func(testext.SampleUploadRequest) bool) int {
+	count := 0
+	for _, call := range calls {
+		if pred(call.Request) {
+			count++
+		}
+	}
+	return count
+}
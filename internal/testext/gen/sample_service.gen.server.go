@@ -394,6 +394,28 @@ func SampleServiceServer(handler testext.SampleService, middleware ...services.M
 				},
 			},
 
+			{
+				ServiceName: "SampleService",
+				Name:        "Progress",
+				NewInput:    func() services.StructPointer { return &testext.SampleRequest{} },
+				Handler: middlewareFuncs.Then(func(ctx context.Context, req any) (any, error) {
+					typedReq, ok := req.(*testext.SampleRequest)
+					if !ok {
+						return nil, fail.Unexpected("invalid request argument type")
+					}
+					return handler.Progress(ctx, typedReq)
+				}),
+				Roles: []string{},
+				Routes: []services.EndpointRoute{
+					{
+						GatewayType: "API",
+						Method:      "GET",
+						Path:        "/v2/progress",
+						Status:      200,
+					},
+				},
+			},
+
 			{
 				ServiceName: "SampleService",
 				Name:        "Redirect",
@@ -530,6 +552,28 @@ func SampleServiceServer(handler testext.SampleService, middleware ...services.M
 					},
 				},
 			},
+
+			{
+				ServiceName: "SampleService",
+				Name:        "Upload",
+				NewInput:    func() services.StructPointer { return &testext.SampleUploadRequest{} },
+				Handler: middlewareFuncs.Then(func(ctx context.Context, req any) (any, error) {
+					typedReq, ok := req.(*testext.SampleUploadRequest)
+					if !ok {
+						return nil, fail.Unexpected("invalid request argument type")
+					}
+					return handler.Upload(ctx, typedReq)
+				}),
+				Roles: []string{},
+				Routes: []services.EndpointRoute{
+					{
+						GatewayType: "API",
+						Method:      "POST",
+						Path:        "/v2/upload/{ID}",
+						Status:      200,
+					},
+				},
+			},
 		},
 	}
 }
@@ -51,6 +51,32 @@ type OtherService interface {
 	//
 	// ON OtherService.ChainFail
 	ChainFailAfter(ctx context.Context, request *OtherRequest) (*OtherResponse, error)
+
+	// SagaStart is the first step of a saga. When it succeeds, it triggers SagaStep.
+	//
+	// COMPENSATE WITH SagaStartUndo
+	SagaStart(ctx context.Context, request *OtherRequest) (*OtherResponse, error)
+
+	// SagaStep is the second step of the saga. When it succeeds, it triggers SagaFail.
+	//
+	// ON OtherService.SagaStart
+	// COMPENSATE WITH SagaStepUndo
+	SagaStep(ctx context.Context, request *OtherRequest) (*OtherResponse, error)
+
+	// SagaFail always fails, so the saga should be unwound by running SagaStepUndo then SagaStartUndo.
+	//
+	// ON OtherService.SagaStep
+	SagaFail(ctx context.Context, request *OtherRequest) (*OtherResponse, error)
+
+	// SagaStartUndo compensates for SagaStart when a later step in the saga fails.
+	//
+	// HTTP OMIT
+	SagaStartUndo(ctx context.Context, request *OtherRequest) (*OtherResponse, error)
+
+	// SagaStepUndo compensates for SagaStep when a later step in the saga fails.
+	//
+	// HTTP OMIT
+	SagaStepUndo(ctx context.Context, request *OtherRequest) (*OtherResponse, error)
 }
 
 // OtherRequest is a basic payload that partially matches the schema of SampleResponse so
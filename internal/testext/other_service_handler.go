@@ -62,3 +62,28 @@ func (svc OtherServiceHandler) ChainFailAfter(_ context.Context, req *OtherReque
 	svc.Sequence.Append("ChainFailAfter:" + req.Text)
 	return &OtherResponse{Text: "ChainFailAfter:" + req.Text}, nil
 }
+
+func (svc OtherServiceHandler) SagaStart(_ context.Context, req *OtherRequest) (*OtherResponse, error) {
+	svc.Sequence.Append("SagaStart:" + req.Text)
+	return &OtherResponse{Text: "SagaStart:" + req.Text}, nil
+}
+
+func (svc OtherServiceHandler) SagaStep(_ context.Context, req *OtherRequest) (*OtherResponse, error) {
+	svc.Sequence.Append("SagaStep:" + req.Text)
+	return &OtherResponse{Text: "SagaStep:" + req.Text}, nil
+}
+
+func (svc OtherServiceHandler) SagaFail(_ context.Context, req *OtherRequest) (*OtherResponse, error) {
+	svc.Sequence.Append("SagaFail:" + req.Text)
+	return nil, fail.Unexpected("no soup for you")
+}
+
+func (svc OtherServiceHandler) SagaStartUndo(_ context.Context, req *OtherRequest) (*OtherResponse, error) {
+	svc.Sequence.Append("SagaStartUndo:" + req.Text)
+	return &OtherResponse{Text: "SagaStartUndo:" + req.Text}, nil
+}
+
+func (svc OtherServiceHandler) SagaStepUndo(_ context.Context, req *OtherRequest) (*OtherResponse, error) {
+	svc.Sequence.Append("SagaStepUndo:" + req.Text)
+	return &OtherResponse{Text: "SagaStepUndo:" + req.Text}, nil
+}
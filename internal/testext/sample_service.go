@@ -85,6 +85,18 @@ type SampleService interface {
 	// GET /redirect
 	Redirect(context.Context, *SampleRedirectRequest) (*SampleRedirectResponse, error)
 
+	// Upload accepts a raw stream of data rather than relying on auto-decoding
+	// the request body.
+	//
+	// POST /upload/{ID}
+	Upload(context.Context, *SampleUploadRequest) (*SampleResponse, error)
+
+	// Progress sends a live stream of updates using server-sent events rather than
+	// responding with a single value.
+	//
+	// GET /progress
+	Progress(context.Context, *SampleRequest) (*SampleProgressResponse, error)
+
 	// Authorization regurgitates the "Authorization" metadata/header.
 	Authorization(context.Context, *SampleRequest) (*SampleResponse, error)
 
@@ -278,6 +290,16 @@ type SampleDownloadResponse struct {
 	services.StreamResponse
 }
 
+type SampleUploadRequest struct {
+	ID   string
+	Text string
+	services.StreamRequest
+}
+
+type SampleProgressResponse struct {
+	services.EventStream[SampleResponse]
+}
+
 type SampleRedirectRequest struct{}
 
 type SampleRedirectResponse struct {
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"context"
 	"io"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/internal/quiet"
 	"github.com/monadicstack/abide/metadata"
 )
 
@@ -64,12 +66,14 @@ func (s SampleServiceHandler) ComplexValuesPath(_ context.Context, req *SampleCo
 
 func (s SampleServiceHandler) Fail4XX(_ context.Context, req *SampleRequest) (*SampleResponse, error) {
 	s.Sequence.Append("Fail4XX:" + req.Text)
-	return nil, fail.AlreadyExists("always a conflict")
+	return nil, fail.AlreadyExists("always a conflict").
+		WithCode("ALWAYS_CONFLICT").
+		WithDetails(fail.ErrorDetail{Field: "ID", Code: "duplicate", Message: "already exists"})
 }
 
 func (s SampleServiceHandler) Fail5XX(_ context.Context, req *SampleRequest) (*SampleResponse, error) {
 	s.Sequence.Append("Fail5XX:" + req.Text)
-	return nil, fail.BadGateway("always a bad gateway")
+	return nil, fail.BadGateway("always a bad gateway").WithRetry(5 * time.Second)
 }
 
 func (s SampleServiceHandler) CustomRoute(_ context.Context, req *SampleRequest) (*SampleResponse, error) {
@@ -128,6 +132,33 @@ func (s SampleServiceHandler) Redirect(_ context.Context, _ *SampleRedirectReque
 	return &SampleRedirectResponse{URI: "/v2/download?Format=text/csv"}, nil
 }
 
+func (s SampleServiceHandler) Upload(_ context.Context, req *SampleUploadRequest) (*SampleResponse, error) {
+	s.Sequence.Append("Upload:" + req.Text)
+
+	var content []byte
+	if req.Content() != nil {
+		defer quiet.Close(req.Content())
+		content, _ = io.ReadAll(req.Content())
+	}
+	text := strings.Join([]string{req.Text, req.ContentFileName(), req.ContentType(), string(content)}, ":")
+	return &SampleResponse{ID: req.ID, Text: text}, nil
+}
+
+func (s SampleServiceHandler) Progress(ctx context.Context, req *SampleRequest) (*SampleProgressResponse, error) {
+	s.Sequence.Append("Progress:" + req.Text)
+
+	res := &SampleProgressResponse{}
+	go func() {
+		defer res.Close()
+		for i := 1; i <= 3; i++ {
+			if !res.Send(ctx, SampleResponse{ID: strconv.Itoa(i), Text: req.Text}) {
+				return
+			}
+		}
+	}()
+	return res, nil
+}
+
 func (s SampleServiceHandler) Authorization(ctx context.Context, req *SampleRequest) (*SampleResponse, error) {
 	s.Sequence.Append("Authorization:" + req.Text)
 	return &SampleResponse{Text: metadata.Authorization(ctx)}, nil
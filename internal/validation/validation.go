@@ -0,0 +1,349 @@
+// Package validation enforces the constraints that you define using `validate` struct tags on your
+// request structs. The parser uses the same tag parsing, so the constraints that we enforce on the
+// server are the same ones that we put in your OpenAPI docs and client-side checks.
+//
+//	type CreateUserRequest struct {
+//		Name  string `validate:"required,min=2,max=50"`
+//		Email string `validate:"required,email"`
+//		Role  string `validate:"oneof=admin member guest"`
+//		Code  string `validate:"len=6,regex=^[A-Z0-9]+$"`
+//	}
+//
+// A value that is empty (zero) is treated as "not provided", so only the "required" rule applies to it. We can't
+// tell a caller that sent 0 or "" from one that sent nothing, so `validate:"min=18"` accepts an Age of 0 (even
+// through a pointer). Add "required" when the zero value isn't acceptable (e.g. `validate:"required,min=1"`).
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/internal/reflection"
+)
+
+// Rules contains all of the constraints defined by a single `validate` struct tag.
+type Rules struct {
+	// Required indicates that the value can not be empty/zero (e.g. "", 0, nil, or an empty slice).
+	Required bool
+	// Min is the minimum value of a number or the minimum length of a string/slice/map. Like the other
+	// rules besides Required, it doesn't apply to zero values.
+	Min *float64
+	// Max is the maximum value of a number or the maximum length of a string/slice/map.
+	Max *float64
+	// Len is the exact length of a string/slice/map (or the exact value of a number).
+	Len *float64
+	// Regex is a regular expression that string values must match.
+	Regex string
+	// OneOf are the only values that are allowed for this field.
+	OneOf []string
+	// Email indicates that string values must be a valid email address.
+	Email bool
+
+	regex *regexp.Regexp
+}
+
+// Empty returns true when the tag didn't define any constraints at all.
+func (rules Rules) Empty() bool {
+	return !rules.Required && rules.Min == nil && rules.Max == nil && rules.Len == nil &&
+		rules.Regex == "" && len(rules.OneOf) == 0 && !rules.Email
+}
+
+// ParseTag parses the value of a `validate` struct tag such as "required,min=3,max=50". Since regular
+// expressions can contain commas, everything after "regex=" is the pattern, so it should be the last rule.
+func ParseTag(tag string) (Rules, error) {
+	rules := Rules{}
+	tag = strings.TrimSpace(tag)
+	for tag != "" {
+		var rule string
+		switch {
+		case strings.HasPrefix(tag, "regex="):
+			rule, tag = tag, ""
+		default:
+			rule, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, value, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		switch name {
+		case "":
+			continue
+		case "required":
+			rules.Required = true
+		case "email":
+			rules.Email = true
+		case "min":
+			rules.Min = parseNumber(value)
+		case "max":
+			rules.Max = parseNumber(value)
+		case "len":
+			rules.Len = parseNumber(value)
+		case "oneof":
+			rules.OneOf = strings.Fields(value)
+		case "regex":
+			rules.Regex = value
+		default:
+			return rules, fmt.Errorf("unknown rule '%s'", name)
+		}
+
+		switch {
+		case name == "min" && rules.Min == nil, name == "max" && rules.Max == nil, name == "len" && rules.Len == nil:
+			return rules, fmt.Errorf("%s: invalid number '%s'", name, value)
+		case name == "oneof" && len(rules.OneOf) == 0:
+			return rules, fmt.Errorf("oneof: no values")
+		}
+	}
+
+	if rules.Regex != "" {
+		regex, err := regexp.Compile(rules.Regex)
+		if err != nil {
+			return rules, fmt.Errorf("regex: %w", err)
+		}
+		rules.regex = regex
+	}
+	return rules, nil
+}
+
+func parseNumber(value string) *float64 {
+	number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return nil
+	}
+	return &number
+}
+
+// Validate checks every field of the struct (or pointer to one) against its `validate` tag, including
+// the fields of nested structs and slices of structs. When one or more fields are invalid, you get back
+// a single 400 error whose message describes every invalid field:
+//
+//	validation failed: Name is required; Email must be a valid email address
+//
+// Field names use the binding/JSON names (e.g. "User.Digits" or "Items[2].Name") since that's what the
+// caller actually sent us. The error has the code "VALIDATION_FAILED" and one detail per invalid field
+// whose code is the name of the rule that failed (e.g. "required" or "max").
+func Validate(value any) error {
+	var failures []fail.ErrorDetail
+	if err := validateValue(reflect.ValueOf(value), "", &failures); err != nil {
+		return err
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+
+	messages := make([]string, len(failures))
+	for i, failure := range failures {
+		messages[i] = failure.Field + " " + failure.Message
+	}
+	return fail.BadRequest("validation failed: %s", strings.Join(messages, "; ")).
+		WithCode("VALIDATION_FAILED").
+		WithDetails(failures...)
+}
+
+func validateValue(value reflect.Value, path string, failures *[]fail.ErrorDetail) error {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		return validateStruct(value, path, failures)
+	case reflect.Slice, reflect.Array:
+		if !reflection.IsStructOrPointerTo(value.Type().Elem()) {
+			return nil
+		}
+		for i := 0; i < value.Len(); i++ {
+			if err := validateValue(value.Index(i), path+"["+strconv.Itoa(i)+"]", failures); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateStruct(value reflect.Value, path string, failures *[]fail.ErrorDetail) error {
+	fields, err := structFields(value.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, field := range fields {
+		fieldValue := value.Field(field.index)
+		if field.embedded {
+			if err = validateValue(fieldValue, path, failures); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fieldPath := field.name
+		if path != "" {
+			fieldPath = path + "." + field.name
+		}
+		if rule, message := field.rules.check(fieldValue); message != "" {
+			*failures = append(*failures, fail.ErrorDetail{Field: fieldPath, Code: rule, Message: message})
+			continue
+		}
+		if err = validateValue(fieldValue, fieldPath, failures); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// structField is the cached validation info for a single field on a struct type.
+type structField struct {
+	index    int
+	name     string
+	embedded bool
+	rules    Rules
+}
+
+var structFieldCache sync.Map
+
+// structFields returns the validation info for every exported field on the struct type. We only
+// parse the tags for each type once.
+func structFields(structType reflect.Type) ([]structField, error) {
+	if cached, ok := structFieldCache.Load(structType); ok {
+		return cached.([]structField), nil
+	}
+
+	var fields []structField
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if field.Tag.Get("json") == "-" {
+			continue
+		}
+		if field.Anonymous && reflection.IsStructOrPointerTo(field.Type) {
+			fields = append(fields, structField{index: i, embedded: true})
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		rules, err := ParseTag(field.Tag.Get("validate"))
+		if err != nil {
+			return nil, fail.Unexpected("invalid validate tag on %s.%s: %v", structType.Name(), field.Name, err)
+		}
+		fields = append(fields, structField{index: i, name: reflection.BindingName(field), rules: rules})
+	}
+
+	structFieldCache.Store(structType, fields)
+	return fields, nil
+}
+
+// check returns the name of the rule that failed and a message describing why the value doesn't satisfy
+// it (e.g. "required" and "is required"). The message is an empty string when the value is valid.
+func (rules Rules) check(value reflect.Value) (string, string) {
+	if rules.Empty() {
+		return "", ""
+	}
+
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			break
+		}
+		value = value.Elem()
+	}
+	if isEmpty(value) {
+		if rules.Required {
+			return "required", "is required"
+		}
+		return "", ""
+	}
+
+	size, units, sized := valueSize(value)
+	switch {
+	case rules.Len != nil && sized && size != *rules.Len:
+		return "len", "must " + sizeMessage("be exactly", *rules.Len, units)
+	case rules.Min != nil && sized && size < *rules.Min:
+		return "min", "must " + sizeMessage("be at least", *rules.Min, units)
+	case rules.Max != nil && sized && size > *rules.Max:
+		return "max", "must " + sizeMessage("be at most", *rules.Max, units)
+	}
+
+	if len(rules.OneOf) > 0 && !containsValue(rules.OneOf, value) {
+		return "oneof", "must be one of [" + strings.Join(rules.OneOf, " ") + "]"
+	}
+	if value.Kind() != reflect.String {
+		return "", ""
+	}
+	if rules.regex != nil && !rules.regex.MatchString(value.String()) {
+		return "regex", "must match the pattern " + rules.Regex
+	}
+	if rules.Email && !isEmail(value.String()) {
+		return "email", "must be a valid email address"
+	}
+	return "", ""
+}
+
+// isEmpty determines if the value is "not provided"; nil, the zero value, or an empty slice/map.
+func isEmpty(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Pointer, reflect.Interface:
+		return value.IsNil()
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	default:
+		return value.IsZero()
+	}
+}
+
+// valueSize returns the number that the min/max/len rules compare against; the length of strings,
+// slices, and maps or the value of numbers. The units are used to describe the size in error messages.
+func valueSize(value reflect.Value) (float64, string, bool) {
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), "characters", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), "items", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(value.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), "", true
+	default:
+		return 0, "", false
+	}
+}
+
+// sizeMessage builds messages like "be at least 3 characters", "have at most 5 items", or "be at least 18".
+func sizeMessage(comparison string, size float64, units string) string {
+	number := strconv.FormatFloat(size, 'f', -1, 64)
+	switch units {
+	case "":
+		return comparison + " " + number
+	case "items":
+		return "have " + strings.TrimPrefix(comparison, "be ") + " " + number + " " + units
+	default:
+		return comparison + " " + number + " " + units
+	}
+}
+
+func containsValue(values []string, value reflect.Value) bool {
+	// Printing the reflect.Value (rather than value.Interface()) works for fields we reached
+	// through unexported embedded structs, too.
+	text := fmt.Sprint(value)
+	for _, allowed := range values {
+		if allowed == text {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmail(value string) bool {
+	address, err := mail.ParseAddress(value)
+	return err == nil && address.Address == value
+}
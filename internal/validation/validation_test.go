@@ -0,0 +1,185 @@
+//go:build unit
+
+package validation_test
+
+import (
+	"testing"
+
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/internal/validation"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestValidationSuite(t *testing.T) {
+	suite.Run(t, new(ValidationSuite))
+}
+
+type ValidationSuite struct {
+	suite.Suite
+}
+
+type validationAddress struct {
+	City string `validate:"required"`
+	Zip  string `validate:"len=5,regex=^[0-9]+$"`
+}
+
+type validationAudit struct {
+	CreatedBy string `validate:"required"`
+}
+
+type validationRequest struct {
+	validationAudit
+	Name      string            `validate:"required,min=2,max=10"`
+	Email     string            `json:"email_address" validate:"email"`
+	Age       int               `validate:"min=18,max=130"`
+	Role      string            `validate:"oneof=admin member"`
+	Level     int               `validate:"oneof=1 2 3"`
+	Tags      []string          `validate:"max=2"`
+	Nickname  *string           `validate:"required,min=3"`
+	Address   validationAddress `validate:"required"`
+	Addresses []validationAddress
+	Ignored   string `json:"-" validate:"required"`
+	NoRules   string
+}
+
+func (suite *ValidationSuite) validRequest() *validationRequest {
+	nickname := "Dude"
+	return &validationRequest{
+		validationAudit: validationAudit{CreatedBy: "Walter"},
+		Name:            "Lebowski",
+		Email:           "dude@example.com",
+		Age:             48,
+		Role:            "admin",
+		Level:           2,
+		Tags:            []string{"bowling"},
+		Nickname:        &nickname,
+		Address:         validationAddress{City: "Los Angeles", Zip: "90001"},
+	}
+}
+
+func (suite *ValidationSuite) TestParseTag() {
+	r := suite.Require()
+
+	rules, err := validation.ParseTag("")
+	r.NoError(err)
+	r.True(rules.Empty())
+
+	rules, err = validation.ParseTag("required, min=2,max=10.5,len=3,oneof=a b  c,email,regex=^[a-z]{1,3}$")
+	r.NoError(err)
+	r.True(rules.Required)
+	r.True(rules.Email)
+	r.Equal(2.0, *rules.Min)
+	r.Equal(10.5, *rules.Max)
+	r.Equal(3.0, *rules.Len)
+	r.Equal([]string{"a", "b", "c"}, rules.OneOf)
+	r.Equal("^[a-z]{1,3}$", rules.Regex)
+
+	_, err = validation.ParseTag("required,nope")
+	r.Error(err)
+	_, err = validation.ParseTag("min=abc")
+	r.Error(err)
+	_, err = validation.ParseTag("oneof=")
+	r.Error(err)
+	_, err = validation.ParseTag("regex=[a-z")
+	r.Error(err)
+}
+
+func (suite *ValidationSuite) TestValid() {
+	r := suite.Require()
+	r.NoError(validation.Validate(suite.validRequest()))
+	r.NoError(validation.Validate(*suite.validRequest()))
+	r.NoError(validation.Validate(nil))
+	r.NoError(validation.Validate("not a struct"))
+
+	// Empty values are "not provided", so only 'required' applies to them.
+	req := suite.validRequest()
+	req.Email = ""
+	req.Age = 0
+	req.Role = ""
+	req.Tags = nil
+	req.Address.Zip = ""
+	r.NoError(validation.Validate(req))
+}
+
+// Every invalid field should be reported in one error, using binding names and paths to nested fields.
+func (suite *ValidationSuite) TestInvalid() {
+	r := suite.Require()
+
+	nickname := "Do"
+	req := suite.validRequest()
+	req.CreatedBy = ""
+	req.Name = "L"
+	req.Email = "dude at example dot com"
+	req.Age = 12
+	req.Role = "bowler"
+	req.Level = 4
+	req.Tags = []string{"a", "b", "c"}
+	req.Nickname = &nickname
+	req.Address.Zip = "9000A"
+	req.Addresses = []validationAddress{{City: "Malibu", Zip: "90265"}, {Zip: "123"}}
+
+	err := validation.Validate(req)
+	r.Error(err)
+	r.True(fail.IsBadRequest(err))
+	r.Equal("validation failed: "+
+		"CreatedBy is required; "+
+		"Name must be at least 2 characters; "+
+		"email_address must be a valid email address; "+
+		"Age must be at least 18; "+
+		"Role must be one of [admin member]; "+
+		"Level must be one of [1 2 3]; "+
+		"Tags must have at most 2 items; "+
+		"Nickname must be at least 3 characters; "+
+		"Address.Zip must match the pattern ^[0-9]+$; "+
+		"Addresses[1].City is required; "+
+		"Addresses[1].Zip must be exactly 5 characters", err.Error())
+
+	// The failures should also be available as machine-readable details.
+	r.Equal("VALIDATION_FAILED", fail.Code(err))
+	details := fail.Details(err)
+	r.Len(details, 11)
+	r.Equal(fail.ErrorDetail{Field: "CreatedBy", Code: "required", Message: "is required"}, details[0])
+	r.Equal(fail.ErrorDetail{Field: "Role", Code: "oneof", Message: "must be one of [admin member]"}, details[4])
+	r.Equal(fail.ErrorDetail{Field: "Addresses[1].Zip", Code: "len", Message: "must be exactly 5 characters"}, details[10])
+
+	req = suite.validRequest()
+	req.Name = "Jeffrey Lebowski"
+	req.Nickname = nil
+	req.Address = validationAddress{}
+	err = validation.Validate(req)
+	r.Error(err)
+	r.Equal("validation failed: "+
+		"Name must be at most 10 characters; "+
+		"Nickname is required; "+
+		"Address is required", err.Error())
+}
+
+// A busted tag is a bug in the service, not the caller's fault.
+func (suite *ValidationSuite) TestInvalidTag() {
+	type badRequest struct {
+		Name string `validate:"required,maximum=5"`
+	}
+
+	err := validation.Validate(&badRequest{Name: "Dude"})
+	suite.Require().Error(err)
+	suite.Require().Equal(500, fail.Status(err))
+	suite.Require().Contains(err.Error(), "badRequest.Name")
+}
+
+// Zero values are "not provided", so only "required" applies to them; even when the field is a pointer.
+func (suite *ValidationSuite) TestZeroValues() {
+	type zeroRequest struct {
+		Age      int    `validate:"min=18"`
+		Count    int    `validate:"required,min=1"`
+		Code     string `validate:"len=6"`
+		Quantity *int   `validate:"min=1"`
+	}
+
+	zero := 0
+	err := validation.Validate(&zeroRequest{Count: 1, Quantity: &zero})
+	suite.Require().NoError(err)
+
+	err = validation.Validate(&zeroRequest{})
+	suite.Require().Error(err)
+	suite.Equal("validation failed: Count is required", err.Error())
+}
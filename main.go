@@ -18,6 +18,7 @@ func main() {
 	rootCmd.AddCommand(cli.GenerateClient{}.Command())
 	rootCmd.AddCommand(cli.GenerateMock{}.Command())
 	rootCmd.AddCommand(cli.GenerateDocs{}.Command())
+	rootCmd.AddCommand(cli.GenerateGraph{}.Command())
 	// rootCmd.AddCommand(cli.CreateService{}.Command())
 
 	log.SetFlags(0)
@@ -0,0 +1,46 @@
+package metadata
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DeadlineHeader is the header that clients use to tell the remote service how much time (in milliseconds)
+// is left before the caller gives up on the request.
+const DeadlineHeader = "X-RPC-Deadline"
+
+// EncodeDeadline returns the number of milliseconds remaining before the context's deadline so that we can
+// send it to the next service in the call chain. We send the time remaining rather than an absolute
+// timestamp, so we don't need to care about clock skew between servers. If the context has no deadline,
+// this will return an empty string.
+func EncodeDeadline(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ""
+	}
+	remaining := time.Until(deadline).Milliseconds()
+	if remaining < 0 {
+		remaining = 0
+	}
+	return strconv.FormatInt(remaining, 10)
+}
+
+// WithEncodedDeadline applies the deadline propagated from an upstream service (the output of EncodeDeadline) to
+// your context. This way, a 5 second budget at the edge shrinks as the request hops from service to service
+// rather than each hop getting its own fresh timeout. Typically, you should NOT call this directly as the
+// gateways will do that for you. If the value is blank or malformed, the context is left alone.
+func WithEncodedDeadline(ctx context.Context, encodedDeadline string) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	remaining, err := strconv.ParseInt(strings.TrimSpace(encodedDeadline), 10, 64)
+	if err != nil || remaining < 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, time.Duration(remaining)*time.Millisecond)
+}
@@ -0,0 +1,63 @@
+//go:build unit
+
+package metadata_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/metadata"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestDeadlineSuite(t *testing.T) {
+	suite.Run(t, new(DeadlineSuite))
+}
+
+type DeadlineSuite struct {
+	suite.Suite
+}
+
+func (suite *DeadlineSuite) TestEncodeDeadline() {
+	suite.Equal("", metadata.EncodeDeadline(nil))
+	suite.Equal("", metadata.EncodeDeadline(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	remaining, err := strconv.Atoi(metadata.EncodeDeadline(ctx))
+	suite.Require().NoError(err)
+	suite.InDelta(5000, remaining, 100)
+
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+	suite.Equal("0", metadata.EncodeDeadline(expired))
+}
+
+func (suite *DeadlineSuite) TestWithEncodedDeadline() {
+	assertNoDeadline := func(encodedDeadline string) {
+		ctx, cancel := metadata.WithEncodedDeadline(context.Background(), encodedDeadline)
+		defer cancel()
+		_, ok := ctx.Deadline()
+		suite.False(ok, "Should not have a deadline: %s", encodedDeadline)
+	}
+	assertNoDeadline("")
+	assertNoDeadline("  ")
+	assertNoDeadline("5s")
+	assertNoDeadline("-100")
+
+	ctx, cancel := metadata.WithEncodedDeadline(nil, "1500")
+	defer cancel()
+	deadline, ok := ctx.Deadline()
+	suite.Require().True(ok)
+	suite.InDelta(1500*time.Millisecond, time.Until(deadline), float64(100*time.Millisecond))
+
+	// An earlier deadline that's already on the context should still win.
+	parent, cancelParent := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelParent()
+	ctx, cancel = metadata.WithEncodedDeadline(parent, "5000")
+	defer cancel()
+	deadline, _ = ctx.Deadline()
+	suite.Less(time.Until(deadline), 200*time.Millisecond)
+}
@@ -49,3 +49,30 @@ func WithRequestHeaders(ctx context.Context, headers map[string][]string) contex
 	}
 	return context.WithValue(ctx, contextKeyRequestHeaders{}, canonicalHeaders)
 }
+
+type contextKeyRemoteAddr struct{}
+
+// RemoteAddr returns the network address of the client that sent the current gateway request
+// (e.g. "203.0.113.7:52814"). Like RequestHeader, this only represents the most recent request,
+// so it won't follow you when you make RPC-style calls to other services. If your gateway sits
+// behind a proxy/load balancer, this will be the proxy's address unless some middleware rewrites
+// the request's RemoteAddr first.
+func RemoteAddr(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if addr, ok := ctx.Value(contextKeyRemoteAddr{}).(string); ok {
+		return addr
+	}
+	return ""
+}
+
+// WithRemoteAddr stores the network address of the client that sent the current gateway request.
+// You typically should not call this on your own as the framework will do that for you as part of
+// our gateways' standard processing.
+func WithRemoteAddr(ctx context.Context, addr string) context.Context {
+	if ctx == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, contextKeyRemoteAddr{}, addr)
+}
@@ -54,3 +54,12 @@ func (suite *RequestSuite) TestWithRequestHeaders() {
 	// If there are multiple values, return a comma-space delimited string of them
 	suite.Equal("application/json, text/html;q=0.9, */*", metadata.RequestHeader(ctx, "Accept"))
 }
+
+func (suite *RequestSuite) TestWithRemoteAddr() {
+	suite.Equal("", metadata.RemoteAddr(nil))
+	suite.Equal("", metadata.RemoteAddr(context.Background()))
+	suite.Nil(metadata.WithRemoteAddr(nil, "127.0.0.1:1234"))
+
+	ctx := metadata.WithRemoteAddr(context.Background(), "127.0.0.1:1234")
+	suite.Equal("127.0.0.1:1234", metadata.RemoteAddr(ctx))
+}
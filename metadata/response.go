@@ -0,0 +1,95 @@
+package metadata
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+type contextKeyResponseHeaders struct{}
+
+// responseHeaders collects the headers that handlers want to send back to the caller. Handlers might
+// set these from other goroutines, so we need to guard them.
+type responseHeaders struct {
+	mutex  sync.Mutex
+	header http.Header
+	frozen bool
+}
+
+// TrackResponseHeaders lets gateways find out which headers the handler wants included in the response. Call
+// this before invoking the handler and call the returned function afterwards to get a copy of whatever headers
+// the handler set using SetResponseHeader(), AddResponseHeader(), or SetCookie(). You typically should not call
+// this on your own as the framework will do that for you as part of our gateways' standard processing.
+func TrackResponseHeaders(ctx context.Context) (context.Context, func() http.Header) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tracked := &responseHeaders{header: http.Header{}}
+	ctx = context.WithValue(ctx, contextKeyResponseHeaders{}, tracked)
+	return ctx, func() http.Header {
+		tracked.mutex.Lock()
+		defer tracked.mutex.Unlock()
+		return tracked.header.Clone()
+	}
+}
+
+// SetResponseHeader includes the header in the response to the current gateway request, replacing any
+// value you previously set for it. This lets you do things like send a "Location" header along w/ a 201
+// or pagination info (e.g. "X-Total-Count") w/o abandoning your nice, clean service interfaces.
+//
+// Like RequestHeader, this only applies to the most recent/current request, and only when it comes through
+// a gateway that supports headers (i.e. the API gateway). Otherwise, this does nothing. Headers are only
+// sent when your handler succeeds.
+func SetResponseHeader(ctx context.Context, name string, value string) {
+	if tracked := trackedResponseHeaders(ctx); tracked != nil {
+		tracked.mutex.Lock()
+		defer tracked.mutex.Unlock()
+		if !tracked.frozen {
+			tracked.header.Set(name, value)
+		}
+	}
+}
+
+// AddResponseHeader is just like SetResponseHeader, but it appends the value to any that you previously
+// set for the header rather than replacing them.
+func AddResponseHeader(ctx context.Context, name string, value string) {
+	if tracked := trackedResponseHeaders(ctx); tracked != nil {
+		tracked.mutex.Lock()
+		defer tracked.mutex.Unlock()
+		if !tracked.frozen {
+			tracked.header.Add(name, value)
+		}
+	}
+}
+
+// SetCookie includes a "Set-Cookie" header in the response to the current gateway request (e.g. to
+// give the caller a session cookie after logging in). You can call this multiple times to set
+// multiple cookies. The same caveats as SetResponseHeader apply.
+func SetCookie(ctx context.Context, cookie *http.Cookie) {
+	if cookie == nil {
+		return
+	}
+	if value := cookie.String(); value != "" {
+		AddResponseHeader(ctx, "Set-Cookie", value)
+	}
+}
+
+// FreezeResponseHeaders locks in whatever response headers have been set so far; any later calls to
+// SetResponseHeader(), AddResponseHeader(), or SetCookie() for this request are ignored. The framework
+// does this when a call times out, so a handler that's still running can't change the headers out from
+// under the gateway that's already responding. You typically should not call this on your own.
+func FreezeResponseHeaders(ctx context.Context) {
+	if tracked := trackedResponseHeaders(ctx); tracked != nil {
+		tracked.mutex.Lock()
+		defer tracked.mutex.Unlock()
+		tracked.frozen = true
+	}
+}
+
+func trackedResponseHeaders(ctx context.Context) *responseHeaders {
+	if ctx == nil {
+		return nil
+	}
+	tracked, _ := ctx.Value(contextKeyResponseHeaders{}).(*responseHeaders)
+	return tracked
+}
@@ -0,0 +1,76 @@
+//go:build unit
+
+package metadata_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/monadicstack/abide/metadata"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestResponseSuite(t *testing.T) {
+	suite.Run(t, new(ResponseSuite))
+}
+
+type ResponseSuite struct {
+	suite.Suite
+}
+
+// Setting headers w/o a gateway tracking them should quietly do nothing.
+func (suite *ResponseSuite) TestDefaults() {
+	suite.NotPanics(func() {
+		metadata.SetResponseHeader(nil, "Location", "/user/123")
+		metadata.AddResponseHeader(nil, "Location", "/user/123")
+		metadata.SetCookie(nil, &http.Cookie{Name: "session", Value: "abc"})
+		metadata.SetResponseHeader(context.Background(), "Location", "/user/123")
+		metadata.SetCookie(context.Background(), nil)
+	})
+
+	ctx, headers := metadata.TrackResponseHeaders(nil)
+	suite.NotNil(ctx)
+	suite.Equal(http.Header{}, headers())
+}
+
+func (suite *ResponseSuite) TestTrackResponseHeaders() {
+	ctx, headers := metadata.TrackResponseHeaders(context.Background())
+
+	metadata.SetResponseHeader(ctx, "location", "/user/123")
+	metadata.SetResponseHeader(ctx, "X-Total-Count", "1")
+	metadata.SetResponseHeader(ctx, "X-Total-Count", "42")
+	metadata.AddResponseHeader(ctx, "Link", `</user?page=2>; rel="next"`)
+	metadata.AddResponseHeader(ctx, "Link", `</user?page=9>; rel="last"`)
+	metadata.SetCookie(ctx, &http.Cookie{Name: "session", Value: "abc", HttpOnly: true})
+	metadata.SetCookie(ctx, &http.Cookie{Name: "theme", Value: "dark"})
+	metadata.SetCookie(ctx, &http.Cookie{Name: ""})
+
+	suite.Equal(http.Header{
+		"Location":      {"/user/123"},
+		"X-Total-Count": {"42"},
+		"Link":          {`</user?page=2>; rel="next"`, `</user?page=9>; rel="last"`},
+		"Set-Cookie":    {"session=abc; HttpOnly", "theme=dark"},
+	}, headers())
+
+	// You get a copy, so changing it doesn't affect what we've tracked.
+	headers().Set("Location", "/user/456")
+	suite.Equal("/user/123", headers().Get("Location"))
+}
+
+// Once frozen, the headers shouldn't change no matter who tries to set them.
+func (suite *ResponseSuite) TestFreezeResponseHeaders() {
+	suite.NotPanics(func() {
+		metadata.FreezeResponseHeaders(nil)
+		metadata.FreezeResponseHeaders(context.Background())
+	})
+
+	ctx, headers := metadata.TrackResponseHeaders(context.Background())
+	metadata.SetResponseHeader(ctx, "Location", "/user/123")
+	metadata.FreezeResponseHeaders(ctx)
+	metadata.SetResponseHeader(ctx, "Location", "/user/456")
+	metadata.AddResponseHeader(ctx, "Link", `</user?page=2>; rel="next"`)
+	metadata.SetCookie(ctx, &http.Cookie{Name: "session", Value: "abc"})
+
+	suite.Equal(http.Header{"Location": {"/user/123"}}, headers())
+}
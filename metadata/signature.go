@@ -0,0 +1,129 @@
+package metadata
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/monadicstack/abide/fail"
+)
+
+// SignatureHeader is the header that carries the HMAC signature of the X-RPC-Metadata header, so gateways can
+// tell that the metadata came from one of your own services rather than some random caller on the internet.
+const SignatureHeader = "X-RPC-Metadata-Signature"
+
+// DefaultSignatureMaxAge is how long a metadata signature is good for when your VerifyConfig doesn't set a MaxAge.
+const DefaultSignatureMaxAge = 5 * time.Minute
+
+// Sign creates the signature of the encoded metadata using the secret key. Clients send this in the
+// X-RPC-Metadata-Signature header when you use clients.WithMetadataSigningKey(). The signature looks like
+// "1697712000.xxxxx"; the unix time that we signed it followed by the HMAC-SHA256 of that time and the
+// metadata. Binding the time to the signature keeps anyone who captures a request from replaying its
+// metadata forever. When there's no metadata or no key, there's nothing to sign, so this returns "".
+func Sign(encodedMetadata EncodedBytes, key []byte, issuedAt time.Time) string {
+	if encodedMetadata == "" || len(key) == 0 {
+		return ""
+	}
+	timestamp := strconv.FormatInt(issuedAt.Unix(), 10)
+	return timestamp + "." + signature(encodedMetadata, key, timestamp)
+}
+
+// Verify determines if the signature of the encoded metadata was created w/ any of the given keys within
+// 'maxAge' of the current time (in either direction, so a little clock skew between servers is fine). You
+// can supply multiple keys so that you can rotate them w/o breaking calls from services that haven't picked
+// up the new key yet.
+func Verify(encodedMetadata EncodedBytes, signedValue string, maxAge time.Duration, keys ...[]byte) bool {
+	if encodedMetadata == "" || signedValue == "" {
+		return false
+	}
+	timestamp, mac, ok := strings.Cut(signedValue, ".")
+	if !ok {
+		return false
+	}
+	issuedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(issuedAt, 0)); age > maxAge || age < -maxAge {
+		return false
+	}
+
+	for _, key := range keys {
+		if len(key) > 0 && hmac.Equal([]byte(signature(encodedMetadata, key, timestamp)), []byte(mac)) {
+			return true
+		}
+	}
+	return false
+}
+
+func signature(encodedMetadata EncodedBytes, key []byte, timestamp string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(encodedMetadata))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyConfig determines whether a gateway trusts the metadata that callers send. Your own services sign
+// their metadata when their clients use clients.WithMetadataSigningKey(), while metadata w/o a valid, recent
+// signature is ignored (or rejected w/ a 401 if you set Reject). The "Authorization" header is unaffected,
+// so callers can still authenticate as usual.
+//
+// Every gateway that accepts metadata from callers has a WithMetadataVerification() option that accepts
+// one of these, as well as a WithUntrustedMetadata() option that uses the zero value, so the gateway never
+// trusts the metadata at all. That's what you want for gateways that face the outside world.
+type VerifyConfig struct {
+	// Keys are the secrets used to verify the metadata's HMAC signature (see clients.WithMetadataSigningKey()).
+	// Metadata signed w/ any of the keys is trusted, so you can rotate keys w/o downtime. When empty, we
+	// never trust the metadata, which is what you want for gateways exposed to the outside world.
+	Keys [][]byte
+	// Reject makes the gateway fail the request w/ a 401 when the caller sends metadata w/ a missing, invalid,
+	// or expired signature. By default, we quietly ignore the metadata and carry on as if the caller never sent it.
+	Reject bool
+	// MaxAge is how old a signature can be before we stop trusting it. This limits how long someone could replay
+	// metadata that they captured from one of your requests. The default is DefaultSignatureMaxAge.
+	MaxAge time.Duration
+}
+
+// Trusted checks the signature of the metadata that the caller sent. It returns the metadata that the gateway
+// should restore, which is blank if we don't trust it. The error is non-nil when the gateway should reject
+// the request instead. A nil config trusts everything, which is the gateways' default behavior.
+func (config *VerifyConfig) Trusted(encodedMetadata EncodedBytes, signature string) (EncodedBytes, error) {
+	if encodedMetadata == "" || config == nil {
+		return encodedMetadata, nil
+	}
+
+	maxAge := config.MaxAge
+	if maxAge <= 0 {
+		maxAge = DefaultSignatureMaxAge
+	}
+	if Verify(encodedMetadata, signature, maxAge, config.Keys...) {
+		return encodedMetadata, nil
+	}
+	if config.Reject {
+		return "", fail.BadCredentials("invalid %s signature", Header)
+	}
+	return "", nil
+}
+
+// Restore decodes the metadata that the caller sent onto the context, but only the metadata that we trust
+// (see Trusted). The error is non-nil when the gateway should reject the request instead. Gateways should
+// use this rather than calling Decode() on whatever the caller sent.
+func (config *VerifyConfig) Restore(ctx context.Context, encodedMetadata EncodedBytes, signature string) (context.Context, error) {
+	trusted, err := config.Trusted(encodedMetadata, signature)
+	if err != nil {
+		return ctx, err
+	}
+	return Decode(ctx, trusted), nil
+}
+
+// RestoreHeader is just like Restore, but it reads the metadata and signature from the request headers
+// of an HTTP-based gateway.
+func (config *VerifyConfig) RestoreHeader(ctx context.Context, header http.Header) (context.Context, error) {
+	return config.Restore(ctx, EncodedBytes(header.Get(Header)), header.Get(SignatureHeader))
+}
@@ -0,0 +1,134 @@
+//go:build unit
+
+package metadata_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/metadata"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestSignatureSuite(t *testing.T) {
+	suite.Run(t, new(SignatureSuite))
+}
+
+type SignatureSuite struct {
+	suite.Suite
+}
+
+func (suite *SignatureSuite) TestSign() {
+	encoded := metadata.EncodedBytes(`{"Authorization":"Token abc"}`)
+	key := []byte("the dude abides")
+	now := time.Unix(1697712000, 0)
+
+	signature := metadata.Sign(encoded, key, now)
+	suite.Regexp(`^1697712000\.[A-Za-z0-9_-]+$`, signature)
+	suite.Equal(signature, metadata.Sign(encoded, key, now), "Signatures should be deterministic")
+	suite.NotEqual(signature, metadata.Sign(encoded, []byte("nihilists"), now), "Different keys should have different signatures")
+	suite.NotEqual(signature, metadata.Sign(`{"Authorization":"Token xyz"}`, key, now), "Different metadata should have different signatures")
+	suite.NotEqual(signature, metadata.Sign(encoded, key, now.Add(time.Second)), "Different times should have different signatures")
+
+	suite.Equal("", metadata.Sign("", key, now), "Should not sign blank metadata")
+	suite.Equal("", metadata.Sign(encoded, nil, now), "Should not sign w/o a key")
+}
+
+func (suite *SignatureSuite) TestVerify() {
+	encoded := metadata.EncodedBytes(`{"Authorization":"Token abc"}`)
+	oldKey := []byte("the dude abides")
+	newKey := []byte("this aggression will not stand")
+	signature := metadata.Sign(encoded, oldKey, time.Now())
+
+	suite.True(metadata.Verify(encoded, signature, time.Minute, oldKey))
+	suite.True(metadata.Verify(encoded, signature, time.Minute, newKey, oldKey), "Should accept any of the keys")
+	suite.False(metadata.Verify(encoded, signature, time.Minute, newKey))
+	suite.False(metadata.Verify(encoded, signature, time.Minute))
+	suite.False(metadata.Verify(`{"Authorization":"Token xyz"}`, signature, time.Minute, oldKey), "Should reject tampered metadata")
+	suite.False(metadata.Verify(encoded, "", time.Minute, oldKey))
+	suite.False(metadata.Verify(encoded, "garbage", time.Minute, oldKey))
+	suite.False(metadata.Verify(encoded, "garbage.garbage", time.Minute, oldKey))
+	suite.False(metadata.Verify("", metadata.Sign("", oldKey, time.Now()), time.Minute, oldKey))
+}
+
+// Signatures are only good for a limited time, so captured metadata can't be replayed forever. We allow
+// a little leeway in both directions since servers' clocks are never perfectly in sync.
+func (suite *SignatureSuite) TestVerify_expired() {
+	encoded := metadata.EncodedBytes(`{"Authorization":"Token abc"}`)
+	key := []byte("the dude abides")
+
+	suite.True(metadata.Verify(encoded, metadata.Sign(encoded, key, time.Now().Add(-50*time.Second)), time.Minute, key))
+	suite.True(metadata.Verify(encoded, metadata.Sign(encoded, key, time.Now().Add(50*time.Second)), time.Minute, key))
+	suite.False(metadata.Verify(encoded, metadata.Sign(encoded, key, time.Now().Add(-2*time.Minute)), time.Minute, key))
+	suite.False(metadata.Verify(encoded, metadata.Sign(encoded, key, time.Now().Add(2*time.Minute)), time.Minute, key))
+
+	// Swapping in a fresh timestamp should break the signature.
+	stale := metadata.Sign(encoded, key, time.Unix(1697712000, 0))
+	forged := metadata.Sign(encoded, key, time.Now())[:len("1697712000")] + stale[len("1697712000"):]
+	suite.False(metadata.Verify(encoded, forged, time.Minute, key))
+}
+
+func (suite *SignatureSuite) TestTrusted() {
+	encoded := metadata.EncodedBytes(`{"Authorization":"Token abc"}`)
+	key := []byte("the dude abides")
+	signature := metadata.Sign(encoded, key, time.Now())
+	staleSignature := metadata.Sign(encoded, key, time.Now().Add(-10*time.Minute))
+
+	// No config means that we trust everything.
+	var config *metadata.VerifyConfig
+	trusted, err := config.Trusted(encoded, "")
+	suite.NoError(err)
+	suite.Equal(encoded, trusted)
+
+	config = &metadata.VerifyConfig{Keys: [][]byte{key}}
+	trusted, err = config.Trusted(encoded, signature)
+	suite.NoError(err)
+	suite.Equal(encoded, trusted)
+
+	trusted, err = config.Trusted(encoded, staleSignature)
+	suite.NoError(err)
+	suite.Equal(metadata.EncodedBytes(""), trusted, "Should use the default max age")
+
+	trusted, err = config.Trusted("", "")
+	suite.NoError(err)
+	suite.Equal(metadata.EncodedBytes(""), trusted)
+
+	config = &metadata.VerifyConfig{Keys: [][]byte{key}, MaxAge: time.Hour}
+	trusted, err = config.Trusted(encoded, staleSignature)
+	suite.NoError(err)
+	suite.Equal(encoded, trusted)
+
+	config = &metadata.VerifyConfig{Keys: [][]byte{key}, Reject: true}
+	_, err = config.Trusted(encoded, "garbage")
+	suite.Error(err)
+	suite.True(fail.IsBadCredentials(err))
+
+}
+
+func (suite *SignatureSuite) TestRestoreHeader() {
+	encoded := metadata.EncodedBytes(`{"Authorization":"Token abc"}`)
+	key := []byte("the dude abides")
+	config := &metadata.VerifyConfig{Keys: [][]byte{key}}
+
+	// Signed metadata should be decoded onto the context.
+	header := http.Header{}
+	header.Set(metadata.Header, string(encoded))
+	header.Set(metadata.SignatureHeader, metadata.Sign(encoded, key, time.Now()))
+	ctx, err := config.RestoreHeader(context.Background(), header)
+	suite.Require().NoError(err)
+	suite.Equal("Token abc", metadata.Authorization(ctx))
+
+	// Unsigned metadata should be ignored...
+	header.Del(metadata.SignatureHeader)
+	ctx, err = config.RestoreHeader(context.Background(), header)
+	suite.Require().NoError(err)
+	suite.Equal("", metadata.Authorization(ctx))
+
+	// ...or rejected if that's what you asked for.
+	config.Reject = true
+	_, err = config.RestoreHeader(context.Background(), header)
+	suite.True(fail.IsBadCredentials(err))
+}
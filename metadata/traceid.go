@@ -3,10 +3,13 @@ package metadata
 import (
 	"context"
 	"math/rand"
+	"sync"
 	"time"
 )
 
+// traceIDRand isn't safe for concurrent use on its own, so lock traceIDMutex before using it.
 var traceIDRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+var traceIDMutex = sync.Mutex{}
 var traceIDRunes = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
 var traceIDLen = 24
 
@@ -37,6 +40,9 @@ func WithTraceID(ctx context.Context, id string) context.Context {
 // NewTraceID generates a pseudo-random request id for your context/request if one wasn't
 // already provided by the client/caller.
 func NewTraceID() string {
+	traceIDMutex.Lock()
+	defer traceIDMutex.Unlock()
+
 	id := make([]rune, traceIDLen)
 	for i := range id {
 		id[i] = traceIDRunes[traceIDRand.Intn(len(traceIDRunes))]
@@ -116,6 +116,23 @@ func (service ServiceDeclaration) FunctionByName(name string) *ServiceFunctionDe
 	return nil
 }
 
+// Errors returns all of the unique failures declared by any of this service's functions. When more than
+// one function declares an error w/ the same name, the first declaration wins.
+func (service ServiceDeclaration) Errors() ErrorResponses {
+	var results ErrorResponses
+	seen := map[string]bool{}
+	for _, function := range service.Functions {
+		for _, errorResponse := range function.Errors {
+			if seen[errorResponse.Name] {
+				continue
+			}
+			seen[errorResponse.Name] = true
+			results = append(results, errorResponse)
+		}
+	}
+	return results
+}
+
 // ServiceFunctionDeclarations defines a collection of related service functions/operations.
 type ServiceFunctionDeclarations []*ServiceFunctionDeclaration
 
@@ -134,6 +151,24 @@ type ServiceFunctionDeclaration struct {
 	// Roles defines the role-based security identifiers that a user/principal must have in order to access
 	// this endpoint. These can be exact values like "admin.write" or parameterized like "group.{Group.ID}.write".
 	Roles []string
+	// Compensate is the name of the function on this same service that undoes the work of this one
+	// should a later step in an event-driven workflow (saga) fail (e.g. "COMPENSATE WITH ReleaseInventory").
+	Compensate string
+	// Errors are the failures that this function has declared that it can return using the "ERRORS" doc
+	// option (e.g. `ERRORS 404 NotFound "user does not exist", 409 Conflict`).
+	Errors ErrorResponses
+	// Timeout is the maximum amount of time the server will let this function run before failing the
+	// call w/ a 408 (e.g. "TIMEOUT 2s"). The default of 0 means there's no endpoint-specific limit.
+	Timeout time.Duration
+	// RateLimit restricts how often callers can invoke this function (e.g. "RATE 100/m BY Authorization").
+	// This is nil when the function doesn't have the "RATE" doc option.
+	RateLimit *RateLimitOptions
+	// MaxBodySize is the largest request body (in bytes) that the API gateway will accept for this function
+	// (e.g. "MAX BODY 5MB"). The default of 0 means that the gateway's own limit applies, if any.
+	MaxBodySize int64
+	// CacheControl is the "Cache-Control" directive for successful responses from this function (e.g. "max-age=60"
+	// from "CACHE max-age=60"). This is blank when the function doesn't have the "CACHE" doc option.
+	CacheControl string
 	// Documentation are all of the comments documenting this operation.
 	Documentation DocumentationLines
 	// Service represents the interface/service that this function belongs to.
@@ -149,6 +184,36 @@ func (f ServiceFunctionDeclaration) String() string {
 	)
 }
 
+// ErrorResponses is the set of failures that a function declares using the "ERRORS" doc option.
+type ErrorResponses []*ErrorResponse
+
+// NotEmpty returns true if there is at least one declared error in this set.
+func (errs ErrorResponses) NotEmpty() bool {
+	return len(errs) > 0
+}
+
+// ErrorResponse is a single failure declared by the "ERRORS" doc option such as `404 NotFound "user does not exist"`.
+type ErrorResponse struct {
+	// Status is the HTTP status code of the failure (e.g. 404).
+	Status int
+	// Name is the identifier for this failure (e.g. "NotFound"). It's used to name the helper functions in
+	// the generated clients, and it's the code that callers can expect on the error (see fail.Matches).
+	Name string
+	// Description explains when/why this failure occurs. It defaults to the standard text for the status.
+	Description string
+}
+
+// RateLimitOptions is the limit declared by the "RATE" doc option such as "RATE 100/m BY Authorization".
+type RateLimitOptions struct {
+	// Limit is the maximum number of calls allowed during each period (e.g. 100).
+	Limit int
+	// Period is the amount of time that the limit applies to (e.g. 1 minute for "/m").
+	Period time.Duration
+	// By determines whose calls count toward the same limit. It's either "Authorization", "IP", or the
+	// name/path of a field on the request (e.g. "Group.ID"). When blank, all callers share the same limit.
+	By string
+}
+
 // FieldDeclarations collects the fields/attributes on a service model.
 type FieldDeclarations []*FieldDeclaration
 
@@ -225,6 +290,28 @@ type FieldBindingOptions struct {
 	Omit bool
 	// Name is the remapped JSON attribute for the associated field (e.g. `json:"user_id"` -> user_id).
 	Name string
+	// Validation contains the constraints from the field's `validate` tag. This is nil when the field
+	// doesn't have any constraints.
+	Validation *FieldValidationOptions
+}
+
+// FieldValidationOptions describes the constraints from a field's `validate` tag (e.g. `validate:"required,max=50"`).
+// The server enforces these at runtime, but we also include them in docs and clients for client-side checks.
+type FieldValidationOptions struct {
+	// Required indicates that the value can not be empty/zero.
+	Required bool
+	// Min is the minimum value of a number or the minimum length of a string/slice/map.
+	Min *float64
+	// Max is the maximum value of a number or the maximum length of a string/slice/map.
+	Max *float64
+	// Len is the exact length of a string/slice/map (or the exact value of a number).
+	Len *float64
+	// Regex is a regular expression that string values must match.
+	Regex string
+	// OneOf are the only values that are allowed for this field.
+	OneOf []string
+	// Email indicates that string values must be a valid email address.
+	Email bool
 }
 
 // NotOmit is a convenience for templates that returns true when we should expose this field to
@@ -446,6 +533,12 @@ type GatewayRoute struct {
 	Path string
 	// Status indicates what success status code the gateway should use when responding via HTTP (e.g. 200, 202, etc)
 	Status int
+	// Delay is used by event routes to indicate how long after the event fires before we handle it (e.g. "10m").
+	Delay time.Duration
+	// Gateway is the name of the gateway (see services.ListenAs) that this route should register with. When
+	// empty, the route registers with every gateway. This comes from the "GATEWAY xxx" doc option, which
+	// only applies to the function's API route; event routes always register with every gateway.
+	Gateway string
 }
 
 // QualifiedPath returns the route's path with the service's PathPrefix prepended to it. This includes a leading "/"
@@ -593,6 +686,8 @@ type TypeDeclaration struct {
 	Fields FieldDeclarations
 	// Documentation are all of the comments documenting this operation.
 	Documentation DocumentationLines
+	// Event is used by types that implement services.EventStreamer to describe the type of each value in the stream.
+	Event *TypeDeclaration
 	// Implements contains some quick checks for whether or not this type implements the various
 	// single function interfaces used to handle raw data responses.
 	Implements struct {
@@ -618,6 +713,8 @@ type TypeDeclaration struct {
 		ContentFileNameGetter bool
 		// ContentFileNameSetter is true when it implements that interface.
 		ContentFileNameSetter bool
+		// EventStreamer is true when it implements that interface.
+		EventStreamer bool
 	}
 }
 
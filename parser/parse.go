@@ -1,12 +1,14 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"go/ast"
 	"go/doc"
 	"go/parser"
 	"go/token"
 	"go/types"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -19,6 +21,7 @@ import (
 	"github.com/monadicstack/abide/internal/implements"
 	"github.com/monadicstack/abide/internal/naming"
 	"github.com/monadicstack/abide/internal/slices"
+	"github.com/monadicstack/abide/internal/validation"
 	"golang.org/x/mod/modfile"
 	"golang.org/x/tools/go/packages"
 )
@@ -54,6 +57,9 @@ var ErrTypeNotTwoParams = fmt.Errorf("must have two params")
 // ErrTypeNotTwoReturns is the error for when your function signature doesn't return two values.
 var ErrTypeNotTwoReturns = fmt.Errorf("must have two return values")
 
+// ErrInvalidValidateTag is the error for when a struct field has a `validate` tag that we can't parse.
+var ErrInvalidValidateTag = fmt.Errorf("invalid validate tag")
+
 // InvalidType is the type instance used by the AST parser to indicate types that the parser couldn't resolve.
 var InvalidType = types.Typ[0]
 
@@ -171,8 +177,7 @@ func registerTypeEntry(ctx *Context, registry TypeRegistry, entry *TypeDeclarati
 	case *types.Struct:
 		// Recursively parse the type information for all the field members of the struct.
 		entry.Kind = reflect.Struct
-		parseStructFields(ctx, registry, entry, tt)
-		return nil
+		return parseStructFields(ctx, registry, entry, tt)
 
 	case *types.Named:
 		// This happens when you do 'type MyRequest SomeModel' when SomeModel is in a different file in the same
@@ -211,6 +216,15 @@ func registerTypeEntry(ctx *Context, registry TypeRegistry, entry *TypeDeclarati
 		entry.Implements.ContentLengthSetter = implements.Method(tt, "SetContentLength", []string{"int"}, nil)
 		entry.Implements.ContentRangeSetter = implements.Method(tt, "SetContentRange", []string{"int", "int", "int"}, nil)
 		entry.Implements.ContentFileNameSetter = implements.Method(tt, "SetContentFileName", []string{"string"}, nil)
+		entry.Implements.EventStreamer = implements.Method(tt, "NextEvent", []string{"context.Context"}, nil)
+
+		// Event streams (e.g. services.EventStream[T]) let you read values using "Events() <-chan T", so
+		// we can use that to figure out what type of values the clients should expect from the stream.
+		if eventsType, ok := implements.MethodResult(tt, "Events").(*types.Chan); ok && entry.Implements.EventStreamer {
+			if entry.Event, err = registerType(ctx, registry, eventsType.Elem()); err != nil {
+				return err
+			}
+		}
 
 	case *types.Array:
 		entry.Basic = entry.Type == t
@@ -244,20 +258,29 @@ func registerTypeEntry(ctx *Context, registry TypeRegistry, entry *TypeDeclarati
 	return err
 }
 
-func parseStructFields(ctx *Context, registry TypeRegistry, model *TypeDeclaration, structType *types.Struct) {
+func parseStructFields(ctx *Context, registry TypeRegistry, model *TypeDeclaration, structType *types.Struct) error {
 	for _, structField := range flattenedStructFields(structType) {
-		fieldDecl := parseStructField(ctx, registry, model, structField)
+		fieldDecl, err := parseStructField(ctx, registry, model, structField)
+		if err != nil {
+			return err
+		}
 		if fieldDecl == nil {
 			continue
 		}
 		model.Fields = append(model.Fields, fieldDecl)
 	}
+	return nil
 }
 
-func parseStructField(ctx *Context, registry TypeRegistry, model *TypeDeclaration, structField *types.Var) *FieldDeclaration {
+func parseStructField(ctx *Context, registry TypeRegistry, model *TypeDeclaration, structField *types.Var) (*FieldDeclaration, error) {
+	// Fields whose types we can't handle are just left out, but a bad `validate` tag anywhere in the
+	// field's type (e.g. on a nested struct) should still fail the whole thing.
 	fieldType, err := registerType(ctx, registry, structField.Type())
+	if errors.Is(err, ErrInvalidValidateTag) {
+		return nil, err
+	}
 	if fieldType.Kind == reflect.Invalid || err != nil {
-		return nil
+		return nil, nil
 	}
 
 	fieldDecl := &FieldDeclaration{
@@ -266,8 +289,10 @@ func parseStructField(ctx *Context, registry TypeRegistry, model *TypeDeclaratio
 		Type:       fieldType,
 		Pointer:    pointerType(structField.Type()),
 	}
-	fieldDecl.Binding = ParseBindingOptions(ctx, fieldDecl, structField)
-	return ApplyFieldDocumentation(ctx, fieldDecl)
+	if fieldDecl.Binding, err = ParseBindingOptions(ctx, fieldDecl, structField); err != nil {
+		return nil, err
+	}
+	return ApplyFieldDocumentation(ctx, fieldDecl), nil
 }
 
 // ParsePackageInfo overlays your project's "go.mod" file and your input file/path to figure
@@ -538,9 +563,40 @@ func ParseServiceFunctions(ctx *Context, service *ServiceDeclaration, interfaceT
 
 		functions = append(functions, function)
 	}
+	if err := applyCompensation(service, functions); err != nil {
+		return nil, err
+	}
 	return functions, nil
 }
 
+// applyCompensation wires up the "COMPENSATE WITH Xxx" doc options. The event gateway needs to subscribe the
+// compensating function to the step that it undoes, so we give the compensating function an extra event route
+// whose path is the step (e.g. ReleaseInventory gets "COMPENSATE InventoryService.ReserveInventory").
+func applyCompensation(service *ServiceDeclaration, functions []*ServiceFunctionDeclaration) error {
+	for _, function := range functions {
+		if function.Compensate == "" {
+			continue
+		}
+
+		var compensate *ServiceFunctionDeclaration
+		for _, f := range functions {
+			if f.Name == function.Compensate {
+				compensate = f
+			}
+		}
+		if compensate == nil {
+			return fmt.Errorf("%s.%s(): compensate with: function %s not found", service.Name, function.Name, function.Compensate)
+		}
+		compensate.Routes = append(compensate.Routes, &GatewayRoute{
+			Function:    compensate,
+			GatewayType: "EVENTS",
+			Method:      "COMPENSATE",
+			Path:        service.Name + "." + function.Name,
+		})
+	}
+	return nil
+}
+
 // ParseServiceFunction captures the information for a single function on a service. This includes all of the
 // doc options that configure the gateway stuff.
 func ParseServiceFunction(ctx *Context, service *ServiceDeclaration, funcType *types.Func) (*ServiceFunctionDeclaration, error) {
@@ -594,7 +650,9 @@ func ParseServiceFunction(ctx *Context, service *ServiceDeclaration, funcType *t
 		return nil, fmt.Errorf("%s(): response struct must be defined in %s", function.Name, ctx.Path)
 	}
 
-	ApplyFunctionDocumentation(ctx, function)
+	if err := ApplyFunctionDocumentation(ctx, function); err != nil {
+		return nil, err
+	}
 	return function, nil
 }
 
@@ -635,21 +693,27 @@ func flattenedStructFields(structType *types.Struct) []*types.Var {
 	return fields
 }
 
-// ParseBindingOptions looks at the `json` tags of the given struct field and returns this field's binding
-// configuration. It indicates whether the field should be left out of JSON marshaling and what field name to
-// use when going to/from JSON format. If the field has no `json` tag, you will get a set of binding options
-// representing the default values (i.e. include the field and use its exact name).
-func ParseBindingOptions(ctx *Context, field *FieldDeclaration, fieldVar *types.Var) *FieldBindingOptions {
+// ParseBindingOptions looks at the `json` and `validate` tags of the given struct field and returns this field's binding
+// options. The `json` tag determines the name that clients use for the field and the `validate` tag describes
+// the constraints that the server enforces on it. An invalid `validate` tag is an error, so you find out when
+// you generate your code rather than the first time somebody calls the function.
+func ParseBindingOptions(ctx *Context, field *FieldDeclaration, fieldVar *types.Var) (*FieldBindingOptions, error) {
+	validationOptions, err := parseValidationOptions(ctx.Tags.ForField(field).Get("validate"))
+	if err != nil {
+		return nil, fmt.Errorf("%s.%s: %w: %v", field.ParentType.Name, field.Name, ErrInvalidValidateTag, err)
+	}
+
 	options := &FieldBindingOptions{
-		Omit: false,
-		Name: varName(fieldVar),
+		Omit:       false,
+		Name:       varName(fieldVar),
+		Validation: validationOptions,
 	}
 
 	// The field doesn't have a 'json' tag assigned or they weirdly defined `json:""`, then
 	// the default binding options reign supreme.
 	tag := ctx.Tags.ForField(field).Get("json")
 	if tag == "" {
-		return options
+		return options, nil
 	}
 
 	// We don't care about 'omitempty' or anything other than the remapped name. The
@@ -657,11 +721,32 @@ func ParseBindingOptions(ctx *Context, field *FieldDeclaration, fieldVar *types.
 	switch name := strings.Split(tag, ",")[0]; name {
 	case "-":
 		options.Omit = true
-		return options
+		return options, nil
 	default:
 		options.Name = name
-		return options
+		return options, nil
+	}
+}
+
+// parseValidationOptions parses a `validate` tag using the same rules as the runtime validation. If the tag
+// is empty, we won't include any constraints in docs/clients.
+func parseValidationOptions(tag string) (*FieldValidationOptions, error) {
+	rules, err := validation.ParseTag(tag)
+	if err != nil {
+		return nil, err
 	}
+	if rules.Empty() {
+		return nil, nil
+	}
+	return &FieldValidationOptions{
+		Required: rules.Required,
+		Min:      rules.Min,
+		Max:      rules.Max,
+		Len:      rules.Len,
+		Regex:    rules.Regex,
+		OneOf:    rules.OneOf,
+		Email:    rules.Email,
+	}, nil
 }
 
 // The first param to all service functions should be a standard "context.Context"
@@ -748,6 +833,149 @@ func parseHTTPStatus(statusText string) int {
 	return int(status)
 }
 
+// parseEventKey parses the right hand side of an "ON FooService.Bar" doc option. The option can
+// optionally include a delay such as "ON FooService.Bar DELAY 10m" to indicate that the subscriber
+// should not handle the event until that much time has passed. If the delay isn't a valid, non-negative
+// duration, we return an error rather than quietly handling the event immediately.
+func parseEventKey(value string) (string, time.Duration, error) {
+	key, delayText, ok := strings.Cut(strings.TrimSpace(value), " DELAY ")
+	if !ok {
+		return key, 0, nil
+	}
+
+	delayText = strings.TrimSpace(delayText)
+	delay, err := time.ParseDuration(delayText)
+	if err != nil || delay < 0 {
+		return "", 0, fmt.Errorf("invalid DELAY '%s': must be a non-negative duration such as '10m'", delayText)
+	}
+	return strings.TrimSpace(key), delay, nil
+}
+
+// parseTimeout parses the right hand side of a "TIMEOUT 2s" doc option. If the value isn't a valid,
+// non-negative duration, we return an error rather than quietly leaving the function w/o a timeout.
+func parseTimeout(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	timeout, err := time.ParseDuration(value)
+	if err != nil || timeout < 0 {
+		return 0, fmt.Errorf("invalid TIMEOUT '%s': must be a non-negative duration such as '2s'", value)
+	}
+	return timeout, nil
+}
+
+// parseRateLimit parses the right hand side of a "RATE 100/m BY Authorization" doc option. The period after
+// the slash can be a unit ("s", "m", "h", or "d") or any duration that time.ParseDuration understands (e.g.
+// "100/10s"). The "BY xxx" portion is optional. If the limit or period is invalid, we return an error rather
+// than quietly leaving the function without any rate limit at all.
+func parseRateLimit(value string) (*RateLimitOptions, error) {
+	rateText, by, _ := strings.Cut(strings.TrimSpace(value), " BY ")
+	rateText = strings.TrimSpace(rateText)
+	invalid := fmt.Errorf("invalid RATE '%s': must be a positive limit per period such as '100/m' or '5/30s'", rateText)
+
+	limitText, periodText, ok := strings.Cut(rateText, "/")
+	if !ok {
+		return nil, invalid
+	}
+
+	limit, err := strconv.Atoi(strings.TrimSpace(limitText))
+	if err != nil || limit <= 0 {
+		return nil, invalid
+	}
+
+	var period time.Duration
+	switch periodText = strings.TrimSpace(periodText); periodText {
+	case "s", "sec", "second":
+		period = time.Second
+	case "m", "min", "minute":
+		period = time.Minute
+	case "h", "hour":
+		period = time.Hour
+	case "d", "day":
+		period = 24 * time.Hour
+	default:
+		period, err = time.ParseDuration(periodText)
+		if err != nil || period <= 0 {
+			return nil, invalid
+		}
+	}
+	return &RateLimitOptions{Limit: limit, Period: period, By: strings.TrimSpace(by)}, nil
+}
+
+// parseByteSize parses the right hand side of a "MAX BODY 5MB" doc option. The units are "B", "KB", "MB", and
+// "GB", where each is 1024 of the previous one. A number w/o units is just a number of bytes. If the value is
+// invalid or too big to fit in an int64, we return an error rather than quietly ignoring the limit.
+func parseByteSize(value string) (int64, error) {
+	invalid := fmt.Errorf("invalid MAX BODY '%s': must be a positive size such as '512KB' or '5MB'", strings.TrimSpace(value))
+	value = strings.ToUpper(strings.TrimSpace(value))
+	numberText := strings.TrimRight(value, "KMGB ")
+
+	multiplier := int64(1)
+	switch strings.TrimSpace(value[len(numberText):]) {
+	case "", "B":
+	case "KB":
+		multiplier = 1024
+	case "MB":
+		multiplier = 1024 * 1024
+	case "GB":
+		multiplier = 1024 * 1024 * 1024
+	default:
+		return 0, invalid
+	}
+
+	size, err := strconv.ParseInt(numberText, 10, 64)
+	if err != nil || size <= 0 || size > math.MaxInt64/multiplier {
+		return 0, invalid
+	}
+	return size * multiplier, nil
+}
+
+// parseErrorResponses parses the right hand side of an "ERRORS" doc option, which is a comma-separated list of
+// failures that look like `404 NotFound "user does not exist"`. The name and quoted description are optional;
+// we'll use the standard status text for them when they're missing. Entries whose status isn't a valid
+// 4XX/5XX code are ignored.
+func parseErrorResponses(value string) ErrorResponses {
+	var results ErrorResponses
+	for _, entry := range splitOutsideQuotes(value, ',') {
+		statusText, rest, _ := strings.Cut(strings.TrimSpace(entry), " ")
+		status, err := strconv.Atoi(statusText)
+		if err != nil || status < 400 || status > 599 {
+			continue
+		}
+
+		name, description := "", strings.TrimSpace(rest)
+		if !strings.HasPrefix(description, `"`) {
+			name, description, _ = strings.Cut(description, " ")
+		}
+		if unquoted, err := strconv.Unquote(strings.TrimSpace(description)); err == nil {
+			description = unquoted
+		}
+		if name == "" {
+			name = strings.ReplaceAll(http.StatusText(status), " ", "")
+		}
+		if description = strings.TrimSpace(description); description == "" {
+			description = http.StatusText(status)
+		}
+		results = append(results, &ErrorResponse{Status: status, Name: name, Description: description})
+	}
+	return results
+}
+
+// splitOutsideQuotes splits the value on each separator that is not inside of a double-quoted string.
+func splitOutsideQuotes(value string, separator rune) []string {
+	var results []string
+	quoted := false
+	start := 0
+	for i, r := range value {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == separator && !quoted:
+			results = append(results, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(results, value[start:])
+}
+
 // ApplyServiceDocumentation takes the documentation comment block above your interface type
 // declaration and applies them to the service snapshot, parsing all Doc Options in the process.
 func ApplyServiceDocumentation(ctx *Context, service *ServiceDeclaration) *ServiceDeclaration {
@@ -773,9 +1001,10 @@ func ApplyServiceDocumentation(ctx *Context, service *ServiceDeclaration) *Servi
 
 // ApplyFunctionDocumentation takes the documentation comment block above your interface function
 // declaration and applies them to the function snapshot, parsing all Doc Options in the process.
-func ApplyFunctionDocumentation(ctx *Context, function *ServiceFunctionDeclaration) {
+// This fails when a doc option is malformed in a way that we can't safely ignore.
+func ApplyFunctionDocumentation(ctx *Context, function *ServiceFunctionDeclaration) error {
 	if ctx == nil || function == nil {
-		return
+		return nil
 	}
 
 	// By default, this endpoint includes an HTTP/API route using RPC-style paths. Event
@@ -792,8 +1021,8 @@ func ApplyFunctionDocumentation(ctx *Context, function *ServiceFunctionDeclarati
 	// Notice that "OPTIONS /" is not one of the cases. That's by design. When the gateway
 	// registers your POST operation (or whatever method), we're actually going to register
 	// that method AND an OPTIONS route for you. By default, the OPTIONS route will simply
-	// reject the request (i.e. no default CORS). If bring your own CORS middleware to the
-	// party it will respond affirmatively before the rejection. There's more info in the
+	// reject the request (i.e. no default CORS). If you use apis.WithCORS() or bring your own
+	// CORS middleware to the party it will respond affirmatively before the rejection. There's more info in the
 	// comments of gateway.New() that describes why we need this limitation for now.
 	for _, line := range ctx.Documentation.ForFunction(function) {
 		switch {
@@ -825,30 +1054,64 @@ func ApplyFunctionDocumentation(ctx *Context, function *ServiceFunctionDeclarati
 			function.Routes = slices.Remove(function.Routes, &apiRoute)
 		case strings.HasPrefix(line, "HTTP "):
 			apiRoute.Status = parseHTTPStatus(line[5:])
+		case strings.HasPrefix(line, "GATEWAY "):
+			// This only targets the API route; "ON xxx" event routes are still handled by your events gateway.
+			apiRoute.Gateway = strings.TrimSpace(line[8:])
 
 		//
 		// Event gateway options
 		//
 		case strings.HasPrefix(line, "ON "):
+			key, delay, err := parseEventKey(line[3:])
+			if err != nil {
+				return fmt.Errorf("%s.%s(): %s: %w", function.Service.Name, function.Name, line, err)
+			}
 			function.Routes = append(function.Routes, &GatewayRoute{
 				Function:    function,
 				GatewayType: "EVENTS",
 				Method:      "ON",
-				Path:        strings.TrimSpace(line[3:]),
+				Path:        key,
+				Delay:       delay,
 			})
 
+		case strings.HasPrefix(line, "COMPENSATE WITH "):
+			function.Compensate = strings.TrimSpace(line[16:])
+
 		//
 		// General purpose options (like for security/metadata)
 		//
 		case strings.HasPrefix(line, "ROLES "):
 			roles := strings.Split(strings.TrimSpace(line[6:]), ",")
 			function.Roles = slices.Map(roles, strings.TrimSpace)
+		case strings.HasPrefix(line, "ERRORS "):
+			function.Errors = append(function.Errors, parseErrorResponses(line[7:])...)
+		case strings.HasPrefix(line, "TIMEOUT "):
+			timeout, err := parseTimeout(line[8:])
+			if err != nil {
+				return fmt.Errorf("%s.%s(): %s: %w", function.Service.Name, function.Name, line, err)
+			}
+			function.Timeout = timeout
+		case strings.HasPrefix(line, "RATE "):
+			rateLimit, err := parseRateLimit(line[5:])
+			if err != nil {
+				return fmt.Errorf("%s.%s(): %s: %w", function.Service.Name, function.Name, line, err)
+			}
+			function.RateLimit = rateLimit
+		case strings.HasPrefix(line, "MAX BODY "):
+			maxBodySize, err := parseByteSize(line[9:])
+			if err != nil {
+				return fmt.Errorf("%s.%s(): %s: %w", function.Service.Name, function.Name, line, err)
+			}
+			function.MaxBodySize = maxBodySize
+		case strings.HasPrefix(line, "CACHE "):
+			function.CacheControl = strings.TrimSpace(line[6:])
 
 		default:
 			function.Documentation = append(function.Documentation, line)
 		}
 	}
 	function.Documentation = function.Documentation.Trim()
+	return nil
 }
 
 // ApplyTypeDocumentation takes the documentation comment block above your struct/alias type
@@ -4,6 +4,7 @@ package parser_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/monadicstack/abide/parser"
 	"github.com/stretchr/testify/suite"
@@ -91,9 +92,14 @@ func (suite *ParserSuite) TestDocOptions() {
 	suite.assertFunction(service, "Jackie", expectedFunction{
 		Documentation: parser.DocumentationLines{},
 		Routes: parser.GatewayRoutes{
-			&parser.GatewayRoute{GatewayType: "API", Method: "PUT", Path: "/dude/jail", Status: 200},
+			&parser.GatewayRoute{GatewayType: "API", Method: "PUT", Path: "/dude/jail", Status: 200, Gateway: "internal"},
 		},
 	})
+	suite.Require().Equal(2*time.Second, service.FunctionByName("Jackie").Timeout, "Jackie(): Incorrect timeout")
+	suite.Require().Equal(time.Duration(0), service.FunctionByName("Maude").Timeout, "Maude(): Should not have a timeout")
+	suite.Require().Equal(&parser.RateLimitOptions{Limit: 100, Period: time.Minute, By: "Authorization"}, service.FunctionByName("Jackie").RateLimit, "Jackie(): Incorrect rate limit")
+	suite.Require().Equal(&parser.RateLimitOptions{Limit: 5, Period: 30 * time.Second}, service.FunctionByName("Maude").RateLimit, "Maude(): Incorrect rate limit")
+	suite.Require().Nil(service.FunctionByName("Donny").RateLimit, "Donny(): Should not have a rate limit")
 
 	suite.assertFunction(service, "Stranger", expectedFunction{
 		Documentation: parser.DocumentationLines{
@@ -114,6 +120,16 @@ func (suite *ParserSuite) TestDocOptions() {
 			&parser.GatewayRoute{GatewayType: "API", Method: "DELETE", Path: "/nihilist/{id}/toe", Status: 200},
 		},
 	})
+	suite.Require().Equal(parser.ErrorResponses{
+		{Status: 404, Name: "NotFound", Description: "the toe does not exist"},
+		{Status: 409, Name: "Conflict", Description: "Conflict"},
+		{Status: 402, Name: "PaymentRequired", Description: "we need the money"},
+	}, service.FunctionByName("RemoveToe").Errors, "RemoveToe(): Incorrect errors")
+	suite.Require().Empty(service.FunctionByName("Rug").Errors, "Rug(): Should not have errors")
+	suite.Require().Equal(int64(5*1024*1024), service.FunctionByName("RemoveToe").MaxBodySize, "RemoveToe(): Incorrect max body size")
+	suite.Require().Equal(int64(0), service.FunctionByName("Rug").MaxBodySize, "Rug(): Should not have a max body size")
+	suite.Require().Equal("private, max-age=60", service.FunctionByName("Dude").CacheControl, "Dude(): Incorrect cache control")
+	suite.Require().Equal("", service.FunctionByName("Rug").CacheControl, "Rug(): Should not have cache control")
 
 	suite.assertFunction(service, "Rug", expectedFunction{
 		Documentation: parser.DocumentationLines{
@@ -150,7 +166,9 @@ func (suite *ParserSuite) TestDocOptions() {
 		},
 		Routes: parser.GatewayRoutes{
 			&parser.GatewayRoute{GatewayType: "EVENTS", Method: "ON", Path: "LebowskiService.Dude"},
-			&parser.GatewayRoute{GatewayType: "EVENTS", Method: "ON", Path: "LebowskiService.Walter"},
+			&parser.GatewayRoute{GatewayType: "EVENTS", Method: "ON", Path: "LebowskiService.Walter", Delay: 90 * time.Minute},
+			// Bowling has "COMPENSATE WITH BowlingEnd", so BowlingEnd should subscribe to undo it.
+			&parser.GatewayRoute{GatewayType: "EVENTS", Method: "COMPENSATE", Path: "LebowskiService.Bowling"},
 		},
 	})
 }
@@ -180,6 +198,16 @@ func (suite *ParserSuite) TestBindingOptions() {
 	suite.Require().Equal("include", binding.Name)
 	suite.Require().False(binding.Omit)
 	suite.Require().True(binding.NotOmit())
+
+	// The `validate` tag constraints should come along for the ride.
+	float := func(value float64) *float64 { return &value }
+	suite.Require().Equal(&parser.FieldValidationOptions{Required: true, Len: float(8)},
+		request.Fields.ByName("ID").Binding.Validation)
+	suite.Require().Equal(&parser.FieldValidationOptions{Min: float(2), Max: float(50), Regex: "^[A-Z][a-z]{1,}$"},
+		request.Fields.ByName("Name").Binding.Validation)
+	suite.Require().Nil(request.Fields.ByName("OmitMe").Binding.Validation)
+	suite.Require().Equal(&parser.FieldValidationOptions{OneOf: []string{"yes", "no"}, Email: true},
+		request.Fields.ByName("IncludeMe").Binding.Validation)
 }
 
 func (suite *ParserSuite) TestFieldTypes() {
@@ -324,6 +352,47 @@ func (suite *ParserSuite) TestErrorResponseNotStruct() {
 	suite.Require().Contains(err.Error(), "error", "Error should mention the need for an error return value")
 }
 
+func (suite *ParserSuite) TestErrorCompensateNotFound() {
+	_, err := parser.ParseFile("testdata/errors/compensate/service.go")
+	suite.Require().Error(err, "Should fail when COMPENSATE WITH refers to a function not on the service")
+	suite.Require().Contains(err.Error(), "Goodbye", "Error should include the missing function name")
+}
+
+func (suite *ParserSuite) TestErrorInvalidValidateTag() {
+	_, err := parser.ParseFile("testdata/errors/validate/service.go")
+	suite.Require().Error(err, "Should fail when a field has an invalid `validate` tag")
+	suite.Require().Contains(err.Error(), "Request.BadRules", "Error should include the struct and field names")
+	suite.Require().Contains(err.Error(), "nope", "Error should include the invalid rule")
+}
+
+func (suite *ParserSuite) TestErrorInvalidDelay() {
+	_, err := parser.ParseFile("testdata/errors/delay/service.go")
+	suite.Require().Error(err, "Should fail when an ON option has an invalid DELAY")
+	suite.Require().Contains(err.Error(), "FooService.Hello()", "Error should include the function name")
+	suite.Require().Contains(err.Error(), "10 minutes", "Error should include the bad duration")
+}
+
+func (suite *ParserSuite) TestErrorInvalidRateLimit() {
+	_, err := parser.ParseFile("testdata/errors/ratelimit/service.go")
+	suite.Require().Error(err, "Should fail when a RATE option is invalid")
+	suite.Require().Contains(err.Error(), "FooService.Hello()", "Error should include the function name")
+	suite.Require().Contains(err.Error(), "lots/m", "Error should include the bad rate")
+}
+
+func (suite *ParserSuite) TestErrorInvalidTimeout() {
+	_, err := parser.ParseFile("testdata/errors/timeout/service.go")
+	suite.Require().Error(err, "Should fail when a TIMEOUT option is invalid")
+	suite.Require().Contains(err.Error(), "FooService.Hello()", "Error should include the function name")
+	suite.Require().Contains(err.Error(), "-2s", "Error should include the bad duration")
+}
+
+func (suite *ParserSuite) TestErrorInvalidMaxBody() {
+	_, err := parser.ParseFile("testdata/errors/maxbody/service.go")
+	suite.Require().Error(err, "Should fail when a MAX BODY option is invalid")
+	suite.Require().Contains(err.Error(), "FooService.Hello()", "Error should include the function name")
+	suite.Require().Contains(err.Error(), "5 megabytes", "Error should include the bad size")
+}
+
 /*
  * ----------- Assertion Helpers ----------------------
  */
@@ -365,6 +434,7 @@ func (suite *ParserSuite) assertFunction(service *parser.ServiceDeclaration, fun
 		suite.Equal(expectedRoute.Path, apiRoute.Path, "%s: API Route: Incorrect path", name)
 		suite.Equal(expectedRoute.Method, apiRoute.Method, "%s: API Route: Incorrect method", name)
 		suite.Equal(expectedRoute.Status, apiRoute.Status, "%s: API Route: Incorrect status", name)
+		suite.Equal(expectedRoute.Gateway, apiRoute.Gateway, "%s: API Route: Incorrect gateway", name)
 	}
 
 	events := f.Routes.Events()
@@ -375,6 +445,7 @@ func (suite *ParserSuite) assertFunction(service *parser.ServiceDeclaration, fun
 		suite.Equal(f, events[i].Function, "%s: Event Route: Incorrect function back-pointer", name)
 		suite.Equal(expectedEvent.Path, events[i].Path, "%s: Event Route: Incorrect path", name)
 		suite.Equal(expectedEvent.Method, events[i].Method, "%s: Event Route: Incorrect method", name)
+		suite.Equal(expectedEvent.Delay, events[i].Delay, "%s: Event Route: Incorrect delay", name)
 	}
 
 	// Only check the model types if specified. Blank means this test doesn't care about the request/response models.
@@ -7,10 +7,10 @@ type BindingService interface {
 }
 
 type Request struct {
-	ID        string `json:"record_id"`
-	Name      string `json:"Name"`
+	ID        string `json:"record_id" validate:"required,len=8"`
+	Name      string `json:"Name" validate:"min=2,max=50,regex=^[A-Z][a-z]{1,}$"`
 	OmitMe    string `json:"-"`
-	IncludeMe string `json:"include,omitempty"`
+	IncludeMe string `json:"include,omitempty" validate:"oneof=yes no,email"`
 }
 
 type Response struct{}
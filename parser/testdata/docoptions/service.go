@@ -29,17 +29,21 @@ type LebowskiService interface {
 	//
 	// GET /dude/{id}/
 	// HTTP 202
+	// CACHE   private, max-age=60
 	Dude(context.Context, *Request) (*Response, error)
 	Walter(context.Context, *Request) (*Response, error)
 	//
 	// HTTP 204
 	//
-	//
 	Donny(context.Context, *Request) (*Response, error)
 	// HTTP 201
 	// POST /dude/{id}/child
+	// RATE 5/30s
 	Maude(context.Context, *Request) (*Response, error)
 	// PUT       /dude/jail
+	// GATEWAY   internal
+	// TIMEOUT   2s
+	// RATE      100/m BY Authorization
 	Jackie(context.Context, *Request) (*Response, error)
 	// Sometimes you eat the bar.
 	//
@@ -48,6 +52,9 @@ type LebowskiService interface {
 	Stranger(context.Context, *Request) (*Response, error)
 	// RemoveToe attempts to extort $1 million.
 	// DELETE /nihilist/{id}/toe
+	// ERRORS 404 NotFound "the toe does not exist", 409
+	// ERRORS 402 PaymentRequired "we need the money", 200 NotAnError
+	// MAX BODY 5MB
 	RemoveToe(context.Context, *Request) (*Response, error)
 	//     HEAD /ties/room/together
 	// * HTTP 202
@@ -60,12 +67,13 @@ type LebowskiService interface {
 	// ON LebowskiService.Dude
 	// ON LebowskiService.Walter
 	// ON LebowskiService.Donny
+	// COMPENSATE WITH BowlingEnd
 	Bowling(context.Context, *Request) (*Response, error)
 
 	// BowlingEnd doesn't include Donny (tears...) or a direct API endpoint.
 	// HTTP OMIT
 	// ON LebowskiService.Dude
-	// ON LebowskiService.Walter
+	// ON LebowskiService.Walter DELAY 90m
 	BowlingEnd(context.Context, *Request) (*Response, error)
 }
 
@@ -0,0 +1,11 @@
+package compensate
+
+import "context"
+
+type FooService interface {
+	// COMPENSATE WITH Goodbye
+	Hello(context.Context, *Request) (*Response, error)
+}
+
+type Request struct{}
+type Response struct{}
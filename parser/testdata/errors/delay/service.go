@@ -0,0 +1,11 @@
+package delay
+
+import "context"
+
+type FooService interface {
+	// ON BarService.Goodbye DELAY 10 minutes
+	Hello(context.Context, *Request) (*Response, error)
+}
+
+type Request struct{}
+type Response struct{}
@@ -0,0 +1,11 @@
+package maxbody
+
+import "context"
+
+type FooService interface {
+	// MAX BODY 5 megabytes
+	Hello(context.Context, *Request) (*Response, error)
+}
+
+type Request struct{}
+type Response struct{}
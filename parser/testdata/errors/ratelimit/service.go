@@ -0,0 +1,11 @@
+package ratelimit
+
+import "context"
+
+type FooService interface {
+	// RATE lots/m BY Authorization
+	Hello(context.Context, *Request) (*Response, error)
+}
+
+type Request struct{}
+type Response struct{}
@@ -0,0 +1,11 @@
+package timeout
+
+import "context"
+
+type FooService interface {
+	// TIMEOUT -2s
+	Hello(context.Context, *Request) (*Response, error)
+}
+
+type Request struct{}
+type Response struct{}
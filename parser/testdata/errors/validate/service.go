@@ -0,0 +1,14 @@
+package validate
+
+import "context"
+
+type FooService interface {
+	Hello(context.Context, *Request) (*Response, error)
+}
+
+type Request struct {
+	Name     string `validate:"required"`
+	BadRules string `validate:"required,nope"`
+}
+
+type Response struct{}
@@ -0,0 +1,218 @@
+package clients
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/monadicstack/abide/internal/quiet"
+)
+
+// CachedResponse is a successful response that the client has stashed away so it doesn't need to fetch it again.
+type CachedResponse struct {
+	// StatusCode is the HTTP status of the original response (always 200 for now).
+	StatusCode int
+	// Header contains the headers from the original response, including its "ETag".
+	Header http.Header
+	// Body is the entire (decompressed) body of the original response.
+	Body []byte
+	// Expires is the time when the response is no longer fresh. After that, we need to ask the remote
+	// service if our copy is still good (using its "ETag") before using it again.
+	Expires time.Time
+}
+
+// response rebuilds the HTTP response that we originally received, so the client can decode it as usual.
+func (cached CachedResponse) response(request *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(cached.StatusCode) + " " + http.StatusText(cached.StatusCode),
+		StatusCode:    cached.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cached.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Request:       request,
+	}
+}
+
+// ResponseCache is the pluggable storage for responses that the client caches when you use WithResponseCache().
+// By default, you should use NewMemoryResponseCache(), but you can implement this using something like Redis
+// if you want multiple instances of your service to share cached responses.
+type ResponseCache interface {
+	// Get looks up the cached response w/ the given key. The boolean is false when there isn't one.
+	Get(key string) (CachedResponse, bool)
+	// Set stores the response under the given key, replacing any previous value.
+	Set(key string, response CachedResponse)
+}
+
+// NewMemoryResponseCache creates a response cache that keeps up to 'maxEntries' responses in memory. Once it's
+// full, we discard the least recently used response to make room for new ones. If 'maxEntries' is 0, we'll
+// hold onto 1000 responses.
+func NewMemoryResponseCache(maxEntries int) ResponseCache {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	return &memoryResponseCache{
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+type memoryResponseCache struct {
+	mutex      sync.Mutex
+	maxEntries int
+	entries    map[string]*list.Element
+	order      *list.List
+}
+
+type memoryResponseCacheEntry struct {
+	key      string
+	response CachedResponse
+}
+
+func (cache *memoryResponseCache) Get(key string) (CachedResponse, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	element, ok := cache.entries[key]
+	if !ok {
+		return CachedResponse{}, false
+	}
+	cache.order.MoveToFront(element)
+	return element.Value.(*memoryResponseCacheEntry).response, true
+}
+
+func (cache *memoryResponseCache) Set(key string, response CachedResponse) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if element, ok := cache.entries[key]; ok {
+		element.Value.(*memoryResponseCacheEntry).response = response
+		cache.order.MoveToFront(element)
+		return
+	}
+
+	cache.entries[key] = cache.order.PushFront(&memoryResponseCacheEntry{key: key, response: response})
+	for cache.order.Len() > cache.maxEntries {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*memoryResponseCacheEntry).key)
+	}
+}
+
+// cacheResponses is the client middleware that honors the "Cache-Control" and "ETag" headers from GET responses:
+//
+//   - Fresh responses (within their "max-age") are used w/o calling the remote service at all.
+//   - Stale responses w/ an ETag are revalidated using "If-None-Match". If the service says that they
+//     haven't changed (304), we use the cached response again.
+//   - Responses marked "no-store" are never cached, and "no-cache" responses are always revalidated.
+//
+// Responses are cached per URL and "Authorization" header, so different callers never see each other's data. We
+// only cache JSON responses; raw file downloads and event streams are left alone.
+func cacheResponses(cache ResponseCache) ClientMiddlewareFunc {
+	return func(request *http.Request, next RoundTripperFunc) (*http.Response, error) {
+		if request.Method != http.MethodGet {
+			return next(request)
+		}
+
+		key := request.URL.String() + "\n" + request.Header.Get("Authorization")
+		cached, ok := cache.Get(key)
+		if ok && time.Now().Before(cached.Expires) {
+			return cached.response(request), nil
+		}
+		if etag := cached.Header.Get("ETag"); ok && etag != "" {
+			request.Header.Set("If-None-Match", etag)
+		}
+
+		response, err := next(request)
+		if err != nil || response == nil {
+			return response, err
+		}
+
+		// Our copy is still good, so we can keep using it for another "max-age".
+		if ok && response.StatusCode == http.StatusNotModified {
+			quiet.Close(response.Body)
+			if expires, cacheable := cacheExpiration(response.Header, cached.Header); cacheable {
+				cached.Expires = expires
+				cache.Set(key, cached)
+			}
+			return cached.response(request), nil
+		}
+
+		if response.StatusCode != http.StatusOK || !strings.HasPrefix(response.Header.Get("Content-Type"), "application/json") {
+			return response, nil
+		}
+		expires, cacheable := cacheExpiration(response.Header)
+		if !cacheable {
+			return response, nil
+		}
+
+		defer quiet.Close(response.Body)
+		body, err := io.ReadAll(response.Body)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read response body: %w", err)
+		}
+
+		cached = CachedResponse{StatusCode: response.StatusCode, Header: response.Header.Clone(), Body: body, Expires: expires}
+		cache.Set(key, cached)
+		return cached.response(request), nil
+	}
+}
+
+// cacheExpiration looks at the "Cache-Control" and "ETag" headers to determine when the response goes stale. The
+// boolean is false when the response shouldn't be cached at all (e.g. "no-store" or we'd never be able to use it).
+// For 304 responses, the service might not repeat all of the headers, so we use the first set that has them.
+func cacheExpiration(headerSets ...http.Header) (time.Time, bool) {
+	var cacheControl, etag string
+	for _, headers := range headerSets {
+		if cacheControl == "" {
+			cacheControl = headers.Get("Cache-Control")
+		}
+		if etag == "" {
+			etag = headers.Get("ETag")
+		}
+	}
+
+	maxAge := 0
+	for _, directive := range strings.Split(strings.ToLower(cacheControl), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch name {
+		case "no-store":
+			return time.Time{}, false
+		case "no-cache":
+			maxAge = -1
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && maxAge >= 0 {
+				maxAge = seconds
+			}
+		}
+	}
+
+	// Without an ETag or max-age, we'd have no way to know when it's ok to use it again.
+	if etag == "" && maxAge <= 0 {
+		return time.Time{}, false
+	}
+	if maxAge <= 0 {
+		return time.Now(), true
+	}
+	return time.Now().Add(time.Duration(maxAge) * time.Second), true
+}
+
+// WithResponseCache makes the client cache responses to GET requests based on their "Cache-Control" and "ETag"
+// headers, such as ones from endpoints w/ the "CACHE max-age=60" doc option. Fresh responses are used w/o calling
+// the remote service, and stale ones are revalidated so we only download the body again when it has changed.
+//
+//	client := gen.UserServiceClient(address, clients.WithResponseCache(clients.NewMemoryResponseCache(1000)))
+func WithResponseCache(cache ResponseCache) ClientOption {
+	return func(rpcClient *Client) {
+		rpcClient.cache = cache
+	}
+}
@@ -0,0 +1,158 @@
+//go:build unit
+
+package clients_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/monadicstack/abide/metadata"
+	"github.com/monadicstack/abide/services/clients"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestCacheSuite(t *testing.T) {
+	suite.Run(t, new(CacheSuite))
+}
+
+type CacheSuite struct {
+	suite.Suite
+}
+
+type cacheServer struct {
+	calls        int
+	ifNoneMatch  string
+	cacheControl string
+	etag         string
+	contentType  string
+	name         string
+}
+
+// roundTrip behaves like a gateway endpoint that supports conditional GETs.
+func (server *cacheServer) roundTrip(r *http.Request) (*http.Response, error) {
+	server.calls++
+	server.ifNoneMatch = r.Header.Get("If-None-Match")
+
+	header := http.Header{"Content-Type": {server.contentType}}
+	if server.cacheControl != "" {
+		header.Set("Cache-Control", server.cacheControl)
+	}
+	if server.etag != "" {
+		header.Set("ETag", server.etag)
+	}
+	if server.etag != "" && server.ifNoneMatch == server.etag {
+		return &http.Response{StatusCode: 304, Header: header, Body: http.NoBody}, nil
+	}
+	body := `{"ID":"123","Name":"` + server.name + `"}`
+	return &http.Response{StatusCode: 200, Header: header, Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+func (suite *CacheSuite) newClient(server *cacheServer, cache clients.ResponseCache) clients.Client {
+	if server.contentType == "" {
+		server.contentType = "application/json"
+	}
+	client := clients.NewClient("Test", "http://localhost:9000", clients.WithResponseCache(cache))
+	client.HTTP.Transport = clients.RoundTripperFunc(server.roundTrip)
+	return client
+}
+
+func (suite *CacheSuite) get(client clients.Client, ctx context.Context, path string) *clientResponse {
+	out := &clientResponse{}
+	suite.Require().NoError(client.Invoke(ctx, "GET", path, &clientRequest{}, out))
+	return out
+}
+
+// Fresh responses should be used w/o calling the remote service again.
+func (suite *CacheSuite) TestMaxAge() {
+	assert := suite.Require()
+	server := &cacheServer{cacheControl: "max-age=60", name: "Dude"}
+	client := suite.newClient(server, clients.NewMemoryResponseCache(10))
+	ctx := context.Background()
+
+	assert.Equal("Dude", suite.get(client, ctx, "/user").Name)
+	server.name = "Walter"
+	assert.Equal("Dude", suite.get(client, ctx, "/user").Name)
+	assert.Equal(1, server.calls)
+
+	// Different URLs and different callers get their own cached responses.
+	assert.Equal("Walter", suite.get(client, ctx, "/user/walter").Name)
+	assert.Equal("Walter", suite.get(client, metadata.WithAuthorization(ctx, "Token abc"), "/user").Name)
+	assert.Equal(3, server.calls)
+
+	// POSTs are never cached.
+	assert.NoError(client.Invoke(ctx, "POST", "/user", &clientRequest{}, &clientResponse{}))
+	assert.NoError(client.Invoke(ctx, "POST", "/user", &clientRequest{}, &clientResponse{}))
+	assert.Equal(5, server.calls)
+}
+
+// Stale responses should be revalidated using their ETag.
+func (suite *CacheSuite) TestETag() {
+	assert := suite.Require()
+	server := &cacheServer{cacheControl: "no-cache", etag: `W/"1"`, name: "Dude"}
+	client := suite.newClient(server, clients.NewMemoryResponseCache(10))
+	ctx := context.Background()
+
+	assert.Equal("Dude", suite.get(client, ctx, "/user").Name)
+	assert.Equal("", server.ifNoneMatch)
+
+	// The service says our copy is still good (304), so we keep using it.
+	server.name = "Walter"
+	assert.Equal("Dude", suite.get(client, ctx, "/user").Name)
+	assert.Equal(`W/"1"`, server.ifNoneMatch)
+	assert.Equal(2, server.calls)
+
+	// The data changed, so we get the new copy.
+	server.etag = `W/"2"`
+	assert.Equal("Walter", suite.get(client, ctx, "/user").Name)
+	assert.Equal(`W/"1"`, server.ifNoneMatch)
+	assert.Equal("Walter", suite.get(client, ctx, "/user").Name)
+	assert.Equal(`W/"2"`, server.ifNoneMatch)
+	assert.Equal(4, server.calls)
+}
+
+// Responses that say not to cache them or that we'd never be able to reuse should always be fetched.
+func (suite *CacheSuite) TestNotCacheable() {
+	assert := suite.Require()
+	ctx := context.Background()
+
+	for _, server := range []*cacheServer{
+		{cacheControl: "no-store", etag: `"1"`},
+		{cacheControl: "max-age=0"},
+		{},
+		{cacheControl: "max-age=60", contentType: "application/octet-stream"},
+	} {
+		client := suite.newClient(server, clients.NewMemoryResponseCache(10))
+		suite.get(client, ctx, "/user")
+		suite.get(client, ctx, "/user")
+		assert.Equal(2, server.calls, "Should not cache: %+v", server)
+		assert.Equal("", server.ifNoneMatch)
+	}
+}
+
+// The memory cache should discard the least recently used responses once it's full.
+func (suite *CacheSuite) TestMemoryResponseCache() {
+	assert := suite.Require()
+	cache := clients.NewMemoryResponseCache(2)
+
+	cache.Set("a", clients.CachedResponse{StatusCode: 200, Body: []byte("a")})
+	cache.Set("b", clients.CachedResponse{StatusCode: 200, Body: []byte("b")})
+	_, ok := cache.Get("a")
+	assert.True(ok)
+
+	cache.Set("c", clients.CachedResponse{StatusCode: 200, Body: []byte("c")})
+	_, ok = cache.Get("b")
+	assert.False(ok, "Least recently used entry should be evicted")
+
+	res, ok := cache.Get("a")
+	assert.True(ok)
+	assert.Equal("a", string(res.Body))
+
+	cache.Set("a", clients.CachedResponse{StatusCode: 200, Body: []byte("A")})
+	res, _ = cache.Get("a")
+	assert.Equal("A", string(res.Body))
+	_, ok = cache.Get("c")
+	assert.True(ok)
+}
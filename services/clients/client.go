@@ -56,13 +56,29 @@ func NewClient(name string, addr string, options ...ClientOption) Client {
 	// Let the user's custom middleware do whatever the hell it wants to the context/request
 	// before our standard middleware finalizes everything.
 	client.middleware = append(client.middleware,
-		writeMetadataHeader,
+		writeMetadataHeader(client.metadataKey),
+		writeDeadlineHeader,
 		writeAuthorizationHeader,
 	)
-	client.roundTrip = client.middleware.Then(client.HTTP.Do)
+	if client.cache != nil {
+		client.middleware = append(client.middleware, cacheResponses(client.cache))
+	}
+	client.middleware = append(client.middleware, decodeCompressedResponse)
+	if client.compressRequests > 0 {
+		client.middleware = append(client.middleware, compressRequestBody(client.compressRequests))
+	}
+	client.roundTrip = client.middleware.Then(client.dispatch)
 	return client
 }
 
+// Transport is the mechanism that delivers an HTTP-shaped RPC request to a remote service and
+// returns its response. By default, clients send requests to the remote service using plain old
+// HTTP, but you can use WithTransport() to deliver them some other way (e.g. over a broker).
+type Transport interface {
+	// RoundTrip sends the request to the named service (e.g. "UserService") and returns the response.
+	RoundTrip(serviceName string, request *http.Request) (*http.Response, error)
+}
+
 // ClientOption is a single configurable setting that modifies some attribute of the RPC client
 // when building one via NewClient().
 type ClientOption func(*Client)
@@ -83,16 +99,44 @@ type Client struct {
 	// Middleware defines all of the units of work we will apply to the request/response when
 	// round-tripping our RPC call to the remote service.
 	middleware clientMiddlewarePipeline
+	// cache holds onto GET responses that the remote service said we can reuse. When nil, we don't cache anything.
+	cache ResponseCache
+	// compressRequests is the minimum size of a request body that we'll gzip before sending it. When zero,
+	// we never compress request bodies.
+	compressRequests int64
+	// metadataKey is the secret we use to sign the metadata we send to remote services. When nil, we
+	// don't sign it.
+	metadataKey []byte
+	// transport is an optional, non-HTTP mechanism for delivering requests to the remote service.
+	transport Transport
 	// roundTrip captures all middleware and the actual request dispatching in a single handler
 	// function. This is what we'll call once we've created the HTTP/RPC request when invoking
 	// one of your client's service functions.
 	roundTrip RoundTripperFunc
 }
 
+// dispatch sends the fully-formed request to the remote service using the client's transport. If
+// you didn't supply one using WithTransport(), we'll just use the HTTP client.
+func (c Client) dispatch(request *http.Request) (*http.Response, error) {
+	if c.transport != nil {
+		return c.transport.RoundTrip(c.Name, request)
+	}
+	return c.HTTP.Do(request)
+}
+
 // Invoke handles the standard request/response logic used to call a service method on the remote service.
 // You should NOT call this yourself. Instead, you should stick to the strongly typed, code-generated
 // service functions on your client.
 func (c Client) Invoke(ctx context.Context, method string, path string, serviceRequest any, serviceResponse any) error {
+	return c.InvokeFunction(ctx, "", method, path, serviceRequest, serviceResponse)
+}
+
+// InvokeFunction is the same as Invoke, but it also records the name of the service function being
+// called (e.g. "GetByID"). Transports that don't use HTTP routes (e.g. gRPC) need this to know which
+// function to call. You should NOT call this yourself; the code-generated clients do it for you.
+func (c Client) InvokeFunction(ctx context.Context, name string, method string, path string, serviceRequest any, serviceResponse any) error {
+	ctx = withInvocation(ctx, Invocation{ServiceName: c.Name, Name: name, Request: serviceRequest})
+
 	// Step 1: Fill in the URL path and query string w/ fields from the request. (e.g. /user/{id} -> /user/abc)
 	// If this is a GET/DELETE/etc. that doesn't support bodies, this will include a query string
 	// with the remaining service request values.
@@ -109,12 +153,16 @@ func (c Client) Invoke(ctx context.Context, method string, path string, serviceR
 	if err != nil {
 		return fmt.Errorf("unable to create request: %w", err)
 	}
+	if streamRequest, ok := serviceRequest.(services.ContentGetter); ok && body != nil {
+		writeContentHeaders(request, streamRequest)
+	}
 
 	// Step 4: Run the request through all middleware and fire it off.
 	response, err := c.roundTrip(request)
 	if err != nil {
 		return fmt.Errorf("round trip error: %w", err)
 	}
+	captureResponse(ctx, response)
 
 	// Step 5: Based on the status code, either populate "out" struct (service response) with the
 	// decoded body/JSON or respond a properly formed error.
@@ -129,6 +177,9 @@ func (c Client) decodeResponse(response *http.Response, serviceResponse any) err
 	if response.StatusCode >= 400 {
 		return c.decodeError(response)
 	}
+	if eventStream, ok := serviceResponse.(services.EventStreamSetter); ok {
+		return c.decodeResponseEvents(response, eventStream)
+	}
 	if raw, ok := serviceResponse.(services.ContentGetter); ok {
 		return c.decodeResponseStream(response, raw)
 	}
@@ -196,6 +247,11 @@ func (c Client) decodeResponseStream(res *http.Response, streamResponse services
 		setter.SetContentFileName(fileName)
 	}
 
+	if setter, ok := streamResponse.(services.ContentModTimeSetter); ok {
+		modTime, _ := http.ParseTime(res.Header.Get("Last-Modified")) // zero time if missing/malformed
+		setter.SetContentModTime(modTime)
+	}
+
 	return nil
 }
 
@@ -207,6 +263,13 @@ func (c Client) decodeError(r *http.Response) error {
 	errData, _ := io.ReadAll(r.Body)
 	contentType := r.Header.Get("Content-Type")
 
+	// The gateway was set up using WithProblemDetails(), so the error is an RFC 7807 problem document.
+	if strings.HasPrefix(contentType, fail.ProblemContentType) {
+		problem := fail.Problem{}
+		_ = json.Unmarshal(errData, &problem)
+		return c.decodeStatusError(r, problem.StatusError())
+	}
+
 	// If the server didn't return JSON, assume that it's just plain text w/ the message to propagate
 	// as you'd get if you invoked `http.Error()`
 	if !strings.HasPrefix(contentType, "application/json") {
@@ -228,16 +291,37 @@ func (c Client) decodeError(r *http.Response) error {
 	if strings.HasPrefix(string(errData), `{`) {
 		err := fail.StatusError{}
 		_ = json.Unmarshal(errData, &err)
-		return fail.New(r.StatusCode, "rpc error: %s", err.Error())
+		return c.decodeStatusError(r, err)
 	}
 
 	// It's JSON, but it's a format we don't recognize, so no message for you. Keep the status, though.
 	return fail.New(r.StatusCode, "service invocation error")
 }
 
+// decodeStatusError finishes up the structured error that we decoded from the response. We keep the
+// error's code, details, and retry hints around for callers that want to use errors.As() to make
+// decisions based on them.
+func (c Client) decodeStatusError(r *http.Response, err fail.StatusError) fail.StatusError {
+	err.Status = r.StatusCode
+	err.Message = "rpc error: " + err.Message
+	if retryAfter, _ := strconv.Atoi(r.Header.Get("Retry-After")); err.RetryAfter == 0 && retryAfter > 0 {
+		err.Retryable = true
+		err.RetryAfter = retryAfter
+	}
+	return err
+}
+
 func (c Client) createRequestBody(method string, serviceRequest any) (io.Reader, error) {
 	switch method {
 	case http.MethodPut, http.MethodPost, http.MethodPatch:
+		// Uploads send the raw content stream as-is. The rest of the request's values are
+		// sent in the query string instead (see buildURL).
+		if streamRequest, ok := serviceRequest.(services.ContentGetter); ok {
+			if content := streamRequest.Content(); content != nil {
+				return content, nil
+			}
+			return http.NoBody, nil
+		}
 		body := &bytes.Buffer{}
 		err := c.codecs.DefaultEncoder().Encode(body, serviceRequest)
 		return body, err
@@ -275,10 +359,15 @@ func (c Client) buildURL(method string, path string, serviceRequest any) string
 	}
 
 	address := c.BaseURL + "/" + strings.Join(pathSegments, "/")
+	_, upload := serviceRequest.(services.ContentGetter)
 	switch method {
 	case http.MethodPut, http.MethodPost, http.MethodPatch:
 		// If we're doing a POST/PUT/PATCH, don't bother adding query string arguments. Non-path
-		// values will just be part of the JSON structure in the request's body.
+		// values will just be part of the JSON structure in the request's body. Uploads are the
+		// exception since the body is the raw content, so the other values go in the query string.
+		if upload {
+			return address + "?" + attributes.Encode()
+		}
 		return address
 	default:
 		// We're doing a GET/DELETE/etc, so all request values must come via query string args.
@@ -286,6 +375,23 @@ func (c Client) buildURL(method string, path string, serviceRequest any) string
 	}
 }
 
+// writeContentHeaders describes the raw content stream you're uploading using the standard Content-Type,
+// Content-Length, and Content-Disposition headers, so the gateway can apply them to the server's request.
+func writeContentHeaders(request *http.Request, streamRequest services.ContentGetter) {
+	request.Header.Set("Content-Type", "application/octet-stream")
+	if getter, ok := streamRequest.(services.ContentTypeGetter); ok && strings.TrimSpace(getter.ContentType()) != "" {
+		request.Header.Set("Content-Type", strings.TrimSpace(getter.ContentType()))
+	}
+	if getter, ok := streamRequest.(services.ContentLengthGetter); ok && getter.ContentLength() > 0 {
+		request.ContentLength = int64(getter.ContentLength())
+	}
+	if getter, ok := streamRequest.(services.ContentFileNameGetter); ok {
+		if fileName := naming.CleanFileName(strings.TrimSpace(getter.ContentFileName())); fileName != "" {
+			request.Header.Set("Content-Disposition", `attachment; filename="`+fileName+`"`)
+		}
+	}
+}
+
 // fixedSegment returns true if the given URL path segment is not wrapped in "{}" indicating that it's a variable.
 func (c Client) fixedSegment(segment string) bool {
 	return !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}")
@@ -308,3 +414,23 @@ func WithHTTPClient(httpClient *http.Client) ClientOption {
 		rpcClient.HTTP = httpClient
 	}
 }
+
+// WithMetadataSigningKey signs the metadata (auth, trace ID, values) that the client sends to remote services
+// using HMAC-SHA256 and the given secret key. Gateways using WithMetadataVerification() w/ the same key
+// will trust your metadata while ignoring metadata from anyone who doesn't know the key. The signature
+// includes the time that we sent the request, so make sure that your servers' clocks are in sync.
+func WithMetadataSigningKey(key []byte) ClientOption {
+	return func(rpcClient *Client) {
+		rpcClient.metadataKey = key
+	}
+}
+
+// WithTransport delivers requests to the remote service using something other than HTTP, such as
+// the request/reply transport from the "rpc" gateway package. All of your client middleware still
+// applies; it's just the final hop to the remote service that changes. When using a transport, you
+// can leave the client's address blank since the transport determines where requests go.
+func WithTransport(transport Transport) ClientOption {
+	return func(rpcClient *Client) {
+		rpcClient.transport = transport
+	}
+}
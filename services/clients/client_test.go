@@ -3,16 +3,23 @@
 package clients_test
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/monadicstack/abide/fail"
 	"github.com/monadicstack/abide/internal/quiet"
 	"github.com/monadicstack/abide/metadata"
 	"github.com/monadicstack/abide/services/clients"
@@ -194,6 +201,42 @@ func (suite *ClientSuite) TestInvoke_httpStatusError() {
 	assert.NotContains(err.Error(), "broke as hell", "Client.Invoke() - not include unknown error message formats")
 }
 
+// Ensures that structured errors and problem documents keep their code, details, and retry hints.
+func (suite *ClientSuite) TestInvoke_structuredError() {
+	assert := suite.Require()
+	client := suite.newClient(func(r *http.Request) (*http.Response, error) {
+		switch r.URL.Path {
+		case "/409":
+			header := http.Header{"Content-Type": []string{"application/json"}}
+			body := `{"Status":409, "Message":"dupe", "Code":"DUPE", "Details":[{"Field":"ID", "Message":"taken"}]}`
+			return &http.Response{StatusCode: 409, Header: header, Body: io.NopCloser(strings.NewReader(body))}, nil
+		case "/503":
+			header := http.Header{"Content-Type": []string{"application/problem+json"}, "Retry-After": []string{"30"}}
+			body := `{"type":"about:blank", "title":"Service Unavailable", "status":503, "detail":"db down", "code":"DB_DOWN"}`
+			return &http.Response{StatusCode: 503, Header: header, Body: io.NopCloser(strings.NewReader(body))}, nil
+		}
+		panic("how did you get here?")
+	})
+
+	err := client.Invoke(context.Background(), "POST", "/409", &clientRequest{}, &clientResponse{})
+	statusErr := fail.StatusError{}
+	assert.True(errors.As(err, &statusErr))
+	assert.Equal(409, statusErr.Status)
+	assert.Equal("rpc error: dupe", statusErr.Message)
+	assert.Equal("DUPE", statusErr.Code)
+	assert.Equal([]fail.ErrorDetail{{Field: "ID", Message: "taken"}}, statusErr.Details)
+	assert.False(statusErr.Retryable)
+
+	err = client.Invoke(context.Background(), "POST", "/503", &clientRequest{}, &clientResponse{})
+	statusErr = fail.StatusError{}
+	assert.True(errors.As(err, &statusErr))
+	assert.Equal(503, statusErr.Status)
+	assert.Equal("rpc error: db down", statusErr.Message)
+	assert.Equal("DB_DOWN", statusErr.Code)
+	assert.True(statusErr.Retryable)
+	assert.Equal(30, statusErr.RetryAfter)
+}
+
 // Check all of the different ways that Invoke() can fail.
 func (suite *ClientSuite) TestInvoke_roundTripError() {
 	assert := suite.Require()
@@ -267,6 +310,200 @@ func (suite *ClientSuite) TestInvoke_includeHeaders() {
 	))
 }
 
+// Metadata should be signed when the client has a signing key.
+func (suite *ClientSuite) TestWithMetadataSigningKey() {
+	assert := suite.Require()
+	key := []byte("the dude abides")
+	var encoded, signature string
+	roundTrip := clients.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		encoded = r.Header.Get("X-RPC-Metadata")
+		signature = r.Header.Get("X-RPC-Metadata-Signature")
+		return suite.respond(200, &clientResponse{ID: "123"})
+	})
+	ctx := metadata.WithValue(context.Background(), "Foo", "Bar")
+
+	client := clients.NewClient("Test", "http://localhost:9000", clients.WithMetadataSigningKey(key))
+	client.HTTP.Transport = roundTrip
+	assert.NoError(client.Invoke(ctx, "POST", "/foo", &clientRequest{}, &clientResponse{}))
+	assert.NotEmpty(encoded)
+	assert.True(metadata.Verify(metadata.EncodedBytes(encoded), signature, time.Minute, key))
+
+	// Nothing to sign.
+	assert.NoError(client.Invoke(context.Background(), "POST", "/foo", &clientRequest{}, &clientResponse{}))
+	assert.Equal("", encoded)
+	assert.Equal("", signature)
+
+	// No key, no signature.
+	client = suite.newClient(roundTrip)
+	assert.NoError(client.Invoke(ctx, "POST", "/foo", &clientRequest{}, &clientResponse{}))
+	assert.NotEmpty(encoded)
+	assert.Equal("", signature)
+}
+
+// The remaining time before the context's deadline should be sent to the remote service.
+func (suite *ClientSuite) TestInvoke_deadlineHeader() {
+	assert := suite.Require()
+	var deadline string
+	client := suite.newClient(func(r *http.Request) (*http.Response, error) {
+		deadline = r.Header.Get("X-RPC-Deadline")
+		return suite.respond(200, &clientResponse{ID: "123"})
+	})
+
+	assert.NoError(client.Invoke(context.Background(), "POST", "/foo", &clientRequest{}, &clientResponse{}))
+	assert.Equal("", deadline, "Should not send a deadline when the context doesn't have one")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(client.Invoke(ctx, "POST", "/foo", &clientRequest{}, &clientResponse{}))
+	remaining, err := strconv.Atoi(deadline)
+	assert.NoError(err)
+	assert.InDelta(5000, remaining, 100)
+}
+
+// The client should ask for compressed responses and transparently decompress them.
+func (suite *ClientSuite) TestInvoke_compressedResponse() {
+	assert := suite.Require()
+	encode := func(encoding string, text string) io.ReadCloser {
+		buf := &bytes.Buffer{}
+		var writer io.WriteCloser = gzip.NewWriter(buf)
+		if encoding == "deflate" {
+			writer = zlib.NewWriter(buf)
+		}
+		_, _ = writer.Write([]byte(text))
+		_ = writer.Close()
+		return io.NopCloser(buf)
+	}
+
+	for _, encoding := range []string{"gzip", "deflate"} {
+		client := suite.newClient(func(r *http.Request) (*http.Response, error) {
+			assert.Equal("gzip, deflate", r.Header.Get("Accept-Encoding"))
+			return &http.Response{
+				StatusCode: 200,
+				Header:     http.Header{"Content-Encoding": {encoding}, "Content-Type": {"application/json"}},
+				Body:       encode(encoding, `{"ID":"123","Name":"Dude"}`),
+			}, nil
+		})
+		out := &clientResponse{}
+		assert.NoError(client.Invoke(context.Background(), "GET", "/foo", &clientRequest{}, out), encoding)
+		assert.Equal(&clientResponse{ID: "123", Name: "Dude"}, out, encoding)
+	}
+
+	client := suite.newClient(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: 200,
+			Header:     http.Header{"Content-Encoding": {"gzip"}},
+			Body:       io.NopCloser(strings.NewReader(`{"ID":"123"}`)),
+		}, nil
+	})
+	assert.Error(client.Invoke(context.Background(), "GET", "/foo", &clientRequest{}, &clientResponse{}))
+}
+
+// Large request bodies should be gzipped when you use WithRequestCompression().
+func (suite *ClientSuite) TestWithRequestCompression() {
+	assert := suite.Require()
+	var encoding string
+	var in *clientRequest
+	client := clients.NewClient("Test", "http://localhost:9000", clients.WithRequestCompression(200))
+	client.HTTP.Transport = clients.RoundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		encoding = r.Header.Get("Content-Encoding")
+		body := io.Reader(r.Body)
+		if encoding == "gzip" {
+			reader, err := gzip.NewReader(r.Body)
+			assert.NoError(err)
+			body = reader
+		}
+		in = &clientRequest{}
+		assert.NoError(json.NewDecoder(body).Decode(in))
+		return suite.respond(200, &clientResponse{ID: "123"})
+	})
+
+	assert.NoError(client.Invoke(context.Background(), "POST", "/foo", &clientRequest{ID: "1"}, &clientResponse{}))
+	assert.Equal("", encoding, "Should not compress small bodies")
+	assert.Equal("1", in.ID)
+
+	id := strings.Repeat("abide", 50)
+	assert.NoError(client.Invoke(context.Background(), "POST", "/foo", &clientRequest{ID: id}, &clientResponse{}))
+	assert.Equal("gzip", encoding, "Should compress large bodies")
+	assert.Equal(id, in.ID)
+
+	// Without the option, we never compress.
+	client = suite.newClient(func(r *http.Request) (*http.Response, error) {
+		encoding = r.Header.Get("Content-Encoding")
+		return suite.respond(200, &clientResponse{ID: "123"})
+	})
+	assert.NoError(client.Invoke(context.Background(), "POST", "/foo", &clientRequest{ID: id}, &clientResponse{}))
+	assert.Equal("", encoding)
+}
+
+// Callers should be able to see the response's status and headers using CaptureResponse().
+func (suite *ClientSuite) TestInvoke_captureResponse() {
+	assert := suite.Require()
+	status := 201
+	client := suite.newClient(func(r *http.Request) (*http.Response, error) {
+		res, _ := suite.respond(status, &clientResponse{ID: "123"})
+		res.Header = http.Header{
+			"Location":   {"/user/123"},
+			"Set-Cookie": {"session=abc; HttpOnly", "theme=dark"},
+		}
+		return res, nil
+	})
+
+	ctx, details := clients.CaptureResponse(context.Background())
+	assert.Equal(0, details.StatusCode)
+	assert.NoError(client.Invoke(ctx, "POST", "/user", &clientRequest{}, &clientResponse{}))
+	assert.Equal(201, details.StatusCode)
+	assert.Equal("/user/123", details.Header.Get("Location"))
+	cookies := details.Cookies()
+	assert.Len(cookies, 2)
+	assert.Equal("session", cookies[0].Name)
+	assert.Equal("abc", cookies[0].Value)
+	assert.True(cookies[0].HttpOnly)
+	assert.Equal("dark", cookies[1].Value)
+
+	// Failures should be captured, too.
+	status = 409
+	assert.Error(client.Invoke(ctx, "POST", "/user", &clientRequest{}, &clientResponse{}))
+	assert.Equal(409, details.StatusCode)
+
+	// Round trip failures leave the details alone.
+	ctx, details = clients.CaptureResponse(context.Background())
+	client = suite.newClient(func(r *http.Request) (*http.Response, error) {
+		return nil, fmt.Errorf("nope")
+	})
+	assert.Error(client.Invoke(ctx, "POST", "/user", &clientRequest{}, &clientResponse{}))
+	assert.Equal(0, details.StatusCode)
+	assert.Empty(details.Header)
+}
+
+// Transports that don't use HTTP routes need to know which function the request is for.
+func (suite *ClientSuite) TestInvokeFunction_invocation() {
+	assert := suite.Require()
+	in := &clientRequest{ID: "123"}
+	client := suite.newClient(func(r *http.Request) (*http.Response, error) {
+		invocation, ok := clients.RequestInvocation(r)
+		assert.True(ok)
+		assert.Equal("Test", invocation.ServiceName)
+		assert.Equal("GetByID", invocation.Name)
+		assert.Equal("Test.GetByID", invocation.QualifiedName())
+		assert.Same(in, invocation.Request)
+		return suite.respond(200, &clientResponse{ID: "123"})
+	})
+	assert.NoError(client.InvokeFunction(context.Background(), "GetByID", "GET", "/foo/{ID}", in, &clientResponse{}))
+
+	// Plain old Invoke() still records the request; we just don't know the function's name.
+	client = suite.newClient(func(r *http.Request) (*http.Response, error) {
+		invocation, ok := clients.RequestInvocation(r)
+		assert.True(ok)
+		assert.Equal("", invocation.Name)
+		assert.Same(in, invocation.Request)
+		return suite.respond(200, &clientResponse{ID: "123"})
+	})
+	assert.NoError(client.Invoke(context.Background(), "GET", "/foo/{ID}", in, &clientResponse{}))
+
+	_, ok := clients.RequestInvocation(httptest.NewRequest(http.MethodGet, "/foo", nil))
+	assert.False(ok)
+}
+
 func (suite *ClientSuite) newClient(roundTripper clients.RoundTripperFunc) clients.Client {
 	client := clients.NewClient("Test", "http://localhost:9000")
 	client.HTTP.Transport = roundTripper
@@ -0,0 +1,102 @@
+package clients
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/monadicstack/abide/services"
+)
+
+// decodeCompressedResponse asks the remote service for a "gzip" or "deflate" response and transparently
+// decompresses it, so the rest of the client never knows the difference. The standard HTTP transport only
+// does this for gzip, and non-HTTP transports (e.g. over a broker) don't do it at all, so we handle it ourselves.
+func decodeCompressedResponse(request *http.Request, next RoundTripperFunc) (*http.Response, error) {
+	if request.Header.Get("Accept-Encoding") == "" {
+		request.Header.Set("Accept-Encoding", "gzip, deflate")
+	}
+
+	response, err := next(request)
+	if err != nil || response == nil {
+		return response, err
+	}
+
+	var body io.Reader
+	switch encoding := strings.ToLower(strings.TrimSpace(response.Header.Get("Content-Encoding"))); encoding {
+	case "gzip":
+		if body, err = gzip.NewReader(response.Body); err != nil {
+			_ = response.Body.Close()
+			return nil, fmt.Errorf("invalid gzip response: %w", err)
+		}
+	case "deflate":
+		// HTTP's "deflate" is really the zlib format (RFC 1950), not a raw deflate stream.
+		if body, err = zlib.NewReader(response.Body); err != nil {
+			_ = response.Body.Close()
+			return nil, fmt.Errorf("invalid deflate response: %w", err)
+		}
+	default:
+		return response, nil
+	}
+
+	// The length was for the compressed bytes, so it's meaningless now.
+	response.Body = decompressedBody{Reader: body, Closer: response.Body}
+	response.Header.Del("Content-Encoding")
+	response.Header.Del("Content-Length")
+	response.ContentLength = -1
+	response.Uncompressed = true
+	return response, nil
+}
+
+// decompressedBody reads the decompressed bytes of a response body, but closes the original body when you're done.
+type decompressedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// compressRequestBody gzips request bodies that are at least 'minSize' bytes. Raw uploads are sent as-is since
+// they're often already compressed (e.g. images or zip files), and we don't know how big they are anyway.
+func compressRequestBody(minSize int64) ClientMiddlewareFunc {
+	return func(request *http.Request, next RoundTripperFunc) (*http.Response, error) {
+		if request.Body == nil || request.ContentLength < minSize || request.Header.Get("Content-Encoding") != "" {
+			return next(request)
+		}
+		if invocation, ok := RequestInvocation(request); ok {
+			if _, upload := invocation.Request.(services.ContentGetter); upload {
+				return next(request)
+			}
+		}
+
+		body := &bytes.Buffer{}
+		writer := gzip.NewWriter(body)
+		if _, err := io.Copy(writer, request.Body); err != nil {
+			return nil, fmt.Errorf("unable to compress request body: %w", err)
+		}
+		if err := writer.Close(); err != nil {
+			return nil, fmt.Errorf("unable to compress request body: %w", err)
+		}
+		_ = request.Body.Close()
+
+		compressed := body.Bytes()
+		request.Body = io.NopCloser(bytes.NewReader(compressed))
+		request.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(compressed)), nil
+		}
+		request.ContentLength = int64(len(compressed))
+		request.Header.Set("Content-Encoding", "gzip")
+		return next(request)
+	}
+}
+
+// WithRequestCompression gzips request bodies that are at least 'minSize' bytes before sending them to the
+// remote service. This is handy when you're sending large payloads to services on the other side of a slow
+// network. The remote API gateway only accepts compressed request bodies when it uses apis.WithCompression(),
+// so make sure that it does before you turn this on; otherwise, your calls fail w/ a 415 error.
+func WithRequestCompression(minSize int64) ClientOption {
+	return func(rpcClient *Client) {
+		rpcClient.compressRequests = minSize
+	}
+}
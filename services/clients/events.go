@@ -0,0 +1,85 @@
+package clients
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/monadicstack/abide/internal/quiet"
+	"github.com/monadicstack/abide/services"
+)
+
+// maxEventSize is the largest single event we're willing to buffer while reading an event stream.
+const maxEventSize = 1024 * 1024
+
+// decodeResponseEvents reads the server-sent events in the background, decoding each one and delivering it
+// to the response's event stream. We return right away so that you can start consuming the values as they
+// arrive. The body is closed once the server finishes the stream or the request's context is canceled.
+func (c Client) decodeResponseEvents(res *http.Response, eventStream services.EventStreamSetter) error {
+	contentType := res.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "text/event-stream") {
+		quiet.Close(res.Body)
+		eventStream.CloseEvents(fmt.Errorf("rpc: expected an event stream, but received '%s'", contentType))
+		return nil
+	}
+
+	ctx := context.Background()
+	if res.Request != nil {
+		ctx = res.Request.Context()
+	}
+
+	go c.readEvents(ctx, res, eventStream)
+	return nil
+}
+
+func (c Client) readEvents(ctx context.Context, res *http.Response, eventStream services.EventStreamSetter) {
+	defer quiet.Close(res.Body)
+
+	decoder := c.codecs.Decoder("application/json")
+	scanner := bufio.NewScanner(res.Body)
+	scanner.Buffer(make([]byte, 0, 4096), maxEventSize)
+
+	eventName := ""
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+
+		switch {
+		case line == "":
+			// A blank line dispatches the event that we've been building up.
+			if len(data) == 0 {
+				continue
+			}
+			if eventName == "error" {
+				eventStream.CloseEvents(errors.New(strings.Join(data, "\n")))
+				return
+			}
+			event := eventStream.NewEvent()
+			if err := decoder.Decode(strings.NewReader(strings.Join(data, "\n")), event); err != nil {
+				eventStream.CloseEvents(fmt.Errorf("rpc: unable to decode event: %w", err))
+				return
+			}
+			if !eventStream.SendEvent(ctx, event) {
+				eventStream.CloseEvents(ctx.Err())
+				return
+			}
+			eventName, data = "", nil
+		case field == "event":
+			eventName = value
+		case field == "data":
+			data = append(data, value)
+		}
+	}
+
+	// Canceling the context will cause reads to fail, but that's not really an error in the stream.
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		eventStream.CloseEvents(fmt.Errorf("rpc: unable to read event stream: %w", err))
+		return
+	}
+	eventStream.CloseEvents(ctx.Err())
+}
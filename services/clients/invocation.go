@@ -0,0 +1,40 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKeyInvocation struct{}
+
+// Invocation describes the service function call that a client request was created for. Transports that
+// don't use HTTP routes (e.g. gRPC) use this to figure out which function to call and with what values
+// rather than picking apart the request's path, query string, and body.
+type Invocation struct {
+	// ServiceName is the name of the service being called (e.g. "UserService").
+	ServiceName string
+	// Name is the name of the service function being called (e.g. "GetByID"). This is blank if the
+	// client was generated before function names were recorded; regenerate it to fix that.
+	Name string
+	// Request is the service request struct that the caller passed to the function.
+	Request any
+}
+
+// QualifiedName returns the fully-qualified name of the function being called (e.g. "UserService.GetByID").
+func (inv Invocation) QualifiedName() string {
+	return inv.ServiceName + "." + inv.Name
+}
+
+// RequestInvocation returns the details about the service function call that the request was created
+// for. The boolean is false if the request didn't come from one of your client's service functions.
+func RequestInvocation(req *http.Request) (Invocation, bool) {
+	if req == nil {
+		return Invocation{}, false
+	}
+	inv, ok := req.Context().Value(contextKeyInvocation{}).(Invocation)
+	return inv, ok
+}
+
+func withInvocation(ctx context.Context, inv Invocation) context.Context {
+	return context.WithValue(ctx, contextKeyInvocation{}, inv)
+}
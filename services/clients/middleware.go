@@ -2,6 +2,7 @@ package clients
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/monadicstack/abide/metadata"
 )
@@ -37,10 +38,26 @@ func (pipeline clientMiddlewarePipeline) Then(handler RoundTripperFunc) RoundTri
 
 // writeMetadataHeader encodes all of the context's (the context on the request) metadata values as
 // JSON and writes that to the "X-RPC-Values" header so that the remote service has access to all
-// of your values as well.
-func writeMetadataHeader(request *http.Request, next RoundTripperFunc) (*http.Response, error) {
-	encodedValues := metadata.Encode(request.Context())
-	request.Header.Set(metadata.Header, string(encodedValues))
+// of your values as well. When you supply a signing key, we also include the metadata's signature,
+// so gateways using WithMetadataVerification() know that it came from one of your services.
+func writeMetadataHeader(signingKey []byte) ClientMiddlewareFunc {
+	return func(request *http.Request, next RoundTripperFunc) (*http.Response, error) {
+		encodedValues := metadata.Encode(request.Context())
+		request.Header.Set(metadata.Header, string(encodedValues))
+		if signature := metadata.Sign(encodedValues, signingKey, time.Now()); signature != "" {
+			request.Header.Set(metadata.SignatureHeader, signature)
+		}
+		return next(request)
+	}
+}
+
+// writeDeadlineHeader tells the remote service how much time is left before the deadline on the request's
+// context expires (if it has one). The remote gateway restores that deadline, so the entire call chain shares
+// the original caller's time budget rather than each hop starting over.
+func writeDeadlineHeader(request *http.Request, next RoundTripperFunc) (*http.Response, error) {
+	if deadline := metadata.EncodeDeadline(request.Context()); deadline != "" {
+		request.Header.Set(metadata.DeadlineHeader, deadline)
+	}
 	return next(request)
 }
 
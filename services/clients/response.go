@@ -0,0 +1,49 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKeyResponseDetails struct{}
+
+// ResponseDetails contains the parts of the remote service's HTTP response that aren't part of your service
+// response struct, such as the "Location" header on a 201 or cookies the service set w/ metadata.SetCookie().
+type ResponseDetails struct {
+	// StatusCode is the HTTP status of the response (e.g. 201).
+	StatusCode int
+	// Header contains all of the response's headers.
+	Header http.Header
+}
+
+// Cookies parses all of the "Set-Cookie" headers in the response.
+func (details *ResponseDetails) Cookies() []*http.Cookie {
+	return (&http.Response{Header: details.Header}).Cookies()
+}
+
+// CaptureResponse lets you see the details of the HTTP response once you call one of your client's service
+// functions w/ the returned context. The details are filled in once the call completes, whether it succeeded
+// or failed. If the call never got a response at all (e.g. the service is down), they remain blank.
+//
+//	ctx, res := clients.CaptureResponse(ctx)
+//	user, err := userClient.Create(ctx, &CreateRequest{...})
+//	location := res.Header.Get("Location")
+//
+// You should only use the context for a single call. Each call replaces the details of the previous one.
+func CaptureResponse(ctx context.Context) (context.Context, *ResponseDetails) {
+	details := &ResponseDetails{Header: http.Header{}}
+	return context.WithValue(ctx, contextKeyResponseDetails{}, details), details
+}
+
+// captureResponse fills in the details for callers that used CaptureResponse() on the request's context.
+func captureResponse(ctx context.Context, response *http.Response) {
+	details, ok := ctx.Value(contextKeyResponseDetails{}).(*ResponseDetails)
+	if !ok || response == nil {
+		return
+	}
+	details.StatusCode = response.StatusCode
+	details.Header = response.Header.Clone()
+	if details.Header == nil {
+		details.Header = http.Header{}
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"time"
 )
 
 // HandlerFunc is the general purpose signature for any endpoint handler.
@@ -36,6 +37,20 @@ type Endpoint struct {
 	// Notices that the roles should be allowed to have path variables that we can fill in
 	// at runtime with the incoming binding data.
 	Roles []string
+	// Timeout is the maximum amount of time that the handler is allowed to run before the call fails
+	// with a 408 (based on the "TIMEOUT xxx" doc option). When the caller's context already has an
+	// earlier deadline (e.g. propagated from an upstream service), that one wins. The default of 0
+	// means that there's no endpoint-specific limit.
+	Timeout time.Duration
+	// RateLimit restricts how often callers can invoke this endpoint (based on the "RATE xxx" doc option).
+	// When nil, callers can invoke it as often as they like.
+	RateLimit *RateLimit
+	// MaxBodySize is the largest request body (in bytes) that gateways should accept for this endpoint
+	// (based on the "MAX BODY xxx" doc option). When 0, the gateway's own limit applies, if any.
+	MaxBodySize int64
+	// CacheControl is the "Cache-Control" header value that gateways include on successful responses
+	// (based on the "CACHE xxx" doc option, such as "max-age=60"). When blank, we don't send one.
+	CacheControl string
 	// Routes defines the actual ingress routes that allow this service operation to
 	// be invoked by various gateways. For instance, they tell you that you can invoke
 	// the API call "GET /user/{ID}" to invoke it or that it should trigger when the
@@ -68,4 +83,12 @@ type EndpointRoute struct {
 	// Status is mainly used by API gateway routes to determine what HTTP status code we should
 	// return to the caller when this endpoint succeeds. By default, this is 200.
 	Status int
+	// Delay is used by event gateway routes to indicate that the endpoint should not handle the
+	// event until this much time has passed since it was published (e.g. "ON CartService.Abandoned DELAY 1h").
+	// The default of 0 means that the endpoint handles the event as soon as possible.
+	Delay time.Duration
+	// Gateway is the name of the gateway that this route should be registered on (i.e. the name
+	// given to services.ListenAs()), based on the "GATEWAY xxx" doc option. When empty, the route
+	// is registered on every gateway that supports its type. If no gateway has this name, Server.Run() fails.
+	Gateway string
 }
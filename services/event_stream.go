@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// EventStreamer provides a way for your service response to indicate that you want to send the caller
+// a live stream of values rather than a single value. The API gateway delivers each value as a
+// server-sent event ("text/event-stream") as soon as it's available.
+//
+// You probably don't want to implement this yourself. Embed services.EventStream[T] in your response instead.
+type EventStreamer interface {
+	// NextEvent blocks until the next value in the stream is available. It returns false when
+	// there are no more values or the context was canceled (e.g. the caller disconnected).
+	NextEvent(ctx context.Context) (any, bool)
+}
+
+// EventStreamSetter allows event stream responses to be properly reconstituted when using the
+// code-generated Go client for your service.
+type EventStreamSetter interface {
+	// NewEvent returns a pointer to a blank value that the client should decode the next event into.
+	NewEvent() any
+	// SendEvent delivers a value that the client decoded (the pointer from NewEvent) to whoever is reading
+	// the stream. It returns false if the value couldn't be delivered because the context was canceled.
+	SendEvent(ctx context.Context, value any) bool
+	// CloseEvents indicates that there are no more values in the stream. The error is non-nil when
+	// the stream ended because something went wrong rather than the server finishing normally.
+	CloseEvents(err error)
+}
+
+// EventStream implements the EventStreamer and EventStreamSetter interfaces. You can embed one of these
+// in your response struct to send the caller a live stream of values such as progress updates for
+// a long-running job. Your handler should return right away and send the values in the background.
+//
+//	type WatchJobResponse struct {
+//		services.EventStream[JobProgress]
+//	}
+//
+//	func (svc JobServiceHandler) WatchJob(ctx context.Context, req *WatchJobRequest) (*WatchJobResponse, error) {
+//		res := &WatchJobResponse{}
+//		go func() {
+//			defer res.Close()
+//			for progress := range svc.jobs.Watch(req.JobID) {
+//				if !res.Send(ctx, progress) {
+//					return // the caller stopped listening
+//				}
+//			}
+//		}()
+//		return res, nil
+//	}
+//
+// When using the code-generated Go client, you read the values using Events(). Cancel the context
+// you passed to the client if you want to stop listening before the server finishes the stream.
+//
+//	res, err := jobClient.WatchJob(ctx, &WatchJobRequest{JobID: "123"})
+//	for progress := range res.Events() {
+//		fmt.Println(progress.Percent)
+//	}
+//
+// GATEWAY COMPATABILITY: This currently only works with the API gateway. Other gateways such as "Events"
+// and "RPC" will auto-encode your response just like any other struct, so the values are not delivered.
+type EventStream[T any] struct {
+	init   sync.Once
+	events chan T
+	err    error
+}
+
+func (stream *EventStream[T]) channel() chan T {
+	stream.init.Do(func() {
+		stream.events = make(chan T)
+	})
+	return stream.events
+}
+
+// Send delivers the value to the caller, blocking until they receive it. It returns false
+// if the value was not delivered because the context was canceled.
+func (stream *EventStream[T]) Send(ctx context.Context, value T) bool {
+	select {
+	case stream.channel() <- value:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Close indicates that there are no more values in the stream. You should call this from the same
+// goroutine that calls Send() once you're done sending values.
+func (stream *EventStream[T]) Close() {
+	close(stream.channel())
+}
+
+// Events returns the channel that receives each value in the stream. The channel is closed
+// when the stream is finished. Check Err() afterwards to see whether the stream ended normally.
+func (stream *EventStream[T]) Events() <-chan T {
+	return stream.channel()
+}
+
+// Err returns the reason that the stream ended early. This is nil if the stream finished normally.
+func (stream *EventStream[T]) Err() error {
+	return stream.err
+}
+
+// NextEvent blocks until the next value is sent to the stream.
+func (stream *EventStream[T]) NextEvent(ctx context.Context) (any, bool) {
+	select {
+	case value, ok := <-stream.channel():
+		if !ok {
+			return nil, false
+		}
+		return value, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// NewEvent returns a pointer to a blank value for the client to decode the next event into.
+func (stream *EventStream[T]) NewEvent() any {
+	return new(T)
+}
+
+// SendEvent delivers a value that the client decoded from the stream. The value should be the
+// pointer from NewEvent(), but the value itself is fine, too.
+func (stream *EventStream[T]) SendEvent(ctx context.Context, value any) bool {
+	switch v := value.(type) {
+	case *T:
+		return stream.Send(ctx, *v)
+	case T:
+		return stream.Send(ctx, v)
+	default:
+		return false
+	}
+}
+
+// CloseEvents indicates that the client has received all of the values in the stream.
+func (stream *EventStream[T]) CloseEvents(err error) {
+	stream.err = err
+	stream.Close()
+}
@@ -0,0 +1,87 @@
+//go:build unit
+
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/monadicstack/abide/services"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventStream_SendClose(t *testing.T) {
+	assert := require.New(t)
+	stream := services.EventStream[string]{}
+
+	go func() {
+		defer stream.Close()
+		stream.Send(context.Background(), "The")
+		stream.Send(context.Background(), "Dude")
+		stream.Send(context.Background(), "Abides")
+	}()
+
+	var values []string
+	for value := range stream.Events() {
+		values = append(values, value)
+	}
+	assert.Equal([]string{"The", "Dude", "Abides"}, values)
+	assert.NoError(stream.Err())
+}
+
+// The gateway reads values through NextEvent() rather than the typed channel.
+func TestEventStream_NextEvent(t *testing.T) {
+	assert := require.New(t)
+	stream := services.EventStream[int]{}
+
+	go func() {
+		defer stream.Close()
+		stream.Send(context.Background(), 42)
+	}()
+
+	value, ok := stream.NextEvent(context.Background())
+	assert.True(ok)
+	assert.Equal(42, value)
+
+	value, ok = stream.NextEvent(context.Background())
+	assert.False(ok)
+	assert.Nil(value)
+}
+
+// Nobody is receiving, so the context should keep Send()/NextEvent() from blocking forever.
+func TestEventStream_Canceled(t *testing.T) {
+	assert := require.New(t)
+	stream := services.EventStream[int]{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.False(stream.Send(ctx, 42))
+	_, ok := stream.NextEvent(ctx)
+	assert.False(ok)
+}
+
+// The Go client decodes into the pointer from NewEvent() and passes that to SendEvent().
+func TestEventStream_SendEvent(t *testing.T) {
+	assert := require.New(t)
+	stream := services.EventStream[string]{}
+
+	event, ok := stream.NewEvent().(*string)
+	assert.True(ok)
+	*event = "Abide"
+
+	go func() {
+		stream.SendEvent(context.Background(), event)
+		stream.SendEvent(context.Background(), "Abide Again")
+		stream.SendEvent(context.Background(), 42) // ignored; wrong type
+		stream.CloseEvents(errors.New("nope"))
+	}()
+
+	var values []string
+	for value := range stream.Events() {
+		values = append(values, value)
+	}
+	assert.Equal([]string{"Abide", "Abide Again"}, values)
+	assert.EqualError(stream.Err(), "nope")
+}
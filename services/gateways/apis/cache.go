@@ -0,0 +1,98 @@
+package apis
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/monadicstack/abide/codec"
+	"github.com/monadicstack/abide/services"
+)
+
+// cacheable determines if the response is something that callers can cache and revalidate. We only bother
+// w/ successful GET/HEAD requests since nobody caches the results of a POST.
+func cacheable(req *http.Request, status int) bool {
+	return (req.Method == http.MethodGet || req.Method == http.MethodHead) && status == http.StatusOK
+}
+
+// writeCacheHeaders includes the endpoint's "CACHE xxx" doc option as the "Cache-Control" header as well as
+// the response's own ETag (if it implements services.ETagGetter).
+func writeCacheHeaders(headers http.Header, req *http.Request, serviceResponse any, status int, cacheControl string) {
+	if !cacheable(req, status) {
+		return
+	}
+	if cacheControl != "" {
+		headers.Set("Cache-Control", cacheControl)
+	}
+	if getter, ok := serviceResponse.(services.ETagGetter); ok {
+		if etag := quoteETag(getter.ETag()); etag != "" {
+			headers.Set("ETag", etag)
+		}
+	}
+}
+
+// respondSuccessHashed encodes the response in memory so that we can use the hash of the body as the ETag
+// for responses that don't supply one themselves. Callers that already have that exact body get a 304 instead.
+func respondSuccessHashed(w http.ResponseWriter, req *http.Request, encoder codec.Encoder, serviceResponse any, status int) {
+	body := &bytes.Buffer{}
+	if err := encoder.Encode(body, serviceResponse); err != nil {
+		respondFailure(w, req, encoder, err)
+		return
+	}
+
+	// It's a weak ETag since the compression middleware might change the bytes we actually send.
+	hash := sha256.Sum256(body.Bytes())
+	w.Header().Set("ETag", `W/"`+hex.EncodeToString(hash[:16])+`"`)
+	if respondNotModified(w, req, status) {
+		return
+	}
+
+	w.Header().Set("Content-Type", encoder.ContentType())
+	w.WriteHeader(status)
+	_, _ = w.Write(body.Bytes())
+}
+
+// respondNotModified replies w/ a 304 and no body when the caller's "If-None-Match" header says that they
+// already have the version of the response identified by our "ETag" header. This returns false when the
+// caller needs the whole response.
+func respondNotModified(w http.ResponseWriter, req *http.Request, status int) bool {
+	headers := w.Header()
+	etag := headers.Get("ETag")
+	if etag == "" || !cacheable(req, status) || !matchesETag(req.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+
+	headers.Del("Content-Type")
+	headers.Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// matchesETag determines if any of the ETags in an "If-None-Match" header (e.g. `"abc", W/"def"`) match the
+// response's ETag. As the spec requires, we use weak comparison, so `W/"abc"` and `"abc"` are a match.
+func matchesETag(ifNoneMatch string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteETag makes sure that the ETag from your response is a valid header value. You can give us `abc`, `"abc"`,
+// or `W/"abc"`, and we'll do the right thing.
+func quoteETag(etag string) string {
+	etag = strings.TrimSpace(etag)
+	switch {
+	case etag == "":
+		return ""
+	case strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`):
+		return etag
+	default:
+		return `"` + strings.ReplaceAll(etag, `"`, "") + `"`
+	}
+}
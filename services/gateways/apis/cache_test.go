@@ -0,0 +1,220 @@
+//go:build unit
+
+package apis_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/apis"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestCacheSuite(t *testing.T) {
+	suite.Run(t, new(CacheSuite))
+}
+
+type CacheSuite struct {
+	suite.Suite
+}
+
+type cacheRequest struct {
+	ID string
+}
+
+type cacheResponse struct {
+	ID string
+}
+
+type cacheTaggedResponse struct {
+	ID string
+}
+
+func (res cacheTaggedResponse) ETag() string {
+	return "v" + res.ID
+}
+
+type cacheStreamResponse struct {
+	services.StreamResponse
+	version string
+}
+
+func (res *cacheStreamResponse) ETag() string {
+	return res.version
+}
+
+// gateway registers "GET /user/{ID}" (CACHE max-age=60), "GET /tagged/{ID}" whose response supplies its own
+// ETag, "GET /file/{ID}" which streams content w/ its own ETag, and "POST /user/{ID}" which is never cached.
+func (suite *CacheSuite) gateway() *apis.Gateway {
+	gw := apis.NewGateway(":0")
+	register := func(name string, method string, path string, cacheControl string, handler services.HandlerFunc) {
+		gw.Register(services.Endpoint{
+			ServiceName:  "UserService",
+			Name:         name,
+			NewInput:     func() services.StructPointer { return &cacheRequest{} },
+			Handler:      handler,
+			CacheControl: cacheControl,
+		}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: method, Path: path, Status: 200})
+	}
+	register("GetByID", "GET", "/user/{ID}", "max-age=60", func(ctx context.Context, req any) (any, error) {
+		return &cacheResponse{ID: req.(*cacheRequest).ID}, nil
+	})
+	register("Update", "POST", "/user/{ID}", "max-age=60", func(ctx context.Context, req any) (any, error) {
+		return &cacheResponse{ID: req.(*cacheRequest).ID}, nil
+	})
+	register("Tagged", "GET", "/tagged/{ID}", "", func(ctx context.Context, req any) (any, error) {
+		return &cacheTaggedResponse{ID: req.(*cacheRequest).ID}, nil
+	})
+	register("File", "GET", "/file/{ID}", "private, max-age=30", func(ctx context.Context, req any) (any, error) {
+		res := &cacheStreamResponse{version: `W/"` + req.(*cacheRequest).ID + `"`}
+		res.SetContent(io.NopCloser(strings.NewReader("Hello " + req.(*cacheRequest).ID)))
+		res.SetContentType("text/plain")
+		return res, nil
+	})
+	return gw
+}
+
+func (suite *CacheSuite) invoke(gw *apis.Gateway, method string, path string, ifNoneMatch string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	w := httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	return w
+}
+
+// Endpoints w/ the "CACHE xxx" doc option should include an ETag based on the body's hash.
+func (suite *CacheSuite) TestHashedETag() {
+	gw := suite.gateway()
+
+	res := suite.invoke(gw, "GET", "/user/123", "")
+	suite.Equal(200, res.Code)
+	suite.Equal("max-age=60", res.Header().Get("Cache-Control"))
+	suite.JSONEq(`{"ID":"123"}`, res.Body.String())
+	etag := res.Header().Get("ETag")
+	suite.True(strings.HasPrefix(etag, `W/"`), "Hashed ETag should be weak: %s", etag)
+
+	// Same body, same ETag. Different body, different ETag.
+	suite.Equal(etag, suite.invoke(gw, "GET", "/user/123", "").Header().Get("ETag"))
+	suite.NotEqual(etag, suite.invoke(gw, "GET", "/user/456", "").Header().Get("ETag"))
+
+	res = suite.invoke(gw, "GET", "/user/123", etag)
+	suite.Equal(304, res.Code)
+	suite.Equal(etag, res.Header().Get("ETag"))
+	suite.Equal("max-age=60", res.Header().Get("Cache-Control"))
+	suite.Equal("", res.Body.String())
+
+	res = suite.invoke(gw, "GET", "/user/123", `"nope", `+strings.TrimPrefix(etag, "W/"))
+	suite.Equal(304, res.Code, "Should use weak comparison on a list of ETags")
+
+	res = suite.invoke(gw, "GET", "/user/456", etag)
+	suite.Equal(200, res.Code)
+	suite.JSONEq(`{"ID":"456"}`, res.Body.String())
+}
+
+// Responses that implement services.ETagGetter should use their own ETag w/ or w/o the "CACHE" doc option.
+func (suite *CacheSuite) TestCustomETag() {
+	gw := suite.gateway()
+
+	res := suite.invoke(gw, "GET", "/tagged/123", "")
+	suite.Equal(200, res.Code)
+	suite.Equal(`"v123"`, res.Header().Get("ETag"))
+	suite.Equal("", res.Header().Get("Cache-Control"))
+
+	res = suite.invoke(gw, "GET", "/tagged/123", `"v123"`)
+	suite.Equal(304, res.Code)
+	suite.Equal("", res.Body.String())
+
+	res = suite.invoke(gw, "GET", "/tagged/123", `*`)
+	suite.Equal(304, res.Code)
+
+	res = suite.invoke(gw, "GET", "/file/abc", "")
+	suite.Equal(200, res.Code)
+	suite.Equal(`W/"abc"`, res.Header().Get("ETag"))
+	suite.Equal("private, max-age=30", res.Header().Get("Cache-Control"))
+	suite.Equal("Hello abc", res.Body.String())
+
+	res = suite.invoke(gw, "GET", "/file/abc", `W/"abc"`)
+	suite.Equal(304, res.Code)
+	suite.Equal("", res.Header().Get("Content-Type"))
+	suite.Equal("", res.Body.String())
+}
+
+// Only GET/HEAD requests are cacheable.
+func (suite *CacheSuite) TestNotCacheable() {
+	gw := suite.gateway()
+
+	res := suite.invoke(gw, "POST", "/user/123", "*")
+	suite.Equal(200, res.Code)
+	suite.Equal("", res.Header().Get("ETag"))
+	suite.Equal("", res.Header().Get("Cache-Control"))
+	suite.JSONEq(`{"ID":"123"}`, res.Body.String())
+}
+
+// ETags and 304s should play nicely w/ compression.
+func (suite *CacheSuite) TestCompression() {
+	gw := apis.NewGateway(":0", apis.WithCompression(apis.CompressionConfig{MinSize: 1}))
+	gw.Register(services.Endpoint{
+		ServiceName:  "UserService",
+		Name:         "GetByID",
+		NewInput:     func() services.StructPointer { return &cacheRequest{} },
+		Handler:      func(ctx context.Context, req any) (any, error) { return &cacheResponse{ID: "123"}, nil },
+		CacheControl: "max-age=60",
+	}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "GET", Path: "/user/{ID}", Status: 200})
+
+	req := httptest.NewRequest("GET", "/user/123", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	suite.Equal(200, w.Code)
+	suite.Equal("gzip", w.Header().Get("Content-Encoding"))
+	etag := w.Header().Get("ETag")
+	suite.NotEmpty(etag)
+
+	req = httptest.NewRequest("GET", "/user/123", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	suite.Equal(http.StatusNotModified, w.Code)
+	suite.Equal("", w.Header().Get("Content-Encoding"))
+	suite.Equal(0, w.Body.Len())
+}
+
+// Compressed bytes aren't identical to the original ones, so strong ETags should become weak ones.
+func (suite *CacheSuite) TestCompression_strongETag() {
+	gw := apis.NewGateway(":0", apis.WithCompression(apis.CompressionConfig{MinSize: 1}))
+	gw.Register(services.Endpoint{
+		ServiceName: "UserService",
+		Name:        "Tagged",
+		NewInput:    func() services.StructPointer { return &cacheRequest{} },
+		Handler:     func(ctx context.Context, req any) (any, error) { return &cacheTaggedResponse{ID: "123"}, nil },
+	}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "GET", Path: "/tagged/{ID}", Status: 200})
+
+	req := httptest.NewRequest("GET", "/tagged/123", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	suite.Equal(200, w.Code)
+	suite.Equal("gzip", w.Header().Get("Content-Encoding"))
+	suite.Equal(`W/"v123"`, w.Header().Get("ETag"))
+
+	req = httptest.NewRequest("GET", "/tagged/123", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", `W/"v123"`)
+	w = httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	suite.Equal(http.StatusNotModified, w.Code)
+
+	// Uncompressed responses should keep the strong ETag.
+	w = httptest.NewRecorder()
+	gw.ServeHTTP(w, httptest.NewRequest("GET", "/tagged/123", nil))
+	suite.Equal(200, w.Code)
+	suite.Equal(`"v123"`, w.Header().Get("ETag"))
+}
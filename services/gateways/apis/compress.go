@@ -0,0 +1,366 @@
+package apis
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/monadicstack/abide/fail"
+)
+
+// CompressionConfig describes which responses the gateway compresses when the caller's "Accept-Encoding"
+// header says that it can handle it. Pass it to WithCompression() when creating your API gateway.
+type CompressionConfig struct {
+	// MinSize is the smallest response (in bytes) that we'll bother compressing. Tiny responses often get
+	// bigger when compressed, so it's not worth the CPU. When zero, we use 1KB.
+	MinSize int
+	// ContentTypes are the media types we'll compress. You can use wildcards such as "text/*". When empty, we
+	// compress JSON, XML, JavaScript, and text. Things like images and zip files are already compressed, so
+	// we leave them alone.
+	ContentTypes []string
+	// Level is the gzip/deflate compression level from 1 (fastest) to 9 (smallest). When zero, we use the
+	// standard library's default.
+	Level int
+	// Encoders supports additional encodings such as "br" (brotli) that the standard library doesn't. These
+	// are preferred over "gzip" and "deflate" when the caller supports them equally.
+	Encoders []CompressionEncoder
+	// MaxRequestSize limits the size (in bytes) of compressed request bodies once we've decompressed them, so
+	// a tiny "zip bomb" can't exhaust our memory. The gateway's WithMaxBodySize() limit (or the endpoint's
+	// "MAX BODY" option) takes precedence when there is one. When zero, we use 10MB.
+	MaxRequestSize int64
+}
+
+// CompressionEncoder lets you plug in a content encoding that the gateway doesn't support out of the box.
+//
+//	apis.CompressionEncoder{
+//		Name: "br",
+//		NewWriter: func(w io.Writer) io.WriteCloser {
+//			return brotli.NewWriter(w)
+//		},
+//	}
+//
+// If your writer has a "Flush() error" method, we'll use it when the handler flushes the response.
+type CompressionEncoder struct {
+	// Name is the "Content-Encoding" value for this encoding (e.g. "br").
+	Name string
+	// NewWriter wraps the response body so that everything written to it is compressed.
+	NewWriter func(w io.Writer) io.WriteCloser
+}
+
+// defaultCompressionTypes are the media types that we compress when you don't specify any yourself.
+var defaultCompressionTypes = []string{
+	"application/json",
+	"application/problem+json",
+	"application/javascript",
+	"application/xml",
+	"text/*",
+}
+
+// encoders lists all of the encodings that the gateway supports in order of preference.
+func (config *CompressionConfig) encoders() []CompressionEncoder {
+	level := config.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	encoders := append([]CompressionEncoder{}, config.Encoders...)
+	return append(encoders,
+		CompressionEncoder{
+			Name: "gzip",
+			NewWriter: func(w io.Writer) io.WriteCloser {
+				writer, _ := gzip.NewWriterLevel(w, level) // only fails for invalid levels
+				if writer == nil {
+					writer = gzip.NewWriter(w)
+				}
+				return writer
+			},
+		},
+		CompressionEncoder{
+			// HTTP's "deflate" is really the zlib format (RFC 1950), not a raw deflate stream.
+			Name: "deflate",
+			NewWriter: func(w io.Writer) io.WriteCloser {
+				writer, _ := zlib.NewWriterLevel(w, level) // only fails for invalid levels
+				if writer == nil {
+					writer = zlib.NewWriter(w)
+				}
+				return writer
+			},
+		},
+	)
+}
+
+// minSize is the smallest response we'll compress, applying the default when necessary.
+func (config *CompressionConfig) minSize() int {
+	if config.MinSize <= 0 {
+		return 1024
+	}
+	return config.MinSize
+}
+
+// maxRequestSize is the largest decompressed request body we'll accept, applying the default when necessary.
+func (config *CompressionConfig) maxRequestSize() int64 {
+	if config.MaxRequestSize <= 0 {
+		return 10 << 20
+	}
+	return config.MaxRequestSize
+}
+
+// allowsContentType determines if responses w/ this "Content-Type" header should be compressed.
+func (config *CompressionConfig) allowsContentType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" {
+		return false
+	}
+
+	contentTypes := config.ContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = defaultCompressionTypes
+	}
+	for _, pattern := range contentTypes {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == mediaType || pattern == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiate picks the encoding that the caller most prefers based on their "Accept-Encoding" header (e.g.
+// "gzip;q=1.0, deflate;q=0.5"). Ties go to whichever one comes first in our list. This returns false when
+// the caller doesn't accept any of the encodings we support.
+func (config *CompressionConfig) negotiate(acceptEncoding string) (CompressionEncoder, bool) {
+	if acceptEncoding == "" {
+		return CompressionEncoder{}, false
+	}
+
+	weights := map[string]float64{}
+	for _, value := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(value, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		weight := 1.0
+		if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+			weight, _ = strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64) // garbage is the same as "don't use this"
+		}
+		weights[name] = weight
+	}
+
+	best, bestWeight := CompressionEncoder{}, 0.0
+	for _, encoder := range config.encoders() {
+		weight, ok := weights[strings.ToLower(encoder.Name)]
+		if !ok {
+			weight = weights["*"]
+		}
+		if weight > bestWeight {
+			best, bestWeight = encoder, weight
+		}
+	}
+	return best, bestWeight > 0
+}
+
+// compressResponse is the middleware that compresses response bodies for callers that accept it. We don't
+// know if the response is worth compressing until the handler sets its headers and starts writing, so the
+// response writer makes that decision on the fly.
+func compressResponse(config *CompressionConfig) HTTPMiddlewareFunc {
+	return func(w http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+		encoder, ok := config.negotiate(req.Header.Get("Accept-Encoding"))
+		if !ok || req.Method == http.MethodHead {
+			next(w, req)
+			return
+		}
+
+		writer := &compressWriter{ResponseWriter: w, config: config, encoder: encoder}
+		next(writer, req)
+		writer.Close()
+	}
+}
+
+// compressWriter decides whether to compress the response once the handler has set all of its headers:
+//
+//   - Responses that already have a "Content-Encoding" (e.g. a pre-gzipped file) are left alone.
+//   - Partial responses (e.g. "Range" requests) are left alone since the byte offsets refer to the original content.
+//   - Responses that aren't one of the config's content types are left alone.
+//   - Responses w/ a "Content-Length" smaller than the minimum size are left alone.
+//
+// When we don't know how big the response is, we buffer the first few bytes until we've seen enough to know
+// that it's worth compressing. Flushing the response (e.g. server-sent events) also ends the buffering.
+type compressWriter struct {
+	http.ResponseWriter
+	config     *CompressionConfig
+	encoder    CompressionEncoder
+	status     int
+	buffer     bytes.Buffer
+	buffering  bool
+	compressor io.WriteCloser
+	committed  bool
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+
+	headers := w.Header()
+	switch {
+	case status < 200 || status == http.StatusNoContent || status == http.StatusNotModified:
+	case status == http.StatusPartialContent || headers.Get("Content-Range") != "":
+	case headers.Get("Content-Encoding") != "":
+	case !w.config.allowsContentType(headers.Get("Content-Type")):
+	default:
+		headers.Add("Vary", "Accept-Encoding")
+		length, err := strconv.Atoi(headers.Get("Content-Length"))
+		switch {
+		case err != nil:
+			w.buffering = true
+			return
+		case length >= w.config.minSize():
+			w.startCompressing()
+			return
+		}
+	}
+	w.commit()
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buffering {
+		w.buffer.Write(data)
+		if w.buffer.Len() >= w.config.minSize() {
+			w.startCompressing()
+		}
+		return len(data), nil
+	}
+	if w.compressor != nil {
+		return w.compressor.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush sends whatever we've got so far to the caller. If we're still waiting to see if the response is big
+// enough to compress, we give up and send it uncompressed; the handler is clearly streaming data to the caller.
+func (w *compressWriter) Flush() {
+	switch {
+	case w.buffering:
+		w.stopBuffering(w.ResponseWriter)
+	case w.compressor != nil:
+		if flusher, ok := w.compressor.(interface{ Flush() error }); ok {
+			_ = flusher.Flush()
+		}
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack lets things like WebSocket upgrades take over the connection as if we weren't here at all.
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	return hijacker.Hijack()
+}
+
+// Close finishes off the response once the handler is done. Buffered responses that never reached the minimum
+// size are sent uncompressed, and compressed responses get their final bytes (e.g. the gzip footer).
+func (w *compressWriter) Close() {
+	switch {
+	case w.status == 0:
+	case w.buffering:
+		w.stopBuffering(w.ResponseWriter)
+	case w.compressor != nil:
+		_ = w.compressor.Close()
+	}
+}
+
+// startCompressing switches the response over to the negotiated encoding, sending along anything we've buffered.
+func (w *compressWriter) startCompressing() {
+	headers := w.Header()
+	headers.Set("Content-Encoding", w.encoder.Name)
+	headers.Del("Content-Length")
+
+	// A strong ETag promises byte-for-byte identical responses, which the compressed bytes are not. Weak
+	// comparison (e.g. for "If-None-Match") still matches the original tag, so 304s keep working.
+	if etag := headers.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		headers.Set("ETag", "W/"+etag)
+	}
+	w.commit()
+
+	w.compressor = w.encoder.NewWriter(w.ResponseWriter)
+	w.stopBuffering(w.compressor)
+}
+
+// stopBuffering writes the buffered bytes to the given writer (either the compressor or the original response),
+// sending the response headers first if we haven't done so already.
+func (w *compressWriter) stopBuffering(writer io.Writer) {
+	w.commit()
+	if w.buffering {
+		w.buffering = false
+		_, _ = writer.Write(w.buffer.Bytes())
+		w.buffer.Reset()
+	}
+}
+
+// commit sends the response headers to the caller, so we can't change our minds about compression after this.
+func (w *compressWriter) commit() {
+	if w.committed {
+		return
+	}
+	w.committed = true
+	w.ResponseWriter.WriteHeader(w.status)
+}
+
+// decompressRequest lets callers send us request bodies w/ a "Content-Encoding" of "gzip" or "deflate" (e.g.
+// the Go client when using clients.WithRequestCompression()). We only do this when the gateway has compression
+// enabled; otherwise, and for any other encoding, the request fails w/ a 415 error. The result indicates whether
+// or not we decompressed the body, so the caller knows to limit how much of it we'll read.
+func decompressRequest(req *http.Request, config *CompressionConfig) (bool, error) {
+	encoding := strings.ToLower(strings.TrimSpace(req.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" {
+		return false, nil
+	}
+	if config == nil {
+		return false, fail.UnsupportedFormat("compressed request bodies are not supported")
+	}
+
+	switch encoding {
+	case "gzip":
+		reader, err := gzip.NewReader(req.Body)
+		if err != nil {
+			return false, fail.BadRequest("invalid gzip request body: %v", err)
+		}
+		req.Body = decompressedBody{Reader: reader, Closer: req.Body}
+	case "deflate":
+		reader, err := zlib.NewReader(req.Body)
+		if err != nil {
+			return false, fail.BadRequest("invalid deflate request body: %v", err)
+		}
+		req.Body = decompressedBody{Reader: reader, Closer: req.Body}
+	default:
+		return false, fail.UnsupportedFormat("unsupported content encoding: %s", encoding)
+	}
+
+	// The length the caller gave us was for the compressed bytes, so it's meaningless now.
+	req.Header.Del("Content-Encoding")
+	req.Header.Del("Content-Length")
+	req.ContentLength = -1
+	return true, nil
+}
+
+// decompressedBody reads the decompressed bytes of a request body, but closes the original body when you're done.
+type decompressedBody struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,313 @@
+//go:build unit
+
+package apis_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/apis"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestCompressionSuite(t *testing.T) {
+	suite.Run(t, new(CompressionSuite))
+}
+
+type CompressionSuite struct {
+	suite.Suite
+}
+
+type compressionRequest struct {
+	Text string
+}
+
+type compressionResponse struct {
+	Text string
+}
+
+type compressionStreamResponse struct {
+	services.StreamResponse
+}
+
+// gateway registers "POST /echo" which responds w/ the request's text as JSON and "POST /stream" which responds
+// w/ the request's text as a raw stream whose type is "image/png" or "text/plain" (pre-gzipped) based on the path.
+func (suite *CompressionSuite) gateway(options ...apis.GatewayOption) *apis.Gateway {
+	gw := apis.NewGateway(":0", options...)
+	gw.Register(services.Endpoint{
+		ServiceName: "EchoService",
+		Name:        "Echo",
+		NewInput:    func() services.StructPointer { return &compressionRequest{} },
+		Handler: func(ctx context.Context, req any) (any, error) {
+			return &compressionResponse{Text: req.(*compressionRequest).Text}, nil
+		},
+	}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "POST", Path: "/echo", Status: 200})
+	gw.Register(services.Endpoint{
+		ServiceName: "EchoService",
+		Name:        "Image",
+		NewInput:    func() services.StructPointer { return &compressionRequest{} },
+		Handler: func(ctx context.Context, req any) (any, error) {
+			res := &compressionStreamResponse{}
+			res.SetContent(io.NopCloser(strings.NewReader(req.(*compressionRequest).Text)))
+			res.SetContentType("image/png")
+			return res, nil
+		},
+	}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "POST", Path: "/image", Status: 200})
+	gw.Register(services.Endpoint{
+		ServiceName: "EchoService",
+		Name:        "Gzipped",
+		NewInput:    func() services.StructPointer { return &compressionRequest{} },
+		Handler: func(ctx context.Context, req any) (any, error) {
+			res := &compressionStreamResponse{}
+			res.SetContent(io.NopCloser(bytes.NewReader(suite.gzip(req.(*compressionRequest).Text))))
+			res.SetContentType("text/plain")
+			return res, nil
+		},
+	}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "POST", Path: "/gzipped", Status: 200})
+	return gw
+}
+
+func (suite *CompressionSuite) invoke(gw *apis.Gateway, path string, text string, headers map[string]string) *http.Response {
+	req := httptest.NewRequest("POST", path, strings.NewReader(`{"Text":"`+text+`"}`))
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	return w.Result()
+}
+
+func (suite *CompressionSuite) gzip(text string) []byte {
+	buf := &bytes.Buffer{}
+	writer := gzip.NewWriter(buf)
+	_, _ = writer.Write([]byte(text))
+	_ = writer.Close()
+	return buf.Bytes()
+}
+
+func (suite *CompressionSuite) zlib(text string) []byte {
+	buf := &bytes.Buffer{}
+	writer := zlib.NewWriter(buf)
+	_, _ = writer.Write([]byte(text))
+	_ = writer.Close()
+	return buf.Bytes()
+}
+
+func (suite *CompressionSuite) readBody(res *http.Response) string {
+	var reader io.Reader = res.Body
+	switch res.Header.Get("Content-Encoding") {
+	case "gzip":
+		gzipReader, err := gzip.NewReader(res.Body)
+		suite.Require().NoError(err)
+		reader = gzipReader
+	case "deflate":
+		zlibReader, err := zlib.NewReader(res.Body)
+		suite.Require().NoError(err)
+		reader = zlibReader
+	}
+	body, err := io.ReadAll(reader)
+	suite.Require().NoError(err)
+	return string(body)
+}
+
+// Large JSON responses should be compressed using whichever encoding the caller prefers.
+func (suite *CompressionSuite) TestCompress() {
+	gw := suite.gateway(apis.WithCompression(apis.CompressionConfig{MinSize: 100}))
+	text := strings.Repeat("abide", 50)
+
+	res := suite.invoke(gw, "/echo", text, map[string]string{"Accept-Encoding": "gzip, deflate"})
+	suite.Equal(200, res.StatusCode)
+	suite.Equal("gzip", res.Header.Get("Content-Encoding"))
+	suite.Equal("Accept-Encoding", res.Header.Get("Vary"))
+	suite.Equal("application/json", res.Header.Get("Content-Type"))
+	suite.JSONEq(`{"Text":"`+text+`"}`, suite.readBody(res))
+
+	res = suite.invoke(gw, "/echo", text, map[string]string{"Accept-Encoding": "gzip;q=0.5, deflate"})
+	suite.Equal("deflate", res.Header.Get("Content-Encoding"))
+	suite.JSONEq(`{"Text":"`+text+`"}`, suite.readBody(res))
+
+	res = suite.invoke(gw, "/echo", text, map[string]string{"Accept-Encoding": "*"})
+	suite.Equal("gzip", res.Header.Get("Content-Encoding"))
+	suite.JSONEq(`{"Text":"`+text+`"}`, suite.readBody(res))
+}
+
+// Responses should be sent as-is when the caller doesn't want compression or it's not worth it.
+func (suite *CompressionSuite) TestCompress_skip() {
+	gw := suite.gateway(apis.WithCompression(apis.CompressionConfig{MinSize: 100}))
+	text := strings.Repeat("abide", 50)
+
+	// The caller doesn't accept any encodings we support.
+	for _, acceptEncoding := range []string{"", "br", "gzip;q=0, deflate;q=0", "identity"} {
+		res := suite.invoke(gw, "/echo", text, map[string]string{"Accept-Encoding": acceptEncoding})
+		suite.Equal("", res.Header.Get("Content-Encoding"), acceptEncoding)
+		suite.JSONEq(`{"Text":"`+text+`"}`, suite.readBody(res))
+	}
+
+	// Not big enough to bother.
+	res := suite.invoke(gw, "/echo", "abide", map[string]string{"Accept-Encoding": "gzip"})
+	suite.Equal("", res.Header.Get("Content-Encoding"))
+	suite.JSONEq(`{"Text":"abide"}`, suite.readBody(res))
+
+	// Not one of the content types we compress.
+	res = suite.invoke(gw, "/image", text, map[string]string{"Accept-Encoding": "gzip"})
+	suite.Equal("", res.Header.Get("Content-Encoding"))
+	suite.Equal(text, suite.readBody(res))
+
+	// Not compressed by default.
+	res = suite.invoke(suite.gateway(), "/echo", text, map[string]string{"Accept-Encoding": "gzip"})
+	suite.Equal("", res.Header.Get("Content-Encoding"))
+	suite.JSONEq(`{"Text":"`+text+`"}`, suite.readBody(res))
+}
+
+// Streams that already set their own encoding should not be compressed a second time.
+func (suite *CompressionSuite) TestCompress_alreadyEncoded() {
+	gw := suite.gateway(
+		apis.WithCompression(apis.CompressionConfig{MinSize: 10}),
+		apis.WithMiddleware(func(w http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+			if req.URL.Path == "/gzipped" {
+				w.Header().Set("Content-Encoding", "gzip")
+			}
+			next(w, req)
+		}),
+	)
+	text := strings.Repeat("abide", 50)
+
+	res := suite.invoke(gw, "/gzipped", text, map[string]string{"Accept-Encoding": "gzip, deflate"})
+	suite.Equal("gzip", res.Header.Get("Content-Encoding"))
+	suite.Equal(text, suite.readBody(res))
+}
+
+// Custom content types and encoders should be honored.
+func (suite *CompressionSuite) TestCompress_config() {
+	gw := suite.gateway(apis.WithCompression(apis.CompressionConfig{
+		MinSize:      10,
+		ContentTypes: []string{"image/*"},
+		Encoders: []apis.CompressionEncoder{
+			{Name: "upper", NewWriter: func(w io.Writer) io.WriteCloser { return upperWriter{w} }},
+		},
+	}))
+	text := strings.Repeat("abide", 50)
+
+	res := suite.invoke(gw, "/image", text, map[string]string{"Accept-Encoding": "gzip, upper"})
+	suite.Equal("upper", res.Header.Get("Content-Encoding"))
+	suite.Equal(strings.ToUpper(text), suite.readBody(res))
+
+	res = suite.invoke(gw, "/image", text, map[string]string{"Accept-Encoding": "gzip"})
+	suite.Equal("gzip", res.Header.Get("Content-Encoding"))
+	suite.Equal(text, suite.readBody(res))
+
+	res = suite.invoke(gw, "/echo", text, map[string]string{"Accept-Encoding": "gzip, upper"})
+	suite.Equal("", res.Header.Get("Content-Encoding"))
+	suite.JSONEq(`{"Text":"`+text+`"}`, suite.readBody(res))
+}
+
+// The gateway should accept compressed request bodies when you've enabled compression.
+func (suite *CompressionSuite) TestDecompressRequest() {
+	gw := suite.gateway(apis.WithCompression(apis.CompressionConfig{}))
+	text := strings.Repeat("abide", 50)
+
+	req := httptest.NewRequest("POST", "/echo", bytes.NewReader(suite.gzip(`{"Text":"`+text+`"}`)))
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	suite.Equal(200, w.Code)
+	suite.JSONEq(`{"Text":"`+text+`"}`, suite.readBody(w.Result()))
+
+	req = httptest.NewRequest("POST", "/echo", bytes.NewReader(suite.zlib(`{"Text":"`+text+`"}`)))
+	req.Header.Set("Content-Encoding", "deflate")
+	w = httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	suite.Equal(200, w.Code)
+	suite.JSONEq(`{"Text":"`+text+`"}`, suite.readBody(w.Result()))
+
+	req = httptest.NewRequest("POST", "/echo", strings.NewReader(`{"Text":"`+text+`"}`))
+	req.Header.Set("Content-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	suite.Equal(400, w.Code)
+
+	req = httptest.NewRequest("POST", "/echo", strings.NewReader(`{"Text":"`+text+`"}`))
+	req.Header.Set("Content-Encoding", "deflate")
+	w = httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	suite.Equal(400, w.Code)
+
+	req = httptest.NewRequest("POST", "/echo", strings.NewReader(`{"Text":"`+text+`"}`))
+	req.Header.Set("Content-Encoding", "br")
+	w = httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	suite.Equal(415, w.Code)
+
+	// The body limit applies to the decompressed bytes, so you can't sneak in a zip bomb.
+	gw = suite.gateway(apis.WithCompression(apis.CompressionConfig{}), apis.WithMaxBodySize(100))
+	req = httptest.NewRequest("POST", "/echo", bytes.NewReader(suite.gzip(`{"Text":"`+text+`"}`)))
+	req.Header.Set("Content-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	suite.Equal(413, w.Code)
+}
+
+// Decompressed request bodies are limited even when the gateway has no body size limit.
+func (suite *CompressionSuite) TestDecompressRequest_maxRequestSize() {
+	gw := suite.gateway(apis.WithCompression(apis.CompressionConfig{MaxRequestSize: 100}))
+	text := strings.Repeat("abide", 50)
+
+	req := httptest.NewRequest("POST", "/echo", bytes.NewReader(suite.gzip(`{"Text":"`+text+`"}`)))
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	suite.Equal(413, w.Code)
+
+	// Uncompressed bodies don't count against the decompression limit.
+	req = httptest.NewRequest("POST", "/echo", strings.NewReader(`{"Text":"`+text+`"}`))
+	w = httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	suite.Equal(200, w.Code)
+
+	// The gateway's own limit trumps the decompression limit.
+	gw = suite.gateway(apis.WithCompression(apis.CompressionConfig{MaxRequestSize: 100}), apis.WithMaxBodySize(1000))
+	req = httptest.NewRequest("POST", "/echo", bytes.NewReader(suite.gzip(`{"Text":"`+text+`"}`)))
+	req.Header.Set("Content-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	suite.Equal(200, w.Code)
+}
+
+// Without compression enabled, the gateway shouldn't decompress anything.
+func (suite *CompressionSuite) TestDecompressRequest_disabled() {
+	gw := suite.gateway()
+	text := strings.Repeat("abide", 50)
+
+	req := httptest.NewRequest("POST", "/echo", bytes.NewReader(suite.gzip(`{"Text":"`+text+`"}`)))
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	suite.Equal(415, w.Code)
+
+	req = httptest.NewRequest("POST", "/echo", strings.NewReader(`{"Text":"`+text+`"}`))
+	req.Header.Set("Content-Encoding", "identity")
+	w = httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	suite.Equal(200, w.Code)
+}
+
+// upperWriter is a silly "encoding" that lets us see that custom encoders are used.
+type upperWriter struct {
+	io.Writer
+}
+
+func (w upperWriter) Write(data []byte) (int, error) {
+	return w.Writer.Write(bytes.ToUpper(data))
+}
+
+func (w upperWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,190 @@
+package apis
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/internal/slices"
+)
+
+// CORSConfig describes which browser-based callers on other origins are allowed to invoke your API.
+// Pass it to WithCORS() when creating your API gateway.
+type CORSConfig struct {
+	// AllowedOrigins are the origins (e.g. "https://app.example.com") allowed to call the API. You can use
+	// wildcards to match a bunch of origins at once (e.g. "https://*.example.com"), and "*" allows everyone.
+	// When empty, we allow all origins (unless AllowCredentials is true, in which case this is required).
+	AllowedOrigins []string
+	// AllowedMethods limits the HTTP methods that browsers can use. When empty, we allow whatever methods
+	// you have registered for the path (e.g. "GET, DELETE" if you have "GET /user/{ID}" and "DELETE /user/{ID}").
+	// Either way, we only ever include methods that are actually registered for the path.
+	AllowedMethods []string
+	// AllowedHeaders are the request headers that browsers can send. When empty, we allow whatever headers
+	// the browser asks for in the preflight request.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers (beyond the basic ones) that browser code is allowed to read.
+	ExposedHeaders []string
+	// AllowCredentials indicates that browsers can include cookies/auth when calling the API. You must
+	// also supply AllowedOrigins, and none of them can be "*".
+	AllowCredentials bool
+	// MaxAge is how long browsers can cache the results of a preflight request. When zero, we leave it up
+	// to the browser's default.
+	MaxAge time.Duration
+}
+
+// validate rejects configs that would let any website make credentialed calls to the API. Browsers don't
+// accept "*" for those, so we'd have to echo back whatever origin the caller sent.
+func (config *CORSConfig) validate() error {
+	if !config.AllowCredentials {
+		return nil
+	}
+	if len(config.AllowedOrigins) == 0 || slices.Contains(config.AllowedOrigins, "*") {
+		return fmt.Errorf("cors: AllowCredentials requires explicit AllowedOrigins")
+	}
+	return nil
+}
+
+// allowsOrigin determines if the request's "Origin" header matches any of the allowed origin patterns.
+func (config *CORSConfig) allowsOrigin(origin string) bool {
+	if len(config.AllowedOrigins) == 0 {
+		return true
+	}
+
+	origin = strings.ToLower(origin)
+	for _, pattern := range config.AllowedOrigins {
+		if pattern == "*" {
+			return true
+		}
+		if ok, _ := path.Match(strings.ToLower(pattern), origin); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsAnyOrigin is true when we can respond w/ "Access-Control-Allow-Origin: *" rather than echoing the
+// caller's origin. Browsers won't accept "*" for requests that include credentials.
+func (config *CORSConfig) allowsAnyOrigin() bool {
+	if config.AllowCredentials {
+		return false
+	}
+	return len(config.AllowedOrigins) == 0 || slices.Contains(config.AllowedOrigins, "*")
+}
+
+// allowsMethod determines if browsers can use the given HTTP method.
+func (config *CORSConfig) allowsMethod(method string) bool {
+	if len(config.AllowedMethods) == 0 {
+		return true
+	}
+	for _, allowed := range config.AllowedMethods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// varyOrigin lets caches know that the response depends on the caller's origin. Unless we allow everyone,
+// that's true even when we reject the origin; otherwise, a cache could hand a response w/o any CORS headers
+// to an origin that we do allow.
+func (config *CORSConfig) varyOrigin(headers http.Header) {
+	if !config.allowsAnyOrigin() {
+		headers.Add("Vary", "Origin")
+	}
+}
+
+// writeOrigin includes the headers that every CORS response needs, preflight or not.
+func (config *CORSConfig) writeOrigin(headers http.Header, origin string) {
+	switch config.allowsAnyOrigin() {
+	case true:
+		headers.Set("Access-Control-Allow-Origin", "*")
+	default:
+		headers.Set("Access-Control-Allow-Origin", origin)
+	}
+	if config.AllowCredentials {
+		headers.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// writeCORSHeaders is the middleware that decorates responses to non-preflight requests from other
+// origins so that browsers will let the calling code see the response.
+func writeCORSHeaders(config *CORSConfig) HTTPMiddlewareFunc {
+	return func(w http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+		config.varyOrigin(w.Header())
+		origin := req.Header.Get("Origin")
+		if origin == "" || !config.allowsOrigin(origin) {
+			next(w, req)
+			return
+		}
+
+		headers := w.Header()
+		config.writeOrigin(headers, origin)
+		if len(config.ExposedHeaders) > 0 {
+			headers.Set("Access-Control-Expose-Headers", strings.Join(config.ExposedHeaders, ", "))
+		}
+		next(w, req)
+	}
+}
+
+// preflightHandler responds to the browser's "OPTIONS" request before it makes the real call. The allowed
+// methods are based on the routes that you've actually registered for the path, so the browser finds out
+// right away that "DELETE /user/{ID}" isn't a thing rather than making the call and getting a 405.
+func (gw *Gateway) preflightHandler(routePath string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		origin := req.Header.Get("Origin")
+		requestMethod := req.Header.Get("Access-Control-Request-Method")
+		if origin == "" || requestMethod == "" {
+			gw.methodNotAllowedHandler(w, req)
+			return
+		}
+
+		encoder := gw.codecs.DefaultEncoder()
+		gw.cors.varyOrigin(w.Header())
+		if !gw.cors.allowsOrigin(origin) {
+			respondFailure(w, req, encoder, fail.PermissionDenied("cors: origin not allowed: %s", origin))
+			return
+		}
+
+		methods := gw.corsMethods(routePath)
+		if !slices.Contains(methods, strings.ToUpper(requestMethod)) {
+			respondFailure(w, req, encoder, fail.MethodNotAllowed("cors: method not allowed: %s", requestMethod))
+			return
+		}
+
+		headers := w.Header()
+		gw.cors.writeOrigin(headers, origin)
+		headers.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		switch {
+		case len(gw.cors.AllowedHeaders) > 0:
+			headers.Set("Access-Control-Allow-Headers", strings.Join(gw.cors.AllowedHeaders, ", "))
+		case req.Header.Get("Access-Control-Request-Headers") != "":
+			headers.Set("Access-Control-Allow-Headers", req.Header.Get("Access-Control-Request-Headers"))
+			headers.Add("Vary", "Access-Control-Request-Headers")
+		}
+		if gw.cors.MaxAge > 0 {
+			headers.Set("Access-Control-Max-Age", strconv.Itoa(int(gw.cors.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// corsMethods looks through all of the registered routes and returns the (sorted) methods that browsers
+// can use to call the given path.
+func (gw *Gateway) corsMethods(routePath string) []string {
+	var methods []string
+	for route := range gw.endpoints {
+		if route.Method == http.MethodOptions || route.Path != routePath {
+			continue
+		}
+		if gw.cors.allowsMethod(route.Method) && !slices.Contains(methods, route.Method) {
+			methods = append(methods, route.Method)
+		}
+	}
+	sort.Strings(methods)
+	return methods
+}
@@ -0,0 +1,183 @@
+//go:build unit
+
+package apis_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/apis"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestCORSSuite(t *testing.T) {
+	suite.Run(t, new(CORSSuite))
+}
+
+type CORSSuite struct {
+	suite.Suite
+}
+
+type corsRequest struct{}
+
+type corsResponse struct {
+	Text string
+}
+
+// gateway creates an API gateway w/ the given CORS config and registers "GET /user/{ID}", "DELETE /user/{ID}",
+// and "POST /user" so that we can see that preflight methods are based on the routes that actually exist.
+func (suite *CORSSuite) gateway(config *apis.CORSConfig) *apis.Gateway {
+	var options []apis.GatewayOption
+	if config != nil {
+		options = append(options, apis.WithCORS(*config))
+	}
+
+	gw := apis.NewGateway(":0", options...)
+	register := func(name string, method string, path string) {
+		gw.Register(services.Endpoint{
+			ServiceName: "UserService",
+			Name:        name,
+			NewInput:    func() services.StructPointer { return &corsRequest{} },
+			Handler: func(ctx context.Context, req any) (any, error) {
+				return &corsResponse{Text: name}, nil
+			},
+		}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: method, Path: path, Status: 200})
+	}
+	register("GetByID", "GET", "/user/{ID}")
+	register("Delete", "DELETE", "/user/{ID}")
+	register("Create", "POST", "/user")
+	return gw
+}
+
+func (suite *CORSSuite) invoke(gw *apis.Gateway, method string, path string, headers map[string]string) *http.Response {
+	req := httptest.NewRequest(method, path, nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	return w.Result()
+}
+
+func (suite *CORSSuite) preflight(gw *apis.Gateway, path string, origin string, method string) *http.Response {
+	return suite.invoke(gw, http.MethodOptions, path, map[string]string{
+		"Origin":                         origin,
+		"Access-Control-Request-Method":  method,
+		"Access-Control-Request-Headers": "Authorization, Content-Type",
+	})
+}
+
+// Without WithCORS(), OPTIONS requests should be rejected just like they always have been.
+func (suite *CORSSuite) TestDisabled() {
+	gw := suite.gateway(nil)
+
+	res := suite.preflight(gw, "/user/123", "https://app.example.com", "GET")
+	suite.Equal(405, res.StatusCode)
+	suite.Equal("", res.Header.Get("Access-Control-Allow-Origin"))
+
+	res = suite.invoke(gw, "GET", "/user/123", map[string]string{"Origin": "https://app.example.com"})
+	suite.Equal(200, res.StatusCode)
+	suite.Equal("", res.Header.Get("Access-Control-Allow-Origin"))
+}
+
+// The default config should allow anybody to call any of the registered methods.
+func (suite *CORSSuite) TestDefaults() {
+	gw := suite.gateway(&apis.CORSConfig{})
+
+	res := suite.preflight(gw, "/user/123", "https://app.example.com", "DELETE")
+	suite.Equal(204, res.StatusCode)
+	suite.Equal("*", res.Header.Get("Access-Control-Allow-Origin"))
+	suite.Equal("DELETE, GET", res.Header.Get("Access-Control-Allow-Methods"))
+	suite.Equal("Authorization, Content-Type", res.Header.Get("Access-Control-Allow-Headers"))
+	suite.Equal("", res.Header.Get("Access-Control-Allow-Credentials"))
+	suite.Equal("", res.Header.Get("Access-Control-Max-Age"))
+
+	res = suite.preflight(gw, "/user", "https://app.example.com", "POST")
+	suite.Equal(204, res.StatusCode)
+	suite.Equal("POST", res.Header.Get("Access-Control-Allow-Methods"))
+
+	// There's no "PUT /user/{ID}" route, so the browser shouldn't even try.
+	res = suite.preflight(gw, "/user/123", "https://app.example.com", "PUT")
+	suite.Equal(405, res.StatusCode)
+
+	res = suite.invoke(gw, "GET", "/user/123", map[string]string{"Origin": "https://app.example.com"})
+	suite.Equal(200, res.StatusCode)
+	suite.Equal("*", res.Header.Get("Access-Control-Allow-Origin"))
+
+	// Plain old OPTIONS requests that aren't preflight requests should still be rejected.
+	res = suite.invoke(gw, http.MethodOptions, "/user/123", nil)
+	suite.Equal(405, res.StatusCode)
+}
+
+// Origin patterns should allow matching origins and reject all others.
+func (suite *CORSSuite) TestAllowedOrigins() {
+	gw := suite.gateway(&apis.CORSConfig{
+		AllowedOrigins: []string{"https://*.example.com", "http://localhost:3000"},
+	})
+
+	res := suite.preflight(gw, "/user/123", "https://app.example.com", "GET")
+	suite.Equal(204, res.StatusCode)
+	suite.Equal("https://app.example.com", res.Header.Get("Access-Control-Allow-Origin"))
+	suite.Contains(res.Header.Values("Vary"), "Origin")
+
+	res = suite.preflight(gw, "/user/123", "http://localhost:3000", "GET")
+	suite.Equal(204, res.StatusCode)
+	suite.Equal("http://localhost:3000", res.Header.Get("Access-Control-Allow-Origin"))
+
+	res = suite.preflight(gw, "/user/123", "https://example.com.evil.com", "GET")
+	suite.Equal(403, res.StatusCode)
+	suite.Equal("", res.Header.Get("Access-Control-Allow-Origin"))
+	suite.Contains(res.Header.Values("Vary"), "Origin")
+
+	// The request still works (CORS is the browser's job), but the browser won't let the caller see it.
+	// Caches still need to know that the response depends on the origin.
+	res = suite.invoke(gw, "GET", "/user/123", map[string]string{"Origin": "https://evil.com"})
+	suite.Equal(200, res.StatusCode)
+	suite.Equal("", res.Header.Get("Access-Control-Allow-Origin"))
+	suite.Contains(res.Header.Values("Vary"), "Origin")
+}
+
+// Credentials w/o explicit origins would let any site call the API as the user, so the gateway should refuse to start.
+func (suite *CORSSuite) TestAllowCredentials_requiresOrigins() {
+	for _, origins := range [][]string{nil, {"*"}, {"https://app.example.com", "*"}} {
+		gw := suite.gateway(&apis.CORSConfig{AllowedOrigins: origins, AllowCredentials: true})
+		suite.Error(gw.Listen(), "Should reject AllowCredentials w/ origins %v", origins)
+
+		res := suite.preflight(gw, "/user/123", "https://evil.com", "GET")
+		suite.Equal(405, res.StatusCode, "CORS should be disabled when the config is invalid")
+		suite.Equal("", res.Header.Get("Access-Control-Allow-Origin"))
+	}
+}
+
+// All of the other knobs in the config should show up in the appropriate headers.
+func (suite *CORSSuite) TestConfig() {
+	gw := suite.gateway(&apis.CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"get", "post"},
+		AllowedHeaders:   []string{"Authorization"},
+		ExposedHeaders:   []string{"X-Request-ID", "Content-Disposition"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	res := suite.preflight(gw, "/user/123", "https://app.example.com", "GET")
+	suite.Equal(204, res.StatusCode)
+	suite.Equal("https://app.example.com", res.Header.Get("Access-Control-Allow-Origin"))
+	suite.Equal("GET", res.Header.Get("Access-Control-Allow-Methods"))
+	suite.Equal("Authorization", res.Header.Get("Access-Control-Allow-Headers"))
+	suite.Equal("true", res.Header.Get("Access-Control-Allow-Credentials"))
+	suite.Equal("600", res.Header.Get("Access-Control-Max-Age"))
+
+	res = suite.preflight(gw, "/user/123", "https://app.example.com", "DELETE")
+	suite.Equal(405, res.StatusCode)
+
+	res = suite.invoke(gw, "GET", "/user/123", map[string]string{"Origin": "https://app.example.com"})
+	suite.Equal(200, res.StatusCode)
+	suite.Equal("https://app.example.com", res.Header.Get("Access-Control-Allow-Origin"))
+	suite.Equal("true", res.Header.Get("Access-Control-Allow-Credentials"))
+	suite.Equal("X-Request-ID, Content-Disposition", res.Header.Get("Access-Control-Expose-Headers"))
+}
@@ -1,10 +1,14 @@
 package apis
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -15,6 +19,7 @@ import (
 	"github.com/monadicstack/abide/fail"
 	"github.com/monadicstack/abide/internal/naming"
 	"github.com/monadicstack/abide/internal/quiet"
+	"github.com/monadicstack/abide/metadata"
 	"github.com/monadicstack/abide/services"
 )
 
@@ -36,7 +41,7 @@ func NewGateway(address string, options ...GatewayOption) *Gateway {
 		option(&gw)
 	}
 
-	gw.router.NotFoundHandler = gw.notFoundHandler
+	gw.router.NotFoundHandler = gw.withProblemDetails(gw.notFoundHandler)
 	return &gw
 }
 
@@ -46,13 +51,22 @@ func NewGateway(address string, options ...GatewayOption) *Gateway {
 // DO NOT CREATE THIS DIRECTLY. Use the NewGateway() constructor to properly set up an
 // API gateway in your main() function.
 type Gateway struct {
-	codecs     codec.Registry
-	middleware HTTPMiddlewareFuncs
-	endpoints  map[httpRoute]services.Endpoint
-	router     *httptreemux.TreeMux
-	server     *http.Server
-	tlsCert    string
-	tlsKey     string
+	codecs         codec.Registry
+	compression    *CompressionConfig
+	cors           *CORSConfig
+	optionErr      error
+	middleware     HTTPMiddlewareFuncs
+	endpoints      map[httpRoute]services.Endpoint
+	maxBodySize    int64
+	maxValueDepth  int
+	maxValues      int
+	metadata       *metadata.VerifyConfig
+	problemDetails bool
+	strictDecoding bool
+	router         *httptreemux.TreeMux
+	server         *http.Server
+	tlsCert        string
+	tlsKey         string
 }
 
 // Type returns "API" to properly tag this type of gateway.
@@ -65,6 +79,9 @@ func (gw *Gateway) Type() services.GatewayType {
 // down gracefully, this will return nil instead of http.ErrServerClosed. All other
 // errors are propagated back.
 func (gw *Gateway) Listen() error {
+	if gw.optionErr != nil {
+		return fmt.Errorf("api gateway error: %w", gw.optionErr)
+	}
 	switch err := gw.listenAndServe(); err {
 	case nil, http.ErrServerClosed:
 		return nil
@@ -92,6 +109,13 @@ func (gw *Gateway) Shutdown(ctx context.Context) error {
 	return gw.server.Shutdown(ctx)
 }
 
+// ServeHTTP lets the gateway's routes handle the request as if it came in through the gateway's
+// own HTTP server. This lets you embed the gateway in another server/mux or feed it requests that
+// arrived by some other means (e.g. the "rpc" gateway does this for requests sent over a broker).
+func (gw *Gateway) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	gw.router.ServeHTTP(w, req)
+}
+
 // Register the operation with the gateway so that it can be exposed for invoking remotely.
 func (gw *Gateway) Register(endpoint services.Endpoint, route services.EndpointRoute) {
 	if route.GatewayType != services.GatewayTypeAPI {
@@ -115,16 +139,29 @@ func (gw *Gateway) Register(endpoint services.Endpoint, route services.EndpointR
 	customFuncs := gw.middleware
 	standardFuncs := HTTPMiddlewareFuncs{
 		recoverFromPanic(gw.codecs.DefaultEncoder()),
-		restoreMetadata(),
+	}
+	if gw.cors != nil {
+		standardFuncs = standardFuncs.Append(writeCORSHeaders(gw.cors))
+	}
+	if gw.problemDetails {
+		standardFuncs = standardFuncs.Append(writeProblemDetails())
+	}
+	if gw.compression != nil {
+		standardFuncs = standardFuncs.Append(compressResponse(gw.compression))
+	}
+	standardFuncs = standardFuncs.Append(
+		restoreMetadata(gw.metadata, gw.codecs.DefaultEncoder()),
+		restoreDeadline(),
 		restoreMetadataHeaders(),
 		restoreMetadataEndpoint(endpoint, route),
 		restoreTraceID(),
 		restoreAuthorization(),
-	}
+	)
 	httpHandler := standardFuncs.Append(customFuncs...).Then(gw.toHTTPHandler(endpoint, route))
 
 	// If you're registering "POST /FooService.Bar" we're going to create a route for
-	// the POST as well as an additional, implicit OPTIONS route. This is so that
+	// the POST as well as an additional, implicit OPTIONS route. When you use WithCORS(), that
+	// route responds to the browser's preflight requests. Otherwise, it's there so that
 	// you can use WithMiddleware(Func) to enable CORS in your API. All of your middleware
 	// is actually part of the router/mux handling (see comments in NewGateway() for details as to why), so
 	// if we don't include an explicit OPTIONS route for this path then your CORS middleware
@@ -179,7 +216,11 @@ func (gw *Gateway) registerOptions(path string) {
 		recover()
 	}()
 
-	gw.router.UsingContext().OPTIONS(path, gw.middleware.Then(gw.methodNotAllowedHandler))
+	optionsHandler := gw.methodNotAllowedHandler
+	if gw.cors != nil {
+		optionsHandler = gw.preflightHandler(path)
+	}
+	gw.router.UsingContext().OPTIONS(path, gw.middleware.Then(gw.withProblemDetails(optionsHandler)))
 }
 
 func (gw *Gateway) toHTTPHandler(endpoint services.Endpoint, route services.EndpointRoute) http.HandlerFunc {
@@ -187,12 +228,39 @@ func (gw *Gateway) toHTTPHandler(endpoint services.Endpoint, route services.Endp
 	// want to support content negotiation, we can put this call inside the handler function
 	// and use the "Accept" header to determine which codec we try to use.
 	//
-	// But for now... only JSON for you.
+	// But for now... only JSON for you. The decoder honors the gateway's strict mode and value limits.
 	encoder := gw.codecs.Encoder("application/json")
-	decoder := gw.codecs.Decoder("application/json")
-	valueDecoder := gw.codecs.ValueDecoder("application/json")
+	decoder := codec.JSONDecoder{Strict: gw.strictDecoding, MaxValues: gw.maxValues, MaxDepth: gw.maxValueDepth}
+	valueDecoder := decoder
+
+	// The endpoint's "MAX BODY xxx" doc option trumps the gateway-wide limit.
+	maxBodySize := gw.maxBodySize
+	if endpoint.MaxBodySize > 0 {
+		maxBodySize = endpoint.MaxBodySize
+	}
 
 	return func(w http.ResponseWriter, req *http.Request) {
+		decompressed, err := decompressRequest(req, gw.compression)
+		if err != nil {
+			respondFailure(w, req, encoder, err)
+			return
+		}
+
+		// Don't bother reading anything if the caller already told us that the body is too big. Otherwise,
+		// make sure that we stop reading once we hit the limit, so one huge request can't exhaust our memory. For
+		// compressed bodies, the limit applies to the decompressed bytes, and there's always a limit.
+		bodyLimit := maxBodySize
+		if decompressed && bodyLimit <= 0 {
+			bodyLimit = gw.compression.maxRequestSize()
+		}
+		if bodyLimit > 0 {
+			if req.ContentLength > bodyLimit {
+				respondFailure(w, req, encoder, &http.MaxBytesError{Limit: bodyLimit})
+				return
+			}
+			req.Body = http.MaxBytesReader(w, req.Body, bodyLimit)
+		}
+
 		// Create a blank request struct that we will populate w/ request body/path/query data.
 		serviceRequest := endpoint.NewInput()
 
@@ -226,7 +294,7 @@ func (gw *Gateway) toHTTPHandler(endpoint services.Endpoint, route services.Endp
 			respondFailure(w, req, encoder, err)
 			return
 		}
-		if err := decoder.Decode(req.Body, &serviceRequest); err != nil {
+		if err := decodeBody(req, decoder, valueDecoder, serviceRequest); err != nil {
 			respondFailure(w, req, encoder, err)
 			return
 		}
@@ -235,12 +303,16 @@ func (gw *Gateway) toHTTPHandler(endpoint services.Endpoint, route services.Endp
 			return
 		}
 
-		serviceResponse, err := endpoint.Handler(req.Context(), serviceRequest)
+		ctx, rateLimit := services.TrackRateLimit(req.Context())
+		ctx, responseHeaders := metadata.TrackResponseHeaders(ctx)
+		serviceResponse, err := endpoint.Handler(ctx, serviceRequest)
+		writeRateLimitHeaders(w, rateLimit)
 		if err != nil {
 			respondFailure(w, req, encoder, err)
 			return
 		}
-		respondSuccess(w, req, encoder, serviceResponse, route.Status)
+		writeResponseHeaders(w, responseHeaders())
+		respondSuccess(w, req, encoder, serviceResponse, route.Status, endpoint.CacheControl)
 	}
 }
 
@@ -274,14 +346,28 @@ type httpRoute struct {
 	Path string
 }
 
-func respondFailure(w http.ResponseWriter, _ *http.Request, encoder codec.Encoder, err error) {
-	status := fail.Status(err)
+func respondFailure(w http.ResponseWriter, req *http.Request, encoder codec.Encoder, err error) {
+	// Reading the request body failed because it was bigger than the gateway/endpoint allows. This could
+	// come from decoding the body or from your handler reading an upload, so we catch it here.
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		err = fail.TooLarge("request body too large: limit is %d bytes", maxBytesErr.Limit)
+	}
+
+	statusErr := fail.From(err)
+	if statusErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(statusErr.RetryAfter))
+	}
+	if usesProblemDetails(req) {
+		respondProblem(w, req, encoder, statusErr)
+		return
+	}
 	w.Header().Set("Content-Type", encoder.ContentType())
-	w.WriteHeader(status)
-	_ = encoder.Encode(w, fail.New(status, err.Error()))
+	w.WriteHeader(statusErr.Status)
+	_ = encoder.Encode(w, statusErr)
 }
 
-func respondSuccess(w http.ResponseWriter, req *http.Request, encoder codec.Encoder, serviceResponse any, status int) {
+func respondSuccess(w http.ResponseWriter, req *http.Request, encoder codec.Encoder, serviceResponse any, status int, cacheControl string) {
 	// If your response implements either of the redirect getter methods, try to forward on to
 	// the desired address using either a 307/308 as needed.
 	//
@@ -299,13 +385,32 @@ func respondSuccess(w http.ResponseWriter, req *http.Request, encoder codec.Enco
 		return
 	}
 
+	// The method's response is a live stream of values, so send each one as a server-sent event.
+	eventStream, ok := serviceResponse.(services.EventStreamer)
+	if ok && respondSuccessEvents(w, req, encoder, eventStream, status) {
+		return
+	}
+
+	// Let the caller know how long they can hang onto the response and which version of the data it is.
+	writeCacheHeaders(w.Header(), req, serviceResponse, status, cacheControl)
+
 	// The method's response appears to want to send raw bytes itself rather than relying
 	// on the auto-JSON (or whatever encoding) that we normally use to marshal responses.
 	// Based on the methods implemented by the response struct, we can send a response w/ different
 	// headers in addition to the raw bytes. See the docs for RespondRawRanged, RespondRawSized,
 	// and RespondRaw for more info on what headers we'll include.
 	streamResponse, ok := serviceResponse.(services.ContentGetter)
-	if ok && respondSuccessStream(w, streamResponse, status) {
+	if ok && respondSuccessStream(w, req, encoder, streamResponse, status) {
+		return
+	}
+
+	// Endpoints w/ the "CACHE xxx" doc option need an ETag, so if the response didn't give us one,
+	// we need to encode it up front so that we can hash it.
+	if cacheControl != "" && w.Header().Get("ETag") == "" && cacheable(req, status) {
+		respondSuccessHashed(w, req, encoder, serviceResponse, status)
+		return
+	}
+	if respondNotModified(w, req, status) {
 		return
 	}
 
@@ -315,6 +420,16 @@ func respondSuccess(w http.ResponseWriter, req *http.Request, encoder codec.Enco
 	_ = encoder.Encode(w, serviceResponse)
 }
 
+// writeResponseHeaders includes any headers that the handler set using metadata.SetResponseHeader() or
+// metadata.SetCookie(). We do this before writing the rest of the response, so headers that we need to
+// control (e.g. "Content-Type" for JSON responses) still win.
+func writeResponseHeaders(w http.ResponseWriter, responseHeaders http.Header) {
+	headers := w.Header()
+	for name, values := range responseHeaders {
+		headers[name] = values
+	}
+}
+
 func respondSuccessRedirect(w http.ResponseWriter, req *http.Request, redirectGetter services.Redirector) bool {
 	redirectURL := redirectGetter.Redirect()
 	if redirectURL == "" {
@@ -335,7 +450,7 @@ func respondSuccessRedirectPermanent(w http.ResponseWriter, req *http.Request, r
 	return true
 }
 
-func respondSuccessStream(w http.ResponseWriter, streamResponse services.ContentGetter, status int) bool {
+func respondSuccessStream(w http.ResponseWriter, req *http.Request, encoder codec.Encoder, streamResponse services.ContentGetter, status int) bool {
 	content := streamResponse.Content()
 	defer quiet.Close(content)
 
@@ -346,12 +461,62 @@ func respondSuccessStream(w http.ResponseWriter, streamResponse services.Content
 	writeContentLength(headers, streamResponse)
 	writeContentRange(headers, streamResponse) // this can change Content-Length, so do this after writeContentLength()!
 	writeContentFileName(headers, streamResponse)
+	writeContentModTime(headers, streamResponse)
+
+	// The caller already has this version of the content, so don't bother sending it again.
+	if respondNotModified(w, req, status) {
+		return true
+	}
+
+	// The caller only wants part of the content (e.g. resuming a download or seeking in a video).
+	if content != nil && respondSuccessStreamRange(w, req, encoder, streamResponse, content, status) {
+		return true
+	}
 
 	w.WriteHeader(status)
 	_, _ = io.Copy(w, content)
 	return true
 }
 
+func respondSuccessEvents(w http.ResponseWriter, req *http.Request, encoder codec.Encoder, eventStream services.EventStreamer, status int) bool {
+	headers := w.Header()
+	headers.Set("Content-Type", "text/event-stream")
+	headers.Set("Cache-Control", "no-cache")
+	headers.Set("Connection", "keep-alive")
+	headers.Set("X-Accel-Buffering", "no") // Keeps proxies like nginx from sitting on the events.
+	w.WriteHeader(status)
+
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	flush()
+
+	// Each value becomes a "data: ..." event. Multi-line values need to have each line
+	// prefixed with "data: " and a blank line marks the end of the event.
+	buf := &bytes.Buffer{}
+	for {
+		value, ok := eventStream.NextEvent(req.Context())
+		if !ok {
+			return true
+		}
+
+		buf.Reset()
+		if err := encoder.Encode(buf, value); err != nil {
+			_, _ = fmt.Fprintf(w, "event: error\ndata: %s\n\n", strings.ReplaceAll(err.Error(), "\n", " "))
+			flush()
+			return true
+		}
+		for _, line := range strings.Split(strings.TrimRight(buf.String(), "\n"), "\n") {
+			_, _ = io.WriteString(w, "data: "+line+"\n")
+		}
+		_, _ = io.WriteString(w, "\n")
+		flush()
+	}
+}
+
 func writeContentType(headers http.Header, streamResponse services.ContentGetter) {
 	// Your stream response will just use the default content type ("application/octet-stream")
 	// because you aren't capable of telling us otherwise.
@@ -425,6 +590,102 @@ func writeContentFileName(headers http.Header, streamResponse services.ContentGe
 	headers.Set("Content-Disposition", `attachment; filename="`+contentFileName+`"`)
 }
 
+func writeContentModTime(headers http.Header, streamResponse services.ContentGetter) {
+	getter, ok := streamResponse.(services.ContentModTimeGetter)
+	if !ok {
+		return
+	}
+
+	modTime := getter.ContentModTime()
+	if modTime.IsZero() {
+		return
+	}
+
+	headers.Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
+}
+
+// decodeBody binds the request body to the service request. Most of the time, this just means decoding
+// the JSON body, but if your request implements services.ContentSetter (e.g. it embeds services.StreamRequest),
+// we'll hand you the raw body stream instead.
+func decodeBody(req *http.Request, decoder codec.Decoder, valueDecoder codec.ValueDecoder, serviceRequest services.StructPointer) error {
+	streamRequest, ok := serviceRequest.(services.ContentSetter)
+	if !ok {
+		return decoder.Decode(req.Body, &serviceRequest)
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mediaType == "multipart/form-data" {
+		return decodeBodyMultipart(req, params["boundary"], valueDecoder, streamRequest)
+	}
+
+	streamRequest.SetContent(req.Body)
+	if setter, ok := streamRequest.(services.ContentTypeSetter); ok {
+		setter.SetContentType(req.Header.Get("Content-Type"))
+	}
+	if setter, ok := streamRequest.(services.ContentLengthSetter); ok && req.ContentLength > 0 {
+		setter.SetContentLength(int(req.ContentLength))
+	}
+	if setter, ok := streamRequest.(services.ContentFileNameSetter); ok {
+		setter.SetContentFileName(naming.DispositionFileName(req.Header.Get("Content-Disposition")))
+	}
+	return nil
+}
+
+// decodeBodyMultipart handles "multipart/form-data" uploads (e.g. a browser form). All of the plain form fields
+// are bound to the service request just like query string values, and the first file part becomes the request's
+// content stream. We don't buffer the file, so any form fields that come AFTER the file are ignored; put your
+// fields before the file in the form.
+func decodeBodyMultipart(req *http.Request, boundary string, valueDecoder codec.ValueDecoder, streamRequest services.ContentSetter) error {
+	if boundary == "" {
+		return fail.BadRequest("invalid multipart request: missing boundary")
+	}
+
+	reader := multipart.NewReader(req.Body, boundary)
+	values := url.Values{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			// The form didn't include a file, so there's no content to read.
+			streamRequest.SetContent(http.NoBody)
+			return valueDecoder.DecodeValues(values, streamRequest)
+		}
+		if err != nil {
+			return multipartError(err, "invalid multipart request: %v", err)
+		}
+
+		if part.FileName() == "" {
+			value, err := io.ReadAll(part)
+			if err != nil {
+				return multipartError(err, "invalid multipart request: %s: %v", part.FormName(), err)
+			}
+			values.Add(part.FormName(), string(value))
+			continue
+		}
+
+		if err = valueDecoder.DecodeValues(values, streamRequest); err != nil {
+			return err
+		}
+		streamRequest.SetContent(part)
+		if setter, ok := streamRequest.(services.ContentTypeSetter); ok {
+			setter.SetContentType(part.Header.Get("Content-Type"))
+		}
+		if setter, ok := streamRequest.(services.ContentFileNameSetter); ok {
+			setter.SetContentFileName(part.FileName())
+		}
+		return nil
+	}
+}
+
+// multipartError blames the caller w/ a 400 error when we fail to read a multipart request. The exception is
+// when the body was bigger than we allow; we leave that alone so the caller gets a 413 instead.
+func multipartError(err error, messageFormat string, args ...any) error {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return err
+	}
+	return fail.BadRequest(messageFormat, args...)
+}
+
 func pathParams(req *http.Request) map[string][]string {
 	params := httptreemux.ContextParams(req.Context())
 	values := url.Values{}
@@ -452,6 +713,106 @@ func WithMiddleware(funcs ...HTTPMiddlewareFunc) GatewayOption {
 	}
 }
 
+// WithCORS lets browser-based code from other origins call your API. The gateway responds to preflight
+// requests for you, allowing the methods that you've actually registered for each path, and includes
+// the appropriate headers on every response so the browser lets your code see it.
+//
+//	gw := apis.NewGateway(":9000", apis.WithCORS(apis.CORSConfig{
+//		AllowedOrigins:   []string{"https://*.example.com"},
+//		AllowCredentials: true,
+//		MaxAge:           time.Hour,
+//	}))
+//
+// When AllowCredentials is true, you must list the exact origins (or patterns) that you trust. Otherwise,
+// any site could make credentialed calls on behalf of your users, so the gateway leaves CORS disabled and
+// Listen() fails instead.
+//
+// Any middleware from WithMiddleware() still fires before the preflight handler, so you can continue
+// to bring your own CORS middleware instead if you need something this doesn't support.
+func WithCORS(config CORSConfig) GatewayOption {
+	return func(gw *Gateway) {
+		if err := config.validate(); err != nil {
+			gw.optionErr = err
+			return
+		}
+		gw.cors = &config
+	}
+}
+
+// WithProblemDetails makes the gateway respond to failures w/ RFC 7807 "application/problem+json"
+// documents instead of the standard {"Status":404, "Message":"..."} error payload. The "instance" is
+// the request's trace ID, and the error's code, details, and retry hints are included as extension
+// members. The generated clients understand both formats.
+func WithProblemDetails() GatewayOption {
+	return func(gw *Gateway) {
+		gw.problemDetails = true
+	}
+}
+
+// WithCompression makes the gateway compress responses using "gzip" or "deflate" for callers that include
+// the appropriate "Accept-Encoding" header. The config determines which responses are big enough to bother and
+// which content types are worth it. Responses that already have a "Content-Encoding" are sent as-is.
+//
+//	gw := apis.NewGateway(":9000", apis.WithCompression(apis.CompressionConfig{
+//		MinSize: 2048,
+//	}))
+//
+// This also lets callers send "gzip" and "deflate" request bodies (see clients.WithRequestCompression()). Without
+// this option, compressed request bodies fail w/ a 415 error. Decompressed bodies are always size-limited; see
+// the config's MaxRequestSize.
+func WithCompression(config CompressionConfig) GatewayOption {
+	return func(gw *Gateway) {
+		gw.compression = &config
+	}
+}
+
+// WithMaxBodySize limits the size (in bytes) of the request bodies that the gateway will accept. Requests
+// w/ larger bodies fail w/ a 413 error. Individual endpoints can use the "MAX BODY 5MB" doc option to
+// override this limit. By default, there is no limit.
+func WithMaxBodySize(maxBytes int64) GatewayOption {
+	return func(gw *Gateway) {
+		gw.maxBodySize = maxBytes
+	}
+}
+
+// WithStrictDecoding makes the gateway reject request bodies w/ a 400 error when the JSON contains fields
+// that don't exist on the service request or when there's more data after the JSON value. By default, the
+// gateway quietly ignores both.
+func WithStrictDecoding() GatewayOption {
+	return func(gw *Gateway) {
+		gw.strictDecoding = true
+	}
+}
+
+// WithValueLimits restricts the path/query/form values that the gateway binds to your service requests.
+// Requests w/ more than 'maxValues' query parameters or whose parameter names are nested deeper than
+// 'maxDepth' (e.g. "User.Address.City" has a depth of 3) fail w/ a 400 error. A value of 0 means that
+// there is no limit, which is the default for both.
+func WithValueLimits(maxValues int, maxDepth int) GatewayOption {
+	return func(gw *Gateway) {
+		gw.maxValues = maxValues
+		gw.maxValueDepth = maxDepth
+	}
+}
+
+// WithMetadataVerification makes the gateway verify the HMAC signature of the X-RPC-Metadata header before
+// restoring it (see metadata.VerifyConfig for the details):
+//
+//	gw := apis.NewGateway(":9000", apis.WithMetadataVerification(metadata.VerifyConfig{
+//		Keys: [][]byte{[]byte(os.Getenv("METADATA_KEY"))},
+//	}))
+func WithMetadataVerification(config metadata.VerifyConfig) GatewayOption {
+	return func(gw *Gateway) {
+		gw.metadata = &config
+	}
+}
+
+// WithUntrustedMetadata makes the gateway ignore the X-RPC-Metadata header entirely. Use this for gateways
+// that face the outside world, where none of the callers are your own services.
+func WithUntrustedMetadata() GatewayOption {
+	return WithMetadataVerification(metadata.VerifyConfig{})
+}
+
 // WithTLSConfig allows the gateway's underlying HTTP server to handle HTTPS requests using
 // the configuration you provide. If you are using the Let's Encrypt auto-cert manager certificate
 // configurations, this is how you can make your gateway adhere to that cert.
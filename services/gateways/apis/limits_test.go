@@ -0,0 +1,128 @@
+//go:build unit
+
+package apis_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/apis"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestLimitsSuite(t *testing.T) {
+	suite.Run(t, new(LimitsSuite))
+}
+
+type LimitsSuite struct {
+	suite.Suite
+}
+
+type limitsRequest struct {
+	Name  string
+	Group struct {
+		ID string
+	}
+}
+
+type limitsUploadRequest struct {
+	services.StreamRequest
+}
+
+// gateway registers "POST /user" (no endpoint limit), "POST /user/big" (20 byte endpoint limit), and
+// "POST /upload" which reads the entire raw body in the handler.
+func (suite *LimitsSuite) gateway(options ...apis.GatewayOption) *apis.Gateway {
+	gw := apis.NewGateway(":0", options...)
+	handler := func(ctx context.Context, req any) (any, error) {
+		return req, nil
+	}
+	gw.Register(services.Endpoint{
+		ServiceName: "UserService",
+		Name:        "Create",
+		NewInput:    func() services.StructPointer { return &limitsRequest{} },
+		Handler:     handler,
+	}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "POST", Path: "/user", Status: 200})
+	gw.Register(services.Endpoint{
+		ServiceName: "UserService",
+		Name:        "CreateBig",
+		NewInput:    func() services.StructPointer { return &limitsRequest{} },
+		Handler:     handler,
+		MaxBodySize: 40,
+	}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "POST", Path: "/user/big", Status: 200})
+	gw.Register(services.Endpoint{
+		ServiceName: "UserService",
+		Name:        "Upload",
+		NewInput:    func() services.StructPointer { return &limitsUploadRequest{} },
+		Handler: func(ctx context.Context, req any) (any, error) {
+			if _, err := io.ReadAll(req.(*limitsUploadRequest).Content()); err != nil {
+				return nil, fmt.Errorf("unable to read upload: %w", err)
+			}
+			return &limitsRequest{Name: "ok"}, nil
+		},
+	}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "POST", Path: "/upload", Status: 200})
+	return gw
+}
+
+func (suite *LimitsSuite) invoke(gw *apis.Gateway, path string, body string, chunked bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, strings.NewReader(body))
+	if chunked {
+		// Pretend that the caller didn't tell us how big the body is.
+		req.ContentLength = -1
+	}
+	w := httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	return w
+}
+
+// Bodies bigger than the gateway/endpoint limit should fail w/ a 413 whether or not the caller
+// told us how big the body is up front.
+func (suite *LimitsSuite) TestMaxBodySize() {
+	gw := suite.gateway(apis.WithMaxBodySize(20))
+	small := `{"Name":"Dude"}`
+	large := `{"Name":"The Dude Abides"}`
+
+	suite.Equal(200, suite.invoke(gw, "/user", small, false).Code)
+	suite.Equal(200, suite.invoke(gw, "/user", small, true).Code)
+	suite.Equal(413, suite.invoke(gw, "/user", large, false).Code)
+	suite.Equal(413, suite.invoke(gw, "/user", large, true).Code)
+
+	// The endpoint's limit should trump the gateway's.
+	suite.Equal(200, suite.invoke(gw, "/user/big", large, true).Code)
+	suite.Equal(413, suite.invoke(gw, "/user/big", `{"Name":"The Dude Abides... and Walter too"}`, true).Code)
+
+	// Handlers that read raw uploads should hit the limit, too.
+	suite.Equal(200, suite.invoke(gw, "/upload", "Hello", true).Code)
+	suite.Equal(413, suite.invoke(gw, "/upload", strings.Repeat("Hello", 10), true).Code)
+
+	// Without a limit, anything goes.
+	gw = suite.gateway()
+	suite.Equal(200, suite.invoke(gw, "/user", `{"Name":"`+strings.Repeat("Dude", 1000)+`"}`, true).Code)
+}
+
+// Strict decoding should reject unknown fields and trailing data w/ a 400.
+func (suite *LimitsSuite) TestStrictDecoding() {
+	gw := suite.gateway(apis.WithStrictDecoding())
+	suite.Equal(200, suite.invoke(gw, "/user", `{"Name":"Dude","Group":{"ID":"1"}}`, false).Code)
+	suite.Equal(400, suite.invoke(gw, "/user", `{"Name":"Dude","Rug":"tied the room together"}`, false).Code)
+	suite.Equal(400, suite.invoke(gw, "/user", `{"Name":"Dude"} {"Name":"Walter"}`, false).Code)
+
+	gw = suite.gateway()
+	suite.Equal(200, suite.invoke(gw, "/user", `{"Name":"Dude","Rug":"tied the room together"}`, false).Code)
+	suite.Equal(200, suite.invoke(gw, "/user", `{"Name":"Dude"} {"Name":"Walter"}`, false).Code)
+}
+
+// Query strings w/ too many values or values nested too deeply should fail w/ a 400.
+func (suite *LimitsSuite) TestValueLimits() {
+	gw := suite.gateway(apis.WithValueLimits(2, 1))
+	suite.Equal(200, suite.invoke(gw, "/user?Name=Dude&Foo=Bar", `{}`, false).Code)
+	suite.Equal(400, suite.invoke(gw, "/user?Name=Dude&Foo=Bar&Goo=Baz", `{}`, false).Code)
+	suite.Equal(400, suite.invoke(gw, "/user?Group.ID=1", `{}`, false).Code)
+
+	gw = suite.gateway()
+	suite.Equal(200, suite.invoke(gw, "/user?Name=Dude&Foo=Bar&Goo=Baz&Group.ID=1", `{}`, false).Code)
+}
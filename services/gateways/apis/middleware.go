@@ -59,10 +59,33 @@ func recoverFromPanic(encoder codec.Encoder) HTTPMiddlewareFunc {
 // metadata values back onto the request context so the rest of the operation already has access
 // to them. This is how Service B automatically has access to the same auth/values/etc. when
 // called from Service A.
-func restoreMetadata() HTTPMiddlewareFunc {
+//
+// When the gateway uses WithMetadataVerification(), we only restore metadata whose signature checks out,
+// so external callers can't forge auth/values that your services would otherwise trust.
+func restoreMetadata(config *metadata.VerifyConfig, encoder codec.Encoder) HTTPMiddlewareFunc {
+	return func(w http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+		ctx, err := config.RestoreHeader(req.Context(), req.Header)
+		if err != nil {
+			respondFailure(w, req, encoder, err)
+			return
+		}
+		next(w, req.WithContext(ctx))
+	}
+}
+
+// restoreDeadline looks for the X-RPC-Deadline header and applies that much time remaining to the request
+// context. This is how a 5 second budget at the edge shrinks as Service A calls Service B calls Service C rather
+// than each hop getting a fresh timeout of its own.
+func restoreDeadline() HTTPMiddlewareFunc {
 	return func(w http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
-		encodedMetadata := metadata.EncodedBytes(req.Header.Get("X-RPC-Metadata"))
-		ctx := metadata.Decode(req.Context(), encodedMetadata)
+		encodedDeadline := req.Header.Get(metadata.DeadlineHeader)
+		if encodedDeadline == "" {
+			next(w, req)
+			return
+		}
+
+		ctx, cancel := metadata.WithEncodedDeadline(req.Context(), encodedDeadline)
+		defer cancel()
 		next(w, req.WithContext(ctx))
 	}
 }
@@ -129,11 +152,12 @@ func restoreTraceID() HTTPMiddlewareFunc {
 	}
 }
 
-// restoreMetadataHeaders places the HTTP header map into the request metadata. This way
-// you can tweak service behavior based on things like Cache-Control or things like that.
+// restoreMetadataHeaders places the HTTP header map and the client's address into the request metadata. This
+// way you can tweak service behavior based on things like Cache-Control or things like that.
 func restoreMetadataHeaders() HTTPMiddlewareFunc {
 	return func(w http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
 		ctx := metadata.WithRequestHeaders(req.Context(), req.Header)
+		ctx = metadata.WithRemoteAddr(ctx, req.RemoteAddr)
 		next(w, req.WithContext(ctx))
 	}
 }
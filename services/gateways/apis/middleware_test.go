@@ -0,0 +1,131 @@
+//go:build unit
+
+package apis_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/metadata"
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/apis"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestMiddlewareSuite(t *testing.T) {
+	suite.Run(t, new(MiddlewareSuite))
+}
+
+type MiddlewareSuite struct {
+	suite.Suite
+}
+
+type deadlineRequest struct{}
+
+type metadataRequest struct{}
+
+// The X-RPC-Metadata header should only be restored when the gateway trusts it.
+func (suite *MiddlewareSuite) TestRestoreMetadata() {
+	key := []byte("the dude abides")
+	encoded := `{"TraceID":"123","Values":{"Role":{"value":"admin"}}}`
+	signature := metadata.Sign(metadata.EncodedBytes(encoded), key, time.Now())
+	staleSignature := metadata.Sign(metadata.EncodedBytes(encoded), key, time.Now().Add(-time.Hour))
+
+	var traceID, role string
+	newGateway := func(options ...apis.GatewayOption) *apis.Gateway {
+		gw := apis.NewGateway(":0", options...)
+		gw.Register(services.Endpoint{
+			ServiceName: "UserService",
+			Name:        "GetByID",
+			NewInput:    func() services.StructPointer { return &metadataRequest{} },
+			Handler: func(ctx context.Context, req any) (any, error) {
+				traceID, role = metadata.TraceID(ctx), ""
+				metadata.Value(ctx, "Role", &role)
+				return req, nil
+			},
+		}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "GET", Path: "/user/{ID}", Status: 200})
+		return gw
+	}
+	invoke := func(gw *apis.Gateway, signature string) int {
+		traceID, role = "", ""
+		req := httptest.NewRequest("GET", "/user/123", nil)
+		req.Header.Set(metadata.Header, encoded)
+		if signature != "" {
+			req.Header.Set(metadata.SignatureHeader, signature)
+		}
+		w := httptest.NewRecorder()
+		gw.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// By default, we trust whatever you send us.
+	gw := newGateway()
+	suite.Equal(200, invoke(gw, ""))
+	suite.Equal("123", traceID)
+	suite.Equal("admin", role)
+
+	// Only metadata signed w/ one of the keys is trusted; everything else is ignored.
+	gw = newGateway(apis.WithMetadataVerification(metadata.VerifyConfig{Keys: [][]byte{[]byte("new key"), key}}))
+	suite.Equal(200, invoke(gw, signature))
+	suite.Equal("123", traceID)
+	suite.Equal("admin", role)
+	suite.Equal(200, invoke(gw, ""))
+	suite.Equal("", role)
+	suite.Equal(200, invoke(gw, "garbage"))
+	suite.Equal("", role)
+	suite.Equal(200, invoke(gw, staleSignature))
+	suite.Equal("", role, "Should not trust replayed metadata")
+
+	// Or we can reject the request altogether.
+	gw = newGateway(apis.WithMetadataVerification(metadata.VerifyConfig{Keys: [][]byte{key}, Reject: true}))
+	suite.Equal(200, invoke(gw, signature))
+	suite.Equal("admin", role)
+	suite.Equal(401, invoke(gw, ""))
+	suite.Equal(401, invoke(gw, "garbage"))
+	suite.Equal(401, invoke(gw, staleSignature))
+
+	// Edge gateways never trust the header, signed or not.
+	gw = newGateway(apis.WithUntrustedMetadata())
+	suite.Equal(200, invoke(gw, signature))
+	suite.NotEqual("123", traceID, "Should generate a new trace ID rather than use the untrusted one")
+	suite.Equal("", role)
+}
+
+// The deadline propagated by the X-RPC-Deadline header should be applied to the handler's context.
+func (suite *MiddlewareSuite) TestRestoreDeadline() {
+	var deadline time.Time
+	var hasDeadline bool
+
+	gw := apis.NewGateway(":0")
+	gw.Register(services.Endpoint{
+		ServiceName: "UserService",
+		Name:        "GetByID",
+		NewInput:    func() services.StructPointer { return &deadlineRequest{} },
+		Handler: func(ctx context.Context, req any) (any, error) {
+			deadline, hasDeadline = ctx.Deadline()
+			return req, nil
+		},
+	}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "GET", Path: "/user/{ID}", Status: 200})
+
+	invoke := func(encodedDeadline string) {
+		req := httptest.NewRequest("GET", "/user/123", nil)
+		if encodedDeadline != "" {
+			req.Header.Set("X-RPC-Deadline", encodedDeadline)
+		}
+		w := httptest.NewRecorder()
+		gw.ServeHTTP(w, req)
+		suite.Require().Equal(200, w.Code)
+	}
+
+	invoke("")
+	suite.False(hasDeadline, "Should not have a deadline when the header is missing")
+
+	invoke("garbage")
+	suite.False(hasDeadline, "Should ignore malformed deadlines")
+
+	invoke("2000")
+	suite.Require().True(hasDeadline, "Should restore the propagated deadline")
+	suite.InDelta(2*time.Second, time.Until(deadline), float64(100*time.Millisecond))
+}
@@ -0,0 +1,53 @@
+package apis
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/monadicstack/abide/codec"
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/metadata"
+)
+
+type problemDetailsKey struct{}
+
+// writeProblemDetails flags the request so that any failure we respond with is an RFC 7807
+// "application/problem+json" document rather than our standard {"Status":404, "Message":"..."} error.
+func writeProblemDetails() HTTPMiddlewareFunc {
+	return func(w http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+		next(w, req.WithContext(context.WithValue(req.Context(), problemDetailsKey{}, true)))
+	}
+}
+
+// usesProblemDetails returns true when the request went through the writeProblemDetails() middleware.
+func usesProblemDetails(req *http.Request) bool {
+	if req == nil {
+		return false
+	}
+	enabled, _ := req.Context().Value(problemDetailsKey{}).(bool)
+	return enabled
+}
+
+// problemInstance identifies the failed request in the problem document. We use the trace ID when
+// the request made it far enough to have one; otherwise we fall back to the caller's X-Request-ID.
+func problemInstance(req *http.Request) string {
+	if traceID := metadata.TraceID(req.Context()); traceID != "" {
+		return traceID
+	}
+	return req.Header.Get("X-Request-ID")
+}
+
+// withProblemDetails wraps handlers that don't go through the standard endpoint middleware (e.g. the
+// 404 and OPTIONS handlers) so that they respect the WithProblemDetails() option, too.
+func (gw *Gateway) withProblemDetails(handler http.HandlerFunc) http.HandlerFunc {
+	if !gw.problemDetails {
+		return handler
+	}
+	return HTTPMiddlewareFuncs{writeProblemDetails()}.Then(handler)
+}
+
+func respondProblem(w http.ResponseWriter, req *http.Request, encoder codec.Encoder, statusErr fail.StatusError) {
+	w.Header().Set("Content-Type", fail.ProblemContentType)
+	w.WriteHeader(statusErr.Status)
+	_ = encoder.Encode(w, fail.NewProblem(statusErr, problemInstance(req)))
+}
@@ -0,0 +1,111 @@
+//go:build unit
+
+package apis_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/apis"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestProblemDetailsSuite(t *testing.T) {
+	suite.Run(t, new(ProblemDetailsSuite))
+}
+
+type ProblemDetailsSuite struct {
+	suite.Suite
+}
+
+type problemRequest struct{}
+
+// gateway creates an API gateway w/ "GET /user/{ID}" that always fails w/ a 404 that has a code and details.
+func (suite *ProblemDetailsSuite) gateway(options ...apis.GatewayOption) *apis.Gateway {
+	gw := apis.NewGateway(":0", options...)
+	gw.Register(services.Endpoint{
+		ServiceName: "UserService",
+		Name:        "GetByID",
+		NewInput:    func() services.StructPointer { return &problemRequest{} },
+		Handler: func(ctx context.Context, req any) (any, error) {
+			return nil, fail.NotFound("user not found").
+				WithCode("USER_NOT_FOUND").
+				WithDetails(fail.ErrorDetail{Field: "ID", Message: "no such user"}).
+				WithRetry(time.Minute)
+		},
+	}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "GET", Path: "/user/{ID}", Status: 200})
+	return gw
+}
+
+func (suite *ProblemDetailsSuite) invoke(gw *apis.Gateway, method string, path string) (*http.Response, map[string]any) {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("X-Request-ID", "trace-123")
+	w := httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+
+	body := map[string]any{}
+	suite.Require().NoError(json.NewDecoder(w.Body).Decode(&body))
+	return w.Result(), body
+}
+
+// Without WithProblemDetails(), failures should use our standard error payload.
+func (suite *ProblemDetailsSuite) TestDisabled() {
+	res, body := suite.invoke(suite.gateway(), "GET", "/user/123")
+	suite.Equal(404, res.StatusCode)
+	suite.Equal("application/json", res.Header.Get("Content-Type"))
+	suite.Equal("60", res.Header.Get("Retry-After"))
+	suite.Equal(map[string]any{
+		"Status":     404.0,
+		"Message":    "user not found",
+		"Code":       "USER_NOT_FOUND",
+		"Details":    []any{map[string]any{"Field": "ID", "Message": "no such user"}},
+		"Retryable":  true,
+		"RetryAfter": 60.0,
+	}, body)
+}
+
+// Service failures should be problem documents whose instance is the trace ID.
+func (suite *ProblemDetailsSuite) TestEndpointFailure() {
+	res, body := suite.invoke(suite.gateway(apis.WithProblemDetails()), "GET", "/user/123")
+	suite.Equal(404, res.StatusCode)
+	suite.Equal("application/problem+json", res.Header.Get("Content-Type"))
+	suite.Equal("60", res.Header.Get("Retry-After"))
+	suite.Equal(map[string]any{
+		"type":       "about:blank",
+		"title":      "Not Found",
+		"status":     404.0,
+		"detail":     "user not found",
+		"instance":   "trace-123",
+		"code":       "USER_NOT_FOUND",
+		"details":    []any{map[string]any{"Field": "ID", "Message": "no such user"}},
+		"retryable":  true,
+		"retryAfter": 60.0,
+	}, body)
+}
+
+// Failures that never make it to an endpoint should be problem documents, too.
+func (suite *ProblemDetailsSuite) TestRoutingFailure() {
+	gw := suite.gateway(apis.WithProblemDetails())
+
+	res, body := suite.invoke(gw, "GET", "/nope")
+	suite.Equal(404, res.StatusCode)
+	suite.Equal("application/problem+json", res.Header.Get("Content-Type"))
+	suite.Equal(map[string]any{
+		"type":     "about:blank",
+		"title":    "Not Found",
+		"status":   404.0,
+		"detail":   "not found",
+		"instance": "trace-123",
+	}, body)
+
+	res, body = suite.invoke(gw, "OPTIONS", "/user/123")
+	suite.Equal(405, res.StatusCode)
+	suite.Equal("application/problem+json", res.Header.Get("Content-Type"))
+	suite.Equal("Method Not Allowed", body["title"])
+}
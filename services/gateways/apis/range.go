@@ -0,0 +1,265 @@
+package apis
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strconv"
+	"strings"
+
+	"github.com/monadicstack/abide/codec"
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/services"
+)
+
+// maxRanges is the most individual ranges we'll honor in a single Range header. Anything more than
+// that is most likely someone trying to make us do a ton of work, so we just send the whole thing.
+const maxRanges = 32
+
+// errRangeNotSatisfiable indicates that the Range header was well-formed, but none of the ranges
+// overlap the content at all (e.g. "bytes=5000-" on a 1000 byte file).
+var errRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// byteRange is a single span of bytes requested by the caller. Unlike our ContentRange() values,
+// the end is inclusive to match the semantics of the HTTP Range/Content-Range headers.
+type byteRange struct {
+	start int64
+	end   int64
+}
+
+func (r byteRange) length() int64 {
+	return r.end - r.start + 1
+}
+
+func (r byteRange) contentRange(size int64) string {
+	return fmt.Sprintf("bytes %d-%d/%d", r.start, r.end, size)
+}
+
+// respondSuccessStreamRange handles the standard "Range" header for stream responses when the handler
+// didn't already do it themselves (i.e. it didn't supply a ContentRange()). We need to know how big the
+// content is in order to do this, so your content must either be an io.ReadSeeker (e.g. an *os.File) or your
+// response must supply a ContentLength(). Seekers let us jump right to the bytes we need while other streams
+// require us to read/discard bytes up to the start of each range.
+//
+// This returns true if we wrote the response. When this returns false, you should just
+// respond with the entire stream as usual.
+func respondSuccessStreamRange(w http.ResponseWriter, req *http.Request, encoder codec.Encoder, streamResponse services.ContentGetter, content io.Reader, status int) bool {
+	headers := w.Header()
+
+	// If you chose a custom status (e.g. 201) or ranged the content yourself, we'll respect that.
+	if status != http.StatusOK || headers.Get("Content-Range") != "" {
+		return false
+	}
+
+	size := contentSize(streamResponse, content)
+	if size <= 0 {
+		return false
+	}
+	headers.Set("Accept-Ranges", "bytes")
+
+	rangeHeader := req.Header.Get("Range")
+	if rangeHeader == "" || req.Method != http.MethodGet {
+		return false
+	}
+	if !ifRangeMatches(req, headers) {
+		return false
+	}
+
+	ranges, err := parseRange(rangeHeader, size)
+	if err != nil {
+		headers.Del("Content-Length")
+		headers.Del("Content-Disposition")
+		headers.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		respondFailure(w, req, encoder, fail.New(http.StatusRequestedRangeNotSatisfiable, "range not satisfiable: %s", rangeHeader))
+		return true
+	}
+
+	// Streams we can't seek on need to read the ranges in order, so if you ask for them out of
+	// order (or overlapping), it's easier (and totally legit) to just give you the whole thing.
+	seeker, seekable := content.(io.Seeker)
+	if len(ranges) == 0 || (!seekable && !rangesAscending(ranges)) {
+		return false
+	}
+
+	reader := rangeReader{content: content, seeker: seeker}
+	if len(ranges) == 1 {
+		headers.Set("Content-Range", ranges[0].contentRange(size))
+		headers.Set("Content-Length", strconv.FormatInt(ranges[0].length(), 10))
+		w.WriteHeader(http.StatusPartialContent)
+		_ = reader.copyRange(w, ranges[0])
+		return true
+	}
+
+	// Multiple ranges are sent as a "multipart/byteranges" body where each part
+	// contains the original content type and the range that it represents.
+	contentType := headers.Get("Content-Type")
+	parts := multipart.NewWriter(w)
+	headers.Del("Content-Length")
+	headers.Set("Content-Type", "multipart/byteranges; boundary="+parts.Boundary())
+	w.WriteHeader(http.StatusPartialContent)
+
+	for _, r := range ranges {
+		part, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":  {contentType},
+			"Content-Range": {r.contentRange(size)},
+		})
+		if err != nil {
+			return true
+		}
+		if err = reader.copyRange(part, r); err != nil {
+			return true
+		}
+	}
+	_ = parts.Close()
+	return true
+}
+
+// contentSize determines the total number of bytes in the stream. We prefer seeking over the length
+// that your response reports since that's the actual size of the data. A size of 0 means that we can't
+// figure it out, so we shouldn't attempt to support ranges.
+func contentSize(streamResponse services.ContentGetter, content io.Reader) int64 {
+	if seeker, ok := content.(io.Seeker); ok {
+		size, err := seeker.Seek(0, io.SeekEnd)
+		if _, seekErr := seeker.Seek(0, io.SeekStart); err == nil && seekErr == nil {
+			return size
+		}
+	}
+	if getter, ok := streamResponse.(services.ContentLengthGetter); ok {
+		return int64(getter.ContentLength())
+	}
+	return 0
+}
+
+// ifRangeMatches checks the "If-Range" header against the response's ETag or Last-Modified header. When
+// it doesn't match, the caller's partial copy is stale, so we should ignore the Range and send everything.
+func ifRangeMatches(req *http.Request, headers http.Header) bool {
+	ifRange := strings.TrimSpace(req.Header.Get("If-Range"))
+	if ifRange == "" {
+		return true
+	}
+
+	// Entity tags must use a strong comparison, so weak tags ("W/...") never match.
+	if strings.HasPrefix(ifRange, `"`) {
+		etag := headers.Get("ETag")
+		return etag != "" && etag == ifRange
+	}
+
+	lastModified, err := http.ParseTime(headers.Get("Last-Modified"))
+	if err != nil {
+		return false
+	}
+	ifRangeTime, err := http.ParseTime(ifRange)
+	if err != nil {
+		return false
+	}
+	return lastModified.Equal(ifRangeTime)
+}
+
+// parseRange parses a "Range" header value such as "bytes=0-499,1000-" or "bytes=-500". If the header
+// is malformed or uses units other than bytes, we return no ranges so that you ignore the header and
+// send the entire stream. We return errRangeNotSatisfiable if none of the ranges overlap the content.
+func parseRange(header string, size int64) ([]byteRange, error) {
+	header = strings.TrimSpace(header)
+	if !strings.HasPrefix(header, "bytes=") {
+		return nil, nil
+	}
+	specs := strings.TrimPrefix(header, "bytes=")
+
+	var ranges []byteRange
+	for _, spec := range strings.Split(specs, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		startValue, endValue, ok := strings.Cut(spec, "-")
+		if !ok {
+			return nil, nil
+		}
+		startValue = strings.TrimSpace(startValue)
+		endValue = strings.TrimSpace(endValue)
+
+		// A suffix range such as "-500" asks for the last 500 bytes.
+		if startValue == "" {
+			suffix, err := strconv.ParseInt(endValue, 10, 64)
+			if err != nil || suffix < 0 {
+				return nil, nil
+			}
+			if suffix == 0 {
+				continue
+			}
+			if suffix > size {
+				suffix = size
+			}
+			ranges = append(ranges, byteRange{start: size - suffix, end: size - 1})
+			continue
+		}
+
+		start, err := strconv.ParseInt(startValue, 10, 64)
+		if err != nil || start < 0 {
+			return nil, nil
+		}
+		end := size - 1
+		if endValue != "" {
+			if end, err = strconv.ParseInt(endValue, 10, 64); err != nil || end < start {
+				return nil, nil
+			}
+		}
+
+		// This range starts past the end of the content, but maybe the other ones are fine.
+		if start >= size {
+			continue
+		}
+		if end >= size {
+			end = size - 1
+		}
+		ranges = append(ranges, byteRange{start: start, end: end})
+	}
+
+	if len(ranges) == 0 {
+		return nil, errRangeNotSatisfiable
+	}
+	if len(ranges) > maxRanges {
+		return nil, nil
+	}
+	return ranges, nil
+}
+
+// rangesAscending returns true when each range starts after the previous one ends. This lets
+// us satisfy all of them in a single pass over a stream that we can't seek on.
+func rangesAscending(ranges []byteRange) bool {
+	for i := 1; i < len(ranges); i++ {
+		if ranges[i].start <= ranges[i-1].end {
+			return false
+		}
+	}
+	return true
+}
+
+// rangeReader copies individual ranges out of the content stream. For seekers, we jump right to the
+// start of each range. Otherwise, we read and discard bytes until we get to the start of the range.
+type rangeReader struct {
+	content  io.Reader
+	seeker   io.Seeker
+	position int64
+}
+
+func (r *rangeReader) copyRange(w io.Writer, br byteRange) error {
+	switch {
+	case r.seeker != nil:
+		if _, err := r.seeker.Seek(br.start, io.SeekStart); err != nil {
+			return err
+		}
+	case br.start > r.position:
+		if _, err := io.CopyN(io.Discard, r.content, br.start-r.position); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.CopyN(w, r.content, br.length())
+	r.position = br.end + 1
+	return err
+}
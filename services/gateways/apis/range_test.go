@@ -0,0 +1,200 @@
+//go:build unit
+
+package apis_test
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/apis"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestRangeSuite(t *testing.T) {
+	suite.Run(t, new(RangeSuite))
+}
+
+type RangeSuite struct {
+	suite.Suite
+}
+
+var rangeModTime = time.Date(1998, time.March, 6, 12, 0, 0, 0, time.UTC)
+
+type rangeRequest struct{}
+
+type rangeResponse struct {
+	services.StreamResponse
+}
+
+// invoke sends a GET request to an endpoint that responds with "The Dude abides". When seekable is true, the
+// content is an io.ReadSeeker. Otherwise, it's a plain stream that only reports its length.
+func (suite *RangeSuite) invoke(seekable bool, headers map[string]string) *http.Response {
+	gw := apis.NewGateway(":0")
+	gw.Register(services.Endpoint{
+		ServiceName: "RangeService",
+		Name:        "Download",
+		NewInput:    func() services.StructPointer { return &rangeRequest{} },
+		Handler: func(ctx context.Context, req any) (any, error) {
+			res := rangeResponse{}
+			switch seekable {
+			case true:
+				res.SetContent(readSeekCloser{strings.NewReader("The Dude abides")})
+			case false:
+				res.SetContent(io.NopCloser(strings.NewReader("The Dude abides")))
+				res.SetContentLength(15)
+			}
+			res.SetContentType("text/plain")
+			res.SetContentModTime(rangeModTime)
+			return &res, nil
+		},
+	}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "GET", Path: "/download", Status: 200})
+
+	req := httptest.NewRequest(http.MethodGet, "/download", nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	gw.ServeHTTP(w, req)
+	return w.Result()
+}
+
+func (suite *RangeSuite) assertBody(res *http.Response, expected string) {
+	body, err := io.ReadAll(res.Body)
+	suite.Require().NoError(err)
+	suite.Equal(expected, string(body))
+}
+
+func (suite *RangeSuite) TestNoRange() {
+	for _, seekable := range []bool{true, false} {
+		res := suite.invoke(seekable, nil)
+		suite.Equal(200, res.StatusCode)
+		suite.Equal("bytes", res.Header.Get("Accept-Ranges"))
+		suite.Equal(rangeModTime.Format(http.TimeFormat), res.Header.Get("Last-Modified"))
+		suite.Equal("", res.Header.Get("Content-Range"))
+		suite.assertBody(res, "The Dude abides")
+	}
+}
+
+func (suite *RangeSuite) TestSingleRange() {
+	for _, seekable := range []bool{true, false} {
+		res := suite.invoke(seekable, map[string]string{"Range": "bytes=4-7"})
+		suite.Equal(206, res.StatusCode)
+		suite.Equal("bytes 4-7/15", res.Header.Get("Content-Range"))
+		suite.Equal("4", res.Header.Get("Content-Length"))
+		suite.Equal("text/plain", res.Header.Get("Content-Type"))
+		suite.assertBody(res, "Dude")
+
+		res = suite.invoke(seekable, map[string]string{"Range": "bytes=9-"})
+		suite.Equal(206, res.StatusCode)
+		suite.Equal("bytes 9-14/15", res.Header.Get("Content-Range"))
+		suite.assertBody(res, "abides")
+
+		res = suite.invoke(seekable, map[string]string{"Range": "bytes=-6"})
+		suite.Equal(206, res.StatusCode)
+		suite.Equal("bytes 9-14/15", res.Header.Get("Content-Range"))
+		suite.assertBody(res, "abides")
+
+		// The end of the range goes past the end of the content, so just give what we have.
+		res = suite.invoke(seekable, map[string]string{"Range": "bytes=9-5000"})
+		suite.Equal(206, res.StatusCode)
+		suite.Equal("bytes 9-14/15", res.Header.Get("Content-Range"))
+		suite.assertBody(res, "abides")
+	}
+}
+
+func (suite *RangeSuite) TestMultiRange() {
+	for _, seekable := range []bool{true, false} {
+		res := suite.invoke(seekable, map[string]string{"Range": "bytes=0-2, 9-"})
+		suite.Equal(206, res.StatusCode)
+
+		mediaType, params, err := mime.ParseMediaType(res.Header.Get("Content-Type"))
+		suite.Require().NoError(err)
+		suite.Equal("multipart/byteranges", mediaType)
+
+		reader := multipart.NewReader(res.Body, params["boundary"])
+		expected := []struct{ contentRange, body string }{
+			{"bytes 0-2/15", "The"},
+			{"bytes 9-14/15", "abides"},
+		}
+		for _, e := range expected {
+			part, err := reader.NextPart()
+			suite.Require().NoError(err)
+			suite.Equal("text/plain", part.Header.Get("Content-Type"))
+			suite.Equal(e.contentRange, part.Header.Get("Content-Range"))
+
+			body, _ := io.ReadAll(part)
+			suite.Equal(e.body, string(body))
+		}
+		_, err = reader.NextPart()
+		suite.Equal(io.EOF, err)
+	}
+}
+
+// Seekable content can jump around, but plain streams can't, so we'll just send everything.
+func (suite *RangeSuite) TestMultiRange_outOfOrder() {
+	res := suite.invoke(true, map[string]string{"Range": "bytes=9-, 0-2"})
+	suite.Equal(206, res.StatusCode)
+
+	res = suite.invoke(false, map[string]string{"Range": "bytes=9-, 0-2"})
+	suite.Equal(200, res.StatusCode)
+	suite.assertBody(res, "The Dude abides")
+}
+
+func (suite *RangeSuite) TestNotSatisfiable() {
+	for _, seekable := range []bool{true, false} {
+		res := suite.invoke(seekable, map[string]string{"Range": "bytes=500-"})
+		suite.Equal(416, res.StatusCode)
+		suite.Equal("bytes */15", res.Header.Get("Content-Range"))
+	}
+}
+
+// Malformed ranges and units we don't support should be ignored.
+func (suite *RangeSuite) TestInvalidRange() {
+	invalid := []string{"bytes=abc", "bytes=7-4", "lines=1-2", "bytes=4"}
+	for _, rangeHeader := range invalid {
+		res := suite.invoke(true, map[string]string{"Range": rangeHeader})
+		suite.Equal(200, res.StatusCode, rangeHeader)
+		suite.assertBody(res, "The Dude abides")
+	}
+}
+
+func (suite *RangeSuite) TestIfRange() {
+	res := suite.invoke(true, map[string]string{
+		"Range":    "bytes=4-7",
+		"If-Range": rangeModTime.Format(http.TimeFormat),
+	})
+	suite.Equal(206, res.StatusCode)
+	suite.assertBody(res, "Dude")
+
+	// The caller's copy is out of date, so they need the whole thing again.
+	res = suite.invoke(true, map[string]string{
+		"Range":    "bytes=4-7",
+		"If-Range": rangeModTime.Add(-time.Hour).Format(http.TimeFormat),
+	})
+	suite.Equal(200, res.StatusCode)
+	suite.assertBody(res, "The Dude abides")
+
+	// We don't have an ETag, so there's nothing to compare against.
+	res = suite.invoke(true, map[string]string{
+		"Range":    "bytes=4-7",
+		"If-Range": `"abc123"`,
+	})
+	suite.Equal(200, res.StatusCode)
+	suite.assertBody(res, "The Dude abides")
+}
+
+type readSeekCloser struct {
+	io.ReadSeeker
+}
+
+func (readSeekCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,23 @@
+package apis
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/monadicstack/abide/services"
+)
+
+// writeRateLimitHeaders lets the caller know where they stand w/ respect to the endpoint's "RATE xxx" doc
+// option using the "RateLimit-Limit", "RateLimit-Remaining", and "RateLimit-Reset" headers. The reset value
+// is the number of seconds until the caller's full limit is available again. Endpoints w/o a rate limit
+// don't get any of these headers.
+func writeRateLimitHeaders(w http.ResponseWriter, status *services.RateLimitStatus) {
+	if status.Limit == 0 {
+		return
+	}
+	headers := w.Header()
+	headers.Set("RateLimit-Limit", strconv.Itoa(status.Limit))
+	headers.Set("RateLimit-Remaining", strconv.Itoa(status.Remaining))
+	headers.Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(status.Reset.Seconds()))))
+}
@@ -0,0 +1,83 @@
+//go:build unit
+
+package apis_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/apis"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestRateLimitSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitSuite))
+}
+
+type RateLimitSuite struct {
+	suite.Suite
+}
+
+type rateLimitRequest struct{}
+
+// Responses from endpoints w/ a rate limit should include the RateLimit-* headers, and throttled
+// responses should also tell the caller when to retry.
+func (suite *RateLimitSuite) TestHeaders() {
+	gw := apis.NewGateway(":0")
+	services.NewServer(
+		services.Listen(gw),
+		services.Register(&services.Service{
+			Name: "UserService",
+			Endpoints: []services.Endpoint{
+				{
+					ServiceName: "UserService",
+					Name:        "Limited",
+					NewInput:    func() services.StructPointer { return &rateLimitRequest{} },
+					RateLimit:   &services.RateLimit{Limit: 2, Period: time.Minute, By: "IP"},
+					Handler:     func(ctx context.Context, req any) (any, error) { return req, nil },
+					Routes:      []services.EndpointRoute{{GatewayType: services.GatewayTypeAPI, Method: "GET", Path: "/limited", Status: 200}},
+				},
+				{
+					ServiceName: "UserService",
+					Name:        "Unlimited",
+					NewInput:    func() services.StructPointer { return &rateLimitRequest{} },
+					Handler:     func(ctx context.Context, req any) (any, error) { return req, nil },
+					Routes:      []services.EndpointRoute{{GatewayType: services.GatewayTypeAPI, Method: "GET", Path: "/unlimited", Status: 200}},
+				},
+			},
+		}),
+	)
+
+	invoke := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.RemoteAddr = "10.0.0.1:5000"
+		w := httptest.NewRecorder()
+		gw.ServeHTTP(w, req)
+		return w
+	}
+
+	w := invoke("/limited")
+	suite.Equal(200, w.Code)
+	suite.Equal("2", w.Header().Get("RateLimit-Limit"))
+	suite.Equal("1", w.Header().Get("RateLimit-Remaining"))
+	suite.Equal("30", w.Header().Get("RateLimit-Reset"))
+
+	w = invoke("/limited")
+	suite.Equal(200, w.Code)
+	suite.Equal("0", w.Header().Get("RateLimit-Remaining"))
+	suite.Equal("60", w.Header().Get("RateLimit-Reset"))
+
+	w = invoke("/limited")
+	suite.Equal(429, w.Code)
+	suite.Equal("2", w.Header().Get("RateLimit-Limit"))
+	suite.Equal("0", w.Header().Get("RateLimit-Remaining"))
+	suite.Equal("30", w.Header().Get("Retry-After"))
+
+	w = invoke("/unlimited")
+	suite.Equal(200, w.Code)
+	suite.Equal("", w.Header().Get("RateLimit-Limit"), "Endpoints w/o a limit shouldn't have headers")
+	suite.Equal("", w.Header().Get("Retry-After"))
+}
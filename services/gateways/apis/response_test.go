@@ -0,0 +1,85 @@
+//go:build unit
+
+package apis_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/metadata"
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/apis"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestResponseHeadersSuite(t *testing.T) {
+	suite.Run(t, new(ResponseHeadersSuite))
+}
+
+type ResponseHeadersSuite struct {
+	suite.Suite
+}
+
+type responseHeadersRequest struct {
+	ID   string
+	Fail bool
+}
+
+type responseHeadersResponse struct {
+	ID string
+}
+
+// gateway registers "POST /user" which sets a few response headers/cookies and fails if you ask it to.
+func (suite *ResponseHeadersSuite) gateway() *apis.Gateway {
+	gw := apis.NewGateway(":0")
+	gw.Register(services.Endpoint{
+		ServiceName: "UserService",
+		Name:        "Create",
+		NewInput:    func() services.StructPointer { return &responseHeadersRequest{} },
+		Handler: func(ctx context.Context, req any) (any, error) {
+			serviceRequest := req.(*responseHeadersRequest)
+			metadata.SetResponseHeader(ctx, "Location", "/user/"+serviceRequest.ID)
+			metadata.SetResponseHeader(ctx, "Content-Type", "text/plain")
+			metadata.SetCookie(ctx, &http.Cookie{Name: "session", Value: "abc", HttpOnly: true})
+			metadata.SetCookie(ctx, &http.Cookie{Name: "theme", Value: "dark"})
+			if serviceRequest.Fail {
+				return nil, fail.BadRequest("nope")
+			}
+			return &responseHeadersResponse{ID: serviceRequest.ID}, nil
+		},
+	}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "POST", Path: "/user", Status: 201})
+	return gw
+}
+
+// Headers/cookies from the handler should be included in successful responses.
+func (suite *ResponseHeadersSuite) TestSuccess() {
+	req := httptest.NewRequest("POST", "/user?ID=123", nil)
+	w := httptest.NewRecorder()
+	suite.gateway().ServeHTTP(w, req)
+	res := w.Result()
+
+	suite.Equal(201, res.StatusCode)
+	suite.Equal("/user/123", res.Header.Get("Location"))
+	suite.Equal("application/json", res.Header.Get("Content-Type"), "Gateway should control the content type")
+	suite.Require().Len(res.Cookies(), 2)
+	suite.Equal("session", res.Cookies()[0].Name)
+	suite.Equal("abc", res.Cookies()[0].Value)
+	suite.True(res.Cookies()[0].HttpOnly)
+	suite.Equal("theme", res.Cookies()[1].Name)
+	suite.JSONEq(`{"ID":"123"}`, w.Body.String())
+}
+
+// Headers/cookies from the handler should not be included when it fails.
+func (suite *ResponseHeadersSuite) TestFailure() {
+	req := httptest.NewRequest("POST", "/user?ID=123&Fail=true", nil)
+	w := httptest.NewRecorder()
+	suite.gateway().ServeHTTP(w, req)
+	res := w.Result()
+
+	suite.Equal(400, res.StatusCode)
+	suite.Equal("", res.Header.Get("Location"))
+	suite.Empty(res.Cookies())
+}
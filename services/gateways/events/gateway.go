@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/monadicstack/abide/codec"
 	"github.com/monadicstack/abide/eventsource"
@@ -28,7 +31,7 @@ func NewGateway(options ...GatewayOption) *Gateway {
 		decoder:        jsonDecoder,
 		valueEncoder:   jsonEncoder,
 		valueDecoder:   jsonDecoder,
-		broker:         local.Broker(),
+		sagas:          &sagas{store: NewMemorySagaStore(0), steps: map[string]string{}},
 		listening:      &sync.WaitGroup{},
 		activeRequests: &sync.WaitGroup{},
 		errorHandler: func(err error) {
@@ -38,6 +41,12 @@ func NewGateway(options ...GatewayOption) *Gateway {
 	for _, option := range options {
 		option(&gw)
 	}
+
+	// We only close the broker during shutdown if it's the default one that we created ourselves.
+	if gw.broker == nil {
+		gw.broker = local.Broker()
+		gw.ownsBroker = true
+	}
 	return &gw
 }
 
@@ -49,7 +58,9 @@ type Gateway struct {
 	valueEncoder   codec.ValueEncoder
 	valueDecoder   codec.ValueDecoder
 	broker         eventsource.Broker
+	ownsBroker     bool
 	errorHandler   fail.ErrorHandler
+	sagas          *sagas
 	routes         []*route
 	listening      *sync.WaitGroup
 	activeRequests *sync.WaitGroup
@@ -101,59 +112,204 @@ func (gw *Gateway) Register(endpoint services.Endpoint, endpointRoute services.E
 	// Lastly, we're not going to actually send these subscriptions to NATS/Redis/etc. yet. The
 	// broker might not have been started up yet, so we just want to construct and capture the
 	// handler information for what we *will* subscribe to once Listen() is fired on this gateway.
+	if endpointRoute.Method == "COMPENSATE" {
+		gw.registerCompensate(endpoint, endpointRoute)
+		return
+	}
+	if endpointRoute.Delay > 0 {
+		gw.registerDelayed(endpoint, endpointRoute)
+		return
+	}
+	gw.routes = append(gw.routes, &route{
+		key:     endpointRoute.Path,
+		group:   endpoint.QualifiedName(),
+		handler: gw.toStreamHandler(endpoint, endpointRoute),
+	})
+}
+
+// registerDelayed handles routes such as "ON CartService.Abandoned DELAY 1h". We don't want to tie
+// up a subscriber for an hour, so we split the work into two subscriptions. The first listens for
+// the original event and immediately re-publishes the exact same payload to a private key that
+// only this endpoint listens to, using PublishAt() so the broker holds onto it until the delay
+// has passed. The second subscription listens on that private key and runs the endpoint handler
+// like any other event route.
+func (gw *Gateway) registerDelayed(endpoint services.Endpoint, endpointRoute services.EndpointRoute) {
+	delayedKey := DelayedKey(endpoint.QualifiedName())
+
+	// The group needs to include the delay. If you have both "ON Foo.Bar" and "ON Foo.Bar DELAY 5m"
+	// on the same endpoint, they'd otherwise end up in the same consumer group, and the two
+	// subscriptions would be stealing each other's events.
 	gw.routes = append(gw.routes, &route{
 		key:     endpointRoute.Path,
+		group:   endpoint.QualifiedName() + ".Delay" + endpointRoute.Delay.String(),
+		handler: gw.toDelayHandler(delayedKey, endpointRoute.Delay),
+	})
+
+	// You might have multiple delayed routes for the same endpoint, but they all funnel into the
+	// same private key, so we only want to subscribe to that once.
+	for _, r := range gw.routes {
+		if r.key == delayedKey {
+			return
+		}
+	}
+	gw.routes = append(gw.routes, &route{
+		key:     delayedKey,
 		group:   endpoint.QualifiedName(),
 		handler: gw.toStreamHandler(endpoint, endpointRoute),
 	})
 }
 
+// toDelayHandler creates the event handler that forwards the original event to the endpoint's private
+// delayed key, so that it's delivered once the given delay has passed since it was first published.
+func (gw *Gateway) toDelayHandler(delayedKey string, delay time.Duration) eventsource.EventHandlerFunc {
+	return func(ctx context.Context, msg *eventsource.EventMessage) error {
+		if err := gw.broker.PublishAt(ctx, delayedKey, msg.Payload, msg.Timestamp.Add(delay)); err != nil {
+			gw.errorHandler(fmt.Errorf("event delay error: %w", err))
+		}
+		return nil
+	}
+}
+
+// registerCompensate handles the routes generated for "COMPENSATE WITH Xxx" doc options. The route's
+// path is the saga step that this endpoint undoes (e.g. "InventoryService.Reserve"), so we subscribe
+// to that step's private compensation key. This also lets the publishing middleware know that the
+// step is part of a saga, so it will record its progress in the saga store.
+func (gw *Gateway) registerCompensate(endpoint services.Endpoint, endpointRoute services.EndpointRoute) {
+	gw.routes = append(gw.routes, &route{
+		key:     gw.sagas.register(endpointRoute.Path),
+		group:   endpoint.QualifiedName(),
+		handler: gw.toCompensateHandler(endpoint, endpointRoute),
+	})
+}
+
 func (gw *Gateway) toStreamHandler(endpoint services.Endpoint, route services.EndpointRoute) eventsource.EventHandlerFunc {
 	return func(ctx context.Context, msg *eventsource.EventMessage) error {
 		gw.activeRequests.Add(1)
 		defer gw.activeRequests.Done()
 
-		event := message{}
-		serviceRequest := endpoint.NewInput()
-
-		// Take the broker's message and read in the service event 'message' data from it.
-		if err := gw.decoder.Decode(bytes.NewBuffer(msg.Payload), &event); err != nil {
-			gw.errorHandler(fmt.Errorf("event decode error: %w", err))
+		ctx, event, serviceRequest, err := gw.decodeEvent(ctx, endpoint, route, msg)
+		if err != nil {
+			gw.errorHandler(err)
 			return nil
 		}
 
-		// The message contains the raw encoded bytes for the response of the service
-		// method that triggered the event. Overlay that data on this handler's input.
-		if err := gw.valueDecoder.DecodeValues(event.Values, &serviceRequest); err != nil {
-			gw.errorHandler(fmt.Errorf("event payload decode error: %w", err))
+		// Anything that this handler publishes is still part of the same saga (if any).
+		ctx = withSagaID(ctx, event.SagaID)
+
+		if _, err = endpoint.Handler(ctx, serviceRequest); err != nil {
+			gw.errorHandler(fmt.Errorf("event handler error: %w", err))
+
+			// A downstream step failed, so start unwinding everything that the saga has done so far.
+			gw.compensate(event.SagaID, true)
 			return nil
 		}
+		return nil
+	}
+}
 
-		// We want to make sure that the metadata context is restored from the invocation
-		// that triggered this originally. For example, we want to make sure that this
-		// event handler uses the same request id as the HTTP/API request that originally
-		// triggered this. It should also have the same authorization info and values, etc.
-		ctx = metadata.Decode(ctx, event.Metadata)
-
-		// This is a new invocation so the route should indicate THIS function, not the
-		// thing that triggered us to execute.
-		ctx = metadata.WithRoute(ctx, metadata.EndpointRoute{
-			ServiceName: endpoint.ServiceName,
-			Name:        endpoint.Name,
-			Type:        gw.Type().String(),
-			Method:      route.Method,
-			Path:        route.Path,
-			Status:      200, // we don't have a doc option for setting this on event routes, so use sane default.
-		})
+// toCompensateHandler creates the event handler that undoes a single step of a failed saga. Once it's
+// done, it kicks off the compensation for the step before it, so we unwind the saga in reverse order.
+func (gw *Gateway) toCompensateHandler(endpoint services.Endpoint, route services.EndpointRoute) eventsource.EventHandlerFunc {
+	return func(ctx context.Context, msg *eventsource.EventMessage) error {
+		gw.activeRequests.Add(1)
+		defer gw.activeRequests.Done()
 
-		if _, err := endpoint.Handler(ctx, serviceRequest); err != nil {
-			gw.errorHandler(fmt.Errorf("event handler error: %w", err))
+		ctx, event, serviceRequest, err := gw.decodeEvent(ctx, endpoint, route, msg)
+		if err != nil {
+			gw.errorHandler(err)
 			return nil
 		}
+
+		// Even if we fail to undo this step, we still want to give the earlier steps an opportunity
+		// to undo their work. The error handler is your opportunity to clean up this step manually.
+		if _, err = endpoint.Handler(ctx, serviceRequest); err != nil {
+			gw.errorHandler(fmt.Errorf("event compensate error: %w", err))
+		}
+		gw.compensate(event.SagaID, false)
 		return nil
 	}
 }
 
+// compensate publishes the event that undoes the most recent step of the given saga. The compensating
+// handler calls this again once it finishes, which is how we walk back through the steps in reverse.
+// The 'failed' flag indicates that this is the initial call made right after a step failed. Every saga
+// has at least one step by then, so if the store has none, the saga has expired or was recorded by
+// another process's memory store, and nothing is going to be undone. We report that rather than
+// quietly doing nothing.
+func (gw *Gateway) compensate(sagaID string, failed bool) {
+	if sagaID == "" {
+		return
+	}
+
+	// Much like publishing, we don't want to be tied to the lifecycle of the handler's context.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// When fanned-out steps of the same saga fail at the same time, only the first one gets to
+	// start walking the saga back. The rest are ignored since the steps are already being undone.
+	if failed {
+		first, err := gw.sagas.compensating(ctx, sagaID)
+		if err != nil {
+			gw.errorHandler(fmt.Errorf("event compensate error: %w", err))
+			return
+		}
+		if !first {
+			return
+		}
+	}
+
+	step, ok, err := gw.sagas.pop(ctx, sagaID)
+	if err != nil {
+		gw.errorHandler(fmt.Errorf("event compensate error: %w", err))
+		return
+	}
+	if !ok {
+		if failed {
+			gw.errorHandler(fmt.Errorf("event compensate error: saga %s: no steps found to compensate; it may have expired or been recorded by a different process's saga store", sagaID))
+		}
+		return
+	}
+	if err = gw.broker.Publish(ctx, step.Key, step.Payload); err != nil {
+		gw.errorHandler(fmt.Errorf("event compensate error: %s: %w", step.Name, err))
+	}
+}
+
+// decodeEvent unpacks the broker's message into the request value for the endpoint, restoring the
+// metadata from the original invocation on the context that we return.
+func (gw *Gateway) decodeEvent(ctx context.Context, endpoint services.Endpoint, route services.EndpointRoute, msg *eventsource.EventMessage) (context.Context, message, services.StructPointer, error) {
+	event := message{}
+	serviceRequest := endpoint.NewInput()
+
+	// Take the broker's message and read in the service event 'message' data from it.
+	if err := gw.decoder.Decode(bytes.NewBuffer(msg.Payload), &event); err != nil {
+		return ctx, event, nil, fmt.Errorf("event decode error: %w", err)
+	}
+
+	// The message contains the raw encoded bytes for the response of the service
+	// method that triggered the event. Overlay that data on this handler's input.
+	if err := gw.valueDecoder.DecodeValues(event.Values, &serviceRequest); err != nil {
+		return ctx, event, nil, fmt.Errorf("event payload decode error: %w", err)
+	}
+
+	// We want to make sure that the metadata context is restored from the invocation
+	// that triggered this originally. For example, we want to make sure that this
+	// event handler uses the same request id as the HTTP/API request that originally
+	// triggered this. It should also have the same authorization info and values, etc.
+	ctx = metadata.Decode(ctx, event.Metadata)
+
+	// This is a new invocation so the route should indicate THIS function, not the
+	// thing that triggered us to execute.
+	ctx = metadata.WithRoute(ctx, metadata.EndpointRoute{
+		ServiceName: endpoint.ServiceName,
+		Name:        endpoint.Name,
+		Type:        gw.Type().String(),
+		Method:      route.Method,
+		Path:        route.Path,
+		Status:      200, // we don't have a doc option for setting this on event routes, so use sane default.
+	})
+	return ctx, event, serviceRequest, nil
+}
+
 // Middleware returns the middleware functions that ALL server routes should include in order
 // to make sure that this gateway actually works. For instance, one of the middleware functions
 // publishes the service operation's success/failure to the event source/stream. This happens
@@ -162,7 +318,7 @@ func (gw *Gateway) toStreamHandler(endpoint services.Endpoint, route services.En
 // just the event gateway.
 func (gw *Gateway) Middleware() services.MiddlewareFuncs {
 	return services.MiddlewareFuncs{
-		publishMiddleware(gw.broker, gw.encoder, gw.valueEncoder, gw.errorHandler),
+		publishMiddleware(gw.broker, gw.encoder, gw.valueEncoder, gw.sagas, gw.errorHandler),
 	}
 }
 
@@ -209,6 +365,14 @@ func (gw *Gateway) Shutdown(ctx context.Context) error {
 	}
 	gw.listening.Done()
 
+	// The default local broker holds delayed events in memory, so stop its timers; those events
+	// are lost either way once the process exits.
+	if closer, ok := gw.broker.(io.Closer); ok && gw.ownsBroker {
+		if err := closer.Close(); err != nil {
+			return fmt.Errorf("event gateway error: shutdown: %w", err)
+		}
+	}
+
 	// Any in-progress requests should get an opportunity to finish before
 	// we consider shutdown 100% complete. They have until either the
 	// context's deadline/cancellation is reached or the process receives
@@ -217,6 +381,13 @@ func (gw *Gateway) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// DelayedKey returns the private event key that the gateway uses to deliver delayed events to
+// the given endpoint (e.g. "FooService.Bar" -> "Delayed.FooService_Bar"). Service names always
+// end in "Service", so this will never collide with the keys of any of your service methods.
+func DelayedKey(qualifiedName string) string {
+	return "Delayed." + strings.ReplaceAll(qualifiedName, ".", "_")
+}
+
 type route struct {
 	key     string
 	group   string
@@ -245,6 +416,17 @@ func WithEncoding(encoder codec.Encoder, decoder codec.Decoder) GatewayOption {
 	}
 }
 
+// WithSagaStore defines where the gateway keeps track of the steps in each saga so that it can publish
+// compensating events when a downstream handler fails. By default, the gateway uses NewMemorySagaStore(),
+// which will lose any in-progress sagas when your process restarts. It's also per-process, so if you run
+// multiple instances behind a shared broker (e.g. NATS), you must supply a store that they all share;
+// otherwise, the instance that handles a failure won't know about steps that other instances recorded.
+func WithSagaStore(store SagaStore) GatewayOption {
+	return func(gw *Gateway) {
+		gw.sagas.store = store
+	}
+}
+
 // WithErrorHandler sets a custom callback function that is invoked any time we encounter an error
 // publishing an event, receiving an event, or executing a service handler. These are all invoked
 // asynchronously, so this is the only way you can perform any custom error handling in those cases.
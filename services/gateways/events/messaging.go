@@ -3,7 +3,9 @@ package events
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"net/url"
+	"strings"
 	"time"
 
 	"github.com/monadicstack/abide/codec"
@@ -39,12 +41,66 @@ type message struct {
 	//   "AuditTrail.Modified": ["2022-11-11T18:55:43+00:00"],
 	// }
 	Values url.Values
+	// SagaID is the identifier of the saga that this event is part of. It's blank unless the
+	// event was published by a function with "COMPENSATE WITH" or one of its downstream handlers.
+	SagaID string `json:",omitempty"`
+}
+
+type contextKeyPublisher struct{}
+
+// publisher captures the broker/encoding that the event gateway is using so that service handlers
+// can publish their own custom events using PublishAt().
+type publisher struct {
+	broker       eventsource.Broker
+	encoder      codec.Encoder
+	valueEncoder codec.ValueEncoder
+}
+
+// PublishAt schedules a custom event that will be delivered to any "ON key" subscribers at the given
+// time. The payload should be a pointer to a struct; it is encoded exactly like a service response, so
+// its fields will be bound to the subscribers' request structs. The context must be one that was
+// passed to your service handler by a server that has an events gateway. This is also where we get
+// the metadata (authorization, trace id, etc.) that will follow the event to its subscribers.
+//
+//	// Send a reminder email an hour after the cart was abandoned.
+//	func (svc CartServiceHandler) Abandon(ctx context.Context, req *AbandonRequest) (*AbandonResponse, error) {
+//		reminder := &AbandonResponse{CartID: req.CartID}
+//		err := events.PublishAt(ctx, "CartService.Reminder", reminder, time.Now().Add(time.Hour))
+//		...
+//	}
+//
+// If the time has already passed, the event will be published immediately.
+func PublishAt(ctx context.Context, key string, payload any, at time.Time) error {
+	pub, ok := ctx.Value(contextKeyPublisher{}).(publisher)
+	if !ok {
+		return fail.Unexpected("publish at: no event gateway available on context")
+	}
+
+	serviceName, name, _ := strings.Cut(key, ".")
+	msg := message{
+		ServiceName: serviceName,
+		Name:        name,
+		Metadata:    metadata.Encode(ctx),
+		Values:      pub.valueEncoder.EncodeValues(payload),
+		SagaID:      sagaID(ctx),
+	}
+
+	buf := &bytes.Buffer{}
+	if err := pub.encoder.Encode(buf, msg); err != nil {
+		return fmt.Errorf("publish at: %w", err)
+	}
+	return pub.broker.PublishAt(ctx, key, buf.Bytes(), at)
 }
 
 // publishMiddleware defines the unit of work that every service endpoint should perform to publish
 // their "I just finished this service function" event; the thing that drives our event gateway.
-func publishMiddleware(broker eventsource.Broker, encoder codec.Encoder, valueEncoder codec.ValueEncoder, errorHandler fail.ErrorHandler) services.MiddlewareFunc {
+func publishMiddleware(broker eventsource.Broker, encoder codec.Encoder, valueEncoder codec.ValueEncoder, sagas *sagas, errorHandler fail.ErrorHandler) services.MiddlewareFunc {
+	pub := publisher{broker: broker, encoder: encoder, valueEncoder: valueEncoder}
+
 	return func(ctx context.Context, req any, next services.HandlerFunc) (any, error) {
+		// Make the broker available to the handler in case it wants to publish custom events.
+		ctx = context.WithValue(ctx, contextKeyPublisher{}, pub)
+
 		response, err := next(ctx, req)
 		if err != nil {
 			// Will need to see my own need and get feedback on if we need to publish a
@@ -76,11 +132,16 @@ func publishMiddleware(broker eventsource.Broker, encoder codec.Encoder, valueEn
 			pubCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second) // make configurable?
 			defer cancel()
 
+			// If this endpoint is a step in a saga, make sure that the event and anything downstream of
+			// it is tied to the saga, so failures further down the line can trigger compensation.
+			sagaID, compensateKey, isStep := sagas.begin(ctx, endpoint.QualifiedName())
+
 			msg := message{
 				ServiceName: endpoint.ServiceName,
 				Name:        endpoint.Name,
 				Metadata:    encodedMetadata,
 				Values:      valueEncoder.EncodeValues(response),
+				SagaID:      sagaID,
 			}
 
 			buf := &bytes.Buffer{}
@@ -88,6 +149,15 @@ func publishMiddleware(broker eventsource.Broker, encoder codec.Encoder, valueEn
 				errorHandler(err)
 				return
 			}
+
+			// The step must be recorded BEFORE we publish. Otherwise, a downstream handler could fail
+			// before we've recorded that there's something to compensate.
+			if isStep {
+				step := SagaStep{Name: endpoint.QualifiedName(), Key: compensateKey, Payload: buf.Bytes()}
+				if err = sagas.push(pubCtx, sagaID, step); err != nil {
+					errorHandler(err)
+				}
+			}
 			if err = broker.Publish(pubCtx, endpoint.QualifiedName(), buf.Bytes()); err != nil {
 				errorHandler(err)
 				return
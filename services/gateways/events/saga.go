@@ -0,0 +1,230 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/monadicstack/abide/metadata"
+)
+
+// SagaStore is the pluggable storage for in-progress sagas. A saga is the chain of event-driven service
+// calls that begins the first time a function with the "COMPENSATE WITH Xxx" doc option succeeds. Every
+// subsequent step that has a compensating function is pushed onto the saga's stack. If any downstream
+// event handler in the chain fails, the gateway pops the steps off one at a time and publishes events
+// to undo them in reverse order.
+//
+// By default, the gateway uses NewMemorySagaStore(), so saga state only lives as long as your process
+// does and is only visible to that process. If you need compensation to survive restarts/deployments or
+// to work across multiple instances of your service, implement this using your database of choice and
+// supply it using the WithSagaStore() option.
+type SagaStore interface {
+	// Push records the fact that a step in the given saga completed successfully.
+	Push(ctx context.Context, sagaID string, step SagaStep) error
+	// Pop removes and returns the most recently pushed step for the given saga. The boolean should be
+	// false once there are no more steps left to compensate (or the saga doesn't exist).
+	Pop(ctx context.Context, sagaID string) (SagaStep, bool, error)
+	// Compensate marks the saga as being compensated. It should return true for the first caller and
+	// false for every caller after that, so that when several fanned-out steps fail at the same time,
+	// only one of them walks the saga's steps back. Later failures are simply ignored.
+	Compensate(ctx context.Context, sagaID string) (bool, error)
+}
+
+// SagaStep describes the compensating event that should be published if we need to undo one
+// step of a saga.
+type SagaStep struct {
+	// Name is the fully qualified name of the step that completed (e.g. "InventoryService.Reserve").
+	Name string
+	// Key is the event key that the compensating function subscribes to (e.g. "Compensate.InventoryService_Reserve").
+	Key string
+	// Payload is the already-encoded event message that we'll publish to Key. It contains the
+	// step's response and metadata, so the compensating function knows what to undo.
+	Payload []byte
+}
+
+// CompensateKey returns the private event key that the gateway uses to deliver compensation events
+// for the given saga step (e.g. "FooService.Bar" -> "Compensate.FooService_Bar"). Service names always
+// end in "Service", so this will never collide with the keys of any of your service methods.
+func CompensateKey(qualifiedName string) string {
+	return "Compensate." + strings.ReplaceAll(qualifiedName, ".", "_")
+}
+
+// NewMemorySagaStore creates a saga store that keeps everything in memory. It is per-process only: other
+// instances of your service can't see its sagas, so it's only suitable when a single process handles
+// every step of the saga (e.g. the local broker or a single instance). Sagas that succeed never
+// need to be compensated, so we have no way of knowing when they're "done". As a result, any saga that
+// hasn't had a step pushed to it in the given amount of time will be discarded. A zero/negative value
+// uses a default of 24 hours.
+func NewMemorySagaStore(expiration time.Duration) SagaStore {
+	if expiration <= 0 {
+		expiration = 24 * time.Hour
+	}
+	return &memorySagaStore{
+		expiration: expiration,
+		sagas:      map[string]*memorySaga{},
+		now:        time.Now,
+	}
+}
+
+type memorySagaStore struct {
+	mutex      sync.Mutex
+	expiration time.Duration
+	sagas      map[string]*memorySaga
+	now        func() time.Time
+	pruned     time.Time
+}
+
+type memorySaga struct {
+	steps        []SagaStep
+	modified     time.Time
+	compensating bool
+}
+
+func (store *memorySagaStore) Push(_ context.Context, sagaID string, step SagaStep) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := store.now()
+	store.prune(now)
+
+	saga, ok := store.sagas[sagaID]
+	if !ok {
+		saga = &memorySaga{}
+		store.sagas[sagaID] = saga
+	}
+	saga.steps = append(saga.steps, step)
+	saga.modified = now
+	return nil
+}
+
+func (store *memorySagaStore) Pop(_ context.Context, sagaID string) (SagaStep, bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	saga, ok := store.sagas[sagaID]
+	if !ok {
+		return SagaStep{}, false, nil
+	}
+	if len(saga.steps) == 0 {
+		// Once a saga is being compensated, we hang onto the empty saga until it expires so that
+		// any failures that trickle in late don't start compensating it all over again.
+		if !saga.compensating {
+			delete(store.sagas, sagaID)
+		}
+		return SagaStep{}, false, nil
+	}
+
+	last := len(saga.steps) - 1
+	step := saga.steps[last]
+	saga.steps = saga.steps[:last]
+	return step, true, nil
+}
+
+func (store *memorySagaStore) Compensate(_ context.Context, sagaID string) (bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := store.now()
+	store.prune(now)
+
+	saga, ok := store.sagas[sagaID]
+	if !ok {
+		saga = &memorySaga{}
+		store.sagas[sagaID] = saga
+	}
+	if saga.compensating {
+		return false, nil
+	}
+	saga.compensating = true
+	saga.modified = now
+	return true, nil
+}
+
+// prune discards any sagas that haven't been modified within the expiration window. We only bother
+// doing this once a second so that it's not O(n) on every push. You should already have the lock
+// when calling this.
+func (store *memorySagaStore) prune(now time.Time) {
+	if now.Sub(store.pruned) < time.Second {
+		return
+	}
+	for sagaID, saga := range store.sagas {
+		if now.Sub(saga.modified) > store.expiration {
+			delete(store.sagas, sagaID)
+		}
+	}
+	store.pruned = now
+}
+
+type contextKeySagaID struct{}
+
+// withSagaID stores the id of the saga that the current invocation is part of (if any).
+func withSagaID(ctx context.Context, sagaID string) context.Context {
+	return context.WithValue(ctx, contextKeySagaID{}, sagaID)
+}
+
+// sagaID returns the id of the saga that the current invocation is part of. This is blank if we are not
+// currently running as part of a saga.
+func sagaID(ctx context.Context) string {
+	id, _ := ctx.Value(contextKeySagaID{}).(string)
+	return id
+}
+
+// sagas tracks which endpoints are saga steps and hands off their state to the store.
+type sagas struct {
+	store SagaStore
+	// steps maps the qualified name of each step (e.g. "InventoryService.Reserve") to the key that its
+	// compensating function subscribes to. It is only written to while registering routes, so it's
+	// safe to read from concurrently once the server is up and running.
+	steps map[string]string
+}
+
+// register indicates that the given step has a compensating function listening on the compensation key.
+func (s *sagas) register(stepName string) string {
+	key := CompensateKey(stepName)
+	s.steps[stepName] = key
+	return key
+}
+
+// begin is called when an endpoint completes successfully. If the endpoint is a saga step, this returns
+// the id of the saga it's part of (creating a new one if necessary) along with the compensation key. The
+// boolean is false if the endpoint is not a step, in which case the saga id from the context passes through.
+func (s *sagas) begin(ctx context.Context, stepName string) (string, string, bool) {
+	id := sagaID(ctx)
+	key, ok := s.steps[stepName]
+	if !ok {
+		return id, "", false
+	}
+	if id == "" {
+		id = metadata.NewTraceID()
+	}
+	return id, key, true
+}
+
+// push records the step so that we can undo it later if we need to.
+func (s *sagas) push(ctx context.Context, sagaID string, step SagaStep) error {
+	if err := s.store.Push(ctx, sagaID, step); err != nil {
+		return fmt.Errorf("saga push: %s: %w", step.Name, err)
+	}
+	return nil
+}
+
+// compensating marks the saga as being compensated. This returns false if some other failure already
+// beat us to it, in which case that failure is already walking the saga back.
+func (s *sagas) compensating(ctx context.Context, sagaID string) (bool, error) {
+	first, err := s.store.Compensate(ctx, sagaID)
+	if err != nil {
+		return false, fmt.Errorf("saga compensate: %s: %w", sagaID, err)
+	}
+	return first, nil
+}
+
+// pop grabs the most recent step in the saga that still needs to be compensated.
+func (s *sagas) pop(ctx context.Context, sagaID string) (SagaStep, bool, error) {
+	step, ok, err := s.store.Pop(ctx, sagaID)
+	if err != nil {
+		return SagaStep{}, false, fmt.Errorf("saga pop: %s: %w", sagaID, err)
+	}
+	return step, ok, nil
+}
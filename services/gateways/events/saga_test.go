@@ -0,0 +1,97 @@
+//go:build unit
+
+package events_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/monadicstack/abide/services/gateways/events"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestSagaSuite(t *testing.T) {
+	suite.Run(t, new(SagaSuite))
+}
+
+type SagaSuite struct {
+	suite.Suite
+}
+
+func (suite *SagaSuite) TestCompensateKey() {
+	suite.Equal("Compensate.FooService_Bar", events.CompensateKey("FooService.Bar"))
+	suite.Equal("Compensate.FooService_Bar_Baz", events.CompensateKey("FooService.Bar.Baz"))
+	suite.Equal("Compensate.Foo", events.CompensateKey("Foo"))
+}
+
+func (suite *SagaSuite) TestMemoryStore_pop() {
+	ctx := context.Background()
+	store := events.NewMemorySagaStore(0)
+
+	_, ok, err := store.Pop(ctx, "nope")
+	suite.Require().NoError(err)
+	suite.False(ok, "Should not find steps for a saga that doesn't exist")
+
+	suite.Require().NoError(store.Push(ctx, "1", events.SagaStep{Name: "A"}))
+	suite.Require().NoError(store.Push(ctx, "2", events.SagaStep{Name: "X"}))
+	suite.Require().NoError(store.Push(ctx, "1", events.SagaStep{Name: "B"}))
+	suite.Require().NoError(store.Push(ctx, "1", events.SagaStep{Name: "C"}))
+
+	// Steps should come back in reverse order, and only for the saga we asked for.
+	for _, expected := range []string{"C", "B", "A"} {
+		step, ok, err := store.Pop(ctx, "1")
+		suite.Require().NoError(err)
+		suite.Require().True(ok)
+		suite.Equal(expected, step.Name)
+	}
+	_, ok, err = store.Pop(ctx, "1")
+	suite.Require().NoError(err)
+	suite.False(ok, "Should not have any steps left once they've all been popped")
+
+	step, ok, err := store.Pop(ctx, "2")
+	suite.Require().NoError(err)
+	suite.Require().True(ok)
+	suite.Equal("X", step.Name)
+}
+
+func (suite *SagaSuite) TestMemoryStore_compensate() {
+	ctx := context.Background()
+	store := events.NewMemorySagaStore(0)
+	suite.Require().NoError(store.Push(ctx, "1", events.SagaStep{Name: "A"}))
+
+	// Only one of the failures that race to compensate the saga should win.
+	winners := int32(0)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			first, err := store.Compensate(ctx, "1")
+			suite.NoError(err)
+			if first {
+				atomic.AddInt32(&winners, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	suite.Equal(int32(1), winners)
+
+	// Popping every step shouldn't let a late failure start compensating the saga all over again.
+	_, ok, err := store.Pop(ctx, "1")
+	suite.Require().NoError(err)
+	suite.Require().True(ok)
+	_, ok, err = store.Pop(ctx, "1")
+	suite.Require().NoError(err)
+	suite.False(ok)
+
+	first, err := store.Compensate(ctx, "1")
+	suite.Require().NoError(err)
+	suite.False(first, "Late failures should not compensate the saga again")
+
+	// Sagas that we've never heard of can still be compensated (the gateway reports that there's nothing to undo).
+	first, err = store.Compensate(ctx, "2")
+	suite.Require().NoError(err)
+	suite.True(first)
+}
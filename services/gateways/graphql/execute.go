@@ -0,0 +1,492 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/internal/reflection"
+	"github.com/monadicstack/abide/services"
+)
+
+// executor runs a single operation, invoking the service function for each of its root fields and
+// then trimming each function's response down to the fields that the caller selected.
+type executor struct {
+	gw        *Gateway
+	request   *http.Request
+	doc       *document
+	variables map[string]any
+
+	mutex  sync.Mutex
+	errors []*responseError
+}
+
+// checkDepth makes sure that the operation's selections aren't nested more than the gateway's maximum depth
+// once we expand all of the fragments. The parser already limits how deep the query text can be, but
+// fragments that spread other fragments let you build something much deeper than what you actually sent.
+func (ex *executor) checkDepth(op *operation) error {
+	if ex.gw.maxDepth <= 0 {
+		return nil
+	}
+	depth, err := ex.selectionDepth(op.selections, map[string]int{}, map[string]bool{})
+	if err != nil {
+		return err
+	}
+	if depth > ex.gw.maxDepth {
+		return fail.BadRequest("graphql: query exceeds the maximum depth of %d", ex.gw.maxDepth)
+	}
+	return nil
+}
+
+// selectionDepth determines how many levels of fields the selection set contains once fragments are expanded. We
+// remember each fragment's depth so that fragments spread over and over again don't take forever to evaluate.
+func (ex *executor) selectionDepth(selections []*selection, fragmentDepths map[string]int, visiting map[string]bool) (int, error) {
+	maxDepth := 0
+	for _, sel := range selections {
+		depth := 0
+		switch {
+		case sel.field != nil:
+			childDepth, err := ex.selectionDepth(sel.field.selections, fragmentDepths, visiting)
+			if err != nil {
+				return 0, err
+			}
+			depth = childDepth + 1
+
+		case sel.spread != "":
+			if visiting[sel.spread] {
+				return 0, fail.BadRequest("graphql: fragment '%s' can not spread itself", sel.spread)
+			}
+			fragmentDepth, ok := fragmentDepths[sel.spread]
+			if !ok {
+				visiting[sel.spread] = true
+				childDepth, err := ex.selectionDepth(ex.doc.fragments[sel.spread].selections, fragmentDepths, visiting)
+				if err != nil {
+					return 0, err
+				}
+				delete(visiting, sel.spread)
+				fragmentDepth, fragmentDepths[sel.spread] = childDepth, childDepth
+			}
+			depth = fragmentDepth
+
+		default:
+			childDepth, err := ex.selectionDepth(sel.inline, fragmentDepths, visiting)
+			if err != nil {
+				return 0, err
+			}
+			depth = childDepth
+		}
+
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+		if ex.gw.maxDepth > 0 && maxDepth > ex.gw.maxDepth {
+			return maxDepth, nil
+		}
+	}
+	return maxDepth, nil
+}
+
+// execute runs the operation and returns the "data" value for the response. Queries don't have side
+// effects, so we resolve all of their fields at once; the spec says that mutations run one at a time.
+func (ex *executor) execute(op *operation) any {
+	rootType, endpoints := "Query", ex.gw.queries
+	if op.kind == "mutation" {
+		rootType, endpoints = "Mutation", ex.gw.mutations
+	}
+
+	fields := ex.collectFields(rootType, op.selections, nil)
+	values := make([]any, len(fields))
+	resolve := func(i int) {
+		key, field := fields[i].key, fields[i].fields[0]
+		values[i] = ex.resolveRoot(rootType, endpoints, field, fields[i].selections(), []any{key})
+	}
+
+	if op.kind == "mutation" {
+		for i := range fields {
+			resolve(i)
+		}
+	} else {
+		wg := sync.WaitGroup{}
+		for i := range fields {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				resolve(i)
+			}(i)
+		}
+		wg.Wait()
+	}
+
+	data := &resultObject{}
+	for i, f := range fields {
+		data.set(f.key, values[i])
+	}
+	return data
+}
+
+// resolveRoot resolves one of the fields on the Query/Mutation type by invoking the service function
+// that it maps to.
+func (ex *executor) resolveRoot(rootType string, endpoints map[string]registration, f *field, selections []*selection, path []any) (result any) {
+	switch f.name {
+	case "__typename":
+		return rootType
+	case "__schema", "__type":
+		ex.fail(path, fail.NotImplemented("graphql: introspection is not supported; use the schema from 'abide docs' instead"))
+		return nil
+	case placeholderField:
+		return nil
+	}
+
+	reg, ok := endpoints[f.name]
+	if !ok {
+		ex.fail(path, fail.BadRequest("graphql: cannot query field '%s' on type '%s'", f.name, rootType))
+		return nil
+	}
+
+	defer func() {
+		if recovery := recover(); recovery != nil {
+			ex.fail(path, fail.Unexpected("%v", recovery))
+			result = nil
+		}
+	}()
+
+	serviceRequest, err := ex.serviceRequest(reg, f)
+	if err != nil {
+		ex.fail(path, err)
+		return nil
+	}
+
+	serviceResponse, err := reg.endpoint.Handler(ex.gw.context(ex.request, reg), serviceRequest)
+	if err != nil {
+		ex.fail(path, err)
+		return nil
+	}
+
+	switch serviceResponse.(type) {
+	case services.ContentGetter, services.EventStreamer:
+		ex.fail(path, fail.NotImplemented("graphql: %s: raw content and event streams are not supported", reg.endpoint.QualifiedName()))
+		return nil
+	}
+	return ex.complete(reflect.ValueOf(serviceResponse), selections, path)
+}
+
+// serviceRequest builds the request struct for the service function using the field's arguments. The
+// arguments are the fields of the request struct, so we just treat them like a JSON object.
+func (ex *executor) serviceRequest(reg registration, f *field) (any, error) {
+	serviceRequest := reg.endpoint.NewInput()
+	if len(f.arguments) == 0 {
+		return serviceRequest, nil
+	}
+
+	arguments := make(map[string]any, len(f.arguments))
+	for _, arg := range f.arguments {
+		arguments[arg.name] = arg.value.resolve(ex.variables)
+	}
+
+	argumentJSON, err := json.Marshal(arguments)
+	if err != nil {
+		return nil, fail.BadRequest("graphql: invalid arguments: %v", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(argumentJSON))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(serviceRequest); err != nil {
+		return nil, fail.BadRequest("graphql: invalid arguments for '%s': %v", f.name, err)
+	}
+	return serviceRequest, nil
+}
+
+// complete converts the value to the output for the field. Scalars are returned as-is, lists are completed
+// item by item, and objects only include the fields that the caller selected.
+func (ex *executor) complete(value reflect.Value, selections []*selection, path []any) any {
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return nil
+	}
+	if isScalar(value) {
+		return ex.scalar(value, path)
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil
+		}
+		results := make([]any, value.Len())
+		for i := range results {
+			results[i] = ex.complete(value.Index(i), selections, append(path[:len(path):len(path)], i))
+		}
+		return results
+
+	case reflect.Struct:
+		if len(selections) == 0 {
+			ex.fail(path, fail.BadRequest("graphql: field '%v' of type '%s' must have a selection of subfields", path[len(path)-1], value.Type().Name()))
+			return nil
+		}
+		return ex.completeObject(value, selections, path)
+
+	default:
+		return value.Interface()
+	}
+}
+
+func (ex *executor) completeObject(value reflect.Value, selections []*selection, path []any) any {
+	typeName := value.Type().Name()
+	result := &resultObject{}
+
+	for _, collected := range ex.collectFields(typeName, selections, nil) {
+		fieldPath := append(path[:len(path):len(path)], collected.key)
+
+		switch name := collected.fields[0].name; name {
+		case "__typename":
+			result.set(collected.key, typeName)
+		case placeholderField:
+			result.set(collected.key, nil)
+		default:
+			index, ok := structFields(value.Type())[name]
+			if !ok {
+				ex.fail(fieldPath, fail.BadRequest("graphql: cannot query field '%s' on type '%s'", name, typeName))
+				result.set(collected.key, nil)
+				continue
+			}
+
+			// The field might be promoted from an embedded struct pointer that's nil.
+			fieldValue, err := value.FieldByIndexErr(index)
+			if err != nil {
+				result.set(collected.key, nil)
+				continue
+			}
+			result.set(collected.key, ex.complete(fieldValue, collected.selections(), fieldPath))
+		}
+	}
+	return result
+}
+
+// scalar returns the value as it should appear in the JSON response. Types with custom marshaling are
+// marshaled here, so they're encoded exactly the way the API gateway would encode them.
+func (ex *executor) scalar(value reflect.Value, path []any) any {
+	if value.CanAddr() {
+		value = value.Addr()
+	}
+	data, err := json.Marshal(value.Interface())
+	if err != nil {
+		ex.fail(path, fail.Unexpected("graphql: unable to encode value: %v", err))
+		return nil
+	}
+	return json.RawMessage(data)
+}
+
+// collectedField is every field in a selection set that shares the same response key. The spec lets you
+// select the same field more than once (e.g. once directly and once in a fragment), and we merge them.
+type collectedField struct {
+	key    string
+	fields []*field
+}
+
+// selections returns all of the sub-selections for all of the fields that were merged.
+func (c collectedField) selections() []*selection {
+	if len(c.fields) == 1 {
+		return c.fields[0].selections
+	}
+	var results []*selection
+	for _, f := range c.fields {
+		results = append(results, f.selections...)
+	}
+	return results
+}
+
+// collectFields flattens the selection set into the fields we need to resolve for the given type. It
+// expands fragments whose type condition matches and skips anything excluded by @skip/@include.
+func (ex *executor) collectFields(typeName string, selections []*selection, visited map[string]bool) []*collectedField {
+	var results []*collectedField
+	indexes := map[string]int{}
+
+	add := func(f *field) {
+		key := f.responseKey()
+		if i, ok := indexes[key]; ok {
+			results[i].fields = append(results[i].fields, f)
+			return
+		}
+		indexes[key] = len(results)
+		results = append(results, &collectedField{key: key, fields: []*field{f}})
+	}
+	addAll := func(fields []*collectedField) {
+		for _, c := range fields {
+			for _, f := range c.fields {
+				add(f)
+			}
+		}
+	}
+
+	for _, sel := range selections {
+		switch {
+		case sel.field != nil:
+			if ex.included(sel.field.directives) {
+				add(sel.field)
+			}
+
+		case sel.spread != "":
+			// Fragments can't spread themselves (directly or indirectly), but protect ourselves anyway.
+			if visited[sel.spread] || !ex.included(sel.directives) {
+				continue
+			}
+			frag := ex.doc.fragments[sel.spread]
+			if frag.typeCondition != typeName {
+				continue
+			}
+			visitedWithFragment := map[string]bool{sel.spread: true}
+			for name := range visited {
+				visitedWithFragment[name] = true
+			}
+			addAll(ex.collectFields(typeName, frag.selections, visitedWithFragment))
+
+		default:
+			if !ex.included(sel.directives) {
+				continue
+			}
+			if sel.typeCondition != "" && sel.typeCondition != typeName {
+				continue
+			}
+			addAll(ex.collectFields(typeName, sel.inline, visited))
+		}
+	}
+	return results
+}
+
+// included evaluates the standard @skip and @include directives.
+func (ex *executor) included(directives []*directive) bool {
+	for _, d := range directives {
+		condition := false
+		for _, arg := range d.arguments {
+			if arg.name == "if" {
+				condition, _ = arg.value.resolve(ex.variables).(bool)
+			}
+		}
+
+		switch {
+		case d.name == "skip" && condition:
+			return false
+		case d.name == "include" && !condition:
+			return false
+		}
+	}
+	return true
+}
+
+// fail records an error for the field at the given path. The field's value will be null.
+func (ex *executor) fail(path []any, err error) {
+	ex.mutex.Lock()
+	defer ex.mutex.Unlock()
+	ex.errors = append(ex.errors, newResponseError(path, err))
+}
+
+// isScalar returns true for values that we return as-is rather than selecting fields from. That's
+// anything that isn't a struct/slice, as well as any type that marshals itself (e.g. time.Time).
+func isScalar(value reflect.Value) bool {
+	valueType := value.Type()
+	switch {
+	case valueType.Implements(jsonMarshalerType), reflect.PointerTo(valueType).Implements(jsonMarshalerType):
+		return true
+	case valueType.Implements(textMarshalerType), reflect.PointerTo(valueType).Implements(textMarshalerType):
+		return true
+	case valueType.Kind() == reflect.Slice && valueType.Elem().Kind() == reflect.Uint8:
+		return true
+	case valueType.Kind() == reflect.Map:
+		return true
+	default:
+		return false
+	}
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// structFieldCache stores the results of structFields() since a type's fields never change.
+var structFieldCache = sync.Map{}
+
+// structFields maps the name of each field as it appears in JSON (and therefore our schema) to its index. Fields
+// of embedded structs are promoted just like the JSON encoder does.
+func structFields(structType reflect.Type) map[string][]int {
+	if cached, ok := structFieldCache.Load(structType); ok {
+		return cached.(map[string][]int)
+	}
+
+	fields := map[string][]int{}
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			structField := t.Field(i)
+			fieldIndex := append(index[:len(index):len(index)], i)
+			if structField.Tag.Get("json") == "-" {
+				continue
+			}
+
+			// Embedded structs (w/o a name in their JSON tag) get their fields promoted.
+			embeddedType := reflection.FlattenPointerType(structField.Type)
+			if structField.Anonymous && embeddedType.Kind() == reflect.Struct && structField.Tag.Get("json") == "" {
+				walk(embeddedType, fieldIndex)
+				continue
+			}
+			if !structField.IsExported() {
+				continue
+			}
+
+			// Fields at a shallower depth win, just like Go's own field promotion.
+			name := reflection.BindingName(structField)
+			if existing, ok := fields[name]; !ok || len(existing) > len(fieldIndex) {
+				fields[name] = fieldIndex
+			}
+		}
+	}
+	walk(structType, nil)
+
+	structFieldCache.Store(structType, fields)
+	return fields
+}
+
+// placeholderField is the field that the generated schema adds to types that would otherwise be
+// empty. It's always null.
+const placeholderField = "_"
+
+// resultObject is a JSON object whose keys are encoded in the order that they were selected, as
+// required by the spec.
+type resultObject struct {
+	keys   []string
+	values []any
+}
+
+func (obj *resultObject) set(key string, value any) {
+	obj.keys = append(obj.keys, key)
+	obj.values = append(obj.values, value)
+}
+
+// MarshalJSON encodes the object with its keys in selection order.
+func (obj *resultObject) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, key := range obj.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		keyJSON, _ := json.Marshal(key)
+		buf.Write(keyJSON)
+		buf.WriteByte(':')
+
+		valueJSON, err := json.Marshal(obj.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(valueJSON)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
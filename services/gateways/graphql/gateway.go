@@ -0,0 +1,346 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/monadicstack/abide/codec"
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/metadata"
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/apis"
+)
+
+// NewGateway creates a gateway that serves all of your service functions as a single GraphQL endpoint.
+// Functions whose API route uses GET are fields on the Query type and everything else is a field on the
+// Mutation type. Each field is named "Service_Function" and its arguments are the fields of the request:
+//
+//	server := services.NewServer(
+//		services.Listen(apis.NewGateway(":9000")),
+//		services.Listen(graphql.NewGateway(":9001")),
+//		services.Register(gen.UserServiceServer(userService)),
+//		services.Register(gen.GroupServiceServer(groupService)),
+//	)
+//
+//	// POST http://localhost:9001
+//	// {"query": "{ UserService_GetByID(ID: \"123\") { Name } }"}
+//
+// Run "abide docs" to generate the schema that describes each service's fields and types.
+func NewGateway(address string, options ...GatewayOption) *Gateway {
+	gw := &Gateway{
+		encoder:     codec.JSONEncoder{},
+		queries:     map[string]registration{},
+		mutations:   map[string]registration{},
+		maxBodySize: DefaultMaxBodySize,
+		maxDepth:    DefaultMaxDepth,
+	}
+	for _, option := range options {
+		option(gw)
+	}
+
+	gw.handler = gw.middleware.Then(gw.serveHTTP)
+	gw.server = &http.Server{Addr: address, Handler: gw}
+	return gw
+}
+
+// Gateway encapsulates the HTTP server that accepts GraphQL requests as well as the logic to resolve
+// fields by invoking service functions. You should not create one of these yourself - use the NewGateway()
+// constructor instead.
+type Gateway struct {
+	server      *http.Server
+	encoder     codec.Encoder
+	middleware  apis.HTTPMiddlewareFuncs
+	handler     http.HandlerFunc
+	queries     map[string]registration
+	mutations   map[string]registration
+	maxBodySize int64
+	maxDepth    int
+	metadata    *metadata.VerifyConfig
+}
+
+// DefaultMaxBodySize is the largest POST body (in bytes) that the gateway accepts unless you
+// use WithMaxBodySize() to change it. That's a whole lot of query text.
+const DefaultMaxBodySize = 1 << 20
+
+// DefaultMaxDepth is how deeply the gateway lets you nest selection sets (and argument values) unless you
+// use WithMaxDepth() to change it.
+const DefaultMaxDepth = 32
+
+// registration is the endpoint behind a query/mutation field. We hang onto the API route that it came
+// from, so the route metadata has the same status that the API gateway would report.
+type registration struct {
+	endpoint services.Endpoint
+	route    services.EndpointRoute
+}
+
+// Type returns "GRAPHQL" to indicate the tagging value for this gateway.
+func (gw *Gateway) Type() services.GatewayType {
+	return services.GatewayTypeGraphQL
+}
+
+// Register adds a field named "Service_Function" to the Query type (GET routes) or the Mutation type
+// (everything else). This gateway serves the same functions as the API gateway, so it only cares about
+// API routes. You will not invoke this yourself! The services.Server will utilize this as necessary.
+func (gw *Gateway) Register(endpoint services.Endpoint, route services.EndpointRoute) {
+	if route.GatewayType != services.GatewayTypeAPI {
+		return
+	}
+
+	// Raw uploads don't have any fields for us to turn into arguments.
+	if _, ok := endpoint.NewInput().(services.ContentSetter); ok {
+		return
+	}
+
+	// An endpoint can have multiple API routes, but it's still just one field to us.
+	name := endpoint.ServiceName + "_" + endpoint.Name
+	if _, ok := gw.queries[name]; ok {
+		return
+	}
+	if _, ok := gw.mutations[name]; ok {
+		return
+	}
+
+	if route.Method == http.MethodGet {
+		gw.queries[name] = registration{endpoint: endpoint, route: route}
+	} else {
+		gw.mutations[name] = registration{endpoint: endpoint, route: route}
+	}
+}
+
+// Listen fires up the underlying HTTP server and blocks until the gateway shuts down. When the gateway
+// shuts down gracefully, this will return nil instead of http.ErrServerClosed.
+func (gw *Gateway) Listen() error {
+	switch err := gw.server.ListenAndServe(); err {
+	case nil, http.ErrServerClosed:
+		return nil
+	default:
+		return fmt.Errorf("graphql gateway error: %w", err)
+	}
+}
+
+// Shutdown attempts to gracefully shut down the HTTP server. It will wait for any in-progress
+// requests to finish and then shut down (unblocking Listen()). You can provide a context
+// with a deadline to limit how long you want to wait before giving up and shutting down anyway.
+func (gw *Gateway) Shutdown(ctx context.Context) error {
+	return gw.server.Shutdown(ctx)
+}
+
+// ServeHTTP handles a single GraphQL request. This lets you embed the gateway in another server/mux
+// (e.g. mount it at "/graphql" on your existing web server) instead of calling Listen().
+func (gw *Gateway) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	gw.handler(w, req)
+}
+
+func (gw *Gateway) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	// Every field in the query shares the same HTTP request, so we only need to decide once
+	// whether to trust the caller's metadata.
+	ctx, err := gw.metadata.RestoreHeader(req.Context(), req.Header)
+	if err != nil {
+		gw.respondFailure(w, err)
+		return
+	}
+	req = req.WithContext(ctx)
+
+	graphqlRequest, err := gw.readRequest(w, req)
+	if err != nil {
+		gw.respondFailure(w, err)
+		return
+	}
+
+	doc, err := parseDocument(graphqlRequest.Query, gw.maxDepth)
+	if err != nil {
+		gw.respondFailure(w, err)
+		return
+	}
+	op, err := doc.operation(graphqlRequest.OperationName)
+	if err != nil {
+		gw.respondFailure(w, err)
+		return
+	}
+
+	switch {
+	case op.kind == "subscription":
+		gw.respondFailure(w, fail.NotImplemented("graphql: subscriptions are not supported"))
+		return
+	case op.kind == "mutation" && req.Method != http.MethodPost:
+		w.Header().Set("Allow", http.MethodPost)
+		gw.respondFailure(w, fail.MethodNotAllowed("graphql: mutations must use POST"))
+		return
+	}
+
+	variables, err := variableValues(op, graphqlRequest.Variables)
+	if err != nil {
+		gw.respondFailure(w, err)
+		return
+	}
+
+	ex := &executor{gw: gw, request: req, doc: doc, variables: variables}
+	if err = ex.checkDepth(op); err != nil {
+		gw.respondFailure(w, err)
+		return
+	}
+	data := ex.execute(op)
+	gw.respond(w, http.StatusOK, &response{Data: data, Errors: ex.errors})
+}
+
+// readRequest supports the standard ways to send GraphQL over HTTP; a GET w/ the query in the query
+// string, a POST w/ a JSON body, or a POST w/ an "application/graphql" body that's just the query.
+func (gw *Gateway) readRequest(w http.ResponseWriter, req *http.Request) (request, error) {
+	graphqlRequest := request{}
+
+	switch req.Method {
+	case http.MethodGet:
+		query := req.URL.Query()
+		graphqlRequest.Query = query.Get("query")
+		graphqlRequest.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := decodeJSON([]byte(variables), &graphqlRequest.Variables); err != nil {
+				return graphqlRequest, fail.BadRequest("graphql: invalid variables: %v", err)
+			}
+		}
+
+	case http.MethodPost:
+		reader := req.Body
+		if gw.maxBodySize > 0 {
+			reader = http.MaxBytesReader(w, req.Body, gw.maxBodySize)
+		}
+		body, err := io.ReadAll(reader)
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return graphqlRequest, fail.TooLarge("graphql: request body too large: limit is %d bytes", maxBytesErr.Limit)
+		}
+		if err != nil {
+			return graphqlRequest, fail.BadRequest("graphql: unable to read request: %v", err)
+		}
+		if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType == "application/graphql" {
+			graphqlRequest.Query = string(body)
+			break
+		}
+		if err = decodeJSON(body, &graphqlRequest); err != nil {
+			return graphqlRequest, fail.BadRequest("graphql: invalid request: %v", err)
+		}
+
+	default:
+		return graphqlRequest, fail.MethodNotAllowed("graphql: requests must use GET or POST")
+	}
+
+	if graphqlRequest.Query == "" {
+		return graphqlRequest, fail.BadRequest("graphql: missing query")
+	}
+	return graphqlRequest, nil
+}
+
+// context builds the context for a single field using the same rules that the API gateway's
+// standard middleware uses for metadata, trace ids, and authorization. The request's context already
+// has whatever metadata we decided to trust from the caller.
+func (gw *Gateway) context(req *http.Request, reg registration) context.Context {
+	ctx := metadata.WithRequestHeaders(req.Context(), req.Header)
+	ctx = metadata.WithRemoteAddr(ctx, req.RemoteAddr)
+	ctx = metadata.WithRoute(ctx, metadata.EndpointRoute{
+		ServiceName: reg.endpoint.ServiceName,
+		Name:        reg.endpoint.Name,
+		Type:        services.GatewayTypeGraphQL.String(),
+		Method:      req.Method,
+		Path:        req.URL.Path,
+		Status:      reg.route.Status,
+	})
+
+	if metadata.TraceID(ctx) == "" {
+		traceID := req.Header.Get("X-Request-ID")
+		if traceID == "" {
+			traceID = metadata.NewTraceID()
+		}
+		ctx = metadata.WithTraceID(ctx, traceID)
+	}
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		ctx = metadata.WithAuthorization(ctx, auth)
+	}
+	return ctx
+}
+
+// respondFailure is used when the request fails before we can execute the operation, so there's
+// no "data" in the response; just the error.
+func (gw *Gateway) respondFailure(w http.ResponseWriter, err error) {
+	gw.respond(w, fail.Status(err), &response{Errors: []*responseError{newResponseError(nil, err)}})
+}
+
+func (gw *Gateway) respond(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", gw.encoder.ContentType())
+	w.WriteHeader(status)
+	_ = gw.encoder.Encode(w, value)
+}
+
+// variableValues fills in defaults for any variables the caller didn't supply and makes sure that
+// all of the required ones are there.
+func variableValues(op *operation, supplied map[string]any) (map[string]any, error) {
+	variables := map[string]any{}
+	for _, definition := range op.variables {
+		variableValue, ok := supplied[definition.name]
+		if !ok && definition.defaultValue != nil {
+			variableValue, ok = definition.defaultValue.resolve(nil), true
+		}
+		if definition.nonNull && variableValue == nil {
+			return nil, fail.BadRequest("graphql: variable '$%s' is required", definition.name)
+		}
+		if ok {
+			variables[definition.name] = variableValue
+		}
+	}
+	return variables, nil
+}
+
+// decodeJSON unmarshals the value, keeping numbers as json.Number, so large ints in variables
+// make it to your request struct without losing precision.
+func decodeJSON(data []byte, out any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(out)
+}
+
+// GatewayOption defines a functional parameter that you can use to set up a GraphQL gateway.
+type GatewayOption func(gw *Gateway)
+
+// WithMiddleware inserts the following chain of HTTP handlers so that they fire before the gateway
+// handles the request, just like apis.WithMiddleware() does for the API gateway. Keep in mind that a
+// single query can resolve fields from several functions, so the middleware fires once for the whole query.
+func WithMiddleware(funcs ...apis.HTTPMiddlewareFunc) GatewayOption {
+	return func(gw *Gateway) {
+		gw.middleware = append(gw.middleware, funcs...)
+	}
+}
+
+// WithMaxBodySize limits the size (in bytes) of the POST bodies that the gateway will accept. Larger requests
+// fail w/ a 413. The default is DefaultMaxBodySize, and you can pass 0 to remove the limit altogether.
+func WithMaxBodySize(maxBytes int64) GatewayOption {
+	return func(gw *Gateway) {
+		gw.maxBodySize = maxBytes
+	}
+}
+
+// WithMaxDepth limits how deeply callers can nest selection sets (including fragments) and argument
+// values. Queries that are nested any deeper fail w/ a 400 before we invoke any of your functions. The
+// default is DefaultMaxDepth, and you can pass 0 to remove the limit altogether (not recommended).
+func WithMaxDepth(maxDepth int) GatewayOption {
+	return func(gw *Gateway) {
+		gw.maxDepth = maxDepth
+	}
+}
+
+// WithMetadataVerification makes the gateway verify the HMAC signature of the X-RPC-Metadata header before
+// restoring it (see metadata.VerifyConfig). When you set Reject, the whole query fails before we resolve any fields.
+func WithMetadataVerification(config metadata.VerifyConfig) GatewayOption {
+	return func(gw *Gateway) {
+		gw.metadata = &config
+	}
+}
+
+// WithUntrustedMetadata makes the gateway ignore the X-RPC-Metadata header entirely.
+func WithUntrustedMetadata() GatewayOption {
+	return WithMetadataVerification(metadata.VerifyConfig{})
+}
@@ -0,0 +1,351 @@
+//go:build integration
+
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/internal/testext"
+	gen "github.com/monadicstack/abide/internal/testext/gen"
+	"github.com/monadicstack/abide/metadata"
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/graphql"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestGatewaySuite(t *testing.T) {
+	suite.Run(t, &GatewaySuite{addresses: testext.NewFreeAddress("localhost", 20700)})
+}
+
+type GatewaySuite struct {
+	suite.Suite
+	addresses testext.FreeAddress
+}
+
+// start fires up a server whose only gateway is the GraphQL gateway. It serves both the sample
+// and other services, so we can make sure that one query can span both of them.
+func (suite *GatewaySuite) start(options ...graphql.GatewayOption) (string, *testext.Sequence, func()) {
+	address := suite.addresses.Next()
+	sequence := &testext.Sequence{}
+	server := services.NewServer(
+		services.Listen(graphql.NewGateway(address, options...)),
+		services.Register(gen.SampleServiceServer(testext.SampleServiceHandler{Sequence: sequence})),
+		services.Register(gen.OtherServiceServer(testext.OtherServiceHandler{Sequence: sequence})),
+	)
+	go func() { _ = server.Run() }()
+	time.Sleep(25 * time.Millisecond)
+
+	return "http://" + address, sequence, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}
+}
+
+// post sends the query (and optional variables) as a standard JSON GraphQL request, returning
+// the HTTP status and response body.
+func (suite *GatewaySuite) post(url string, query string, variables map[string]any, headers ...string) (int, string) {
+	body, err := json.Marshal(map[string]any{"query": query, "variables": variables})
+	suite.Require().NoError(err)
+
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(string(body)))
+	suite.Require().NoError(err)
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	return suite.do(req)
+}
+
+func (suite *GatewaySuite) do(req *http.Request) (int, string) {
+	res, err := http.DefaultClient.Do(req)
+	suite.Require().NoError(err)
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	suite.Require().NoError(err)
+	return res.StatusCode, string(resBody)
+}
+
+// Callers should only get back the fields that they asked for, in the order that they asked for them.
+func (suite *GatewaySuite) TestQuery() {
+	url, sequence, shutdown := suite.start()
+	defer shutdown()
+
+	status, body := suite.post(url, `{ SampleService_CustomRoute(ID: "123", Text: "Abide") { Text } }`, nil)
+	suite.Equal(200, status)
+	suite.Equal(`{"data":{"SampleService_CustomRoute":{"Text":"Route:Abide"}}}`, strings.TrimSpace(body))
+	suite.Equal([]string{"CustomRoute:Abide"}, sequence.Values())
+
+	status, body = suite.post(url, `query Lookup { route: SampleService_CustomRoute(ID: "123", Text: "Abide") { __typename Text ID } }`, nil)
+	suite.Equal(200, status)
+	suite.Equal(`{"data":{"route":{"__typename":"SampleResponse","Text":"Route:Abide","ID":"123"}}}`, strings.TrimSpace(body))
+}
+
+// You should be able to send queries using GET, too.
+func (suite *GatewaySuite) TestQuery_get() {
+	address, _, shutdown := suite.start()
+	defer shutdown()
+
+	query := url.Values{
+		"query":     []string{`query Lookup($id: String!) { SampleService_CustomRoute(ID: $id) { ID } }`},
+		"variables": []string{`{"id": "123"}`},
+	}
+	req, _ := http.NewRequest(http.MethodGet, address+"?"+query.Encode(), nil)
+	status, body := suite.do(req)
+	suite.Equal(200, status)
+	suite.JSONEq(`{"data":{"SampleService_CustomRoute":{"ID":"123"}}}`, body)
+
+	// Mutations have side effects, so you shouldn't be able to run them using GET.
+	query = url.Values{"query": []string{`mutation { SampleService_Defaults { ID } }`}}
+	req, _ = http.NewRequest(http.MethodGet, address+"?"+query.Encode(), nil)
+	status, _ = suite.do(req)
+	suite.Equal(405, status)
+}
+
+// A single query should be able to fetch data from multiple services at once.
+func (suite *GatewaySuite) TestMutation_multipleServices() {
+	url, sequence, shutdown := suite.start()
+	defer shutdown()
+
+	status, body := suite.post(url, `
+		mutation Everything($text: String = "Abide") {
+			SampleService_Defaults(Text: $text) { Text }
+			OtherService_SpaceOut(Text: $text) { ...otherFields }
+		}
+		fragment otherFields on OtherResponse {
+			Text
+			UniqueThing
+		}`, nil)
+	suite.Equal(200, status)
+	suite.JSONEq(`{"data":{
+		"SampleService_Defaults": {"Text":"Defaults:Abide"},
+		"OtherService_SpaceOut": {"Text":"A b i d e", "UniqueThing":false}
+	}}`, body)
+
+	// Mutations run one at a time, in order.
+	suite.Equal([]string{"Defaults:Abide", "SpaceOut:Abide"}, sequence.Values())
+}
+
+// Nested objects, custom JSON marshaling, and time values should come through the way the API gateway sends them.
+func (suite *GatewaySuite) TestComplexValues() {
+	url, _, shutdown := suite.start()
+	defer shutdown()
+
+	status, body := suite.post(url, `
+		mutation Complex($user: SampleUserInput) {
+			SampleService_ComplexValues(InUser: $user, InFlag: true, InTime: "2022-01-02T03:04:05Z") {
+				OutFlag
+				OutTime
+				OutTimePtr
+				OutUser {
+					ID
+					Digits
+					AttentionString
+					MarshalToString
+					... on SampleUser @include(if: true) { Name }
+					Age @skip(if: true)
+				}
+			}
+		}`, map[string]any{
+		"user": map[string]any{
+			"ID":              "123",
+			"Name":            "Dude",
+			"Digits":          "555-1234",
+			"AttentionString": "1m",
+			"MarshalToString": "home@example.com,work@example.com",
+		},
+	})
+	suite.Equal(200, status)
+	suite.JSONEq(`{"data":{"SampleService_ComplexValues":{
+		"OutFlag": true,
+		"OutTime": "2022-01-02T03:04:05Z",
+		"OutTimePtr": null,
+		"OutUser": {
+			"ID": "123",
+			"Digits": "555-1234",
+			"AttentionString": "1m0s",
+			"MarshalToString": "home@example.com,work@example.com",
+			"Name": "Dude"
+		}
+	}}}`, body)
+}
+
+// A failure in one field shouldn't prevent us from getting the others.
+func (suite *GatewaySuite) TestFailure() {
+	url, _, shutdown := suite.start()
+	defer shutdown()
+
+	status, body := suite.post(url, `mutation {
+		SampleService_Fail4XX { Text }
+		SampleService_Defaults(Text: "Abide") { Text }
+		SampleService_Panic { Text }
+	}`, nil)
+	suite.Equal(200, status)
+
+	res := struct {
+		Data   map[string]any
+		Errors []struct {
+			Message    string
+			Path       []any
+			Extensions struct{ Status int }
+		}
+	}{}
+	suite.Require().NoError(json.Unmarshal([]byte(body), &res))
+	suite.Nil(res.Data["SampleService_Fail4XX"])
+	suite.Nil(res.Data["SampleService_Panic"])
+	suite.Equal(map[string]any{"Text": "Defaults:Abide"}, res.Data["SampleService_Defaults"])
+
+	suite.Require().Len(res.Errors, 2)
+	suite.Equal("always a conflict", res.Errors[0].Message)
+	suite.Equal([]any{"SampleService_Fail4XX"}, res.Errors[0].Path)
+	suite.Equal(409, res.Errors[0].Extensions.Status)
+	suite.Equal([]any{"SampleService_Panic"}, res.Errors[1].Path)
+	suite.Equal(500, res.Errors[1].Extensions.Status)
+}
+
+// Selecting fields that don't exist or passing arguments that don't exist should fail the field.
+func (suite *GatewaySuite) TestInvalidFields() {
+	url, _, shutdown := suite.start()
+	defer shutdown()
+
+	_, body := suite.post(url, `{ SampleService_Nope { Text } }`, nil)
+	suite.Contains(body, `"SampleService_Nope":null`)
+	suite.Contains(body, `cannot query field 'SampleService_Nope' on type 'Query'`)
+
+	_, body = suite.post(url, `mutation { SampleService_Defaults(Text: "Abide") { Nope } }`, nil)
+	suite.Contains(body, `cannot query field 'Nope' on type 'SampleResponse'`)
+	suite.Contains(body, `"path":["SampleService_Defaults","Nope"]`)
+
+	_, body = suite.post(url, `mutation { SampleService_Defaults(Nope: "Abide") { Text } }`, nil)
+	suite.Contains(body, `"SampleService_Defaults":null`)
+	suite.Contains(body, `"Status":400`)
+
+	// Objects need a selection set since we don't know which fields you want.
+	_, body = suite.post(url, `mutation { SampleService_Defaults }`, nil)
+	suite.Contains(body, `must have a selection of subfields`)
+
+	// Raw content, streams, and HTTP OMIT functions aren't available.
+	_, body = suite.post(url, `{ SampleService_Download { Format } }`, nil)
+	suite.Contains(body, `raw content and event streams are not supported`)
+	_, body = suite.post(url, `mutation { SampleService_OmitMe { Text } }`, nil)
+	suite.Contains(body, `cannot query field 'SampleService_OmitMe' on type 'Mutation'`)
+}
+
+// Problems with the request itself should fail the whole request w/o any data.
+func (suite *GatewaySuite) TestBadRequest() {
+	url, _, shutdown := suite.start()
+	defer shutdown()
+
+	status, body := suite.post(url, `{ SampleService_CustomRoute(ID: "123" { Text } }`, nil)
+	suite.Equal(400, status)
+	suite.NotContains(body, `"data"`)
+	suite.Contains(body, `syntax error at 1:39`)
+
+	status, _ = suite.post(url, ``, nil)
+	suite.Equal(400, status)
+
+	status, body = suite.post(url, `query A { __typename } query B { __typename }`, nil)
+	suite.Equal(400, status)
+	suite.Contains(body, "operationName is required")
+
+	status, body = suite.post(url, `query A($id: String!) { SampleService_CustomRoute(ID: $id) { ID } }`, nil)
+	suite.Equal(400, status)
+	suite.Contains(body, "variable '$id' is required")
+
+	status, _ = suite.post(url, `subscription { SampleService_Progress { Text } }`, nil)
+	suite.Equal(501, status)
+}
+
+// Deeply nested queries should be rejected before they can blow up the stack, whether they're
+// nested directly in the query text or by spreading fragments inside other fragments.
+func (suite *GatewaySuite) TestMaxDepth() {
+	url, _, shutdown := suite.start(graphql.WithMaxDepth(4))
+	defer shutdown()
+
+	status, body := suite.post(url, `{ SampleService_CustomRoute(ID: "123") { ID Text } }`, nil)
+	suite.Equal(200, status, body)
+
+	status, body = suite.post(url, `{ a { b { c { d { e } } } } }`, nil)
+	suite.Equal(400, status)
+	suite.Contains(body, "maximum depth of 4")
+
+	status, body = suite.post(url, `{ SampleService_CustomRoute(ID: [[[["123"]]]]) { ID } }`, nil)
+	suite.Equal(400, status)
+	suite.Contains(body, "maximum depth of 4")
+
+	status, body = suite.post(url, `
+		query { SampleService_CustomRoute(ID: "123") { ...A } }
+		fragment A on SampleResponse { a { ...B } }
+		fragment B on SampleResponse { b { ...C } }
+		fragment C on SampleResponse { c { ...D } }
+		fragment D on SampleResponse { d { ID } }`, nil)
+	suite.Equal(400, status)
+	suite.Contains(body, "maximum depth of 4")
+
+	status, body = suite.post(url, `query { SampleService_CustomRoute(ID: "123") { ...A } } fragment A on SampleResponse { ID ...A }`, nil)
+	suite.Equal(400, status)
+	suite.Contains(body, "can not spread itself")
+
+	// Even w/ the default limit, a ridiculously deep query is just a bad request.
+	url, sequence, shutdown2 := suite.start()
+	defer shutdown2()
+
+	depth := 100_000
+	status, body = suite.post(url, strings.Repeat("{a", depth)+strings.Repeat("}", depth), nil)
+	suite.Equal(400, status)
+	suite.Contains(body, "maximum depth of 32")
+	suite.Empty(sequence.Values())
+}
+
+// Request bodies bigger than the gateway allows should be rejected w/o reading the whole thing.
+func (suite *GatewaySuite) TestMaxBodySize() {
+	url, _, shutdown := suite.start(graphql.WithMaxBodySize(100))
+	defer shutdown()
+
+	status, body := suite.post(url, `{ SampleService_CustomRoute(ID: "123") { ID } }`, nil)
+	suite.Equal(200, status, body)
+
+	status, body = suite.post(url, `{ SampleService_CustomRoute(ID: "`+strings.Repeat("1", 100)+`") { ID } }`, nil)
+	suite.Equal(413, status)
+	suite.Contains(body, "limit is 100 bytes")
+}
+
+// Only metadata signed by one of our own services should be trusted when the gateway verifies it.
+func (suite *GatewaySuite) TestMetadataVerification() {
+	key := []byte("the dude abides")
+	encoded := metadata.EncodedBytes(`{"Authorization":"The Dude Abides"}`)
+	query := `mutation { SampleService_Authorization { Text } }`
+
+	url, _, shutdown := suite.start(graphql.WithMetadataVerification(metadata.VerifyConfig{Keys: [][]byte{key}}))
+	defer shutdown()
+
+	signature := metadata.Sign(encoded, key, time.Now())
+	_, body := suite.post(url, query, nil, metadata.Header, string(encoded), metadata.SignatureHeader, signature)
+	suite.JSONEq(`{"data":{"SampleService_Authorization":{"Text":"The Dude Abides"}}}`, body)
+
+	_, body = suite.post(url, query, nil, metadata.Header, string(encoded), metadata.SignatureHeader, "garbage")
+	suite.JSONEq(`{"data":{"SampleService_Authorization":{"Text":""}}}`, body)
+
+	url, _, shutdown2 := suite.start(graphql.WithMetadataVerification(metadata.VerifyConfig{Keys: [][]byte{key}, Reject: true}))
+	defer shutdown2()
+
+	status, _ := suite.post(url, query, nil, metadata.Header, string(encoded), metadata.SignatureHeader, "garbage")
+	suite.Equal(401, status)
+}
+
+// The Authorization header should make it onto the context just like the API gateway.
+func (suite *GatewaySuite) TestAuthorization() {
+	url, _, shutdown := suite.start()
+	defer shutdown()
+
+	_, body := suite.post(url, `mutation { SampleService_Authorization { Text } }`, nil, "Authorization", "The Dude Abides")
+	suite.JSONEq(`{"data":{"SampleService_Authorization":{"Text":"The Dude Abides"}}}`, body)
+}
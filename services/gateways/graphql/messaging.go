@@ -0,0 +1,40 @@
+package graphql
+
+import (
+	"github.com/monadicstack/abide/fail"
+)
+
+// request is the standard GraphQL-over-HTTP request. It's the JSON body of POST requests and the
+// query string of GET requests.
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// response is the standard GraphQL response. Data is omitted when the request failed before we
+// could run the operation at all (e.g. a syntax error).
+type response struct {
+	Data   any              `json:"data,omitempty"`
+	Errors []*responseError `json:"errors,omitempty"`
+}
+
+// responseError describes a single failure. Extensions includes the HTTP-style status of the error,
+// so you can tell a 404 from a 403 just like you can with the API gateway.
+type responseError struct {
+	Message    string          `json:"message"`
+	Path       []any           `json:"path,omitempty"`
+	Extensions errorExtensions `json:"extensions"`
+}
+
+type errorExtensions struct {
+	Status int `json:"Status"`
+}
+
+func newResponseError(path []any, err error) *responseError {
+	return &responseError{
+		Message:    err.Error(),
+		Path:       path,
+		Extensions: errorExtensions{Status: fail.Status(err)},
+	}
+}
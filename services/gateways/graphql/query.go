@@ -0,0 +1,861 @@
+package graphql
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/monadicstack/abide/fail"
+)
+
+// document is the parsed version of the query text that the caller sent us.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+// operation is a single "query { ... }" or "mutation { ... }" in the document.
+type operation struct {
+	kind       string
+	name       string
+	variables  []*variableDefinition
+	directives []*directive
+	selections []*selection
+}
+
+// variableDefinition describes one of the "$name: Type = default" values that the operation accepts.
+type variableDefinition struct {
+	name         string
+	nonNull      bool
+	defaultValue *value
+}
+
+// fragment is a named, reusable set of selections (e.g. "fragment userFields on User { ID Name }").
+type fragment struct {
+	name          string
+	typeCondition string
+	selections    []*selection
+}
+
+// selection is a single entry in a selection set. It's either a field, a fragment spread ("...userFields"),
+// or an inline fragment ("... on User { ID }"). The directives are only used by fragments; fields have their own.
+type selection struct {
+	field         *field
+	spread        string
+	inline        []*selection
+	typeCondition string
+	directives    []*directive
+}
+
+// field is a request for one of the fields on a type, including the Query/Mutation root types.
+type field struct {
+	alias      string
+	name       string
+	arguments  []*argument
+	directives []*directive
+	selections []*selection
+}
+
+// responseKey is the name that the field's value should have in the response.
+func (f field) responseKey() string {
+	if f.alias != "" {
+		return f.alias
+	}
+	return f.name
+}
+
+type directive struct {
+	name      string
+	arguments []*argument
+}
+
+type argument struct {
+	name  string
+	value *value
+}
+
+type valueKind int
+
+const (
+	valueVariable valueKind = iota
+	valueInt
+	valueFloat
+	valueString
+	valueBoolean
+	valueNull
+	valueEnum
+	valueList
+	valueObject
+)
+
+// value is a literal (or variable reference) that appears in an argument or variable default.
+type value struct {
+	kind   valueKind
+	raw    string
+	list   []*value
+	object []*argument
+}
+
+// resolve converts the literal to the equivalent JSON-friendly Go value, using the operation's variables
+// to fill in any "$name" references.
+func (v *value) resolve(variables map[string]any) any {
+	switch v.kind {
+	case valueVariable:
+		return variables[v.raw]
+	case valueInt, valueFloat:
+		return json.Number(v.raw)
+	case valueString, valueEnum:
+		return v.raw
+	case valueBoolean:
+		return v.raw == "true"
+	case valueList:
+		results := make([]any, len(v.list))
+		for i, item := range v.list {
+			results[i] = item.resolve(variables)
+		}
+		return results
+	case valueObject:
+		results := make(map[string]any, len(v.object))
+		for _, field := range v.object {
+			results[field.name] = field.value.resolve(variables)
+		}
+		return results
+	default:
+		return nil
+	}
+}
+
+// parseDocument parses the query text that the caller sent us. We don't have a schema at runtime, so
+// the only validation we do is that the document is well-formed and that fragments actually exist. Selection
+// sets, list/object values, and type references can't be nested more than 'maxDepth' levels deep, so
+// a malicious query like "{a{a{a{a..." can't blow up the stack.
+func parseDocument(query string, maxDepth int) (*document, error) {
+	p := &queryParser{lexer: queryLexer{input: query, line: 1, column: 1}, maxDepth: maxDepth}
+	doc, err := p.parseDocument()
+	if err != nil {
+		return nil, err
+	}
+	if err = doc.validateFragments(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// validateFragments makes sure that every fragment spread refers to a fragment that actually exists.
+func (doc *document) validateFragments() error {
+	var check func(selections []*selection) error
+	check = func(selections []*selection) error {
+		for _, sel := range selections {
+			switch {
+			case sel.field != nil:
+				if err := check(sel.field.selections); err != nil {
+					return err
+				}
+			case sel.spread != "":
+				if _, ok := doc.fragments[sel.spread]; !ok {
+					return fail.BadRequest("graphql: unknown fragment '%s'", sel.spread)
+				}
+			default:
+				if err := check(sel.inline); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for _, op := range doc.operations {
+		if err := check(op.selections); err != nil {
+			return err
+		}
+	}
+	for _, frag := range doc.fragments {
+		if err := check(frag.selections); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// operation finds the operation that we should execute. You only need to supply the name when the
+// document contains more than one operation.
+func (doc *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(doc.operations) != 1 {
+			return nil, fail.BadRequest("graphql: operationName is required when the query contains %d operations", len(doc.operations))
+		}
+		return doc.operations[0], nil
+	}
+	for _, op := range doc.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, fail.BadRequest("graphql: unknown operation '%s'", name)
+}
+
+type queryParser struct {
+	lexer    queryLexer
+	token    token
+	depth    int
+	maxDepth int
+}
+
+// descend is called when we enter a nested structure (selection set, list, etc.). Make sure that you
+// call ascend() when you're done parsing it. This fails when the query is nested too deeply.
+func (p *queryParser) descend() error {
+	p.depth++
+	if p.maxDepth > 0 && p.depth > p.maxDepth {
+		return fail.BadRequest("graphql: syntax error at %d:%d: query exceeds the maximum depth of %d", p.token.line, p.token.column, p.maxDepth)
+	}
+	return nil
+}
+
+// ascend is called when we're done parsing a nested structure that we entered using descend().
+func (p *queryParser) ascend() {
+	p.depth--
+}
+
+func (p *queryParser) parseDocument() (*document, error) {
+	doc := &document{fragments: map[string]*fragment{}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.token.kind == tokenEOF {
+		return nil, fail.BadRequest("graphql: query is empty")
+	}
+
+	for p.token.kind != tokenEOF {
+		switch {
+		case p.peek("{"):
+			// The shorthand for a query: "{ UserService_GetByID(ID: "123") { Name } }"
+			selections, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: selections})
+
+		case p.peekName("query"), p.peekName("mutation"), p.peekName("subscription"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+
+		case p.peekName("fragment"):
+			frag, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := doc.fragments[frag.name]; ok {
+				return nil, fail.BadRequest("graphql: duplicate fragment '%s'", frag.name)
+			}
+			doc.fragments[frag.name] = frag
+
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	if len(doc.operations) == 0 {
+		return nil, fail.BadRequest("graphql: query does not contain any operations")
+	}
+	return doc, nil
+}
+
+func (p *queryParser) parseOperation() (*operation, error) {
+	op := &operation{kind: p.token.text}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	if p.token.kind == tokenName {
+		if op.name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		if op.variables, err = p.parseVariableDefinitions(); err != nil {
+			return nil, err
+		}
+	}
+	if op.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if op.selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return op, nil
+}
+
+func (p *queryParser) parseVariableDefinitions() ([]*variableDefinition, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	var definitions []*variableDefinition
+	for !p.peek(")") {
+		if err := p.expect("$"); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+
+		definition := &variableDefinition{name: name}
+		if definition.nonNull, err = p.parseType(); err != nil {
+			return nil, err
+		}
+		if p.peek("=") {
+			if err = p.next(); err != nil {
+				return nil, err
+			}
+			if definition.defaultValue, err = p.parseValue(true); err != nil {
+				return nil, err
+			}
+		}
+		if _, err = p.parseDirectives(); err != nil {
+			return nil, err
+		}
+		definitions = append(definitions, definition)
+	}
+	return definitions, p.expect(")")
+}
+
+// parseType consumes a type reference like "String", "[String!]", or "UserInput!". We don't have a
+// schema to check it against, so we only care about whether the variable is required.
+func (p *queryParser) parseType() (nonNull bool, err error) {
+	if err = p.descend(); err != nil {
+		return false, err
+	}
+	defer p.ascend()
+
+	if p.peek("[") {
+		if err = p.next(); err != nil {
+			return false, err
+		}
+		if _, err = p.parseType(); err != nil {
+			return false, err
+		}
+		if err = p.expect("]"); err != nil {
+			return false, err
+		}
+	} else if _, err = p.expectName(); err != nil {
+		return false, err
+	}
+
+	if p.peek("!") {
+		return true, p.next()
+	}
+	return false, nil
+}
+
+func (p *queryParser) parseFragment() (*fragment, error) {
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	frag := &fragment{}
+	if frag.name, err = p.expectName(); err != nil {
+		return nil, err
+	}
+	if frag.name == "on" {
+		return nil, fail.BadRequest("graphql: fragments can not be named 'on'")
+	}
+	if !p.peekName("on") {
+		return nil, p.unexpected()
+	}
+	if err = p.next(); err != nil {
+		return nil, err
+	}
+	if frag.typeCondition, err = p.expectName(); err != nil {
+		return nil, err
+	}
+	if _, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if frag.selections, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return frag, nil
+}
+
+func (p *queryParser) parseSelectionSet() ([]*selection, error) {
+	if err := p.descend(); err != nil {
+		return nil, err
+	}
+	defer p.ascend()
+
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	var selections []*selection
+	for !p.peek("}") {
+		sel, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, sel)
+	}
+	if len(selections) == 0 {
+		return nil, fail.BadRequest("graphql: selection sets can not be empty")
+	}
+	return selections, p.expect("}")
+}
+
+func (p *queryParser) parseSelection() (*selection, error) {
+	var err error
+	sel := &selection{}
+
+	if !p.peek("...") {
+		if sel.field, err = p.parseField(); err != nil {
+			return nil, err
+		}
+		return sel, nil
+	}
+
+	if err = p.next(); err != nil {
+		return nil, err
+	}
+
+	// A fragment spread looks like "...userFields" whereas an inline fragment looks
+	// like "... on User { ID }" or just "... @include(if: $foo) { ID }".
+	if p.token.kind == tokenName && !p.peekName("on") {
+		if sel.spread, err = p.expectName(); err != nil {
+			return nil, err
+		}
+		if sel.directives, err = p.parseDirectives(); err != nil {
+			return nil, err
+		}
+		return sel, nil
+	}
+
+	if p.peekName("on") {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		if sel.typeCondition, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+	if sel.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if sel.inline, err = p.parseSelectionSet(); err != nil {
+		return nil, err
+	}
+	return sel, nil
+}
+
+func (p *queryParser) parseField() (*field, error) {
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &field{name: name}
+	if p.peek(":") {
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		f.alias = name
+		if f.name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		if f.arguments, err = p.parseArguments(false); err != nil {
+			return nil, err
+		}
+	}
+
+	if f.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if f.selections, err = p.parseSelectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+func (p *queryParser) parseArguments(constant bool) ([]*argument, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	var arguments []*argument
+	for !p.peek(")") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(":"); err != nil {
+			return nil, err
+		}
+		val, err := p.parseValue(constant)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, &argument{name: name, value: val})
+	}
+	return arguments, p.expect(")")
+}
+
+func (p *queryParser) parseDirectives() ([]*directive, error) {
+	var directives []*directive
+	for p.peek("@") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+
+		d := &directive{name: name}
+		if p.peek("(") {
+			if d.arguments, err = p.parseArguments(false); err != nil {
+				return nil, err
+			}
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+// parseValue parses an argument value. When 'constant' is true (e.g. variable defaults) the value
+// is not allowed to refer to other variables.
+func (p *queryParser) parseValue(constant bool) (*value, error) {
+	if err := p.descend(); err != nil {
+		return nil, err
+	}
+	defer p.ascend()
+
+	tok := p.token
+	switch {
+	case tok.kind == tokenPunctuator && tok.text == "$" && !constant:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		return &value{kind: valueVariable, raw: name}, err
+
+	case tok.kind == tokenPunctuator && tok.text == "[":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		list := &value{kind: valueList, list: []*value{}}
+		for !p.peek("]") {
+			item, err := p.parseValue(constant)
+			if err != nil {
+				return nil, err
+			}
+			list.list = append(list.list, item)
+		}
+		return list, p.expect("]")
+
+	case tok.kind == tokenPunctuator && tok.text == "{":
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		object := &value{kind: valueObject}
+		for !p.peek("}") {
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if err = p.expect(":"); err != nil {
+				return nil, err
+			}
+			fieldValue, err := p.parseValue(constant)
+			if err != nil {
+				return nil, err
+			}
+			object.object = append(object.object, &argument{name: name, value: fieldValue})
+		}
+		return object, p.expect("}")
+
+	case tok.kind == tokenInt:
+		return &value{kind: valueInt, raw: tok.text}, p.next()
+	case tok.kind == tokenFloat:
+		return &value{kind: valueFloat, raw: tok.text}, p.next()
+	case tok.kind == tokenString:
+		return &value{kind: valueString, raw: tok.text}, p.next()
+	case tok.kind == tokenName && (tok.text == "true" || tok.text == "false"):
+		return &value{kind: valueBoolean, raw: tok.text}, p.next()
+	case tok.kind == tokenName && tok.text == "null":
+		return &value{kind: valueNull}, p.next()
+	case tok.kind == tokenName:
+		return &value{kind: valueEnum, raw: tok.text}, p.next()
+	default:
+		return nil, p.unexpected()
+	}
+}
+
+func (p *queryParser) next() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.token = tok
+	return nil
+}
+
+func (p *queryParser) peek(punctuator string) bool {
+	return p.token.kind == tokenPunctuator && p.token.text == punctuator
+}
+
+func (p *queryParser) peekName(name string) bool {
+	return p.token.kind == tokenName && p.token.text == name
+}
+
+func (p *queryParser) expect(punctuator string) error {
+	if !p.peek(punctuator) {
+		return p.unexpected()
+	}
+	return p.next()
+}
+
+func (p *queryParser) expectName() (string, error) {
+	if p.token.kind != tokenName {
+		return "", p.unexpected()
+	}
+	name := p.token.text
+	return name, p.next()
+}
+
+func (p *queryParser) unexpected() error {
+	if p.token.kind == tokenEOF {
+		return fail.BadRequest("graphql: syntax error at %d:%d: unexpected end of query", p.token.line, p.token.column)
+	}
+	return fail.BadRequest("graphql: syntax error at %d:%d: unexpected '%s'", p.token.line, p.token.column, p.token.text)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	line   int
+	column int
+}
+
+// byteOrderMark is ignored just like whitespace is.
+const byteOrderMark = "\uFEFF"
+
+// queryLexer breaks the query text up into tokens as described in the "Lexical Tokens" section of the spec.
+type queryLexer struct {
+	input  string
+	offset int
+	line   int
+	column int
+}
+
+func (lex *queryLexer) next() (token, error) {
+	lex.skipIgnored()
+	tok := token{line: lex.line, column: lex.column}
+	if lex.offset >= len(lex.input) {
+		return tok, nil
+	}
+
+	ch := lex.input[lex.offset]
+	switch {
+	case strings.HasPrefix(lex.input[lex.offset:], "..."):
+		lex.advance(3)
+		tok.kind, tok.text = tokenPunctuator, "..."
+	case strings.IndexByte("!$&()=:@[]{}|", ch) >= 0:
+		lex.advance(1)
+		tok.kind, tok.text = tokenPunctuator, string(ch)
+	case ch == '_' || isLetter(ch):
+		start := lex.offset
+		for lex.offset < len(lex.input) && isNameChar(lex.input[lex.offset]) {
+			lex.advance(1)
+		}
+		tok.kind, tok.text = tokenName, lex.input[start:lex.offset]
+	case ch == '-' || isDigit(ch):
+		return lex.number(tok)
+	case strings.HasPrefix(lex.input[lex.offset:], `"""`):
+		return lex.blockString(tok)
+	case ch == '"':
+		return lex.string(tok)
+	default:
+		r, _ := utf8.DecodeRuneInString(lex.input[lex.offset:])
+		return tok, fail.BadRequest("graphql: syntax error at %d:%d: unexpected character '%c'", lex.line, lex.column, r)
+	}
+	return tok, nil
+}
+
+// skipIgnored moves past whitespace, commas, and comments. None of those mean anything in GraphQL.
+func (lex *queryLexer) skipIgnored() {
+	for lex.offset < len(lex.input) {
+		switch ch := lex.input[lex.offset]; {
+		case ch == '#':
+			for lex.offset < len(lex.input) && lex.input[lex.offset] != '\n' && lex.input[lex.offset] != '\r' {
+				lex.advance(1)
+			}
+		case ch == ' ' || ch == '\t' || ch == ',' || ch == '\n' || ch == '\r':
+			lex.advance(1)
+		case strings.HasPrefix(lex.input[lex.offset:], byteOrderMark):
+			lex.advance(len(byteOrderMark))
+		default:
+			return
+		}
+	}
+}
+
+func (lex *queryLexer) number(tok token) (token, error) {
+	start := lex.offset
+	if lex.input[lex.offset] == '-' {
+		lex.advance(1)
+	}
+	lex.digits()
+
+	tok.kind = tokenInt
+	if lex.offset < len(lex.input) && lex.input[lex.offset] == '.' {
+		tok.kind = tokenFloat
+		lex.advance(1)
+		lex.digits()
+	}
+	if lex.offset < len(lex.input) && (lex.input[lex.offset] == 'e' || lex.input[lex.offset] == 'E') {
+		tok.kind = tokenFloat
+		lex.advance(1)
+		if lex.offset < len(lex.input) && (lex.input[lex.offset] == '+' || lex.input[lex.offset] == '-') {
+			lex.advance(1)
+		}
+		lex.digits()
+	}
+
+	tok.text = lex.input[start:lex.offset]
+	if _, err := strconv.ParseFloat(tok.text, 64); err != nil {
+		return tok, fail.BadRequest("graphql: syntax error at %d:%d: invalid number '%s'", tok.line, tok.column, tok.text)
+	}
+	return tok, nil
+}
+
+func (lex *queryLexer) digits() {
+	for lex.offset < len(lex.input) && isDigit(lex.input[lex.offset]) {
+		lex.advance(1)
+	}
+}
+
+// string reads a quoted string value. The escape sequences are the same as JSON's, so we let the
+// JSON decoder do the heavy lifting once we've found the closing quote.
+func (lex *queryLexer) string(tok token) (token, error) {
+	start := lex.offset
+	lex.advance(1)
+	for lex.offset < len(lex.input) {
+		switch lex.input[lex.offset] {
+		case '\\':
+			lex.advance(2)
+		case '"':
+			lex.advance(1)
+			tok.kind = tokenString
+			if err := json.Unmarshal([]byte(lex.input[start:lex.offset]), &tok.text); err != nil {
+				return tok, fail.BadRequest("graphql: syntax error at %d:%d: invalid string", tok.line, tok.column)
+			}
+			return tok, nil
+		case '\n', '\r':
+			return tok, fail.BadRequest("graphql: syntax error at %d:%d: unterminated string", tok.line, tok.column)
+		default:
+			lex.advance(1)
+		}
+	}
+	return tok, fail.BadRequest("graphql: syntax error at %d:%d: unterminated string", tok.line, tok.column)
+}
+
+// blockString reads a triple-quoted string, removing the common indentation the way the spec says to.
+func (lex *queryLexer) blockString(tok token) (token, error) {
+	lex.advance(3)
+	raw := strings.Builder{}
+	for lex.offset < len(lex.input) {
+		switch {
+		case strings.HasPrefix(lex.input[lex.offset:], `\"""`):
+			raw.WriteString(`"""`)
+			lex.advance(4)
+		case strings.HasPrefix(lex.input[lex.offset:], `"""`):
+			lex.advance(3)
+			tok.kind, tok.text = tokenString, blockStringValue(raw.String())
+			return tok, nil
+		default:
+			raw.WriteByte(lex.input[lex.offset])
+			lex.advance(1)
+		}
+	}
+	return tok, fail.BadRequest("graphql: syntax error at %d:%d: unterminated string", tok.line, tok.column)
+}
+
+// advance moves the lexer forward n bytes, keeping track of the line/column so errors can point you to
+// the right spot in your query.
+func (lex *queryLexer) advance(n int) {
+	for i := 0; i < n && lex.offset < len(lex.input); i++ {
+		if lex.input[lex.offset] == '\n' {
+			lex.line++
+			lex.column = 0
+		}
+		lex.offset++
+		lex.column++
+	}
+}
+
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if lineIndent := len(line) - len(trimmed); indent < 0 || lineIndent < indent {
+			indent = lineIndent
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isLetter(ch byte) bool {
+	return (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isNameChar(ch byte) bool {
+	return ch == '_' || isLetter(ch) || isDigit(ch)
+}
@@ -0,0 +1,314 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/monadicstack/abide/codec"
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/metadata"
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/apis"
+)
+
+// NewGateway creates a gateway that serves your service functions as JSON messages using gRPC's framing, paths,
+// and status codes. It is NOT a general-purpose gRPC server: it exists so that infrastructure that understands
+// gRPC (load balancers, service meshes, etc.) can route calls between Abide services. Each function is
+// available at the path "/Service/Function" (e.g. "/UserService/GetByID"):
+//
+//	server := services.NewServer(
+//		services.Listen(apis.NewGateway(":9000")),
+//		services.Listen(grpc.NewGateway(":9001", grpc.WithTLSFiles("cert.pem", "key.pem"))),
+//		services.Register(gen.UserServiceServer(userService)),
+//	)
+//
+// Abide services don't have protobuf schemas, so the only content type is "application/grpc+json". Protobuf
+// clients that send "application/grpc" or "application/grpc+proto" get an UNIMPLEMENTED status. Your callers
+// should use NewTransport() or a gRPC client that you've configured with a JSON codec.
+//
+// HTTP/2 is only available over TLS; the gateway does not support cleartext HTTP/2 ("h2c"). Supply one of
+// the TLS options unless the only callers are Abide clients using NewTransport(), or put a proxy that
+// terminates h2c in front of the gateway.
+func NewGateway(address string, options ...GatewayOption) *Gateway {
+	gw := &Gateway{
+		encoder:    codec.JSONEncoder{},
+		decoder:    codec.JSONDecoder{},
+		endpoints:  map[string]registration{},
+		maxMessage: maxMessageSize,
+	}
+	gw.server = &http.Server{Addr: address, Handler: gw}
+	for _, option := range options {
+		option(gw)
+	}
+
+	gw.handler = gw.middleware.Then(gw.serveHTTP)
+	return gw
+}
+
+// Gateway encapsulates the HTTP/2 server that accepts gRPC requests as well as the logic to invoke
+// service functions based on them. You should not create one of these yourself - use the NewGateway()
+// constructor instead.
+type Gateway struct {
+	server     *http.Server
+	tlsCert    string
+	tlsKey     string
+	encoder    codec.Encoder
+	decoder    codec.Decoder
+	middleware apis.HTTPMiddlewareFuncs
+	handler    http.HandlerFunc
+	endpoints  map[string]registration
+	metadata   *metadata.VerifyConfig
+	maxMessage int64
+}
+
+// registration is the endpoint behind a single gRPC method. We hang onto the API route that it came
+// from, so the route metadata has the same status that the API gateway would report.
+type registration struct {
+	endpoint services.Endpoint
+	route    services.EndpointRoute
+}
+
+// Type returns "GRPC" to indicate the tagging value for this gateway.
+func (gw *Gateway) Type() services.GatewayType {
+	return services.GatewayTypeGRPC
+}
+
+// Register makes the endpoint available at the gRPC path "/Service/Function". This gateway serves the
+// same functions as the API gateway, so it only cares about API routes. You will not invoke this
+// yourself! The services.Server will utilize this as necessary.
+func (gw *Gateway) Register(endpoint services.Endpoint, route services.EndpointRoute) {
+	if route.GatewayType != services.GatewayTypeAPI {
+		return
+	}
+
+	// An endpoint can have multiple API routes, but it's still just one gRPC method to us.
+	path := "/" + endpoint.ServiceName + "/" + endpoint.Name
+	if _, ok := gw.endpoints[path]; ok {
+		return
+	}
+	gw.endpoints[path] = registration{endpoint: endpoint, route: route}
+}
+
+// Listen fires up the underlying HTTP/2 server and blocks until the gateway shuts down. When the gateway
+// shuts down gracefully, this will return nil instead of http.ErrServerClosed.
+func (gw *Gateway) Listen() error {
+	var err error
+	switch {
+	case gw.UseTLS():
+		err = gw.server.ListenAndServeTLS(gw.tlsCert, gw.tlsKey)
+	default:
+		err = gw.server.ListenAndServe()
+	}
+
+	switch err {
+	case nil, http.ErrServerClosed:
+		return nil
+	default:
+		return fmt.Errorf("grpc gateway error: %w", err)
+	}
+}
+
+// Shutdown attempts to gracefully shut down the HTTP/2 server. It will wait for any in-progress
+// requests to finish and then shut down (unblocking Listen()). You can provide a context
+// with a deadline to limit how long you want to wait before giving up and shutting down anyway.
+func (gw *Gateway) Shutdown(ctx context.Context) error {
+	return gw.server.Shutdown(ctx)
+}
+
+// UseTLS returns true when you supplied TLS files/config, so the gateway serves HTTP/2 over TLS.
+func (gw *Gateway) UseTLS() bool {
+	return gw.tlsCert != "" || gw.tlsKey != "" || gw.server.TLSConfig != nil
+}
+
+// ServeHTTP handles a single gRPC call. This lets you embed the gateway in another HTTP/2 server/mux
+// instead of calling Listen().
+func (gw *Gateway) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	gw.handler(w, req)
+}
+
+func (gw *Gateway) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "grpc: requests must use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// We can only reply using the gRPC protocol if the caller speaks it. Once we know that they do,
+	// all failures are reported using the gRPC status rather than the HTTP status.
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if !strings.HasPrefix(mediaType, "application/grpc") {
+		http.Error(w, "grpc: unsupported content type: "+mediaType, http.StatusUnsupportedMediaType)
+		return
+	}
+	if mediaType != contentType {
+		gw.respondFailure(w, codeUnimplemented, fail.NotImplemented("grpc: unsupported content type '%s'; use '%s'", mediaType, contentType))
+		return
+	}
+
+	reg, ok := gw.endpoints[req.URL.Path]
+	if !ok {
+		gw.respondFailure(w, codeUnimplemented, fail.NotImplemented("grpc: unknown method: '%s'", req.URL.Path))
+		return
+	}
+
+	ctx, err := gw.metadata.RestoreHeader(req.Context(), req.Header)
+	if err != nil {
+		gw.respondFailure(w, codeForStatus(fail.Status(err)), err)
+		return
+	}
+
+	ctx = gw.context(ctx, req, reg)
+	if timeoutValue := req.Header.Get(headerTimeout); timeoutValue != "" {
+		timeout, err := decodeTimeout(timeoutValue)
+		if err != nil {
+			gw.respondFailure(w, codeInvalidArgument, fail.BadRequest("grpc: %v", err))
+			return
+		}
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// The endpoint's "MAX BODY xxx" doc option trumps the gateway-wide limit.
+	maxSize := gw.maxMessage
+	if reg.endpoint.MaxBodySize > 0 {
+		maxSize = reg.endpoint.MaxBodySize
+	}
+	message, err := readMessage(req.Body, req.Header.Get(headerEncoding), maxSize)
+	if err != nil {
+		gw.respondFailure(w, codeForStatus(fail.Status(err)), err)
+		return
+	}
+
+	serviceRequest := reg.endpoint.NewInput()
+	if err = gw.decoder.Decode(bytes.NewReader(message), serviceRequest); err != nil {
+		gw.respondFailure(w, codeInvalidArgument, fail.BadRequest("grpc: unable to decode request: %v", err))
+		return
+	}
+
+	serviceResponse, err := gw.invoke(ctx, reg, serviceRequest)
+	if err != nil {
+		gw.respondFailure(w, codeForStatus(fail.Status(err)), err)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if err = gw.encoder.Encode(buf, serviceResponse); err != nil {
+		gw.respondFailure(w, codeInternal, fail.Unexpected("grpc: unable to encode response: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Trailer", headerStatus+", "+headerMessage)
+	w.WriteHeader(http.StatusOK)
+	if err = writeMessage(w, bytes.TrimSpace(buf.Bytes())); err != nil {
+		return
+	}
+	w.Header().Set(headerStatus, strconv.Itoa(codeOK))
+}
+
+// invoke runs the service function, turning any panics into unexpected errors.
+func (gw *Gateway) invoke(ctx context.Context, reg registration, serviceRequest any) (serviceResponse any, err error) {
+	defer func() {
+		if recovery := recover(); recovery != nil {
+			err = fail.Unexpected("%v", recovery)
+		}
+	}()
+	return reg.endpoint.Handler(ctx, serviceRequest)
+}
+
+// respondFailure sends a "Trailers-Only" response; the gRPC status goes in the headers and there's no body.
+func (gw *Gateway) respondFailure(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set(headerStatus, strconv.Itoa(code))
+	w.Header().Set(headerMessage, encodeStatusMessage(err.Error()))
+	w.Header().Set(headerAbideStatus, strconv.Itoa(fail.Status(err)))
+	w.WriteHeader(http.StatusOK)
+}
+
+// context builds the context for a single call using the same rules that the API gateway's standard
+// middleware uses for metadata, trace ids, and authorization. gRPC metadata is just HTTP/2 headers,
+// so callers supply them exactly the same way. The given context already has whatever metadata we
+// decided to trust from the caller's X-RPC-Metadata header.
+func (gw *Gateway) context(ctx context.Context, req *http.Request, reg registration) context.Context {
+	ctx = metadata.WithRequestHeaders(ctx, req.Header)
+	ctx = metadata.WithRemoteAddr(ctx, req.RemoteAddr)
+	ctx = metadata.WithRoute(ctx, metadata.EndpointRoute{
+		ServiceName: reg.endpoint.ServiceName,
+		Name:        reg.endpoint.Name,
+		Type:        services.GatewayTypeGRPC.String(),
+		Method:      req.Method,
+		Path:        req.URL.Path,
+		Status:      reg.route.Status,
+	})
+
+	if metadata.TraceID(ctx) == "" {
+		traceID := req.Header.Get("X-Request-ID")
+		if traceID == "" {
+			traceID = metadata.NewTraceID()
+		}
+		ctx = metadata.WithTraceID(ctx, traceID)
+	}
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		ctx = metadata.WithAuthorization(ctx, auth)
+	}
+	return ctx
+}
+
+// GatewayOption defines a functional parameter that you can use to set up a gRPC gateway.
+type GatewayOption func(gw *Gateway)
+
+// WithMiddleware inserts the following chain of HTTP handlers so that they fire before the actual
+// handler for your service function, just like apis.WithMiddleware() does for the API gateway.
+func WithMiddleware(funcs ...apis.HTTPMiddlewareFunc) GatewayOption {
+	return func(gw *Gateway) {
+		gw.middleware = append(gw.middleware, funcs...)
+	}
+}
+
+// WithMetadataVerification makes the gateway verify the HMAC signature of the X-RPC-Metadata header before
+// restoring it (see metadata.VerifyConfig). Rejected calls fail w/ an UNAUTHENTICATED status.
+func WithMetadataVerification(config metadata.VerifyConfig) GatewayOption {
+	return func(gw *Gateway) {
+		gw.metadata = &config
+	}
+}
+
+// WithUntrustedMetadata makes the gateway ignore the X-RPC-Metadata header entirely.
+func WithUntrustedMetadata() GatewayOption {
+	return WithMetadataVerification(metadata.VerifyConfig{})
+}
+
+// WithMaxMessageSize limits the size (in bytes) of the request messages that the gateway will accept. Larger
+// messages fail w/ a RESOURCE_EXHAUSTED status. Individual endpoints can use the "MAX BODY 5MB" doc option to
+// override this limit. The default is 4MB, which matches most other gRPC implementations.
+func WithMaxMessageSize(maxBytes int64) GatewayOption {
+	return func(gw *Gateway) {
+		if maxBytes > 0 {
+			gw.maxMessage = maxBytes
+		}
+	}
+}
+
+// WithTLSConfig allows the gateway's underlying server to handle HTTP/2 requests over TLS using
+// the configuration you provide.
+func WithTLSConfig(config *tls.Config) GatewayOption {
+	return func(gw *Gateway) {
+		gw.server.TLSConfig = config
+	}
+}
+
+// WithTLSFiles allows the gateway's underlying server to handle HTTP/2 requests over TLS using
+// the cert/key files provided.
+func WithTLSFiles(certFile string, keyFile string) GatewayOption {
+	return func(gw *Gateway) {
+		gw.tlsCert = certFile
+		gw.tlsKey = keyFile
+	}
+}
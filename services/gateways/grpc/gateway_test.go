@@ -0,0 +1,300 @@
+//go:build integration
+
+package grpc_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/internal/testext"
+	gen "github.com/monadicstack/abide/internal/testext/gen"
+	"github.com/monadicstack/abide/metadata"
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/clients"
+	"github.com/monadicstack/abide/services/gateways/grpc"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestGatewaySuite(t *testing.T) {
+	suite.Run(t, &GatewaySuite{addresses: testext.NewFreeAddress("localhost", 20600)})
+}
+
+type GatewaySuite struct {
+	suite.Suite
+	addresses testext.FreeAddress
+}
+
+// start fires up a server whose only gateway is the gRPC gateway. It uses plain HTTP/1.1, which
+// is fine for our own transport.
+func (suite *GatewaySuite) start() (testext.SampleService, func()) {
+	return suite.startWith(nil)
+}
+
+// startWith is just like start, but you can customize the gateway and the client that calls it.
+func (suite *GatewaySuite) startWith(options []grpc.GatewayOption, clientOptions ...clients.ClientOption) (testext.SampleService, func()) {
+	address := suite.addresses.Next()
+	server := services.NewServer(
+		services.Listen(grpc.NewGateway(address, options...)),
+		services.Register(gen.SampleServiceServer(testext.SampleServiceHandler{Sequence: &testext.Sequence{}})),
+	)
+	go func() { _ = server.Run() }()
+	time.Sleep(25 * time.Millisecond)
+
+	clientOptions = append(clientOptions, clients.WithTransport(grpc.NewTransport(address)))
+	client := gen.SampleServiceClient("", clientOptions...)
+	return client, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}
+}
+
+// startTLS serves the gateway over HTTP/2 w/ TLS like a real gRPC server would.
+func (suite *GatewaySuite) startTLS() *httptest.Server {
+	gw := grpc.NewGateway("")
+	services.NewServer(
+		services.Listen(gw),
+		services.Register(gen.SampleServiceServer(testext.SampleServiceHandler{Sequence: &testext.Sequence{}})),
+	)
+
+	server := httptest.NewUnstartedServer(gw)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	return server
+}
+
+// call makes a raw gRPC call the way that a non-Abide gRPC client would.
+func (suite *GatewaySuite) call(server *httptest.Server, path string, contentType string, message string) *http.Response {
+	body := &bytes.Buffer{}
+	prefix := make([]byte, 5)
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(message)))
+	body.Write(prefix)
+	body.WriteString(message)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+path, body)
+	suite.Require().NoError(err)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("TE", "trailers")
+
+	res, err := server.Client().Do(req)
+	suite.Require().NoError(err)
+	return res
+}
+
+func (suite *GatewaySuite) TestDefaults() {
+	client, shutdown := suite.start()
+	defer shutdown()
+
+	res, err := client.Defaults(context.Background(), &testext.SampleRequest{Text: "Abide"})
+	suite.Require().NoError(err)
+	suite.Equal("Defaults:Abide", res.Text)
+}
+
+// The values that the API gateway gets from the path/query should come through in the message instead.
+func (suite *GatewaySuite) TestCustomRoutes() {
+	client, shutdown := suite.start()
+	defer shutdown()
+
+	res, err := client.CustomRoute(context.Background(), &testext.SampleRequest{ID: "123", Text: "Abide"})
+	suite.Require().NoError(err)
+	suite.Equal("123", res.ID)
+	suite.Equal("Route:Abide", res.Text)
+
+	res, err = client.CustomRouteQuery(context.Background(), &testext.SampleRequest{ID: "456", Text: "Abide"})
+	suite.Require().NoError(err)
+	suite.Equal("456", res.ID)
+	suite.Equal("Route:Abide", res.Text)
+}
+
+// Errors should come back with the same status/message they would over HTTP.
+func (suite *GatewaySuite) TestFailure() {
+	client, shutdown := suite.start()
+	defer shutdown()
+
+	_, err := client.Fail4XX(context.Background(), &testext.SampleRequest{})
+	suite.Require().Error(err)
+	suite.Equal(409, fail.Status(err))
+	suite.Contains(err.Error(), "always a conflict")
+
+	_, err = client.Fail5XX(context.Background(), &testext.SampleRequest{})
+	suite.Require().Error(err)
+	suite.Equal(502, fail.Status(err))
+	suite.Contains(err.Error(), "always a bad gateway")
+}
+
+// Metadata such as authorization should follow the request as gRPC metadata.
+func (suite *GatewaySuite) TestAuthorization() {
+	client, shutdown := suite.start()
+	defer shutdown()
+
+	ctx := metadata.WithAuthorization(context.Background(), "The Dude Abides")
+	res, err := client.Authorization(ctx, &testext.SampleRequest{})
+	suite.Require().NoError(err)
+	suite.Equal("The Dude Abides", res.Text)
+}
+
+// Only metadata signed by one of our own services should be trusted when the gateway verifies it.
+func (suite *GatewaySuite) TestMetadataVerification() {
+	key := []byte("the dude abides")
+	options := []grpc.GatewayOption{grpc.WithMetadataVerification(metadata.VerifyConfig{Keys: [][]byte{key}, Reject: true})}
+	ctx := metadata.WithAuthorization(context.Background(), "The Dude Abides")
+
+	client, shutdown := suite.startWith(options, clients.WithMetadataSigningKey(key))
+	defer shutdown()
+	res, err := client.Authorization(ctx, &testext.SampleRequest{})
+	suite.Require().NoError(err)
+	suite.Equal("The Dude Abides", res.Text)
+
+	client, shutdown2 := suite.startWith(options, clients.WithMetadataSigningKey([]byte("nihilists")))
+	defer shutdown2()
+	_, err = client.Authorization(ctx, &testext.SampleRequest{})
+	suite.Require().Error(err)
+	suite.True(fail.IsBadCredentials(err), "Should reject metadata signed w/ the wrong key: %v", err)
+
+	client, shutdown3 := suite.startWith(options)
+	defer shutdown3()
+	_, err = client.Authorization(ctx, &testext.SampleRequest{})
+	suite.Require().Error(err)
+	suite.True(fail.IsBadCredentials(err), "Should reject unsigned metadata: %v", err)
+}
+
+// The caller's deadline should be sent as the "grpc-timeout", so the service gives up when the caller does.
+func (suite *GatewaySuite) TestTimeout() {
+	client, shutdown := suite.start()
+	defer shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	startTime := time.Now()
+	_, err := client.Sleep(ctx, &testext.SampleRequest{})
+	suite.Require().Error(err)
+	suite.Less(time.Since(startTime), 2*time.Second)
+}
+
+// Timeouts that we can't parse (or that are too large to represent) are the caller's fault.
+func (suite *GatewaySuite) TestTimeout_invalid() {
+	server := suite.startTLS()
+	defer server.Close()
+
+	for _, timeout := range []string{"soon", "10X", "99999999H"} {
+		body := bytes.NewBuffer([]byte{0, 0, 0, 0, 2, '{', '}'})
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/SampleService/Defaults", body)
+		suite.Require().NoError(err)
+		req.Header.Set("Content-Type", "application/grpc+json")
+		req.Header.Set("Grpc-Timeout", timeout)
+
+		res, err := server.Client().Do(req)
+		suite.Require().NoError(err)
+		_ = res.Body.Close()
+		suite.Equal("3", res.Header.Get("Grpc-Status"), "Timeout '%s' should be INVALID_ARGUMENT", timeout)
+	}
+}
+
+// A plain old gRPC client should be able to call us over HTTP/2 and get the status in the trailers.
+func (suite *GatewaySuite) TestHTTP2() {
+	server := suite.startTLS()
+	defer server.Close()
+
+	res := suite.call(server, "/SampleService/Defaults", "application/grpc+json", `{"Text":"Abide"}`)
+	defer res.Body.Close()
+
+	suite.Equal(2, res.ProtoMajor)
+	suite.Equal(200, res.StatusCode)
+	suite.Equal("application/grpc+json", res.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(res.Body)
+	suite.Require().NoError(err)
+	suite.Require().Greater(len(body), 5)
+	suite.Equal(uint32(len(body)-5), binary.BigEndian.Uint32(body[1:5]))
+	suite.JSONEq(`{"ID":"", "Text":"Defaults:Abide"}`, string(body[5:]))
+	suite.Equal("0", res.Trailer.Get("Grpc-Status"))
+
+	// Our own transport should work over HTTP/2, too.
+	client := gen.SampleServiceClient("", clients.WithTransport(grpc.NewTransport(server.URL, grpc.WithHTTPClient(server.Client()))))
+	sampleResponse, err := client.Defaults(context.Background(), &testext.SampleRequest{Text: "Abide"})
+	suite.Require().NoError(err)
+	suite.Equal("Defaults:Abide", sampleResponse.Text)
+}
+
+// Protocol-level failures should be reported using gRPC status codes.
+func (suite *GatewaySuite) TestHTTP2_failure() {
+	server := suite.startTLS()
+	defer server.Close()
+
+	res := suite.call(server, "/SampleService/Fail4XX", "application/grpc+json", `{}`)
+	_ = res.Body.Close()
+	suite.Equal("6", res.Header.Get("Grpc-Status")) // ALREADY_EXISTS
+	suite.Equal("always a conflict", res.Header.Get("Grpc-Message"))
+
+	res = suite.call(server, "/SampleService/Nope", "application/grpc+json", `{}`)
+	_ = res.Body.Close()
+	suite.Equal("12", res.Header.Get("Grpc-Status")) // UNIMPLEMENTED
+
+	res = suite.call(server, "/SampleService/Defaults", "application/grpc", `{}`)
+	_ = res.Body.Close()
+	suite.Equal("12", res.Header.Get("Grpc-Status")) // UNIMPLEMENTED
+
+	res = suite.call(server, "/SampleService/Defaults", "application/grpc+json", `{"Text":42}`)
+	_ = res.Body.Close()
+	suite.Equal("3", res.Header.Get("Grpc-Status")) // INVALID_ARGUMENT
+
+	res = suite.call(server, "/SampleService/Defaults", "text/plain", `{}`)
+	_ = res.Body.Close()
+	suite.Equal(415, res.StatusCode)
+	suite.True(strings.HasPrefix(res.Header.Get("Content-Type"), "text/plain"))
+}
+
+// Messages larger than the gateway's limit (or the endpoint's "MAX BODY" limit) should be rejected
+// w/o ever calling the service function.
+func (suite *GatewaySuite) TestMaxMessageSize() {
+	type echoRequest struct{ Text string }
+	gw := grpc.NewGateway("", grpc.WithMaxMessageSize(100))
+	register := func(name string, maxBodySize int64) {
+		gw.Register(services.Endpoint{
+			ServiceName: "EchoService",
+			Name:        name,
+			MaxBodySize: maxBodySize,
+			NewInput:    func() services.StructPointer { return &echoRequest{} },
+			Handler: func(ctx context.Context, req any) (any, error) {
+				return req, nil
+			},
+		}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "POST", Path: "/" + name, Status: 200})
+	}
+	register("Default", 0)
+	register("Small", 20)
+	register("Large", 500)
+
+	call := func(name string, text string) string {
+		message := `{"Text":"` + text + `"}`
+		body := &bytes.Buffer{}
+		prefix := make([]byte, 5)
+		binary.BigEndian.PutUint32(prefix[1:], uint32(len(message)))
+		body.Write(prefix)
+		body.WriteString(message)
+
+		req := httptest.NewRequest(http.MethodPost, "/EchoService/"+name, body)
+		req.Header.Set("Content-Type", "application/grpc+json")
+		w := httptest.NewRecorder()
+		gw.ServeHTTP(w, req)
+		if status := w.Result().Trailer.Get("Grpc-Status"); status != "" {
+			return status
+		}
+		return w.Header().Get("Grpc-Status")
+	}
+
+	suite.Equal("0", call("Default", strings.Repeat("a", 50)))
+	suite.Equal("8", call("Default", strings.Repeat("a", 200))) // RESOURCE_EXHAUSTED
+	suite.Equal("0", call("Small", "a"))
+	suite.Equal("8", call("Small", strings.Repeat("a", 50)))
+	suite.Equal("0", call("Large", strings.Repeat("a", 200)))
+	suite.Equal("8", call("Large", strings.Repeat("a", 600)))
+}
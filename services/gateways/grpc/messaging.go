@@ -0,0 +1,266 @@
+package grpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/monadicstack/abide/fail"
+)
+
+const (
+	// contentType is the only gRPC content type that we support. Abide services don't have protobuf
+	// schemas, so messages are encoded using JSON just like they are for the API gateway.
+	contentType = "application/grpc+json"
+	// maxMessageSize is the largest single message we're willing to read unless you say otherwise. This
+	// matches the default that most gRPC implementations use.
+	maxMessageSize = 4 * 1024 * 1024
+	// prefixSize is the size of the header in front of every message: a 1-byte compression
+	// flag followed by the 4-byte, big-endian length of the message.
+	prefixSize = 5
+)
+
+const (
+	headerStatus   = "Grpc-Status"
+	headerMessage  = "Grpc-Message"
+	headerTimeout  = "Grpc-Timeout"
+	headerEncoding = "Grpc-Encoding"
+	// headerAbideStatus carries the original HTTP-style status of an error, so that Abide clients end up
+	// with exactly the same error status that they'd get over HTTP. Several statuses map to the same
+	// gRPC code, so we'd lose that information otherwise.
+	headerAbideStatus = "Abide-Status"
+)
+
+// The gRPC status codes. See https://grpc.github.io/grpc/core/md_doc_statuscodes.html for details.
+const (
+	codeOK                 = 0
+	codeCanceled           = 1
+	codeUnknown            = 2
+	codeInvalidArgument    = 3
+	codeDeadlineExceeded   = 4
+	codeNotFound           = 5
+	codeAlreadyExists      = 6
+	codePermissionDenied   = 7
+	codeResourceExhausted  = 8
+	codeFailedPrecondition = 9
+	codeUnimplemented      = 12
+	codeInternal           = 13
+	codeUnavailable        = 14
+	codeUnauthenticated    = 16
+)
+
+// codeForStatus maps the HTTP-style status of an error to the gRPC code that most closely describes it.
+func codeForStatus(status int) int {
+	switch status {
+	case http.StatusBadRequest:
+		return codeInvalidArgument
+	case http.StatusUnauthorized:
+		return codeUnauthenticated
+	case http.StatusForbidden:
+		return codePermissionDenied
+	case http.StatusNotFound, http.StatusGone:
+		return codeNotFound
+	case http.StatusConflict:
+		return codeAlreadyExists
+	case http.StatusPreconditionFailed:
+		return codeFailedPrecondition
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codeResourceExhausted
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return codeUnimplemented
+	case http.StatusRequestTimeout, http.StatusGatewayTimeout:
+		return codeDeadlineExceeded
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codeUnavailable
+	case 499:
+		return codeCanceled
+	}
+
+	switch {
+	case status >= 500:
+		return codeInternal
+	default:
+		return codeUnknown
+	}
+}
+
+// statusForCode maps a gRPC code back to the HTTP-style status that most closely describes it. We only
+// need this when the server didn't tell us the original status (i.e. it's not an Abide gateway).
+func statusForCode(code int) int {
+	switch code {
+	case codeCanceled:
+		return 499
+	case codeInvalidArgument:
+		return http.StatusBadRequest
+	case codeDeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codeNotFound:
+		return http.StatusNotFound
+	case codeAlreadyExists:
+		return http.StatusConflict
+	case codePermissionDenied:
+		return http.StatusForbidden
+	case codeResourceExhausted:
+		return http.StatusTooManyRequests
+	case codeFailedPrecondition:
+		return http.StatusPreconditionFailed
+	case codeUnimplemented:
+		return http.StatusNotImplemented
+	case codeUnavailable:
+		return http.StatusServiceUnavailable
+	case codeUnauthenticated:
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeMessage writes a single length-prefixed, uncompressed message.
+func writeMessage(w io.Writer, message []byte) error {
+	prefix := make([]byte, prefixSize)
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(message)))
+	if _, err := w.Write(prefix); err != nil {
+		return err
+	}
+	_, err := w.Write(message)
+	return err
+}
+
+// readMessage reads a single length-prefixed message, decompressing it if necessary. The encoding
+// is the value of the "grpc-encoding" header, which tells us how compressed messages were compressed.
+// Messages larger than 'maxSize' bytes (before or after decompression) fail w/ a 413 error.
+func readMessage(r io.Reader, encoding string, maxSize int64) ([]byte, error) {
+	prefix := make([]byte, prefixSize)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, fail.BadRequest("grpc: missing message")
+		}
+		return nil, fail.BadRequest("grpc: invalid message: %v", err)
+	}
+
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if int64(size) > maxSize {
+		return nil, fail.TooLarge("grpc: message is larger than %d bytes", maxSize)
+	}
+
+	message := make([]byte, size)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return nil, fail.BadRequest("grpc: invalid message: %v", err)
+	}
+	if prefix[0] == 0 {
+		return message, nil
+	}
+
+	if encoding != "gzip" {
+		return nil, fail.NotImplemented("grpc: unsupported message encoding: '%s'", encoding)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(message))
+	if err != nil {
+		return nil, fail.BadRequest("grpc: invalid message: %v", err)
+	}
+	message, err = io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, fail.BadRequest("grpc: invalid message: %v", err)
+	}
+	if int64(len(message)) > maxSize {
+		return nil, fail.TooLarge("grpc: message is larger than %d bytes", maxSize)
+	}
+	return message, nil
+}
+
+// encodeTimeout formats the duration as a "grpc-timeout" header value (e.g. "1500m").
+func encodeTimeout(timeout time.Duration) string {
+	if timeout <= 0 {
+		return "1n"
+	}
+	// The value can be at most 8 digits, so use the most precise unit that fits.
+	units := []struct {
+		suffix   string
+		duration time.Duration
+	}{
+		{"n", time.Nanosecond},
+		{"u", time.Microsecond},
+		{"m", time.Millisecond},
+		{"S", time.Second},
+		{"M", time.Minute},
+	}
+	for _, unit := range units {
+		if value := timeout / unit.duration; value < 100_000_000 {
+			return strconv.FormatInt(int64(value), 10) + unit.suffix
+		}
+	}
+	return strconv.FormatInt(int64(timeout/time.Hour), 10) + "H"
+}
+
+// decodeTimeout parses a "grpc-timeout" header value (e.g. "1500m") into a duration. Values too large to fit
+// in a time.Duration (e.g. "99999999H") are rejected rather than overflowing into a nonsense deadline.
+func decodeTimeout(value string) (time.Duration, error) {
+	if len(value) < 2 || len(value) > 9 {
+		return 0, fmt.Errorf("invalid timeout: '%s'", value)
+	}
+	amount, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+	if err != nil || amount < 0 {
+		return 0, fmt.Errorf("invalid timeout: '%s'", value)
+	}
+
+	var unit time.Duration
+	switch value[len(value)-1] {
+	case 'H':
+		unit = time.Hour
+	case 'M':
+		unit = time.Minute
+	case 'S':
+		unit = time.Second
+	case 'm':
+		unit = time.Millisecond
+	case 'u':
+		unit = time.Microsecond
+	case 'n':
+		unit = time.Nanosecond
+	default:
+		return 0, fmt.Errorf("invalid timeout: '%s'", value)
+	}
+
+	if amount > math.MaxInt64/int64(unit) {
+		return 0, fmt.Errorf("invalid timeout: '%s' is too large", value)
+	}
+	return time.Duration(amount) * unit, nil
+}
+
+// encodeStatusMessage percent-encodes the error message for the "grpc-message" header as the spec requires.
+func encodeStatusMessage(message string) string {
+	builder := strings.Builder{}
+	for i := 0; i < len(message); i++ {
+		switch c := message[i]; {
+		case c < 0x20 || c > 0x7E || c == '%':
+			builder.WriteString(fmt.Sprintf("%%%02X", c))
+		default:
+			builder.WriteByte(c)
+		}
+	}
+	return builder.String()
+}
+
+// decodeStatusMessage reverses encodeStatusMessage. Invalid escapes are left as-is.
+func decodeStatusMessage(value string) string {
+	builder := strings.Builder{}
+	for i := 0; i < len(value); i++ {
+		if value[i] == '%' && i+2 < len(value) {
+			if c, err := strconv.ParseUint(value[i+1:i+3], 16, 8); err == nil {
+				builder.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		builder.WriteByte(value[i])
+	}
+	return builder.String()
+}
@@ -0,0 +1,171 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/monadicstack/abide/codec"
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/internal/quiet"
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/clients"
+)
+
+// NewTransport creates a client transport that calls remote services using gRPC-framed JSON rather than
+// the API gateway's HTTP routes. The services you're calling must be running a gateway from NewGateway()
+// or some other gRPC server that accepts the JSON codec; protobuf-only servers won't understand it. Use clients.WithTransport() to have your generated
+// clients use it:
+//
+//	transport := grpc.NewTransport("https://users.internal:9001")
+//	userClient := gen.UserServiceClient("", clients.WithTransport(transport))
+//
+// Your client must have been generated with a version of Abide that records function names; if it's not,
+// just regenerate it. Like clients.NewClient(), an address without a scheme is assumed to be "http://".
+func NewTransport(address string, options ...TransportOption) *Transport {
+	if !strings.HasPrefix(address, "http://") && !strings.HasPrefix(address, "https://") {
+		address = "http://" + strings.TrimPrefix(address, "/")
+	}
+
+	transport := Transport{
+		address: strings.TrimSuffix(address, "/"),
+		encoder: codec.JSONEncoder{},
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, option := range options {
+		option(&transport)
+	}
+	return &transport
+}
+
+// Transport sends client requests to remote services as gRPC calls. You should not create one of
+// these yourself - use NewTransport() instead.
+type Transport struct {
+	address string
+	encoder codec.Encoder
+	http    *http.Client
+}
+
+// RoundTrip calls the function that the client request was created for using the gRPC protocol. The
+// response looks exactly like the one you'd get from the API gateway, so the client can decode it (or
+// the error) as usual.
+func (t *Transport) RoundTrip(serviceName string, httpRequest *http.Request) (*http.Response, error) {
+	invocation, ok := clients.RequestInvocation(httpRequest)
+	if !ok || invocation.Name == "" {
+		return nil, fail.Unexpected("grpc transport: unknown function for %s %s: regenerate your client", httpRequest.Method, httpRequest.URL.Path)
+	}
+	if _, ok = invocation.Request.(services.ContentGetter); ok {
+		return nil, fail.NotImplemented("grpc transport: %s: raw content requests are not supported", invocation.QualifiedName())
+	}
+
+	req, err := t.newRequest(httpRequest.Context(), serviceName, invocation, httpRequest.Header)
+	if err != nil {
+		return nil, fmt.Errorf("grpc transport: %w", err)
+	}
+
+	res, err := t.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("grpc transport: %w", err)
+	}
+	return t.toHTTPResponse(httpRequest, res)
+}
+
+func (t *Transport) newRequest(ctx context.Context, serviceName string, invocation clients.Invocation, header http.Header) (*http.Request, error) {
+	message := &bytes.Buffer{}
+	if err := t.encoder.Encode(message, invocation.Request); err != nil {
+		return nil, fmt.Errorf("unable to encode request: %w", err)
+	}
+	body := &bytes.Buffer{}
+	if err := writeMessage(body, bytes.TrimSpace(message.Bytes())); err != nil {
+		return nil, fmt.Errorf("unable to encode request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.address+"/"+serviceName+"/"+invocation.Name, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Carry over the authorization, metadata, and anything else your client middleware added.
+	req.Header = header.Clone()
+	req.Header.Del("Accept")
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("TE", "trailers")
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Header.Set(headerTimeout, encodeTimeout(time.Until(deadline)))
+	}
+	return req, nil
+}
+
+// toHTTPResponse converts the gRPC response into the response you'd get from the API gateway; a JSON
+// body with a 200 status or a JSON error with the status that most closely matches the gRPC code.
+func (t *Transport) toHTTPResponse(httpRequest *http.Request, res *http.Response) (*http.Response, error) {
+	defer quiet.Close(res.Body)
+
+	if res.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(res.Body)
+		return nil, fail.New(res.StatusCode, "grpc transport: %s", strings.TrimSpace(string(body)))
+	}
+
+	// Successful calls put the status in the trailers after the message. Failures usually send a
+	// "Trailers-Only" response where the status is in the headers and there's no message at all.
+	var message []byte
+	if res.Header.Get(headerStatus) == "" {
+		var err error
+		if message, err = readMessage(res.Body, res.Header.Get(headerEncoding), maxMessageSize); err != nil {
+			return nil, fmt.Errorf("grpc transport: %w", err)
+		}
+		_, _ = io.Copy(io.Discard, res.Body) // make sure we've reached the trailers
+	}
+
+	status := res.Header
+	if status.Get(headerStatus) == "" {
+		status = res.Trailer
+	}
+
+	code, err := strconv.Atoi(status.Get(headerStatus))
+	if err != nil {
+		return nil, fail.Unexpected("grpc transport: missing or invalid status: '%s'", status.Get(headerStatus))
+	}
+	if code == codeOK {
+		return t.newResponse(httpRequest, http.StatusOK, message), nil
+	}
+
+	errStatus, err := strconv.Atoi(status.Get(headerAbideStatus))
+	if err != nil {
+		errStatus = statusForCode(code)
+	}
+	errBody := &bytes.Buffer{}
+	_ = t.encoder.Encode(errBody, fail.New(errStatus, decodeStatusMessage(status.Get(headerMessage))))
+	return t.newResponse(httpRequest, errStatus, errBody.Bytes()), nil
+}
+
+func (t *Transport) newResponse(httpRequest *http.Request, status int, body []byte) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{t.encoder.ContentType()}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       httpRequest,
+	}
+}
+
+// TransportOption defines a functional parameter that you can use to set up a client transport.
+type TransportOption func(t *Transport)
+
+// WithHTTPClient sets the HTTP client the transport uses to make gRPC calls. Make sure that it supports
+// HTTP/2 if you're calling a real gRPC server. By default, we use a client with a 30 second timeout that
+// uses HTTP/2 for "https://" addresses.
+func WithHTTPClient(httpClient *http.Client) TransportOption {
+	return func(t *Transport) {
+		t.http = httpClient
+	}
+}
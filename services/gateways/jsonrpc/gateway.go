@@ -0,0 +1,427 @@
+package jsonrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/monadicstack/abide/codec"
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/metadata"
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/apis"
+)
+
+// NewGateway creates a gateway that serves all of your service operations using JSON-RPC 2.0 over
+// HTTP. Every call is a POST to the same URL, and the "method" is the fully-qualified name of the
+// operation you want to call (e.g. "UserService.GetByID"):
+//
+//	server := services.NewServer(
+//		services.Listen(apis.NewGateway(":9000")),
+//		services.Listen(jsonrpc.NewGateway(":9001")),
+//		services.Register(gen.UserServiceServer(userService)),
+//	)
+//
+//	// POST http://localhost:9001
+//	// {"jsonrpc": "2.0", "id": 1, "method": "UserService.GetByID", "params": {"ID": "123"}}
+//
+// The gateway serves the same operations as the API gateway, and it supports batches and notifications
+// as described in the JSON-RPC 2.0 spec.
+func NewGateway(address string, options ...GatewayOption) *Gateway {
+	gw := &Gateway{
+		encoder:          codec.JSONEncoder{},
+		decoder:          codec.JSONDecoder{},
+		endpoints:        map[string]registration{},
+		maxBodySize:      DefaultMaxBodySize,
+		maxBatchSize:     DefaultMaxBatchSize,
+		batchConcurrency: DefaultBatchConcurrency,
+	}
+	for _, option := range options {
+		option(gw)
+	}
+
+	gw.handler = gw.middleware.Then(gw.serveHTTP)
+	gw.server = &http.Server{Addr: address, Handler: gw}
+	return gw
+}
+
+// Gateway encapsulates the HTTP server that accepts JSON-RPC requests as well as the logic to invoke
+// service operations based on them. You should not create one of these yourself - use the NewGateway()
+// constructor instead.
+type Gateway struct {
+	server     *http.Server
+	encoder    codec.Encoder
+	decoder    codec.Decoder
+	middleware apis.HTTPMiddlewareFuncs
+	handler    http.HandlerFunc
+	endpoints  map[string]registration
+	metadata   *metadata.VerifyConfig
+
+	maxBodySize         int64
+	maxEndpointBodySize int64
+	maxBatchSize        int
+	batchConcurrency    int
+}
+
+// DefaultMaxBodySize is the largest request body (in bytes) that the gateway accepts unless you use
+// WithMaxBodySize() to change it. Endpoints w/ a larger "MAX BODY" doc option raise the limit for the
+// whole request, but only that endpoint's params can actually be that big.
+const DefaultMaxBodySize = 1 << 20
+
+// DefaultMaxBatchSize is the most calls that you can make in a single batch unless you use
+// WithMaxBatchSize() to change it.
+const DefaultMaxBatchSize = 100
+
+// DefaultBatchConcurrency is how many calls in a batch we run at the same time unless you use
+// WithBatchConcurrency() to change it.
+const DefaultBatchConcurrency = 10
+
+// registration is the endpoint behind a JSON-RPC method name (e.g. "UserService.GetByID"). We hang onto
+// the API route that it came from, so the route metadata has the same status that the API gateway would report.
+type registration struct {
+	endpoint services.Endpoint
+	route    services.EndpointRoute
+}
+
+// Type returns "JSONRPC" to indicate the tagging value for this gateway.
+func (gw *Gateway) Type() services.GatewayType {
+	return services.GatewayTypeJSONRPC
+}
+
+// Register makes the endpoint available to callers using its fully-qualified name as the method. This
+// gateway serves the same operations as the API gateway, so it only cares about API routes. You will not
+// invoke this yourself! The services.Server will utilize this as necessary.
+func (gw *Gateway) Register(endpoint services.Endpoint, route services.EndpointRoute) {
+	if route.GatewayType != services.GatewayTypeAPI {
+		return
+	}
+
+	// An endpoint can have multiple API routes, but it's still just one method to us.
+	if _, ok := gw.endpoints[endpoint.QualifiedName()]; ok {
+		return
+	}
+	gw.endpoints[endpoint.QualifiedName()] = registration{endpoint: endpoint, route: route}
+	if endpoint.MaxBodySize > gw.maxEndpointBodySize {
+		gw.maxEndpointBodySize = endpoint.MaxBodySize
+	}
+}
+
+// Listen fires up the underlying HTTP server and blocks until the gateway shuts down. When the gateway
+// shuts down gracefully, this will return nil instead of http.ErrServerClosed.
+func (gw *Gateway) Listen() error {
+	switch err := gw.server.ListenAndServe(); err {
+	case nil, http.ErrServerClosed:
+		return nil
+	default:
+		return fmt.Errorf("jsonrpc gateway error: %w", err)
+	}
+}
+
+// Shutdown attempts to gracefully shut down the HTTP server. It will wait for any in-progress
+// requests to finish and then shut down (unblocking Listen()). You can provide a context
+// with a deadline to limit how long you want to wait before giving up and shutting down anyway.
+func (gw *Gateway) Shutdown(ctx context.Context) error {
+	return gw.server.Shutdown(ctx)
+}
+
+// ServeHTTP handles a single JSON-RPC request/batch. This lets you embed the gateway in another
+// server/mux (e.g. mount it at "/rpc" on your existing web server) instead of calling Listen().
+func (gw *Gateway) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	gw.handler(w, req)
+}
+
+func (gw *Gateway) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		gw.respond(w, http.StatusMethodNotAllowed, failure(nil, codeInvalidRequest, fail.MethodNotAllowed("jsonrpc: requests must use POST")))
+		return
+	}
+
+	// Every call in a batch shares the same HTTP request, so we only need to decide once
+	// whether to trust the caller's metadata.
+	ctx, err := gw.metadata.RestoreHeader(req.Context(), req.Header)
+	if err != nil {
+		gw.respond(w, http.StatusOK, serviceFailure(nil, err))
+		return
+	}
+	req = req.WithContext(ctx)
+
+	if maxBodySize := gw.requestBodySize(); maxBodySize > 0 {
+		req.Body = http.MaxBytesReader(w, req.Body, maxBodySize)
+	}
+	body, err := io.ReadAll(req.Body)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		tooLarge := fail.TooLarge("jsonrpc: request body too large: limit is %d bytes", maxBytesErr.Limit)
+		gw.respond(w, http.StatusRequestEntityTooLarge, failure(nil, codeInvalidRequest, tooLarge))
+		return
+	}
+	if err != nil || !json.Valid(body) {
+		gw.respond(w, http.StatusOK, failure(nil, codeParseError, fail.BadRequest("jsonrpc: parse error")))
+		return
+	}
+
+	// A single request gets a single response, unless it was a notification.
+	body = bytes.TrimSpace(body)
+	if body[0] != '[' {
+		if res := gw.call(req, body); res != nil {
+			gw.respond(w, http.StatusOK, res)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var batch []json.RawMessage
+	if err = json.Unmarshal(body, &batch); err != nil || len(batch) == 0 {
+		gw.respond(w, http.StatusOK, failure(nil, codeInvalidRequest, fail.BadRequest("jsonrpc: invalid request")))
+		return
+	}
+	if gw.maxBatchSize > 0 && len(batch) > gw.maxBatchSize {
+		tooLarge := fail.TooLarge("jsonrpc: batch too large: limit is %d calls", gw.maxBatchSize)
+		gw.respond(w, http.StatusOK, failure(nil, codeInvalidRequest, tooLarge))
+		return
+	}
+
+	results := gw.callBatch(req, batch)
+
+	responses := make([]*response, 0, len(results))
+	for _, res := range results {
+		if res != nil {
+			responses = append(responses, res)
+		}
+	}
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	gw.respond(w, http.StatusOK, responses)
+}
+
+// requestBodySize determines the largest request body that we'll read. It's the gateway's limit unless one
+// of the endpoints allows something bigger.
+func (gw *Gateway) requestBodySize() int64 {
+	if gw.maxBodySize <= 0 || gw.maxEndpointBodySize <= gw.maxBodySize {
+		return gw.maxBodySize
+	}
+	return gw.maxEndpointBodySize
+}
+
+// callBatch runs all of the calls in the batch. They're independent of each other, so we run several at
+// once, but we use a fixed number of workers, so a big batch can't spin up an unlimited number of goroutines.
+// The spec doesn't require the responses to be in any particular order, but we keep them in request order anyway.
+func (gw *Gateway) callBatch(req *http.Request, batch []json.RawMessage) []*response {
+	workers := gw.batchConcurrency
+	if workers <= 0 || workers > len(batch) {
+		workers = len(batch)
+	}
+
+	results := make([]*response, len(batch))
+	indexes := make(chan int)
+	wg := sync.WaitGroup{}
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = gw.call(req, batch[i])
+			}
+		}()
+	}
+
+	for i := range batch {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return results
+}
+
+// call invokes the service operation for a single request. It returns nil if the request
+// was a notification, since the caller doesn't want to hear back, even if it fails.
+func (gw *Gateway) call(httpRequest *http.Request, raw json.RawMessage) *response {
+	req := request{}
+	if err := json.Unmarshal(raw, &req); err != nil || req.JSONRPC != version || req.Method == "" {
+		return failure(requestID(raw), codeInvalidRequest, fail.BadRequest("jsonrpc: invalid request"))
+	}
+
+	res := gw.invoke(httpRequest, req)
+	if req.notification() {
+		return nil
+	}
+	return res
+}
+
+func (gw *Gateway) invoke(httpRequest *http.Request, req request) (res *response) {
+	defer func() {
+		if recovery := recover(); recovery != nil {
+			res = failure(req.ID, codeInternalError, fail.Unexpected("%v", recovery))
+		}
+	}()
+
+	reg, ok := gw.endpoints[req.Method]
+	if !ok {
+		return failure(req.ID, codeMethodNotFound, fail.NotFound("jsonrpc: method not found: '%s'", req.Method))
+	}
+
+	serviceRequest := reg.endpoint.NewInput()
+	params, err := requestParams(req.Params)
+	if err != nil {
+		return failure(req.ID, codeInvalidParams, err)
+	}
+	if maxBodySize := gw.paramsSize(reg); maxBodySize > 0 && int64(len(params)) > maxBodySize {
+		return serviceFailure(req.ID, fail.TooLarge("jsonrpc: params too large: limit is %d bytes", maxBodySize))
+	}
+	if len(params) > 0 {
+		if err = gw.decoder.Decode(bytes.NewReader(params), serviceRequest); err != nil {
+			return failure(req.ID, codeInvalidParams, fail.BadRequest("jsonrpc: invalid params: %v", err))
+		}
+	}
+
+	serviceResponse, err := reg.endpoint.Handler(gw.context(httpRequest, reg), serviceRequest)
+	if err != nil {
+		return serviceFailure(req.ID, err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err = gw.encoder.Encode(buf, serviceResponse); err != nil {
+		return failure(req.ID, codeInternalError, fail.Unexpected("jsonrpc: unable to encode result: %v", err))
+	}
+	return &response{JSONRPC: version, Result: buf.Bytes(), ID: req.ID}
+}
+
+// paramsSize is the largest params that the endpoint accepts. Like the API gateway, the endpoint's
+// "MAX BODY" doc option overrides the gateway's limit.
+func (gw *Gateway) paramsSize(reg registration) int64 {
+	if reg.endpoint.MaxBodySize > 0 {
+		return reg.endpoint.MaxBodySize
+	}
+	return gw.maxBodySize
+}
+
+// context builds the context for a single call using the same rules that the API gateway's
+// standard middleware uses for metadata, trace ids, and authorization. The request's context already
+// has whatever metadata we decided to trust from the caller.
+func (gw *Gateway) context(req *http.Request, reg registration) context.Context {
+	ctx := metadata.WithRequestHeaders(req.Context(), req.Header)
+	ctx = metadata.WithRemoteAddr(ctx, req.RemoteAddr)
+	ctx = metadata.WithRoute(ctx, metadata.EndpointRoute{
+		ServiceName: reg.endpoint.ServiceName,
+		Name:        reg.endpoint.Name,
+		Type:        services.GatewayTypeJSONRPC.String(),
+		Method:      req.Method,
+		Path:        req.URL.Path,
+		Status:      reg.route.Status,
+	})
+
+	if metadata.TraceID(ctx) == "" {
+		traceID := req.Header.Get("X-Request-ID")
+		if traceID == "" {
+			traceID = metadata.NewTraceID()
+		}
+		ctx = metadata.WithTraceID(ctx, traceID)
+	}
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		ctx = metadata.WithAuthorization(ctx, auth)
+	}
+	return ctx
+}
+
+func (gw *Gateway) respond(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", gw.encoder.ContentType())
+	w.WriteHeader(status)
+	_ = gw.encoder.Encode(w, value)
+}
+
+// requestParams returns the JSON that we should decode into the service request. Our service operations
+// accept a single request struct, so params should be an object with its fields. We also accept the
+// by-position form as long as there's exactly one value (the object).
+func requestParams(params json.RawMessage) (json.RawMessage, error) {
+	params = bytes.TrimSpace(params)
+	if len(params) == 0 || bytes.Equal(params, []byte("null")) {
+		return nil, nil
+	}
+	if params[0] != '[' {
+		return params, nil
+	}
+
+	var positional []json.RawMessage
+	if err := json.Unmarshal(params, &positional); err != nil {
+		return nil, fail.BadRequest("jsonrpc: invalid params: %v", err)
+	}
+	switch len(positional) {
+	case 0:
+		return nil, nil
+	case 1:
+		return positional[0], nil
+	default:
+		return nil, fail.BadRequest("jsonrpc: invalid params: expected 1 positional value, got %d", len(positional))
+	}
+}
+
+// requestID tries to salvage the id from a request that is otherwise invalid, so that the
+// caller can tell which request in a batch the error belongs to.
+func requestID(raw json.RawMessage) json.RawMessage {
+	idOnly := struct {
+		ID json.RawMessage `json:"id"`
+	}{}
+	_ = json.Unmarshal(raw, &idOnly)
+	return idOnly.ID
+}
+
+// GatewayOption defines a functional parameter that you can use to set up a JSON-RPC gateway.
+type GatewayOption func(gw *Gateway)
+
+// WithMiddleware inserts the following chain of HTTP handlers so that they fire before the gateway
+// handles the request, just like apis.WithMiddleware() does for the API gateway. Keep in mind that a
+// single HTTP request can contain a batch of calls, so the middleware fires once for the whole batch.
+func WithMiddleware(funcs ...apis.HTTPMiddlewareFunc) GatewayOption {
+	return func(gw *Gateway) {
+		gw.middleware = append(gw.middleware, funcs...)
+	}
+}
+
+// WithMetadataVerification makes the gateway verify the HMAC signature of the X-RPC-Metadata header before
+// restoring it (see metadata.VerifyConfig). Every call in a batch shares the same header, so when you set
+// Reject, the whole batch fails w/ a single error response.
+func WithMetadataVerification(config metadata.VerifyConfig) GatewayOption {
+	return func(gw *Gateway) {
+		gw.metadata = &config
+	}
+}
+
+// WithUntrustedMetadata makes the gateway ignore the X-RPC-Metadata header entirely.
+func WithUntrustedMetadata() GatewayOption {
+	return WithMetadataVerification(metadata.VerifyConfig{})
+}
+
+// WithMaxBodySize limits the size (in bytes) of the request bodies that the gateway will accept. Larger requests
+// fail w/ a 413. Endpoints w/ the "MAX BODY" doc option use their own limit for their params instead. The default
+// is DefaultMaxBodySize, and you can pass 0 to remove the limit altogether.
+func WithMaxBodySize(maxBytes int64) GatewayOption {
+	return func(gw *Gateway) {
+		gw.maxBodySize = maxBytes
+	}
+}
+
+// WithMaxBatchSize limits the number of calls that a caller can make in a single batch. Larger batches are
+// rejected w/o running any of the calls. The default is DefaultMaxBatchSize, and you can pass 0 to remove
+// the limit altogether.
+func WithMaxBatchSize(maxCalls int) GatewayOption {
+	return func(gw *Gateway) {
+		gw.maxBatchSize = maxCalls
+	}
+}
+
+// WithBatchConcurrency limits how many calls in a batch the gateway runs at the same time. The rest wait
+// until one of those finishes. The default is DefaultBatchConcurrency.
+func WithBatchConcurrency(workers int) GatewayOption {
+	return func(gw *Gateway) {
+		gw.batchConcurrency = workers
+	}
+}
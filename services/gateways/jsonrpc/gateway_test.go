@@ -0,0 +1,262 @@
+//go:build integration
+
+package jsonrpc_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/internal/testext"
+	gen "github.com/monadicstack/abide/internal/testext/gen"
+	"github.com/monadicstack/abide/metadata"
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/jsonrpc"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestGatewaySuite(t *testing.T) {
+	suite.Run(t, &GatewaySuite{addresses: testext.NewFreeAddress("localhost", 20500)})
+}
+
+type GatewaySuite struct {
+	suite.Suite
+	addresses testext.FreeAddress
+}
+
+// start fires up a server whose only gateway is the JSON-RPC gateway.
+func (suite *GatewaySuite) start(options ...jsonrpc.GatewayOption) (string, *testext.Sequence, func()) {
+	address := suite.addresses.Next()
+	sequence := &testext.Sequence{}
+	server := services.NewServer(
+		services.Listen(jsonrpc.NewGateway(address, options...)),
+		services.Register(gen.SampleServiceServer(testext.SampleServiceHandler{Sequence: sequence})),
+	)
+	go func() { _ = server.Run() }()
+	time.Sleep(25 * time.Millisecond)
+
+	return "http://" + address, sequence, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}
+}
+
+// post sends the raw JSON body to the gateway, returning the HTTP status and response body.
+func (suite *GatewaySuite) post(url string, body string, headers ...string) (int, string) {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	suite.Require().NoError(err)
+	for i := 0; i < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	suite.Require().NoError(err)
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	suite.Require().NoError(err)
+	return res.StatusCode, string(resBody)
+}
+
+func (suite *GatewaySuite) TestCall() {
+	url, sequence, shutdown := suite.start()
+	defer shutdown()
+
+	status, body := suite.post(url, `{"jsonrpc":"2.0", "id":1, "method":"SampleService.Defaults", "params":{"Text":"Abide"}}`)
+	suite.Equal(200, status)
+	suite.JSONEq(`{"jsonrpc":"2.0", "id":1, "result":{"ID":"", "Text":"Defaults:Abide"}}`, body)
+	suite.Equal([]string{"Defaults:Abide"}, sequence.Values())
+
+	// By-position params are fine as long as there's just the one request object.
+	status, body = suite.post(url, `{"jsonrpc":"2.0", "id":"abc", "method":"SampleService.Defaults", "params":[{"Text":"Abide"}]}`)
+	suite.Equal(200, status)
+	suite.JSONEq(`{"jsonrpc":"2.0", "id":"abc", "result":{"ID":"", "Text":"Defaults:Abide"}}`, body)
+}
+
+// The Authorization header should make it onto the context just like the API gateway.
+func (suite *GatewaySuite) TestCall_authorization() {
+	url, _, shutdown := suite.start()
+	defer shutdown()
+
+	status, body := suite.post(url, `{"jsonrpc":"2.0", "id":1, "method":"SampleService.Authorization"}`, "Authorization", "The Dude Abides")
+	suite.Equal(200, status)
+	suite.JSONEq(`{"jsonrpc":"2.0", "id":1, "result":{"ID":"", "Text":"The Dude Abides"}}`, body)
+}
+
+// Only metadata signed by one of our own services should be trusted when the gateway verifies it.
+func (suite *GatewaySuite) TestCall_metadataVerification() {
+	key := []byte("the dude abides")
+	encoded := metadata.EncodedBytes(`{"Authorization":"The Dude Abides"}`)
+	call := `{"jsonrpc":"2.0", "id":1, "method":"SampleService.Authorization"}`
+
+	url, _, shutdown := suite.start(jsonrpc.WithMetadataVerification(metadata.VerifyConfig{Keys: [][]byte{key}}))
+	defer shutdown()
+
+	signature := metadata.Sign(encoded, key, time.Now())
+	_, body := suite.post(url, call, metadata.Header, string(encoded), metadata.SignatureHeader, signature)
+	suite.JSONEq(`{"jsonrpc":"2.0", "id":1, "result":{"ID":"", "Text":"The Dude Abides"}}`, body)
+
+	_, body = suite.post(url, call, metadata.Header, string(encoded))
+	suite.JSONEq(`{"jsonrpc":"2.0", "id":1, "result":{"ID":"", "Text":""}}`, body)
+
+	staleSignature := metadata.Sign(encoded, key, time.Now().Add(-time.Hour))
+	_, body = suite.post(url, call, metadata.Header, string(encoded), metadata.SignatureHeader, staleSignature)
+	suite.JSONEq(`{"jsonrpc":"2.0", "id":1, "result":{"ID":"", "Text":""}}`, body)
+
+	url, _, shutdown2 := suite.start(jsonrpc.WithMetadataVerification(metadata.VerifyConfig{Keys: [][]byte{key}, Reject: true}))
+	defer shutdown2()
+
+	_, body = suite.post(url, call, metadata.Header, string(encoded), metadata.SignatureHeader, "garbage")
+	suite.Contains(body, `"Status":401`)
+}
+
+// Service errors and protocol errors should come back as JSON-RPC error objects.
+func (suite *GatewaySuite) TestCall_failure() {
+	url, _, shutdown := suite.start()
+	defer shutdown()
+
+	status, body := suite.post(url, `{"jsonrpc":"2.0", "id":1, "method":"SampleService.Fail4XX"}`)
+	suite.Equal(200, status)
+	suite.JSONEq(`{"jsonrpc":"2.0", "id":1, "error":{"code":-32000, "message":"always a conflict", "data":{"Status":409}}}`, body)
+
+	status, body = suite.post(url, `{"jsonrpc":"2.0", "id":1, "method":"SampleService.Fail5XX"}`)
+	suite.Equal(200, status)
+	suite.JSONEq(`{"jsonrpc":"2.0", "id":1, "error":{"code":-32603, "message":"always a bad gateway", "data":{"Status":502}}}`, body)
+
+	status, body = suite.post(url, `{"jsonrpc":"2.0", "id":1, "method":"SampleService.Nope"}`)
+	suite.Equal(200, status)
+	suite.JSONEq(`{"jsonrpc":"2.0", "id":1, "error":{"code":-32601, "message":"jsonrpc: method not found: 'SampleService.Nope'", "data":{"Status":404}}}`, body)
+
+	status, body = suite.post(url, `{"jsonrpc":"2.0", "id":1, "method":"SampleService.Defaults", "params":{"Text":42}}`)
+	suite.Equal(200, status)
+	suite.Contains(body, `"code":-32602`)
+
+	status, body = suite.post(url, `{"jsonrpc":"1.0", "id":1, "method":"SampleService.Defaults"}`)
+	suite.Equal(200, status)
+	suite.JSONEq(`{"jsonrpc":"2.0", "id":1, "error":{"code":-32600, "message":"jsonrpc: invalid request", "data":{"Status":400}}}`, body)
+
+	status, body = suite.post(url, `{"jsonrpc":"2.0", "id":1, "method":`)
+	suite.Equal(200, status)
+	suite.JSONEq(`{"jsonrpc":"2.0", "id":null, "error":{"code":-32700, "message":"jsonrpc: parse error", "data":{"Status":400}}}`, body)
+}
+
+// Notifications still invoke the method, but the caller doesn't get a response.
+func (suite *GatewaySuite) TestNotification() {
+	url, sequence, shutdown := suite.start()
+	defer shutdown()
+
+	status, body := suite.post(url, `{"jsonrpc":"2.0", "method":"SampleService.Defaults", "params":{"Text":"Abide"}}`)
+	suite.Equal(204, status)
+	suite.Equal("", body)
+	suite.Equal([]string{"Defaults:Abide"}, sequence.Values())
+
+	// Even failures are silent.
+	status, body = suite.post(url, `{"jsonrpc":"2.0", "method":"SampleService.Fail4XX"}`)
+	suite.Equal(204, status)
+	suite.Equal("", body)
+}
+
+func (suite *GatewaySuite) TestBatch() {
+	url, _, shutdown := suite.start()
+	defer shutdown()
+
+	status, body := suite.post(url, `[
+		{"jsonrpc":"2.0", "id":1, "method":"SampleService.Defaults", "params":{"Text":"A"}},
+		{"jsonrpc":"2.0", "method":"SampleService.Defaults", "params":{"Text":"B"}},
+		{"jsonrpc":"2.0", "id":2, "method":"SampleService.Fail4XX"},
+		{"foo":"bar"},
+		{"jsonrpc":"2.0", "id":3, "method":"SampleService.Defaults", "params":{"Text":"C"}}
+	]`)
+	suite.Equal(200, status)
+	suite.JSONEq(`[
+		{"jsonrpc":"2.0", "id":1, "result":{"ID":"", "Text":"Defaults:A"}},
+		{"jsonrpc":"2.0", "id":2, "error":{"code":-32000, "message":"always a conflict", "data":{"Status":409}}},
+		{"jsonrpc":"2.0", "id":null, "error":{"code":-32600, "message":"jsonrpc: invalid request", "data":{"Status":400}}},
+		{"jsonrpc":"2.0", "id":3, "result":{"ID":"", "Text":"Defaults:C"}}
+	]`, body)
+
+	// A batch of nothing but notifications gets no response at all.
+	status, body = suite.post(url, `[
+		{"jsonrpc":"2.0", "method":"SampleService.Defaults", "params":{"Text":"A"}},
+		{"jsonrpc":"2.0", "method":"SampleService.Defaults", "params":{"Text":"B"}}
+	]`)
+	suite.Equal(204, status)
+	suite.Equal("", body)
+
+	status, body = suite.post(url, `[]`)
+	suite.Equal(200, status)
+	suite.JSONEq(`{"jsonrpc":"2.0", "id":null, "error":{"code":-32600, "message":"jsonrpc: invalid request", "data":{"Status":400}}}`, body)
+}
+
+// Batches can't be bigger than the gateway allows, and we only run so many of their calls at once.
+func (suite *GatewaySuite) TestBatch_limits() {
+	url, sequence, shutdown := suite.start(jsonrpc.WithMaxBatchSize(3), jsonrpc.WithBatchConcurrency(1))
+	defer shutdown()
+
+	status, body := suite.post(url, `[
+		{"jsonrpc":"2.0", "id":1, "method":"SampleService.Defaults", "params":{"Text":"A"}},
+		{"jsonrpc":"2.0", "id":2, "method":"SampleService.Defaults", "params":{"Text":"B"}},
+		{"jsonrpc":"2.0", "id":3, "method":"SampleService.Defaults", "params":{"Text":"C"}}
+	]`)
+	suite.Equal(200, status)
+	suite.Contains(body, "Defaults:C")
+	suite.Equal([]string{"Defaults:A", "Defaults:B", "Defaults:C"}, sequence.Values(), "One worker should run the calls in order")
+
+	sequence.Reset()
+	status, body = suite.post(url, `[
+		{"jsonrpc":"2.0", "id":1, "method":"SampleService.Defaults", "params":{"Text":"A"}},
+		{"jsonrpc":"2.0", "id":2, "method":"SampleService.Defaults", "params":{"Text":"B"}},
+		{"jsonrpc":"2.0", "id":3, "method":"SampleService.Defaults", "params":{"Text":"C"}},
+		{"jsonrpc":"2.0", "id":4, "method":"SampleService.Defaults", "params":{"Text":"D"}}
+	]`)
+	suite.Equal(200, status)
+	suite.JSONEq(`{"jsonrpc":"2.0", "id":null, "error":{"code":-32600, "message":"jsonrpc: batch too large: limit is 3 calls", "data":{"Status":413}}}`, body)
+	suite.Empty(sequence.Values(), "Should not run any of the calls")
+}
+
+// Request bodies can't be bigger than the gateway allows, but endpoints w/ a "MAX BODY" can raise
+// or lower the limit for their own params.
+func (suite *GatewaySuite) TestMaxBodySize() {
+	gw := jsonrpc.NewGateway("", jsonrpc.WithMaxBodySize(100))
+	register := func(name string, maxBodySize int64) {
+		gw.Register(services.Endpoint{
+			ServiceName: "SampleService",
+			Name:        name,
+			MaxBodySize: maxBodySize,
+			NewInput:    func() services.StructPointer { return &testext.SampleRequest{} },
+			Handler: func(ctx context.Context, req any) (any, error) {
+				return &testext.SampleResponse{Text: req.(*testext.SampleRequest).Text}, nil
+			},
+		}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "POST", Path: "/" + name, Status: 200})
+	}
+	register("Small", 20)
+	register("Default", 0)
+	register("Large", 500)
+	post := func(method string, text string) (int, string) {
+		body := `{"jsonrpc":"2.0", "id":1, "method":"SampleService.` + method + `", "params":{"Text":"` + text + `"}}`
+		w := httptest.NewRecorder()
+		gw.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+		return w.Code, w.Body.String()
+	}
+
+	_, body := post("Small", "Abide")
+	suite.Contains(body, `"result"`)
+	_, body = post("Small", strings.Repeat("Abide", 5))
+	suite.Contains(body, `"Status":413`)
+
+	_, body = post("Default", strings.Repeat("Abide", 10))
+	suite.Contains(body, `"result"`)
+	_, body = post("Default", strings.Repeat("Abide", 30))
+	suite.Contains(body, `"Status":413`)
+
+	_, body = post("Large", strings.Repeat("Abide", 30))
+	suite.Contains(body, `"result"`)
+	status, body := post("Large", strings.Repeat("Abide", 120))
+	suite.Equal(413, status)
+	suite.Contains(body, "limit is 500 bytes")
+}
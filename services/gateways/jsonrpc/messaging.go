@@ -0,0 +1,93 @@
+package jsonrpc
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/monadicstack/abide/fail"
+)
+
+// version is the only value of the "jsonrpc" member that we accept/send.
+const version = "2.0"
+
+// The error codes reserved by the JSON-RPC 2.0 spec. Codes from -32000 to -32099 are
+// reserved for implementation-defined server errors.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+	codeServerError    = -32000
+)
+
+// request is a single JSON-RPC call. The request body is either one of these or an array of them (a batch).
+type request struct {
+	// JSONRPC must be exactly "2.0".
+	JSONRPC string `json:"jsonrpc"`
+	// Method is the fully-qualified name of the service operation to call (e.g. "UserService.GetByID").
+	Method string `json:"method"`
+	// Params is the service request, either as an object or as an array containing one object.
+	Params json.RawMessage `json:"params"`
+	// ID is the caller's identifier for this call. When the "id" member is missing entirely, the
+	// request is a notification, so we still call the method, but we don't reply.
+	ID json.RawMessage `json:"id"`
+}
+
+// notification is true when the caller does not want a response for this request.
+func (req request) notification() bool {
+	return req.ID == nil
+}
+
+// response is the reply for a single (non-notification) request. Exactly one of Result/Error is set.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *errorObject    `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"`
+}
+
+// errorObject is the JSON-RPC representation of a failure. The data always includes the
+// HTTP-style status from the original error, so callers can tell a 404 from a 403 even though
+// JSON-RPC lumps most service failures together under the same code.
+type errorObject struct {
+	Code    int       `json:"code"`
+	Message string    `json:"message"`
+	Data    errorData `json:"data"`
+}
+
+// errorData is the extra information we include with every error object.
+type errorData struct {
+	Status int `json:"Status"`
+}
+
+// errorCode maps the HTTP-style status of an error to the closest JSON-RPC error code.
+func errorCode(status int) int {
+	switch {
+	case status == http.StatusBadRequest:
+		return codeInvalidParams
+	case status >= 500:
+		return codeInternalError
+	default:
+		return codeServerError
+	}
+}
+
+// failure creates the response for a request that failed with the given JSON-RPC code.
+func failure(id json.RawMessage, code int, err error) *response {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &response{
+		JSONRPC: version,
+		Error:   &errorObject{Code: code, Message: err.Error(), Data: errorData{Status: fail.Status(err)}},
+		ID:      id,
+	}
+}
+
+// serviceFailure creates the response for a request whose service operation returned an error. Bad
+// requests map to "invalid params", unexpected failures map to "internal error", and everything else
+// is a generic server error. The original status is always available as "data.Status".
+func serviceFailure(id json.RawMessage, err error) *response {
+	return failure(id, errorCode(fail.Status(err)), err)
+}
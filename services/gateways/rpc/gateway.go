@@ -0,0 +1,278 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/monadicstack/abide/codec"
+	"github.com/monadicstack/abide/eventsource"
+	"github.com/monadicstack/abide/eventsource/local"
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/internal/wait"
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/apis"
+)
+
+// NewGateway creates a gateway that accepts RPC requests from a broker rather than over HTTP. This lets
+// your internal services talk to each other over something like NATS without exposing any HTTP ports. Clients
+// send requests to this gateway using the transport from NewTransport():
+//
+//	broker := nats.Broker(nats.WithAddress("nats://localhost:4222"))
+//
+//	// Server side
+//	server := services.NewServer(
+//		services.Listen(rpc.NewGateway(rpc.WithBroker(broker))),
+//		services.Register(gen.UserServiceServer(userService)),
+//	)
+//
+//	// Client side
+//	userClient := gen.UserServiceClient("", clients.WithTransport(rpc.NewTransport(broker)))
+//
+// The gateway serves all of your API routes (same paths, same binding rules, same middleware), so your
+// clients behave exactly the same as they do over HTTP. By default, it uses local.Broker(), so it only
+// works for clients in the same process unless you supply a different broker using WithBroker().
+func NewGateway(options ...GatewayOption) *Gateway {
+	jsonEncoder := codec.JSONEncoder{}
+	jsonDecoder := codec.JSONDecoder{}
+	gw := Gateway{
+		encoder:        jsonEncoder,
+		decoder:        jsonDecoder,
+		broker:         local.Broker(),
+		serviceNames:   map[string]bool{},
+		done:           make(chan struct{}),
+		activeRequests: &sync.WaitGroup{},
+		errorHandler: func(err error) {
+			log.Printf("[rpc error] %v\n", err)
+		},
+	}
+	for _, option := range options {
+		option(&gw)
+	}
+
+	// We don't listen for HTTP requests, but we want the exact same routing/binding/middleware
+	// behavior that the API gateway has, so we just feed it the requests we get from the broker.
+	apiOptions := append([]apis.GatewayOption{apis.WithMiddleware(gw.middleware...)}, gw.apiOptions...)
+	gw.api = apis.NewGateway("", apiOptions...)
+	return &gw
+}
+
+// Gateway encapsulates the logic to invoke service operations based on requests received from a
+// broker. You should not create one of these yourself - use the NewGateway() constructor instead.
+type Gateway struct {
+	encoder        codec.Encoder
+	decoder        codec.Decoder
+	broker         eventsource.Broker
+	errorHandler   fail.ErrorHandler
+	middleware     apis.HTTPMiddlewareFuncs
+	apiOptions     []apis.GatewayOption
+	api            *apis.Gateway
+	serviceNames   map[string]bool
+	mutex          sync.Mutex
+	subscriptions  []eventsource.Subscription
+	shuttingDown   bool
+	done           chan struct{}
+	activeRequests *sync.WaitGroup
+}
+
+// Type returns "RPC" to indicate the tagging value for this gateway.
+func (gw *Gateway) Type() services.GatewayType {
+	return services.GatewayTypeRPC
+}
+
+// Register adds the given service endpoint to the routing rules for this gateway. This gateway serves
+// the same routes as the API gateway, so it only cares about API routes. You will not invoke this
+// yourself! The services.Server will utilize this as necessary.
+func (gw *Gateway) Register(endpoint services.Endpoint, route services.EndpointRoute) {
+	if route.GatewayType != services.GatewayTypeAPI {
+		return
+	}
+	gw.serviceNames[endpoint.ServiceName] = true
+	gw.api.Register(endpoint, route)
+}
+
+// Listen causes the gateway to start subscribing to requests for all of the services that it serves. This
+// will block until we're told to stop by calling Shutdown().
+func (gw *Gateway) Listen() error {
+	if err := gw.subscribe(); err != nil {
+		return err
+	}
+	<-gw.done
+	return nil
+}
+
+// subscribe starts listening for requests for all of our services. We hold the lock the whole time so
+// that a Shutdown() in the middle of this doesn't miss any of the subscriptions.
+func (gw *Gateway) subscribe() error {
+	gw.mutex.Lock()
+	defer gw.mutex.Unlock()
+
+	// We were shut down before we ever started listening, so don't bother.
+	if gw.shuttingDown {
+		return nil
+	}
+
+	// Every instance of the service is in the same consumer group, so only one of them will handle
+	// any given request. We want load balancing, not broadcasting.
+	for serviceName := range gw.serviceNames {
+		key := RequestKey(serviceName)
+		subscription, err := gw.broker.SubscribeGroup(key, key, gw.handleRequest)
+		if err != nil {
+			return fmt.Errorf("rpc gateway error: listen: %w", err)
+		}
+		gw.subscriptions = append(gw.subscriptions, subscription)
+	}
+	return nil
+}
+
+// Shutdown gracefully stops the gateway. It will allow all of the in-progress requests to finish
+// up before doing so. You can provide a deadline to the context parameter to limit how much time
+// you're willing to give them before shutting down anyway. It's safe to call this before Listen(),
+// or more than once.
+func (gw *Gateway) Shutdown(ctx context.Context) error {
+	gw.mutex.Lock()
+	subscriptions := gw.subscriptions
+	gw.subscriptions = nil
+	if !gw.shuttingDown {
+		gw.shuttingDown = true
+		close(gw.done)
+	}
+	gw.mutex.Unlock()
+
+	errs, _ := fail.NewGroup(ctx)
+	for _, subscription := range subscriptions {
+		errs.Go(subscription.Unsubscribe)
+	}
+
+	// Make sure that we have stopped listening for all of our services' requests.
+	if err := errs.Wait(); err != nil {
+		return fmt.Errorf("rpc gateway error: shutdown: %w", err)
+	}
+
+	wait.ContextOrGroupOrInterrupt(ctx, gw.activeRequests)
+	return nil
+}
+
+// handleRequest is the broker handler that runs a single request through the API routes and
+// publishes the results back to the client transport that sent it.
+func (gw *Gateway) handleRequest(ctx context.Context, msg *eventsource.EventMessage) error {
+	gw.activeRequests.Add(1)
+	defer gw.activeRequests.Done()
+
+	req := request{}
+	if err := gw.decoder.Decode(bytes.NewBuffer(msg.Payload), &req); err != nil {
+		gw.errorHandler(fmt.Errorf("rpc request decode error: %w", err))
+		return nil
+	}
+
+	// If the caller has already given up, there's no sense in doing the work.
+	if !req.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, req.Deadline)
+		defer cancel()
+	}
+
+	res := gw.serve(ctx, req)
+
+	buf := &bytes.Buffer{}
+	if err := gw.encoder.Encode(buf, res); err != nil {
+		gw.errorHandler(fmt.Errorf("rpc response encode error: %w", err))
+		return nil
+	}
+
+	// We want to try and reply even if the caller's deadline passed while we were working. We don't
+	// want to spend forever trying, though.
+	replyCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := gw.broker.Publish(replyCtx, req.ReplyTo, buf.Bytes()); err != nil {
+		gw.errorHandler(fmt.Errorf("rpc reply error: %w", err))
+	}
+	return nil
+}
+
+// serve runs the request through the API routes exactly as if it came in over HTTP, capturing
+// the results in a response message.
+func (gw *Gateway) serve(ctx context.Context, req request) response {
+	httpRequest, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bytes.NewReader(req.Body))
+	if err != nil {
+		return gw.failure(req, fail.BadRequest("rpc: invalid request: %v", err))
+	}
+	if req.Header != nil {
+		httpRequest.Header = req.Header
+	}
+
+	recorder := &responseRecorder{header: http.Header{}}
+	gw.api.ServeHTTP(recorder, httpRequest)
+
+	return response{
+		ID:     req.ID,
+		Status: recorder.status,
+		Header: recorder.header,
+		Body:   recorder.body.Bytes(),
+	}
+}
+
+// failure creates a reply message that looks like the error response you'd get from the API gateway.
+func (gw *Gateway) failure(req request, err error) response {
+	statusErr := fail.From(err)
+	body := &bytes.Buffer{}
+	_ = gw.encoder.Encode(body, statusErr)
+
+	return response{
+		ID:     req.ID,
+		Status: statusErr.Status,
+		Header: http.Header{"Content-Type": []string{gw.encoder.ContentType()}},
+		Body:   body.Bytes(),
+	}
+}
+
+// GatewayOption defines a functional parameter that you can use to set up an RPC gateway.
+type GatewayOption func(gw *Gateway)
+
+// WithBroker defines the broker that the gateway will listen to for requests. By default, the gateway
+// uses a local broker, so only client transports in the same process can reach it.
+func WithBroker(broker eventsource.Broker) GatewayOption {
+	return func(gw *Gateway) {
+		gw.broker = broker
+	}
+}
+
+// WithMiddleware inserts the following chain of HTTP handlers so that they fire before the actual
+// handler for your service endpoint, just like apis.WithMiddleware() does for the API gateway.
+func WithMiddleware(funcs ...apis.HTTPMiddlewareFunc) GatewayOption {
+	return func(gw *Gateway) {
+		gw.middleware = append(gw.middleware, funcs...)
+	}
+}
+
+// WithAPIOptions applies the options to the API gateway that this gateway runs every request through, so
+// you get the same behavior over the broker as you do over HTTP. This is how you set things like request
+// body limits, metadata verification, or CORS rules. Options that only make sense for an HTTP server
+// (e.g. TLS) have no effect.
+//
+//	gw := rpc.NewGateway(
+//		rpc.WithBroker(broker),
+//		rpc.WithAPIOptions(
+//			apis.WithMaxBodySize(1 << 20),
+//			apis.WithMetadataVerification(metadata.VerifyConfig{Keys: keys}),
+//		),
+//	)
+func WithAPIOptions(options ...apis.GatewayOption) GatewayOption {
+	return func(gw *Gateway) {
+		gw.apiOptions = append(gw.apiOptions, options...)
+	}
+}
+
+// WithErrorHandler sets a custom callback function that is invoked any time we encounter an error
+// receiving a request or sending a reply. These happen asynchronously, so there's no caller that we
+// can report them to. Errors from your service functions are sent back to the caller as usual.
+func WithErrorHandler(handler fail.ErrorHandler) GatewayOption {
+	return func(gw *Gateway) {
+		gw.errorHandler = handler
+	}
+}
@@ -0,0 +1,168 @@
+//go:build integration
+
+package rpc_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/eventsource"
+	"github.com/monadicstack/abide/eventsource/local"
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/internal/testext"
+	gen "github.com/monadicstack/abide/internal/testext/gen"
+	"github.com/monadicstack/abide/metadata"
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/clients"
+	"github.com/monadicstack/abide/services/gateways/apis"
+	"github.com/monadicstack/abide/services/gateways/rpc"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestGatewaySuite(t *testing.T) {
+	suite.Run(t, new(GatewaySuite))
+}
+
+type GatewaySuite struct {
+	suite.Suite
+}
+
+// start fires up a server whose only gateway is the RPC gateway, so any successful
+// calls had to have gone through the broker.
+func (suite *GatewaySuite) start(options ...rpc.GatewayOption) (eventsource.Broker, func()) {
+	broker := local.Broker()
+	options = append(options, rpc.WithBroker(broker))
+	server := services.NewServer(
+		services.Listen(rpc.NewGateway(options...)),
+		services.Register(gen.SampleServiceServer(testext.SampleServiceHandler{Sequence: &testext.Sequence{}})),
+	)
+	go func() { _ = server.Run() }()
+	time.Sleep(25 * time.Millisecond)
+
+	return broker, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}
+}
+
+func (suite *GatewaySuite) client(broker eventsource.Broker, options ...rpc.TransportOption) testext.SampleService {
+	return gen.SampleServiceClient("", clients.WithTransport(rpc.NewTransport(broker, options...)))
+}
+
+func (suite *GatewaySuite) TestDefaults() {
+	broker, shutdown := suite.start()
+	defer shutdown()
+
+	res, err := suite.client(broker).Defaults(context.Background(), &testext.SampleRequest{Text: "Abide"})
+	suite.Require().NoError(err)
+	suite.Equal("Defaults:Abide", res.Text)
+}
+
+// Path params, query strings, and bodies should all be bound just like they are over HTTP.
+func (suite *GatewaySuite) TestCustomRoutes() {
+	broker, shutdown := suite.start()
+	defer shutdown()
+
+	client := suite.client(broker)
+	res, err := client.CustomRoute(context.Background(), &testext.SampleRequest{ID: "123", Text: "Abide"})
+	suite.Require().NoError(err)
+	suite.Equal("123", res.ID)
+	suite.Equal("Route:Abide", res.Text)
+
+	res, err = client.CustomRouteQuery(context.Background(), &testext.SampleRequest{ID: "456", Text: "Abide"})
+	suite.Require().NoError(err)
+	suite.Equal("456", res.ID)
+	suite.Equal("Route:Abide", res.Text)
+
+	res, err = client.CustomRouteBody(context.Background(), &testext.SampleRequest{ID: "789", Text: "Abide"})
+	suite.Require().NoError(err)
+	suite.Equal("789", res.ID)
+	suite.Equal("Route:Abide", res.Text)
+}
+
+// Errors should come back with the same status/message they would over HTTP.
+func (suite *GatewaySuite) TestFailure() {
+	broker, shutdown := suite.start()
+	defer shutdown()
+
+	_, err := suite.client(broker).Fail4XX(context.Background(), &testext.SampleRequest{})
+	suite.Require().Error(err)
+	suite.Equal(409, fail.Status(err))
+	suite.Contains(strings.ToLower(err.Error()), "always a conflict")
+}
+
+// Metadata such as authorization should follow the request through the broker.
+func (suite *GatewaySuite) TestAuthorization() {
+	broker, shutdown := suite.start()
+	defer shutdown()
+
+	ctx := metadata.WithAuthorization(context.Background(), "The Dude Abides")
+	res, err := suite.client(broker).Authorization(ctx, &testext.SampleRequest{})
+	suite.Require().NoError(err)
+	suite.Equal("The Dude Abides", res.Text)
+}
+
+// Raw content responses should make it back to the caller intact.
+func (suite *GatewaySuite) TestDownload() {
+	broker, shutdown := suite.start()
+	defer shutdown()
+
+	res, err := suite.client(broker).Download(context.Background(), &testext.SampleDownloadRequest{Format: "text/plain"})
+	suite.Require().NoError(err)
+
+	content, err := io.ReadAll(res.Content())
+	suite.Require().NoError(err)
+	suite.Equal("Donny, you're out of your element!", string(content))
+	suite.Equal("text/plain", res.ContentType())
+	suite.Equal("dude.txt", res.ContentFileName())
+}
+
+// If no gateway is listening, we should give up once the context is done rather than hang forever.
+func (suite *GatewaySuite) TestNoGateway() {
+	client := suite.client(local.Broker(), rpc.WithTimeout(50*time.Millisecond))
+
+	startTime := time.Now()
+	_, err := client.Defaults(context.Background(), &testext.SampleRequest{Text: "Abide"})
+	suite.Require().Error(err)
+	suite.Less(time.Since(startTime), 5*time.Second)
+}
+
+// The options for the API gateway should apply to requests that come in over the broker, too.
+func (suite *GatewaySuite) TestAPIOptions() {
+	broker, shutdown := suite.start(rpc.WithAPIOptions(apis.WithMaxBodySize(10)))
+	defer shutdown()
+
+	_, err := suite.client(broker).CustomRouteBody(context.Background(), &testext.SampleRequest{ID: "123", Text: strings.Repeat("Abide", 10)})
+	suite.Require().Error(err)
+	suite.True(fail.IsTooLarge(err), "Should apply the API gateway's body limit: %v", err)
+}
+
+// Shutting down before (or while) we start listening shouldn't panic or leave Listen() hanging.
+func (suite *GatewaySuite) TestShutdown_beforeListen() {
+	gw := rpc.NewGateway()
+	gw.Register(services.Endpoint{ServiceName: "SampleService", Name: "Defaults"}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "GET", Path: "/"})
+	suite.Require().NoError(gw.Shutdown(context.Background()))
+	suite.Require().NoError(gw.Shutdown(context.Background()), "Should be able to shut down more than once")
+
+	done := make(chan error, 1)
+	go func() { done <- gw.Listen() }()
+	select {
+	case err := <-done:
+		suite.NoError(err)
+	case <-time.After(time.Second):
+		suite.Fail("Listen() should return right away after Shutdown()")
+	}
+
+	// Racing the two shouldn't be a problem either.
+	for i := 0; i < 10; i++ {
+		gw = rpc.NewGateway()
+		gw.Register(services.Endpoint{ServiceName: "SampleService", Name: "Defaults"}, services.EndpointRoute{GatewayType: services.GatewayTypeAPI, Method: "GET", Path: "/"})
+		go func(gw *rpc.Gateway) { done <- gw.Listen() }(gw)
+		suite.Require().NoError(gw.Shutdown(context.Background()))
+		suite.NoError(<-done)
+	}
+}
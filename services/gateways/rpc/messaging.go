@@ -0,0 +1,78 @@
+package rpc
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+)
+
+// RequestKey returns the broker key that a gateway listens to in order to handle requests
+// for the given service (e.g. "UserService" -> "RPC.UserService").
+func RequestKey(serviceName string) string {
+	return "RPC." + serviceName
+}
+
+// ReplyKey returns the broker key that a client transport listens to in order to receive the
+// replies for its requests (e.g. "RPCReply.a8dj2kd0fs").
+func ReplyKey(inbox string) string {
+	return "RPCReply." + inbox
+}
+
+// request is the envelope that a client transport publishes to the broker in order to invoke a
+// function on a remote service. It's basically an HTTP request, so the gateway can route it exactly
+// the same way that the API gateway would. This also means that all of the client and gateway
+// middleware (metadata, authorization, etc.) works the same regardless of how you get there.
+type request struct {
+	// ID is the correlation id that the transport uses to match replies with the requests waiting on them.
+	ID string
+	// ReplyTo is the broker key that the gateway should publish the response to.
+	ReplyTo string
+	// Deadline is the time that the caller will stop waiting for a response. The gateway uses this as
+	// the deadline for the handler's context, so it doesn't keep working after no one cares anymore.
+	Deadline time.Time
+	// Method is the HTTP method of the API route we're invoking (e.g. "GET", "POST", etc).
+	Method string
+	// URL is the path and query string of the API route that we're invoking (e.g. "/user/123?Verbose=true").
+	URL string
+	// Header contains all of the HTTP headers, including the ones that carry our metadata.
+	Header http.Header
+	// Body is the raw request body (e.g. the JSON-encoded service request for POST/PUT/PATCH).
+	Body []byte
+}
+
+// response is the envelope that a gateway publishes back to the client transport once the
+// service function has finished. It contains everything that the API gateway wrote to the
+// HTTP response, so the client can decode it exactly like it would an HTTP response.
+type response struct {
+	// ID is the correlation id of the request that this is a reply to.
+	ID string
+	// Status is the HTTP status code of the response (e.g. 200, 404, etc).
+	Status int
+	// Header contains all of the HTTP headers that the gateway wrote to the response.
+	Header http.Header
+	// Body is the raw response body.
+	Body []byte
+}
+
+// responseRecorder is the http.ResponseWriter that we feed to the API routes so that we can capture
+// everything they write and send it back to the caller in a single reply message.
+type responseRecorder struct {
+	status int
+	header http.Header
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(data)
+}
@@ -0,0 +1,230 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/monadicstack/abide/codec"
+	"github.com/monadicstack/abide/eventsource"
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/internal/quiet"
+	"github.com/monadicstack/abide/metadata"
+)
+
+// NewTransport creates a client transport that sends requests to remote services over the broker rather
+// than HTTP. The services you're calling must be running a gateway from NewGateway() that is connected to
+// the same broker. Use clients.WithTransport() to have your generated clients use it:
+//
+//	transport := rpc.NewTransport(broker)
+//	userClient := gen.UserServiceClient("", clients.WithTransport(transport))
+//	groupClient := gen.GroupServiceClient("", clients.WithTransport(transport))
+//
+// You can (and should) share a single transport between all of your clients.
+func NewTransport(broker eventsource.Broker, options ...TransportOption) *Transport {
+	jsonEncoder := codec.JSONEncoder{}
+	jsonDecoder := codec.JSONDecoder{}
+	transport := Transport{
+		encoder: jsonEncoder,
+		decoder: jsonDecoder,
+		broker:  broker,
+		inbox:   ReplyKey(metadata.NewTraceID()),
+		timeout: 30 * time.Second,
+		http:    http.DefaultClient,
+		pending: map[string]chan response{},
+	}
+	for _, option := range options {
+		option(&transport)
+	}
+	return &transport
+}
+
+// Transport sends HTTP-shaped RPC requests to remote services over a broker and waits for their
+// correlated replies. You should not create one of these yourself - use NewTransport() instead.
+type Transport struct {
+	encoder      codec.Encoder
+	decoder      codec.Decoder
+	broker       eventsource.Broker
+	inbox        string
+	timeout      time.Duration
+	http         *http.Client
+	sequence     uint64
+	mutex        sync.Mutex
+	subscription eventsource.Subscription
+	pending      map[string]chan response
+}
+
+// RoundTrip publishes the request for the given service to the broker and blocks until the service's
+// gateway replies or the request's context is done. If the context does not have a deadline, we'll
+// only wait for the transport's timeout (30 seconds by default).
+func (t *Transport) RoundTrip(serviceName string, httpRequest *http.Request) (*http.Response, error) {
+	ctx := httpRequest.Context()
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+
+	if err := t.listen(); err != nil {
+		return nil, fmt.Errorf("rpc transport: %w", err)
+	}
+
+	req, err := t.newRequest(ctx, httpRequest)
+	if err != nil {
+		return nil, fmt.Errorf("rpc transport: %w", err)
+	}
+
+	// Make sure that we're waiting for the reply BEFORE we send the request. Otherwise, a really
+	// fast service could reply before we know who to hand the response to.
+	replies := make(chan response, 1)
+	t.mutex.Lock()
+	t.pending[req.ID] = replies
+	t.mutex.Unlock()
+
+	defer func() {
+		t.mutex.Lock()
+		delete(t.pending, req.ID)
+		t.mutex.Unlock()
+	}()
+
+	buf := &bytes.Buffer{}
+	if err = t.encoder.Encode(buf, req); err != nil {
+		return nil, fmt.Errorf("rpc transport: %w", err)
+	}
+	if err = t.broker.Publish(ctx, RequestKey(serviceName), buf.Bytes()); err != nil {
+		return nil, fmt.Errorf("rpc transport: %w", err)
+	}
+
+	select {
+	case res := <-replies:
+		return t.toHTTPResponse(ctx, httpRequest, res)
+	case <-ctx.Done():
+		return nil, fail.Timeout("rpc transport: %s: no reply: %v", serviceName, ctx.Err())
+	}
+}
+
+// Close stops listening for replies. Any requests that are still waiting will time out.
+func (t *Transport) Close() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.subscription == nil {
+		return nil
+	}
+	err := t.subscription.Unsubscribe()
+	t.subscription = nil
+	return err
+}
+
+// listen lazily subscribes to this transport's inbox, so we don't connect to the broker until
+// the first time you make a request.
+func (t *Transport) listen() error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.subscription != nil {
+		return nil
+	}
+
+	subscription, err := t.broker.Subscribe(t.inbox, t.receive)
+	if err != nil {
+		return fmt.Errorf("unable to listen for replies: %w", err)
+	}
+	t.subscription = subscription
+	return nil
+}
+
+// receive is the broker handler that hands replies to the requests that are waiting for them.
+func (t *Transport) receive(_ context.Context, msg *eventsource.EventMessage) error {
+	res := response{}
+	if err := t.decoder.Decode(bytes.NewBuffer(msg.Payload), &res); err != nil {
+		return nil
+	}
+
+	t.mutex.Lock()
+	replies, ok := t.pending[res.ID]
+	t.mutex.Unlock()
+
+	// If no one's waiting anymore, the request probably timed out. Nothing to do.
+	if !ok {
+		return nil
+	}
+	select {
+	case replies <- res:
+	default:
+	}
+	return nil
+}
+
+func (t *Transport) newRequest(ctx context.Context, httpRequest *http.Request) (request, error) {
+	var body []byte
+	if httpRequest.Body != nil {
+		defer quiet.Close(httpRequest.Body)
+
+		var err error
+		if body, err = io.ReadAll(httpRequest.Body); err != nil {
+			return request{}, fmt.Errorf("unable to read request body: %w", err)
+		}
+	}
+
+	deadline, _ := ctx.Deadline()
+	return request{
+		ID:       strconv.FormatUint(atomic.AddUint64(&t.sequence, 1), 10),
+		ReplyTo:  t.inbox,
+		Deadline: deadline,
+		Method:   httpRequest.Method,
+		URL:      httpRequest.URL.RequestURI(),
+		Header:   httpRequest.Header,
+		Body:     body,
+	}, nil
+}
+
+func (t *Transport) toHTTPResponse(ctx context.Context, httpRequest *http.Request, res response) (*http.Response, error) {
+	// Redirects to some other address (e.g. a file on S3) can't go over the broker, so we'll follow
+	// them using good old HTTP just like the standard HTTP client would if you were using it.
+	location := res.Header.Get("Location")
+	if res.Status >= 300 && res.Status < 400 && location != "" {
+		redirectRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+		if err != nil {
+			return nil, fmt.Errorf("rpc transport: invalid redirect: %w", err)
+		}
+		return t.http.Do(redirectRequest)
+	}
+
+	return &http.Response{
+		Status:        strconv.Itoa(res.Status) + " " + http.StatusText(res.Status),
+		StatusCode:    res.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        res.Header,
+		Body:          io.NopCloser(bytes.NewReader(res.Body)),
+		ContentLength: int64(len(res.Body)),
+		Request:       httpRequest,
+	}, nil
+}
+
+// TransportOption defines a functional parameter that you can use to set up a client transport.
+type TransportOption func(t *Transport)
+
+// WithTimeout sets how long the transport will wait for a reply when the request's context does not
+// already have a deadline. By default, this is 30 seconds.
+func WithTimeout(timeout time.Duration) TransportOption {
+	return func(t *Transport) {
+		t.timeout = timeout
+	}
+}
+
+// WithHTTPClient sets the HTTP client the transport uses to follow redirects that a service responds
+// with (e.g. to a file on S3). By default, this is http.DefaultClient.
+func WithHTTPClient(httpClient *http.Client) TransportOption {
+	return func(t *Transport) {
+		t.http = httpClient
+	}
+}
@@ -0,0 +1,387 @@
+package websockets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/monadicstack/abide/eventsource"
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/metadata"
+	"github.com/monadicstack/abide/services"
+)
+
+const (
+	// writeWait is how long we're willing to wait for a single frame to be written to the socket.
+	writeWait = 10 * time.Second
+	// pongWait is how long the client can go without sending us anything (even a pong) before we
+	// assume that the connection is dead.
+	pongWait = 60 * time.Second
+	// pingPeriod is how often we ping the client. It must be less than pongWait.
+	pingPeriod = (pongWait * 9) / 10
+	// maxFrameSize is the largest frame that we're willing to read from the client.
+	maxFrameSize = 1024 * 1024
+)
+
+func newConnection(gw *Gateway, socket *websocket.Conn, req *http.Request) *connection {
+	ctx, cancel := context.WithCancel(req.Context())
+	conn := &connection{
+		gw:            gw,
+		socket:        socket,
+		request:       req,
+		ctx:           ctx,
+		cancel:        cancel,
+		subscriptions: map[string]eventsource.Subscription{},
+	}
+	if gw.maxInvocations > 0 {
+		conn.invocationSlots = make(chan struct{}, gw.maxInvocations)
+	}
+	return conn
+}
+
+// connection manages a single client's socket. Frames are read one at a time, but each invocation
+// runs in its own goroutine, so writes to the socket are serialized using the write mutex. The number
+// of those goroutines is capped by the size of the invocation slots channel (nil means no limit).
+type connection struct {
+	gw                *Gateway
+	socket            *websocket.Conn
+	request           *http.Request
+	ctx               context.Context
+	cancel            context.CancelFunc
+	writeMutex        sync.Mutex
+	subscriptions     map[string]eventsource.Subscription
+	subscriptionMutex sync.Mutex
+	activeInvocations sync.WaitGroup
+	invocationSlots   chan struct{}
+	deadlineMutex     sync.Mutex
+	draining          bool
+}
+
+// serve reads frames from the client until the client disconnects or the gateway tells us to
+// drain, blocking the whole time. The socket is closed by the time this returns.
+func (conn *connection) serve() {
+	defer conn.close()
+
+	conn.socket.SetReadLimit(maxFrameSize)
+	_ = conn.extendReadDeadline()
+	conn.socket.SetPongHandler(func(string) error {
+		return conn.extendReadDeadline()
+	})
+	go conn.keepAlive()
+
+	for {
+		_, data, err := conn.socket.ReadMessage()
+		if err != nil {
+			// The client went away or we're draining. Either way, there's nothing to report.
+			return
+		}
+		if err = conn.extendReadDeadline(); err != nil {
+			return
+		}
+		conn.handleFrame(data)
+	}
+}
+
+// extendReadDeadline gives the client more time to send us something, unless we're draining, in
+// which case we want the pending/next read to fail right away.
+func (conn *connection) extendReadDeadline() error {
+	conn.deadlineMutex.Lock()
+	defer conn.deadlineMutex.Unlock()
+
+	if conn.draining {
+		return nil
+	}
+	return conn.socket.SetReadDeadline(time.Now().Add(pongWait))
+}
+
+// drain tells the connection to stop reading frames, let in-progress invocations finish,
+// and then close the socket. This does not block.
+func (conn *connection) drain() {
+	conn.deadlineMutex.Lock()
+	defer conn.deadlineMutex.Unlock()
+
+	conn.draining = true
+	_ = conn.socket.SetReadDeadline(time.Now())
+}
+
+func (conn *connection) isDraining() bool {
+	conn.deadlineMutex.Lock()
+	defer conn.deadlineMutex.Unlock()
+	return conn.draining
+}
+
+// close cleans up after the read loop exits. If the client disconnected, there's no one to send
+// results to, so we cancel any work in progress. If we're draining, we let that work finish first.
+func (conn *connection) close() {
+	if !conn.isDraining() {
+		conn.cancel()
+	}
+	conn.unsubscribeAll()
+	conn.activeInvocations.Wait()
+	conn.cancel()
+
+	closeCode, closeText := websocket.CloseNormalClosure, ""
+	if conn.isDraining() {
+		closeCode, closeText = websocket.CloseGoingAway, "server shutting down"
+	}
+
+	conn.writeMutex.Lock()
+	defer conn.writeMutex.Unlock()
+	_ = conn.socket.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, closeText), time.Now().Add(writeWait))
+	_ = conn.socket.Close()
+}
+
+// keepAlive periodically pings the client, so that we notice dead connections and so that
+// proxies/load balancers don't close the connection for being idle.
+func (conn *connection) keepAlive() {
+	ticker := time.NewTicker(pingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-conn.ctx.Done():
+			return
+		case <-ticker.C:
+			conn.writeMutex.Lock()
+			err := conn.socket.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait))
+			conn.writeMutex.Unlock()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleFrame decodes a single frame from the client and dispatches it based on its type.
+func (conn *connection) handleFrame(data []byte) {
+	req := frame{}
+	if err := conn.gw.decoder.Decode(bytes.NewReader(data), &req); err != nil {
+		conn.writeError(req, fail.BadRequest("websocket: invalid frame: %v", err))
+		return
+	}
+
+	switch req.Type {
+	case frameInvoke:
+		if !conn.acquireInvocation() {
+			conn.writeError(req, fail.Throttled("websocket: too many calls in flight; the limit is %d", cap(conn.invocationSlots)))
+			return
+		}
+		conn.activeInvocations.Add(1)
+		go conn.invoke(req)
+	case frameSubscribe:
+		conn.subscribe(req)
+	case frameUnsubscribe:
+		conn.unsubscribe(req)
+	default:
+		conn.writeError(req, fail.BadRequest("websocket: invalid frame type: '%s'", req.Type))
+	}
+}
+
+// acquireInvocation claims one of the connection's invocation slots w/o blocking. It returns false when
+// the client already has as many calls in flight as the gateway allows.
+func (conn *connection) acquireInvocation() bool {
+	if conn.invocationSlots == nil {
+		return true
+	}
+	select {
+	case conn.invocationSlots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// releaseInvocation frees up the slot claimed by acquireInvocation() once the call is done.
+func (conn *connection) releaseInvocation() {
+	if conn.invocationSlots != nil {
+		<-conn.invocationSlots
+	}
+}
+
+// invoke runs the service operation named in the frame and sends the result back to the client
+// using the same correlation id.
+func (conn *connection) invoke(req frame) {
+	defer conn.activeInvocations.Done()
+	defer conn.releaseInvocation()
+	defer func() {
+		if recovery := recover(); recovery != nil {
+			conn.writeError(req, fail.Unexpected("%v", recovery))
+		}
+	}()
+
+	reg, ok := conn.gw.endpoints[req.Method]
+	if !ok {
+		conn.writeError(req, fail.NotFound("websocket: unknown method: '%s'", req.Method))
+		return
+	}
+
+	serviceRequest := reg.endpoint.NewInput()
+	if len(req.Value) > 0 {
+		if err := conn.gw.decoder.Decode(bytes.NewReader(req.Value), serviceRequest); err != nil {
+			conn.writeError(req, fail.BadRequest("websocket: unable to decode request: %v", err))
+			return
+		}
+	}
+
+	status := reg.route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	ctx, err := conn.context(req)
+	if err != nil {
+		conn.writeError(req, err)
+		return
+	}
+	ctx = metadata.WithRoute(ctx, metadata.EndpointRoute{
+		ServiceName: reg.endpoint.ServiceName,
+		Name:        reg.endpoint.Name,
+		Type:        services.GatewayTypeWebSocket.String(),
+		Status:      status,
+	})
+
+	serviceResponse, err := reg.endpoint.Handler(ctx, serviceRequest)
+	if err != nil {
+		conn.writeError(req, err)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	if err = conn.gw.encoder.Encode(buf, serviceResponse); err != nil {
+		conn.writeError(req, fail.Unexpected("websocket: unable to encode response: %v", err))
+		return
+	}
+	conn.write(frame{Type: frameResult, ID: req.ID, Status: status, Value: buf.Bytes()})
+}
+
+// context builds the context for an invocation/subscription using the same rules as the API
+// gateway, except that the frame can override the authorization and trace id from the upgrade request. This
+// fails when the frame's metadata signature doesn't check out and the gateway is set up to reject those.
+func (conn *connection) context(req frame) (context.Context, error) {
+	ctx, err := conn.gw.metadata.Restore(conn.ctx, req.Metadata, req.MetadataSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = metadata.WithRequestHeaders(ctx, conn.request.Header)
+	ctx = metadata.WithRemoteAddr(ctx, conn.request.RemoteAddr)
+
+	if metadata.TraceID(ctx) == "" {
+		traceID := req.TraceID
+		if traceID == "" {
+			traceID = metadata.NewTraceID()
+		}
+		ctx = metadata.WithTraceID(ctx, traceID)
+	}
+
+	auth := req.Authorization
+	if auth == "" {
+		auth = conn.request.Header.Get("Authorization")
+	}
+	if auth != "" {
+		ctx = metadata.WithAuthorization(ctx, auth)
+	}
+	return ctx, nil
+}
+
+// subscribe starts pushing events with the frame's key to this client. Subscribing to the
+// same key more than once is harmless; you'll still only receive each event once.
+func (conn *connection) subscribe(req frame) {
+	ctx, err := conn.context(req)
+	if err != nil {
+		conn.writeError(req, err)
+		return
+	}
+	if isWildcard(req.Key) {
+		conn.writeError(req, fail.PermissionDenied("websocket: unable to subscribe to '%s'; wildcards are not allowed", req.Key))
+		return
+	}
+	if err = conn.gw.authorizeSubscribe(ctx, req.Key); err != nil {
+		conn.writeError(req, err)
+		return
+	}
+
+	conn.subscriptionMutex.Lock()
+	defer conn.subscriptionMutex.Unlock()
+
+	if _, ok := conn.subscriptions[req.Key]; !ok {
+		subscription, err := conn.gw.broker.Subscribe(req.Key, conn.pushEvent)
+		if err != nil {
+			conn.writeError(req, fail.Unexpected("websocket: unable to subscribe to '%s': %v", req.Key, err))
+			return
+		}
+		conn.subscriptions[req.Key] = subscription
+	}
+	conn.write(frame{Type: frameResult, ID: req.ID, Key: req.Key, Status: http.StatusOK})
+}
+
+// unsubscribe stops pushing events with the frame's key to this client.
+func (conn *connection) unsubscribe(req frame) {
+	conn.subscriptionMutex.Lock()
+	defer conn.subscriptionMutex.Unlock()
+
+	if subscription, ok := conn.subscriptions[req.Key]; ok {
+		delete(conn.subscriptions, req.Key)
+		if err := subscription.Unsubscribe(); err != nil {
+			conn.writeError(req, fail.Unexpected("websocket: unable to unsubscribe from '%s': %v", req.Key, err))
+			return
+		}
+	}
+	conn.write(frame{Type: frameResult, ID: req.ID, Key: req.Key, Status: http.StatusOK})
+}
+
+func (conn *connection) unsubscribeAll() {
+	conn.subscriptionMutex.Lock()
+	defer conn.subscriptionMutex.Unlock()
+
+	for key, subscription := range conn.subscriptions {
+		if err := subscription.Unsubscribe(); err != nil {
+			conn.gw.errorHandler(fmt.Errorf("websocket unsubscribe error: %s: %w", key, err))
+		}
+	}
+	conn.subscriptions = map[string]eventsource.Subscription{}
+}
+
+// pushEvent is the broker handler that forwards an event that this client subscribed to.
+func (conn *connection) pushEvent(_ context.Context, msg *eventsource.EventMessage) error {
+	evt := eventMessage{}
+	if err := conn.gw.decoder.Decode(bytes.NewReader(msg.Payload), &evt); err != nil {
+		conn.gw.errorHandler(fmt.Errorf("websocket event decode error: %w", err))
+		return nil
+	}
+
+	buf := &bytes.Buffer{}
+	if err := conn.gw.encoder.Encode(buf, unflatten(evt.Values)); err != nil {
+		conn.gw.errorHandler(fmt.Errorf("websocket event encode error: %w", err))
+		return nil
+	}
+	conn.write(frame{Type: frameEvent, Key: msg.Key, Value: buf.Bytes()})
+	return nil
+}
+
+// writeError sends an error frame with the same status/message that the API gateway would respond with.
+func (conn *connection) writeError(req frame, err error) {
+	conn.write(frame{Type: frameError, ID: req.ID, Key: req.Key, Status: fail.Status(err), Message: err.Error()})
+}
+
+// write sends a single frame to the client. Frames can come from any number of invocations or
+// event handlers at once, but the socket only supports one writer at a time.
+func (conn *connection) write(res frame) {
+	buf := &bytes.Buffer{}
+	if err := conn.gw.encoder.Encode(buf, res); err != nil {
+		conn.gw.errorHandler(fmt.Errorf("websocket frame encode error: %w", err))
+		return
+	}
+
+	conn.writeMutex.Lock()
+	defer conn.writeMutex.Unlock()
+
+	_ = conn.socket.SetWriteDeadline(time.Now().Add(writeWait))
+	if err := conn.socket.WriteMessage(websocket.TextMessage, buf.Bytes()); err != nil && conn.ctx.Err() == nil {
+		conn.gw.errorHandler(fmt.Errorf("websocket write error: %w", err))
+	}
+}
@@ -0,0 +1,288 @@
+package websockets
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/monadicstack/abide/codec"
+	"github.com/monadicstack/abide/eventsource"
+	"github.com/monadicstack/abide/eventsource/local"
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/internal/wait"
+	"github.com/monadicstack/abide/metadata"
+	"github.com/monadicstack/abide/services"
+)
+
+// NewGateway creates a gateway that lets clients such as browser dashboards open a single WebSocket
+// connection and use it to make as many service calls as they like. Each call is a JSON frame with
+// a correlation id, so the client can have many calls in flight at once and match up the results
+// as they arrive. Clients can also subscribe to event keys (e.g. "UserService.Created") to have the
+// gateway push those events to them as they're published.
+//
+//	server := services.NewServer(
+//		services.Listen(apis.NewGateway(":9000")),
+//		services.Listen(events.NewGateway(events.WithBroker(broker))),
+//		services.Listen(websockets.NewGateway(":9001", websockets.WithBroker(broker))),
+//		services.Register(gen.UserServiceServer(userService)),
+//	)
+//
+// The gateway serves the same operations as the API gateway, so any function that you can call over
+// HTTP you can call using "Service.Method" over the socket. Events can contain data that the caller
+// shouldn't see, so clients can't subscribe to any events until you use WithSubscribeAuthorizer()
+// to decide which ones they're allowed to receive.
+func NewGateway(address string, options ...GatewayOption) *Gateway {
+	gw := &Gateway{
+		encoder:           codec.JSONEncoder{},
+		decoder:           codec.JSONDecoder{},
+		broker:            local.Broker(),
+		endpoints:         map[string]registration{},
+		connections:       map[*connection]bool{},
+		activeConnections: &sync.WaitGroup{},
+		maxInvocations:    DefaultMaxInvocations,
+		errorHandler: func(err error) {
+			log.Printf("[websocket error] %v\n", err)
+		},
+	}
+	gw.authorizeSubscribe = denySubscriptions
+	for _, option := range options {
+		option(gw)
+	}
+
+	gw.server = &http.Server{Addr: address, Handler: gw}
+	return gw
+}
+
+// Gateway encapsulates the HTTP server that accepts WebSocket connections as well as the logic to
+// invoke service operations based on the frames that clients send over them. You should not create
+// one of these yourself - use the NewGateway() constructor instead.
+type Gateway struct {
+	server             *http.Server
+	upgrader           websocket.Upgrader
+	encoder            codec.Encoder
+	decoder            codec.Decoder
+	broker             eventsource.Broker
+	errorHandler       fail.ErrorHandler
+	authorizeSubscribe SubscribeAuthorizer
+	metadata           *metadata.VerifyConfig
+	endpoints          map[string]registration
+	connectionMutex    sync.Mutex
+	connections        map[*connection]bool
+	activeConnections  *sync.WaitGroup
+	shuttingDown       bool
+	maxInvocations     int
+}
+
+// DefaultMaxInvocations is how many calls a single connection can have in flight at once unless you use
+// WithMaxInvocations() to change it.
+const DefaultMaxInvocations = 100
+
+// registration is the endpoint behind an "invoke" frame's method (e.g. "UserService.GetByID"). We hang
+// onto the API route that it came from, so results have the same status that the API gateway would use.
+type registration struct {
+	endpoint services.Endpoint
+	route    services.EndpointRoute
+}
+
+// Type returns "WEBSOCKET" to indicate the tagging value for this gateway.
+func (gw *Gateway) Type() services.GatewayType {
+	return services.GatewayTypeWebSocket
+}
+
+// Register makes the endpoint available to clients as "Service.Method". This gateway serves the same
+// operations as the API gateway, so it only cares about API routes. You will not invoke this
+// yourself! The services.Server will utilize this as necessary.
+func (gw *Gateway) Register(endpoint services.Endpoint, route services.EndpointRoute) {
+	if route.GatewayType != services.GatewayTypeAPI {
+		return
+	}
+
+	// An endpoint can have multiple API routes, but it's still just one "Service.Method" to us.
+	if _, ok := gw.endpoints[endpoint.QualifiedName()]; ok {
+		return
+	}
+	gw.endpoints[endpoint.QualifiedName()] = registration{endpoint: endpoint, route: route}
+}
+
+// Listen fires up the underlying HTTP server that accepts WebSocket connections. This will block
+// until we're told to stop by calling Shutdown(). When the gateway shuts down gracefully, this will
+// return nil instead of http.ErrServerClosed.
+func (gw *Gateway) Listen() error {
+	switch err := gw.server.ListenAndServe(); err {
+	case nil, http.ErrServerClosed:
+		return nil
+	default:
+		return fmt.Errorf("websocket gateway error: %w", err)
+	}
+}
+
+// Shutdown stops accepting new connections and closes the existing ones. Each connection stops reading
+// new frames, but any calls that are already in progress get to finish and send their results before
+// the socket closes. You can provide a deadline to the context parameter to limit how much time
+// you're willing to give them before shutting down anyway.
+func (gw *Gateway) Shutdown(ctx context.Context) error {
+	if err := gw.server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("websocket gateway error: shutdown: %w", err)
+	}
+
+	// The HTTP server forgets about connections once they're upgraded, so we need to close them ourselves.
+	gw.connectionMutex.Lock()
+	gw.shuttingDown = true
+	for conn := range gw.connections {
+		conn.drain()
+	}
+	gw.connectionMutex.Unlock()
+
+	wait.ContextOrGroupOrInterrupt(ctx, gw.activeConnections)
+	return nil
+}
+
+// ServeHTTP upgrades the request to a WebSocket connection and serves frames on it until either
+// the client disconnects or the gateway shuts down. This lets you embed the gateway in another
+// server/mux (e.g. mount it at "/ws" on your existing web server) instead of calling Listen().
+func (gw *Gateway) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// The upgrader already responds with the appropriate HTTP error when this fails.
+	socket, err := gw.upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return
+	}
+
+	conn := newConnection(gw, socket, req)
+	if !gw.track(conn) {
+		conn.drain()
+	}
+	defer gw.untrack(conn)
+
+	conn.serve()
+}
+
+// track remembers the connection so that Shutdown() can close it. It returns false if the
+// gateway is already shutting down, in which case the connection should be closed right away.
+func (gw *Gateway) track(conn *connection) bool {
+	gw.connectionMutex.Lock()
+	defer gw.connectionMutex.Unlock()
+
+	gw.activeConnections.Add(1)
+	gw.connections[conn] = true
+	return !gw.shuttingDown
+}
+
+func (gw *Gateway) untrack(conn *connection) {
+	gw.connectionMutex.Lock()
+	defer gw.connectionMutex.Unlock()
+
+	delete(gw.connections, conn)
+	gw.activeConnections.Done()
+}
+
+// denySubscriptions is the default SubscribeAuthorizer. You need to opt in to subscriptions using
+// WithSubscribeAuthorizer() so that we never push events to someone who shouldn't see them.
+func denySubscriptions(_ context.Context, key string) error {
+	return fail.PermissionDenied("websocket: unable to subscribe to '%s'; subscriptions are not enabled", key)
+}
+
+// SubscribeAuthorizer decides whether the client is allowed to subscribe to the given event key. The
+// context contains the same metadata (authorization, request headers, etc.) that a service call made
+// over this connection would have, so you can check the caller's credentials. Return a non-nil error
+// such as fail.PermissionDenied() to reject the subscription. The gateway never lets clients subscribe
+// to wildcard keys (e.g. "UserService.*"), so the key is always a specific event.
+type SubscribeAuthorizer func(ctx context.Context, key string) error
+
+// AllowEvents is a SubscribeAuthorizer that lets any client subscribe to the given event keys (e.g.
+// "UserService.Created"), but nothing else. Only use this for events whose data is fine for anyone
+// who can connect to see; write your own SubscribeAuthorizer to check the caller's credentials.
+func AllowEvents(keys ...string) SubscribeAuthorizer {
+	allowed := map[string]bool{}
+	for _, key := range keys {
+		allowed[key] = true
+	}
+	return func(_ context.Context, key string) error {
+		if !allowed[key] {
+			return fail.PermissionDenied("websocket: unable to subscribe to '%s'", key)
+		}
+		return nil
+	}
+}
+
+// isWildcard determines if the event key would match more than one event (e.g. "UserService.*").
+func isWildcard(key string) bool {
+	for _, token := range strings.Split(key, ".") {
+		if token == "*" || token == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// GatewayOption defines a functional parameter that you can use to set up a WebSocket gateway.
+type GatewayOption func(gw *Gateway)
+
+// WithBroker defines the broker that the gateway subscribes to in order to push events to clients. This
+// should be the same broker that your events gateway publishes to. By default, the gateway uses a local
+// broker, so it only sees events published in the same process.
+func WithBroker(broker eventsource.Broker) GatewayOption {
+	return func(gw *Gateway) {
+		gw.broker = broker
+	}
+}
+
+// WithCheckOrigin lets you decide which browser origins are allowed to open connections. By default,
+// the gateway rejects any request whose Origin header doesn't match the Host, so you will need this if
+// your dashboard is served from a different domain/port than this gateway.
+func WithCheckOrigin(checkOrigin func(req *http.Request) bool) GatewayOption {
+	return func(gw *Gateway) {
+		gw.upgrader.CheckOrigin = checkOrigin
+	}
+}
+
+// WithSubscribeAuthorizer decides which event keys clients are allowed to subscribe to. By default,
+// clients can't subscribe to any events at all.
+//
+//	gw := websockets.NewGateway(":9001", websockets.WithSubscribeAuthorizer(func(ctx context.Context, key string) error {
+//		if !isAdmin(metadata.Authorization(ctx)) {
+//			return fail.PermissionDenied("admins only")
+//		}
+//		return nil
+//	}))
+func WithSubscribeAuthorizer(authorizer SubscribeAuthorizer) GatewayOption {
+	return func(gw *Gateway) {
+		gw.authorizeSubscribe = authorizer
+	}
+}
+
+// WithErrorHandler sets a custom callback function that is invoked any time we encounter an error
+// decoding an event or writing to a socket. These happen asynchronously, so there's no caller that we
+// can report them to. Errors from your service functions are sent back to the caller as usual.
+func WithErrorHandler(handler fail.ErrorHandler) GatewayOption {
+	return func(gw *Gateway) {
+		gw.errorHandler = handler
+	}
+}
+
+// WithMaxInvocations limits how many calls a single connection can have in flight at the same time. Each
+// call runs in its own goroutine, so this keeps one client from spinning up an unlimited number of them. Calls
+// over the limit fail right away w/ a 429 error rather than waiting. The default is DefaultMaxInvocations, and
+// a zero/negative value removes the limit.
+func WithMaxInvocations(maxCalls int) GatewayOption {
+	return func(gw *Gateway) {
+		gw.maxInvocations = maxCalls
+	}
+}
+
+// WithMetadataVerification makes the gateway verify the signature of the metadata in each frame before
+// restoring it (see metadata.VerifyConfig). The frame's "MetadataSignature" is the same value that other
+// clients send in the X-RPC-Metadata-Signature header. Rejected frames get a 401 error frame.
+func WithMetadataVerification(config metadata.VerifyConfig) GatewayOption {
+	return func(gw *Gateway) {
+		gw.metadata = &config
+	}
+}
+
+// WithUntrustedMetadata makes the gateway ignore the metadata in frames entirely.
+func WithUntrustedMetadata() GatewayOption {
+	return WithMetadataVerification(metadata.VerifyConfig{})
+}
@@ -0,0 +1,276 @@
+//go:build integration
+
+package websockets_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/monadicstack/abide/eventsource/local"
+	"github.com/monadicstack/abide/internal/testext"
+	gen "github.com/monadicstack/abide/internal/testext/gen"
+	"github.com/monadicstack/abide/metadata"
+	"github.com/monadicstack/abide/services"
+	"github.com/monadicstack/abide/services/gateways/events"
+	"github.com/monadicstack/abide/services/gateways/websockets"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestGatewaySuite(t *testing.T) {
+	suite.Run(t, &GatewaySuite{addresses: testext.NewFreeAddress("localhost", 20400)})
+}
+
+type GatewaySuite struct {
+	suite.Suite
+	addresses testext.FreeAddress
+}
+
+// frame mirrors the gateway's JSON envelope so that we can talk to it like a browser would.
+type frame struct {
+	Type          string
+	ID            string          `json:",omitempty"`
+	Method        string          `json:",omitempty"`
+	Key           string          `json:",omitempty"`
+	Authorization string          `json:",omitempty"`
+	Metadata      string          `json:",omitempty"`
+	MetadataSig   string          `json:"MetadataSignature,omitempty"`
+	Status        int             `json:",omitempty"`
+	Message       string          `json:",omitempty"`
+	Value         json.RawMessage `json:",omitempty"`
+}
+
+// start fires up a server with the WebSocket gateway as well as an events gateway sharing
+// the same broker, so that we can receive pushes for the service calls we make.
+func (suite *GatewaySuite) start(options ...websockets.GatewayOption) (*websocket.Conn, func()) {
+	address := suite.addresses.Next()
+	broker := local.Broker()
+	options = append(options, websockets.WithBroker(broker))
+	server := services.NewServer(
+		services.Listen(events.NewGateway(events.WithBroker(broker))),
+		services.Listen(websockets.NewGateway(address, options...)),
+		services.Register(gen.SampleServiceServer(testext.SampleServiceHandler{Sequence: &testext.Sequence{}})),
+	)
+	go func() { _ = server.Run() }()
+	time.Sleep(25 * time.Millisecond)
+
+	header := http.Header{"Authorization": []string{"Socket Auth"}}
+	socket, _, err := websocket.DefaultDialer.Dial("ws://"+address, header)
+	suite.Require().NoError(err)
+
+	return socket, func() {
+		_ = socket.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}
+}
+
+func (suite *GatewaySuite) send(socket *websocket.Conn, req frame) {
+	suite.Require().NoError(socket.WriteJSON(req))
+}
+
+func (suite *GatewaySuite) receive(socket *websocket.Conn) frame {
+	_ = socket.SetReadDeadline(time.Now().Add(time.Second))
+	res := frame{}
+	suite.Require().NoError(socket.ReadJSON(&res))
+	return res
+}
+
+func (suite *GatewaySuite) invoke(socket *websocket.Conn, id string, method string, value string) frame {
+	suite.send(socket, frame{Type: "invoke", ID: id, Method: method, Value: json.RawMessage(value)})
+	return suite.receive(socket)
+}
+
+func (suite *GatewaySuite) TestInvoke() {
+	socket, shutdown := suite.start()
+	defer shutdown()
+
+	res := suite.invoke(socket, "1", "SampleService.Defaults", `{"Text":"Abide"}`)
+	suite.Equal("result", res.Type)
+	suite.Equal("1", res.ID)
+	suite.Equal(200, res.Status)
+	suite.JSONEq(`{"ID":"", "Text":"Defaults:Abide"}`, string(res.Value))
+}
+
+// Many calls can be in flight at once, so each result should come back tagged with its own id.
+func (suite *GatewaySuite) TestInvoke_multiplexed() {
+	socket, shutdown := suite.start()
+	defer shutdown()
+
+	suite.send(socket, frame{Type: "invoke", ID: "a", Method: "SampleService.Defaults", Value: json.RawMessage(`{"Text":"A"}`)})
+	suite.send(socket, frame{Type: "invoke", ID: "b", Method: "SampleService.Defaults", Value: json.RawMessage(`{"Text":"B"}`)})
+	suite.send(socket, frame{Type: "invoke", ID: "c", Method: "SampleService.Defaults", Value: json.RawMessage(`{"Text":"C"}`)})
+
+	results := map[string]string{}
+	for i := 0; i < 3; i++ {
+		res := suite.receive(socket)
+		response := testext.SampleResponse{}
+		suite.Require().NoError(json.Unmarshal(res.Value, &response))
+		results[res.ID] = response.Text
+	}
+	suite.Equal(map[string]string{"a": "Defaults:A", "b": "Defaults:B", "c": "Defaults:C"}, results)
+}
+
+// Once a connection has as many calls in flight as the gateway allows, new calls should fail right away.
+func (suite *GatewaySuite) TestInvoke_maxInvocations() {
+	socket, shutdown := suite.start(websockets.WithMaxInvocations(1))
+	defer shutdown()
+
+	suite.send(socket, frame{Type: "invoke", ID: "slow", Method: "SampleService.Sleep", Value: json.RawMessage(`{}`)})
+	res := suite.invoke(socket, "fast", "SampleService.Defaults", `{"Text":"Abide"}`)
+	suite.Equal("error", res.Type)
+	suite.Equal("fast", res.ID)
+	suite.Equal(429, res.Status)
+}
+
+// Errors should come back with the same status/message they would over HTTP.
+func (suite *GatewaySuite) TestInvoke_failure() {
+	socket, shutdown := suite.start()
+	defer shutdown()
+
+	res := suite.invoke(socket, "1", "SampleService.Fail4XX", `{}`)
+	suite.Equal("error", res.Type)
+	suite.Equal("1", res.ID)
+	suite.Equal(409, res.Status)
+	suite.Contains(res.Message, "always a conflict")
+
+	res = suite.invoke(socket, "2", "SampleService.Nope", `{}`)
+	suite.Equal("error", res.Type)
+	suite.Equal("2", res.ID)
+	suite.Equal(404, res.Status)
+
+	res = suite.invoke(socket, "3", "SampleService.Defaults", `"not an object"`)
+	suite.Equal("error", res.Type)
+	suite.Equal("3", res.ID)
+	suite.Equal(400, res.Status)
+
+	suite.send(socket, frame{Type: "bogus", ID: "4"})
+	res = suite.receive(socket)
+	suite.Equal("error", res.Type)
+	suite.Equal("4", res.ID)
+	suite.Equal(400, res.Status)
+}
+
+// The upgrade request's Authorization header applies to every call unless the frame overrides it.
+func (suite *GatewaySuite) TestInvoke_authorization() {
+	socket, shutdown := suite.start()
+	defer shutdown()
+
+	res := suite.invoke(socket, "1", "SampleService.Authorization", `{}`)
+	suite.JSONEq(`{"ID":"", "Text":"Socket Auth"}`, string(res.Value))
+
+	suite.send(socket, frame{Type: "invoke", ID: "2", Method: "SampleService.Authorization", Authorization: "The Dude Abides"})
+	res = suite.receive(socket)
+	suite.JSONEq(`{"ID":"", "Text":"The Dude Abides"}`, string(res.Value))
+}
+
+// Only metadata signed by one of our own services should be trusted when the gateway verifies it.
+func (suite *GatewaySuite) TestInvoke_metadataVerification() {
+	key := []byte("the dude abides")
+	socket, shutdown := suite.start(websockets.WithMetadataVerification(metadata.VerifyConfig{Keys: [][]byte{key}, Reject: true}))
+	defer shutdown()
+
+	encoded := metadata.EncodedBytes(`{"TraceID":"123"}`)
+	signature := metadata.Sign(encoded, key, time.Now())
+	suite.send(socket, frame{Type: "invoke", ID: "1", Method: "SampleService.Defaults", Metadata: string(encoded), MetadataSig: signature})
+	res := suite.receive(socket)
+	suite.Equal("result", res.Type)
+	suite.Equal(200, res.Status)
+
+	suite.send(socket, frame{Type: "invoke", ID: "2", Method: "SampleService.Defaults", Metadata: string(encoded), MetadataSig: "garbage"})
+	res = suite.receive(socket)
+	suite.Equal("error", res.Type)
+	suite.Equal("2", res.ID)
+	suite.Equal(401, res.Status)
+
+	staleSignature := metadata.Sign(encoded, key, time.Now().Add(-time.Hour))
+	suite.send(socket, frame{Type: "subscribe", ID: "3", Key: "SampleService.Defaults", Metadata: string(encoded), MetadataSig: staleSignature})
+	res = suite.receive(socket)
+	suite.Equal("error", res.Type)
+	suite.Equal("3", res.ID)
+	suite.Equal(401, res.Status)
+}
+
+// Once subscribed, we should receive an event frame every time the event is published.
+func (suite *GatewaySuite) TestSubscribe() {
+	socket, shutdown := suite.start(websockets.WithSubscribeAuthorizer(websockets.AllowEvents("SampleService.Defaults")))
+	defer shutdown()
+
+	suite.send(socket, frame{Type: "subscribe", ID: "1", Key: "SampleService.Defaults"})
+	res := suite.receive(socket)
+	suite.Equal("result", res.Type)
+	suite.Equal("1", res.ID)
+	suite.Equal("SampleService.Defaults", res.Key)
+
+	res = suite.invoke(socket, "2", "SampleService.Defaults", `{"Text":"Abide"}`)
+	suite.Equal("result", res.Type)
+	suite.Equal("2", res.ID)
+
+	res = suite.receive(socket)
+	suite.Equal("event", res.Type)
+	suite.Equal("SampleService.Defaults", res.Key)
+	suite.JSONEq(`{"ID":"", "Text":"Defaults:Abide"}`, string(res.Value))
+
+	// We shouldn't hear about the next call once we unsubscribe.
+	suite.send(socket, frame{Type: "unsubscribe", ID: "3", Key: "SampleService.Defaults"})
+	res = suite.receive(socket)
+	suite.Equal("result", res.Type)
+	suite.Equal("3", res.ID)
+
+	res = suite.invoke(socket, "4", "SampleService.Defaults", `{"Text":"Abide"}`)
+	suite.Equal("result", res.Type)
+	suite.Equal("4", res.ID)
+
+	_ = socket.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	suite.Error(socket.ReadJSON(&res), "Should not have received another event")
+}
+
+// By default, you can't subscribe to anything; you need to opt in to the events clients can receive.
+func (suite *GatewaySuite) TestSubscribe_unauthorized() {
+	socket, shutdown := suite.start()
+	defer shutdown()
+
+	suite.send(socket, frame{Type: "subscribe", ID: "1", Key: "SampleService.Defaults"})
+	res := suite.receive(socket)
+	suite.Equal("error", res.Type)
+	suite.Equal("1", res.ID)
+	suite.Equal(403, res.Status)
+
+	suite.send(socket, frame{Type: "subscribe", ID: "2", Key: "SampleService.*"})
+	res = suite.receive(socket)
+	suite.Equal("error", res.Type)
+	suite.Equal("2", res.ID)
+	suite.Equal(403, res.Status)
+}
+
+// Only the keys that the authorizer allows are fine, and wildcards never are, even when the authorizer
+// would otherwise allow them.
+func (suite *GatewaySuite) TestSubscribe_forbidden() {
+	socket, shutdown := suite.start(websockets.WithSubscribeAuthorizer(websockets.AllowEvents("SampleService.Defaults")))
+	defer shutdown()
+
+	suite.send(socket, frame{Type: "subscribe", ID: "1", Key: "OtherService.SayHello"})
+	res := suite.receive(socket)
+	suite.Equal("error", res.Type)
+	suite.Equal("1", res.ID)
+	suite.Equal(403, res.Status)
+
+	suite.send(socket, frame{Type: "subscribe", ID: "2", Key: "SampleService.Defaults"})
+	res = suite.receive(socket)
+	suite.Equal("result", res.Type)
+	suite.Equal("2", res.ID)
+
+	socket, shutdown2 := suite.start(websockets.WithSubscribeAuthorizer(func(context.Context, string) error { return nil }))
+	defer shutdown2()
+
+	for i, key := range []string{"SampleService.*", "*.Defaults", "*", "SampleService."} {
+		suite.send(socket, frame{Type: "subscribe", ID: "wildcard", Key: key})
+		res = suite.receive(socket)
+		suite.Equal("error", res.Type, "Case %d: %s", i, key)
+		suite.Equal(403, res.Status, "Case %d: %s", i, key)
+	}
+}
@@ -0,0 +1,105 @@
+package websockets
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/monadicstack/abide/metadata"
+)
+
+const (
+	// frameInvoke is sent by the client to call a service operation (e.g. "UserService.GetByID").
+	frameInvoke = "invoke"
+	// frameSubscribe is sent by the client to start receiving pushes for an event key.
+	frameSubscribe = "subscribe"
+	// frameUnsubscribe is sent by the client to stop receiving pushes for an event key.
+	frameUnsubscribe = "unsubscribe"
+	// frameResult is sent by the gateway when an invocation or (un)subscribe succeeds.
+	frameResult = "result"
+	// frameError is sent by the gateway when an invocation or (un)subscribe fails.
+	frameError = "error"
+	// frameEvent is sent by the gateway when an event the client subscribed to was published.
+	frameEvent = "event"
+)
+
+// frame is the JSON envelope for every message sent over the socket in either direction. The
+// client picks the ID for each invoke/subscribe frame, and the gateway uses that same ID on
+// the result/error frame it sends back, so the client can have many calls in flight at once.
+//
+//	--> {"Type":"invoke", "ID":"42", "Method":"UserService.GetByID", "Value":{"ID":"123"}}
+//	<-- {"Type":"result", "ID":"42", "Status":200, "Value":{"ID":"123", "Name":"The Dude"}}
+//
+//	--> {"Type":"subscribe", "ID":"43", "Key":"UserService.Created"}
+//	<-- {"Type":"result", "ID":"43", "Status":200, "Key":"UserService.Created"}
+//	<-- {"Type":"event", "Key":"UserService.Created", "Value":{"ID":"456", "Name":"Walter"}}
+type frame struct {
+	// Type indicates what the frame is for (e.g. "invoke", "result", "event").
+	Type string
+	// ID is the correlation id that ties a result/error frame back to the frame that caused it.
+	ID string `json:",omitempty"`
+	// Method is the "Service.Method" that an invoke frame is calling.
+	Method string `json:",omitempty"`
+	// Key is the event key for subscribe, unsubscribe, and event frames.
+	Key string `json:",omitempty"`
+	// Authorization optionally overrides the Authorization header from the socket's upgrade
+	// request for this one invocation.
+	Authorization string `json:",omitempty"`
+	// TraceID optionally sets the request/trace id for this one invocation. The gateway
+	// generates one for you if you leave it blank.
+	TraceID string `json:",omitempty"`
+	// Metadata is the encoded metadata from the caller's context (e.g. when a Go service makes the call).
+	Metadata metadata.EncodedBytes `json:",omitempty"`
+	// MetadataSignature is the signature of the encoded metadata (see metadata.Sign()). Gateways using
+	// WithMetadataVerification() ignore the metadata unless this checks out.
+	MetadataSignature string `json:",omitempty"`
+	// Status is the HTTP-style status code for a result/error frame (e.g. 200, 404, 500).
+	Status int `json:",omitempty"`
+	// Message is the error message for an error frame.
+	Message string `json:",omitempty"`
+	// Value is the request for an invoke frame, the response for a result frame, or the
+	// event's payload for an event frame.
+	Value json.RawMessage `json:",omitempty"`
+}
+
+// eventMessage is the subset of the event gateway's envelope that we need in order to push
+// the event to subscribed clients. The events package doesn't export its message type, but
+// the JSON encoding is all that matters here.
+type eventMessage struct {
+	ServiceName string
+	Name        string
+	Values      url.Values
+}
+
+// unflatten turns the event gateway's flattened values (e.g. "ContactInfo.Email") back into
+// a nested structure, so browser clients don't need to know about our binding conventions. The
+// values are all strings since the event envelope doesn't carry the original types.
+//
+//	{"ID": ["123"], "ContactInfo.Email": ["dude@example.com"]}
+//	--> {"ID": "123", "ContactInfo": {"Email": "dude@example.com"}}
+func unflatten(values url.Values) map[string]any {
+	result := map[string]any{}
+	for key, value := range values {
+		node := result
+		segments := strings.Split(key, ".")
+		for _, segment := range segments[:len(segments)-1] {
+			child, ok := node[segment].(map[string]any)
+			if !ok {
+				child = map[string]any{}
+				node[segment] = child
+			}
+			node = child
+		}
+
+		leaf := segments[len(segments)-1]
+		switch len(value) {
+		case 0:
+			node[leaf] = nil
+		case 1:
+			node[leaf] = value[0]
+		default:
+			node[leaf] = value
+		}
+	}
+	return result
+}
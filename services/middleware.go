@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"runtime/debug"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/monadicstack/abide/internal/naming"
 	"github.com/monadicstack/abide/internal/reflection"
 	"github.com/monadicstack/abide/internal/slices"
+	"github.com/monadicstack/abide/internal/validation"
 	"github.com/monadicstack/abide/metadata"
 )
 
@@ -57,15 +59,85 @@ func recoverMiddleware(handler OnPanicFunc) MiddlewareFunc {
 	return func(ctx context.Context, req any, next HandlerFunc) (response any, err error) {
 		defer func() {
 			if recovery := recover(); recovery != nil {
+				// Panics from a handler that ran in another goroutine (see timeoutMiddleware) carry
+				// that goroutine's stack, which is far more useful than ours.
+				stack := debug.Stack()
+				if hp, ok := recovery.(handlerPanic); ok {
+					recovery, stack = hp.value, hp.stack
+				}
+
 				// This changes the 'err' return value so the request fails as expected.
 				err = toError(recovery)
-				handler(err, debug.Stack())
+				handler(err, stack)
 			}
 		}()
 		return next(ctx, req)
 	}
 }
 
+// handlerPanic is what we re-panic with when a handler running in its own goroutine panics. It
+// keeps the original panic value along w/ the stack trace of the goroutine where it happened.
+type handlerPanic struct {
+	value any
+	stack []byte
+}
+
+func (hp handlerPanic) String() string {
+	return fmt.Sprintf("%v\n\n%s", hp.value, hp.stack)
+}
+
+// timeoutMiddleware enforces the endpoint's "TIMEOUT xxx" doc option as well as any deadline that the caller
+// propagated to us. The handler runs w/ a context that expires at the earlier of the two. Should it still be
+// running when that happens, we don't wait for it; the call fails immediately w/ a 408 error.
+func timeoutMiddleware(endpoint Endpoint) MiddlewareFunc {
+	type result struct {
+		response any
+		err      error
+		panic    *handlerPanic
+	}
+
+	return func(ctx context.Context, req any, next HandlerFunc) (any, error) {
+		if endpoint.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, endpoint.Timeout)
+			defer cancel()
+		}
+		if _, ok := ctx.Deadline(); !ok {
+			return next(ctx, req)
+		}
+
+		// The handler runs in its own goroutine so we can bail when the deadline passes even if the
+		// handler ignores the context. Panics are handed back to this goroutine (w/ the stack trace of
+		// the goroutine where they happened) so that the recovery middleware can still deal with them.
+		done := make(chan result, 1)
+		go func() {
+			defer func() {
+				if recovery := recover(); recovery != nil {
+					done <- result{panic: &handlerPanic{value: recovery, stack: debug.Stack()}}
+				}
+			}()
+			response, err := next(ctx, req)
+			done <- result{response: response, err: err}
+		}()
+
+		select {
+		case res := <-done:
+			if res.panic != nil {
+				panic(*res.panic)
+			}
+			return res.response, res.err
+		case <-ctx.Done():
+			// The handler might still be running, so don't let it change the response headers now
+			// that the gateway is about to respond w/ the timeout.
+			metadata.FreezeResponseHeaders(ctx)
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fail.Timeout("%s: deadline exceeded", endpoint.QualifiedName())
+			}
+			return nil, ctx.Err()
+		}
+	}
+}
+
 // rolesMiddleware takes the raw doc option roles list such as ["admin.write", "group.{ID}.write"] and populates
 // the path variables w/ runtime values, so you end up with a roles list like ["admin.write", "group.123.write"]. For
 // any path variables that can't be properly mapped to a runtime value, those will end up blank (e.g. "group..write").
@@ -84,3 +156,15 @@ func rolesMiddleware(endpoint Endpoint) MiddlewareFunc {
 		return next(metadata.WithRoute(ctx, route), req)
 	}
 }
+
+// validateMiddleware enforces the `validate` struct tags on the request (e.g. `validate:"required,max=50"`) before
+// your handler ever sees it. When any of the fields are invalid, the call fails w/ a single 400 error that
+// describes every invalid field rather than just the first one.
+func validateMiddleware() MiddlewareFunc {
+	return func(ctx context.Context, req any, next HandlerFunc) (any, error) {
+		if err := validation.Validate(req); err != nil {
+			return nil, err
+		}
+		return next(ctx, req)
+	}
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/internal/reflection"
+	"github.com/monadicstack/abide/metadata"
+)
+
+// RateLimit restricts how often callers are allowed to invoke an endpoint, based on the
+// "RATE 100/m BY Authorization" doc option.
+type RateLimit struct {
+	// Limit is the maximum number of calls allowed during each period (e.g. 100).
+	Limit int
+	// Period is the amount of time that the limit applies to (e.g. 1 minute).
+	Period time.Duration
+	// By determines whose calls count toward the same limit:
+	//
+	//   - "Authorization": Each set of credentials gets its own limit.
+	//   - "IP": Each client address gets its own limit.
+	//   - Anything else is the name/path of a request field (e.g. "Group.ID"), so each value gets its own limit.
+	//
+	// When blank, all callers share the same limit.
+	By string
+}
+
+// RateLimitStatus describes where the caller stands w/ respect to an endpoint's rate limit after the
+// most recent call. Gateways use this to send headers like "RateLimit-Remaining" back to the caller.
+type RateLimitStatus struct {
+	// Allowed is true when the call did not exceed the limit.
+	Allowed bool
+	// Limit is the maximum number of calls allowed during each period.
+	Limit int
+	// Remaining is the number of calls the caller can make right now before being throttled.
+	Remaining int
+	// Reset is the amount of time until the caller's full limit is available again.
+	Reset time.Duration
+	// RetryAfter is the amount of time the caller should wait before trying again when not allowed.
+	RetryAfter time.Duration
+}
+
+// RateLimitStore is the pluggable storage for rate limit state. Each key identifies a single bucket of
+// tokens (e.g. one endpoint + one caller), and every call takes one token from it. Tokens gradually
+// refill so that a full bucket of 'Limit' tokens is available again after 'Period'.
+//
+// By default, the server uses NewMemoryRateLimitStore(), so limits only apply to a single instance of
+// your service. If you're running multiple instances and need them to share limits, implement this
+// using something like Redis and supply it using the WithRateLimitStore() option.
+type RateLimitStore interface {
+	// Take consumes a single token from the key's bucket (if there's one available) and reports the
+	// status of the bucket afterwards.
+	Take(ctx context.Context, key string, limit RateLimit) (RateLimitStatus, error)
+}
+
+// NewMemoryRateLimitStore creates a rate limit store that keeps every caller's token bucket in memory.
+// Buckets that have completely refilled are discarded, so idle callers don't take up space.
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{
+		buckets: map[string]*tokenBucket{},
+		now:     time.Now,
+	}
+}
+
+type memoryRateLimitStore struct {
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+	pruned  time.Time
+	now     func() time.Time
+}
+
+type tokenBucket struct {
+	tokens  float64
+	full    time.Time
+	updated time.Time
+}
+
+func (store *memoryRateLimitStore) Take(_ context.Context, key string, limit RateLimit) (RateLimitStatus, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	now := store.now()
+	store.prune(now)
+
+	capacity := float64(limit.Limit)
+	tokensPerSecond := capacity / limit.Period.Seconds()
+	secondsUntil := func(tokens float64) time.Duration {
+		return time.Duration(tokens / tokensPerSecond * float64(time.Second))
+	}
+
+	bucket, ok := store.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: capacity, updated: now}
+		store.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.updated).Seconds()*tokensPerSecond)
+	bucket.updated = now
+
+	status := RateLimitStatus{Limit: limit.Limit}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		status.Allowed = true
+	} else {
+		status.RetryAfter = secondsUntil(1 - bucket.tokens)
+	}
+	status.Remaining = int(bucket.tokens)
+	status.Reset = secondsUntil(capacity - bucket.tokens)
+	bucket.full = now.Add(status.Reset)
+	return status, nil
+}
+
+// prune discards any buckets that have completely refilled since they were last used; a fresh bucket
+// is exactly the same thing. We only bother doing this once a second so that it's not O(n) on every
+// call. You should already have the lock when calling this.
+func (store *memoryRateLimitStore) prune(now time.Time) {
+	if now.Sub(store.pruned) < time.Second {
+		return
+	}
+	for key, bucket := range store.buckets {
+		if !now.Before(bucket.full) {
+			delete(store.buckets, key)
+		}
+	}
+	store.pruned = now
+}
+
+type contextKeyRateLimitStatus struct{}
+
+// TrackRateLimit lets gateways find out the caller's rate limit status once the endpoint handler runs. Call
+// this before invoking the handler; afterwards, the status will be filled in if the endpoint has a rate
+// limit. Otherwise, it will be left as the zero value.
+func TrackRateLimit(ctx context.Context) (context.Context, *RateLimitStatus) {
+	status := &RateLimitStatus{}
+	return context.WithValue(ctx, contextKeyRateLimitStatus{}, status), status
+}
+
+// rateLimitMiddleware enforces the endpoint's "RATE xxx" doc option, failing w/ a 429 error when the caller
+// has used up their limit. Event handlers are never throttled since that would just drop the events.
+func rateLimitMiddleware(endpoint Endpoint, store RateLimitStore) MiddlewareFunc {
+	return func(ctx context.Context, req any, next HandlerFunc) (any, error) {
+		if endpoint.RateLimit == nil || metadata.Route(ctx).Type == GatewayTypeEvents.String() {
+			return next(ctx, req)
+		}
+
+		limit := *endpoint.RateLimit
+		status, err := store.Take(ctx, rateLimitKey(ctx, endpoint, req), limit)
+		if err != nil {
+			return nil, fail.Unexpected("rate limit error: %v", err)
+		}
+		if tracked, ok := ctx.Value(contextKeyRateLimitStatus{}).(*RateLimitStatus); ok {
+			*tracked = status
+		}
+		if !status.Allowed {
+			return nil, fail.Throttled("rate limit exceeded: %s allows %d calls every %v", endpoint.QualifiedName(), limit.Limit, limit.Period).
+				WithRetry(status.RetryAfter)
+		}
+		return next(ctx, req)
+	}
+}
+
+// rateLimitKey identifies the token bucket that this call should take from based on the limit's "BY xxx" value.
+func rateLimitKey(ctx context.Context, endpoint Endpoint, req any) string {
+	key := endpoint.QualifiedName() + ":" + endpoint.RateLimit.By + ":"
+	switch endpoint.RateLimit.By {
+	case "":
+		return key
+	case "Authorization":
+		return key + metadata.Authorization(ctx)
+	case "IP":
+		addr := metadata.RemoteAddr(ctx)
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			return key + host
+		}
+		return key + addr
+	default:
+		var value string
+		reflection.ToBindingValue(req, endpoint.RateLimit.By, &value)
+		return key + value
+	}
+}
@@ -0,0 +1,119 @@
+//go:build unit
+
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/monadicstack/abide/fail"
+	"github.com/monadicstack/abide/metadata"
+	"github.com/monadicstack/abide/services"
+	"github.com/stretchr/testify/suite"
+)
+
+func TestRateLimitSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitSuite))
+}
+
+type RateLimitSuite struct {
+	suite.Suite
+}
+
+type rateLimitRequest struct {
+	GroupID string
+}
+
+// The memory store should let callers use their whole limit in a burst and then gradually refill.
+func (suite *RateLimitSuite) TestMemoryStore() {
+	ctx := context.Background()
+	store := services.NewMemoryRateLimitStore()
+	limit := services.RateLimit{Limit: 2, Period: 100 * time.Millisecond}
+
+	status, err := store.Take(ctx, "Dude", limit)
+	suite.Require().NoError(err)
+	suite.True(status.Allowed)
+	suite.Equal(2, status.Limit)
+	suite.Equal(1, status.Remaining)
+	suite.Equal(time.Duration(0), status.RetryAfter)
+
+	status, _ = store.Take(ctx, "Dude", limit)
+	suite.True(status.Allowed)
+	suite.Equal(0, status.Remaining)
+
+	status, _ = store.Take(ctx, "Dude", limit)
+	suite.False(status.Allowed, "Should be throttled once the limit is used up")
+	suite.Equal(0, status.Remaining)
+	suite.InDelta(50*time.Millisecond, status.RetryAfter, float64(10*time.Millisecond), "One token refills every 50ms")
+	suite.InDelta(100*time.Millisecond, status.Reset, float64(10*time.Millisecond), "The whole bucket refills in 100ms")
+
+	// Each key gets its own bucket.
+	status, _ = store.Take(ctx, "Walter", limit)
+	suite.True(status.Allowed, "Other keys should not be affected")
+
+	time.Sleep(60 * time.Millisecond)
+	status, _ = store.Take(ctx, "Dude", limit)
+	suite.True(status.Allowed, "Should be allowed once a token refills")
+}
+
+// Endpoints w/ a rate limit should fail w/ a 429 once the caller has used up their limit, and each
+// "BY xxx" value should get its own limit.
+func (suite *RateLimitSuite) TestServer() {
+	newServer := func(by string) *services.Server {
+		return services.NewServer(services.Register(&services.Service{
+			Name: "GroupService",
+			Endpoints: []services.Endpoint{
+				{
+					ServiceName: "GroupService",
+					Name:        "Rename",
+					NewInput:    func() services.StructPointer { return &rateLimitRequest{} },
+					RateLimit:   &services.RateLimit{Limit: 1, Period: time.Minute, By: by},
+					Handler: func(ctx context.Context, req any) (any, error) {
+						return req, nil
+					},
+				},
+			},
+		}))
+	}
+	invoke := func(server *services.Server, ctx context.Context, groupID string) (*services.RateLimitStatus, error) {
+		ctx, status := services.TrackRateLimit(ctx)
+		_, err := server.Invoke(ctx, "GroupService", "Rename", &rateLimitRequest{GroupID: groupID})
+		return status, err
+	}
+
+	server := newServer("")
+	status, err := invoke(server, context.Background(), "1")
+	suite.Require().NoError(err)
+	suite.Equal(services.RateLimitStatus{Allowed: true, Limit: 1, Remaining: 0, Reset: time.Minute}, *status)
+	status, err = invoke(server, context.Background(), "2")
+	suite.Require().Error(err, "All callers should share the limit when there's no 'BY'")
+	suite.True(fail.IsThrottled(err))
+	suite.True(fail.IsRetryable(err))
+	suite.Equal(60, fail.From(err).RetryAfter)
+	suite.False(status.Allowed)
+
+	server = newServer("GroupID")
+	suite.Require().NoError(suite.ignoreStatus(invoke(server, context.Background(), "1")))
+	suite.Require().NoError(suite.ignoreStatus(invoke(server, context.Background(), "2")))
+	suite.Require().Error(suite.ignoreStatus(invoke(server, context.Background(), "1")))
+
+	server = newServer("Authorization")
+	dude := metadata.WithAuthorization(context.Background(), "Token Dude")
+	walter := metadata.WithAuthorization(context.Background(), "Token Walter")
+	suite.Require().NoError(suite.ignoreStatus(invoke(server, dude, "1")))
+	suite.Require().NoError(suite.ignoreStatus(invoke(server, walter, "1")))
+	suite.Require().Error(suite.ignoreStatus(invoke(server, dude, "1")))
+
+	server = newServer("IP")
+	home := metadata.WithRemoteAddr(context.Background(), "10.0.0.1:5000")
+	homeOtherPort := metadata.WithRemoteAddr(context.Background(), "10.0.0.1:6000")
+	work := metadata.WithRemoteAddr(context.Background(), "10.0.0.2:5000")
+	suite.Require().NoError(suite.ignoreStatus(invoke(server, home, "1")))
+	suite.Require().NoError(suite.ignoreStatus(invoke(server, work, "1")))
+	suite.Require().Error(suite.ignoreStatus(invoke(server, homeOtherPort, "1")), "Ports shouldn't matter")
+}
+
+func (suite *RateLimitSuite) ignoreStatus(_ *services.RateLimitStatus, err error) error {
+	return err
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
@@ -27,6 +28,16 @@ const (
 	GatewayTypeAPI = GatewayType("API")
 	// GatewayTypeEvents marks a gateway as being event-sourced using publish/subscribe.
 	GatewayTypeEvents = GatewayType("EVENTS")
+	// GatewayTypeRPC marks a gateway as serving RPC requests using request/reply over a broker.
+	GatewayTypeRPC = GatewayType("RPC")
+	// GatewayTypeJSONRPC marks a gateway as serving JSON-RPC 2.0 requests over HTTP.
+	GatewayTypeJSONRPC = GatewayType("JSONRPC")
+	// GatewayTypeGRPC marks a gateway as serving requests using the gRPC wire protocol.
+	GatewayTypeGRPC = GatewayType("GRPC")
+	// GatewayTypeWebSocket marks a gateway as serving requests and event pushes over WebSocket connections.
+	GatewayTypeWebSocket = GatewayType("WEBSOCKET")
+	// GatewayTypeGraphQL marks a gateway as serving GraphQL queries and mutations over HTTP.
+	GatewayTypeGraphQL = GatewayType("GRAPHQL")
 )
 
 // Gateway describes a way to execute operations on some underlying service. By
@@ -38,7 +49,9 @@ type Gateway interface {
 	// Type returns the identifier used to distinguish this gateway from others registered
 	// for the same service.
 	Type() GatewayType
-	// Register adds an ingress handler for the given operation/endpoint to this gateway.
+	// Register adds an ingress handler for the given operation/endpoint to this gateway. The
+	// server offers every route to every gateway, so implementations should quietly ignore
+	// routes for gateway types that they don't support.
 	Register(endpoint Endpoint, route EndpointRoute)
 	// Listen causes the gateway to start accepting requests to invoke methods on the
 	// underlying service. Implementations should attempt to follow these rules:
@@ -121,10 +134,10 @@ func (svc Service) Endpoint(name string) (Endpoint, bool) {
 //	)
 func NewServer(options ...ServerOption) *Server {
 	instance := Server{
-		gateways:          map[GatewayType]Gateway{},
 		shutdownComplete:  &sync.WaitGroup{},
 		gatewayMiddleware: MiddlewareFuncs{},
 		endpoints:         map[string]Endpoint{},
+		rateLimits:        NewMemoryRateLimitStore(),
 		onPanic: func(err error, stack []byte) {
 			fmt.Printf("Panic: %v\n%v\n", err, string(stack))
 		},
@@ -136,7 +149,7 @@ func NewServer(options ...ServerOption) *Server {
 	// If any of the gateways require special processing in all of the handler (like
 	// events need to publish on every invocation), capture those once.
 	for _, gw := range instance.gateways {
-		mw, ok := gw.(GatewayMiddleware)
+		mw, ok := gw.Gateway.(GatewayMiddleware)
 		if !ok {
 			continue
 		}
@@ -156,8 +169,8 @@ func NewServer(options ...ServerOption) *Server {
 // them talking to each other. You should not create one of these yourself. Instead, you should
 // use the NewServer() constructor to do that for you.
 type Server struct {
-	// gateways contains the individual gateways accepting requests.
-	gateways map[GatewayType]Gateway
+	// gateways contains the individual gateways accepting requests, in the order they were added.
+	gateways []namedGateway
 	// services are the actual generated service endpoint handlers that we'll route requests to.
 	services []*Service
 	// endpoints are all of the individual operations across all registered services in this server.
@@ -167,9 +180,27 @@ type Server struct {
 	// gatewayMiddleware aggregates all endpoint middleware functions that we want to occur on ALL
 	// endpoints regardless of the gateway that's handling it.
 	gatewayMiddleware MiddlewareFuncs
+	// rateLimits keeps track of how many calls each caller has made to endpoints w/ the "RATE xxx" doc option.
+	rateLimits RateLimitStore
 	// onPanic is a customizable callback that lets you perform custom logging/logic whenever the server
 	// recovers from a panic that occurred during your function calls.
 	onPanic OnPanicFunc
+	// unroutable describes any routes whose "GATEWAY xxx" option names a gateway that we don't have. We
+	// can't fail NewServer(), so Run() reports these instead.
+	unroutable []string
+}
+
+// namedGateway pairs a gateway with the (optional) name it was given using ListenAs(), so
+// routes with the "GATEWAY xxx" doc option only get registered on the gateway they target.
+type namedGateway struct {
+	Gateway
+	name string
+}
+
+// accepts determines if this gateway should be offered the route. Routes that don't target a
+// specific gateway are offered to all of them.
+func (gw namedGateway) accepts(route EndpointRoute) bool {
+	return route.Gateway == "" || route.Gateway == gw.name
 }
 
 func (server *Server) registerEndpoint(endpoint Endpoint) {
@@ -178,19 +209,44 @@ func (server *Server) registerEndpoint(endpoint Endpoint) {
 	// handlers have everything that the framework offers at their disposal. Additionally,
 	// the recovery middleware should always be the outermost handler to clean up
 	// after any crap that happens anywhere else in the pipeline.
-	endpoint.Handler = MiddlewareFuncs{recoverMiddleware(server.onPanic), rolesMiddleware(endpoint)}.
+	endpoint.Handler = MiddlewareFuncs{
+		recoverMiddleware(server.onPanic),
+		rateLimitMiddleware(endpoint, server.rateLimits),
+		timeoutMiddleware(endpoint),
+		rolesMiddleware(endpoint),
+		validateMiddleware(),
+	}.
 		Append(server.gatewayMiddleware...).
 		Then(endpoint.Handler)
 
 	server.endpoints[endpoint.QualifiedName()] = endpoint
 
+	// Most gateways only care about their own routes, but some can serve another gateway's routes
+	// (e.g. the RPC gateway serves API routes over a broker), so let each gateway decide.
 	for _, route := range endpoint.Routes {
-		if gw, ok := server.gateways[route.GatewayType]; ok {
-			gw.Register(endpoint, route)
+		if route.Gateway != "" && !server.hasGateway(route.Gateway) {
+			server.unroutable = append(server.unroutable, fmt.Sprintf("%s (%s %s) targets gateway '%s'",
+				endpoint.QualifiedName(), route.Method, route.Path, route.Gateway))
+			continue
+		}
+		for _, gw := range server.gateways {
+			if gw.accepts(route) {
+				gw.Register(endpoint, route)
+			}
 		}
 	}
 }
 
+// hasGateway determines whether any of the gateways were given this name using ListenAs().
+func (server *Server) hasGateway(name string) bool {
+	for _, gw := range server.gateways {
+		if gw.name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Invoke allows you to manually trigger any registered service endpoint/function given the name
 // of the service/method. I'd suggest you stick to using the generated clients to invoke functions
 // on your services rather than using this. This primarily exists to aid in testing - it's not really
@@ -210,10 +266,17 @@ func (server *Server) Invoke(ctx context.Context, serviceName string, methodName
 
 // Run turns on every gateway currently assigned to this service runtime. Call this
 // once your service setup and registration is complete in order to start accepting
-// incoming requests through your gateway(s).
+// incoming requests through your gateway(s). This fails w/o starting any gateways if
+// a route's "GATEWAY xxx" option names a gateway that you didn't add using ListenAs(),
+// since that operation would otherwise be unreachable.
 func (server *Server) Run() error {
 	server.shutdownComplete.Add(1)
 
+	if len(server.unroutable) > 0 {
+		server.shutdownComplete.Done()
+		return fmt.Errorf("unknown gateway: %s; add it using services.ListenAs()", strings.Join(server.unroutable, "; "))
+	}
+
 	errs, _ := fail.NewGroup(context.Background())
 	for _, gw := range server.gateways {
 		errs.Go(gw.Listen)
@@ -293,12 +356,29 @@ func (server *Server) ShutdownOnInterrupt(gracefulTimeout time.Duration) {
 type ServerOption func(*Server)
 
 // Listen adds another gateway to the server. You can supply this option more
-// than once in order to provide multiple types of gateways. For instance, you can
+// than once in order to provide multiple gateways. For instance, you can
 // call it once to provide settings for an API/HTTP gateway and again to provide
-// settings for an event source gateway.
+// settings for an event source gateway. You can even supply multiple gateways of
+// the same type (e.g. two API gateways listening on different ports).
 func Listen(gw Gateway) ServerOption {
+	return ListenAs("", gw)
+}
+
+// ListenAs adds another gateway to the server just like Listen(), but it gives the gateway
+// a name that routes can target using the "GATEWAY xxx" doc option. Routes that target a
+// name are only registered on gateways with that name, so you can keep admin-only functions
+// off of your public listener:
+//
+//	server := services.NewServer(
+//		services.Listen(apis.NewGateway(":443")),
+//		services.ListenAs("internal", apis.NewGateway(":9090")),
+//		services.Register(gen.UserServiceServer(userService)),
+//	)
+//
+// Routes w/o the "GATEWAY xxx" option are still registered on every gateway, named or not.
+func ListenAs(name string, gw Gateway) ServerOption {
 	return func(server *Server) {
-		server.gateways[gw.Type()] = gw
+		server.gateways = append(server.gateways, namedGateway{Gateway: gw, name: name})
 	}
 }
 
@@ -324,3 +404,12 @@ func OnPanic(handler OnPanicFunc) ServerOption {
 		server.onPanic = handler
 	}
 }
+
+// WithRateLimitStore changes where the server keeps track of how many calls each caller has made to
+// endpoints w/ the "RATE xxx" doc option. By default, this state is kept in memory, so each instance of
+// your service enforces its limits independently. Supply your own store if instances need to share them.
+func WithRateLimitStore(store RateLimitStore) ServerOption {
+	return func(server *Server) {
+		server.rateLimits = store
+	}
+}
@@ -4,13 +4,19 @@ package services_test
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"net/http"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/monadicstack/abide/fail"
 	"github.com/monadicstack/abide/internal/quiet"
 	"github.com/monadicstack/abide/internal/testext"
 	gen "github.com/monadicstack/abide/internal/testext/gen"
+	"github.com/monadicstack/abide/metadata"
 	"github.com/monadicstack/abide/services"
 	"github.com/monadicstack/abide/services/gateways/apis"
 	"github.com/monadicstack/abide/services/gateways/events"
@@ -267,6 +273,83 @@ func (suite *ServerSuite) TestEventChain() {
 	})
 }
 
+// Ensures that when a downstream handler in a saga fails, the compensating functions for every step
+// that already completed run in reverse order.
+func (suite *ServerSuite) TestSagaCompensation() {
+	server, calls, shutdown := suite.start()
+	defer shutdown()
+
+	calls.Reset()
+	res, err := server.Invoke(context.Background(), "OtherService", "SagaStart", &testext.OtherRequest{Text: "Abide"})
+	suite.Require().NoError(err)
+	suite.Equal("SagaStart:Abide", suite.responseText(res))
+	suite.assertInvoked(calls, []string{
+		"SagaStart:Abide",
+		"SagaStep:SagaStart:Abide",
+		"SagaFail:SagaStep:SagaStart:Abide",
+
+		// Compensating functions receive the output of the step they're undoing.
+		"SagaStepUndo:SagaStep:SagaStart:Abide",
+		"SagaStartUndo:SagaStart:Abide",
+	})
+
+	// The steps should be undone in the reverse order that they were performed.
+	values := calls.Values()
+	suite.Equal("SagaStepUndo:SagaStep:SagaStart:Abide", values[3])
+	suite.Equal("SagaStartUndo:SagaStart:Abide", values[4])
+}
+
+// forgetfulSagaStore never remembers any steps, much like another process's memory store.
+type forgetfulSagaStore struct{}
+
+func (forgetfulSagaStore) Push(context.Context, string, events.SagaStep) error {
+	return nil
+}
+
+func (forgetfulSagaStore) Pop(context.Context, string) (events.SagaStep, bool, error) {
+	return events.SagaStep{}, false, nil
+}
+
+func (forgetfulSagaStore) Compensate(context.Context, string) (bool, error) {
+	return true, nil
+}
+
+// When a step fails but the store has no record of the saga, the gateway should say so rather than
+// silently skipping the compensation.
+func (suite *ServerSuite) TestSagaCompensation_noSteps() {
+	errs := make(chan error, 10)
+
+	server := services.NewServer(
+		services.Listen(events.NewGateway(
+			events.WithSagaStore(forgetfulSagaStore{}),
+			events.WithErrorHandler(func(err error) { errs <- err }),
+		)),
+		services.Register(gen.OtherServiceServer(testext.OtherServiceHandler{Sequence: &testext.Sequence{}})),
+	)
+	go func() { _ = server.Run() }()
+	time.Sleep(25 * time.Millisecond)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}()
+
+	_, err := server.Invoke(context.Background(), "OtherService", "SagaStart", &testext.OtherRequest{Text: "Abide"})
+	suite.Require().NoError(err)
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case err = <-errs:
+			if strings.Contains(err.Error(), "no steps found to compensate") {
+				return
+			}
+		case <-timeout:
+			suite.FailNow("Gateway should report that there were no steps to compensate")
+		}
+	}
+}
+
 func (suite *ServerSuite) TestPanic() {
 	server, calls, shutdown := suite.start()
 	defer shutdown()
@@ -284,3 +367,288 @@ func (suite *ServerSuite) TestPanic() {
 	// suite.start() when setting up the service.
 	suite.assertInvoked(calls, []string{"OnPanic:don't"})
 }
+
+// Multiple gateways of the same type should all serve requests. Routes that target a named gateway
+// using "GATEWAY xxx" should only be available on that gateway.
+// When fanned-out steps of the same saga fail at the same time, only the first failure should walk the
+// saga back. The others shouldn't start a second compensation chain that fights over the same steps.
+func (suite *ServerSuite) TestSagaCompensation_concurrentFailures() {
+	errs := make(chan error, 10)
+	undos := make(chan struct{}, 10)
+
+	// Both failing handlers wait for each other, so they're guaranteed to fail at the same time.
+	failing := sync.WaitGroup{}
+	failing.Add(2)
+	failHandler := func(ctx context.Context, req any) (any, error) {
+		failing.Done()
+		failing.Wait()
+		return nil, fmt.Errorf("nope")
+	}
+	newInput := func() services.StructPointer { return &testext.SampleRequest{} }
+	onStart := []services.EndpointRoute{
+		{GatewayType: services.GatewayTypeEvents, Method: "ON", Path: "FanService.Start"},
+	}
+
+	server := services.NewServer(
+		services.Listen(events.NewGateway(
+			events.WithErrorHandler(func(err error) { errs <- err }),
+		)),
+		services.Register(&services.Service{
+			Name: "FanService",
+			Endpoints: []services.Endpoint{
+				{
+					ServiceName: "FanService",
+					Name:        "Start",
+					NewInput:    newInput,
+					Handler: func(ctx context.Context, req any) (any, error) {
+						return &testext.SampleResponse{Text: "OK"}, nil
+					},
+				},
+				{
+					ServiceName: "FanService",
+					Name:        "StartUndo",
+					NewInput:    newInput,
+					Handler: func(ctx context.Context, req any) (any, error) {
+						undos <- struct{}{}
+						return &testext.SampleResponse{}, nil
+					},
+					Routes: []services.EndpointRoute{
+						{GatewayType: services.GatewayTypeEvents, Method: "COMPENSATE", Path: "FanService.Start"},
+					},
+				},
+				{ServiceName: "FanService", Name: "FailA", NewInput: newInput, Handler: failHandler, Routes: onStart},
+				{ServiceName: "FanService", Name: "FailB", NewInput: newInput, Handler: failHandler, Routes: onStart},
+			},
+		}),
+	)
+	go func() { _ = server.Run() }()
+	time.Sleep(25 * time.Millisecond)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}()
+
+	_, err := server.Invoke(context.Background(), "FanService", "Start", &testext.SampleRequest{})
+	suite.Require().NoError(err)
+
+	select {
+	case <-undos:
+	case <-time.After(5 * time.Second):
+		suite.FailNow("Start should have been compensated")
+	}
+
+	// Give a second compensation chain (if there was one) plenty of time to make some noise.
+	time.Sleep(100 * time.Millisecond)
+	suite.Len(undos, 0, "Start should only be compensated once")
+	for len(errs) > 0 {
+		err = <-errs
+		suite.NotContains(err.Error(), "compensate", "Only the first failure should compensate the saga")
+	}
+}
+
+func (suite *ServerSuite) TestMultipleGateways() {
+	publicAddress := suite.addresses.Next()
+	internalAddress := suite.addresses.Next()
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return &testext.SampleResponse{Text: "OK"}, nil
+	}
+	newInput := func() services.StructPointer { return &testext.SampleRequest{} }
+	service := &services.Service{
+		Name: "AdminService",
+		Endpoints: []services.Endpoint{
+			{
+				ServiceName: "AdminService",
+				Name:        "Status",
+				Handler:     handler,
+				NewInput:    newInput,
+				Routes: []services.EndpointRoute{
+					{GatewayType: services.GatewayTypeAPI, Method: "GET", Path: "/status", Status: 200},
+				},
+			},
+			{
+				ServiceName: "AdminService",
+				Name:        "Purge",
+				Handler:     handler,
+				NewInput:    newInput,
+				Routes: []services.EndpointRoute{
+					{GatewayType: services.GatewayTypeAPI, Method: "GET", Path: "/purge", Status: 200, Gateway: "internal"},
+				},
+			},
+		},
+	}
+
+	server := services.NewServer(
+		services.Listen(apis.NewGateway(publicAddress)),
+		services.ListenAs("internal", apis.NewGateway(internalAddress)),
+		services.Register(service),
+	)
+	go func() { _ = server.Run() }()
+	time.Sleep(25 * time.Millisecond)
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}()
+
+	status := func(url string) int {
+		res, err := http.Get(url)
+		suite.Require().NoError(err)
+		defer quiet.Close(res.Body)
+		return res.StatusCode
+	}
+	suite.Equal(200, status("http://"+publicAddress+"/status"))
+	suite.Equal(200, status("http://"+internalAddress+"/status"))
+	suite.Equal(404, status("http://"+publicAddress+"/purge"))
+	suite.Equal(200, status("http://"+internalAddress+"/purge"))
+}
+
+// A route that targets a gateway we never added would be unreachable, so the server shouldn't start.
+func (suite *ServerSuite) TestMultipleGateways_unknownGateway() {
+	server := services.NewServer(
+		services.Listen(apis.NewGateway(suite.addresses.Next())),
+		services.Register(&services.Service{
+			Name: "AdminService",
+			Endpoints: []services.Endpoint{
+				{
+					ServiceName: "AdminService",
+					Name:        "Purge",
+					NewInput:    func() services.StructPointer { return &testext.SampleRequest{} },
+					Handler: func(ctx context.Context, req any) (any, error) {
+						return &testext.SampleResponse{Text: "OK"}, nil
+					},
+					Routes: []services.EndpointRoute{
+						{GatewayType: services.GatewayTypeAPI, Method: "GET", Path: "/purge", Status: 200, Gateway: "internal"},
+					},
+				},
+			},
+		}),
+	)
+
+	err := server.Run()
+	suite.Require().Error(err)
+	suite.Contains(err.Error(), "AdminService.Purge")
+	suite.Contains(err.Error(), "'internal'")
+}
+
+type validatedRequest struct {
+	Name  string `validate:"required,max=10"`
+	Email string `validate:"email"`
+}
+
+// Requests should be validated using their `validate` tags before the handler ever sees them.
+func (suite *ServerSuite) TestValidation() {
+	calls := &testext.Sequence{}
+	server := services.NewServer(services.Register(&services.Service{
+		Name: "UserService",
+		Endpoints: []services.Endpoint{
+			{
+				ServiceName: "UserService",
+				Name:        "Create",
+				NewInput:    func() services.StructPointer { return &validatedRequest{} },
+				Handler: func(ctx context.Context, req any) (any, error) {
+					calls.Append("Create:" + req.(*validatedRequest).Name)
+					return req, nil
+				},
+			},
+		},
+	}))
+
+	_, err := server.Invoke(context.Background(), "UserService", "Create", &validatedRequest{Email: "nope"})
+	suite.Require().Error(err)
+	suite.Equal(400, fail.Status(err))
+	suite.Equal("validation failed: Name is required; Email must be a valid email address", err.Error())
+	suite.Empty(calls.Values())
+
+	_, err = server.Invoke(context.Background(), "UserService", "Create", &validatedRequest{Name: "Dude", Email: "dude@example.com"})
+	suite.Require().NoError(err)
+	suite.Equal([]string{"Create:Dude"}, calls.Values())
+}
+
+type sleepRequest struct {
+	Sleep time.Duration
+}
+
+// Handlers that run longer than the endpoint's TIMEOUT or the caller's deadline should fail w/ a 408
+// without us waiting around for them to finish.
+func (suite *ServerSuite) TestTimeout() {
+	panicStacks := make(chan string, 1)
+	lateHeaders := make(chan struct{})
+	server := services.NewServer(
+		services.Register(&services.Service{
+			Name: "SleepService",
+			Endpoints: []services.Endpoint{
+				{
+					ServiceName: "SleepService",
+					Name:        "Sleep",
+					NewInput:    func() services.StructPointer { return &sleepRequest{} },
+					Timeout:     50 * time.Millisecond,
+					Handler: func(ctx context.Context, req any) (any, error) {
+						// Intentionally ignore the context; the server should give up on us anyway.
+						time.Sleep(req.(*sleepRequest).Sleep)
+						return req, nil
+					},
+				},
+				{
+					ServiceName: "SleepService",
+					Name:        "Panic",
+					NewInput:    func() services.StructPointer { return &sleepRequest{} },
+					Timeout:     50 * time.Millisecond,
+					Handler: func(ctx context.Context, req any) (any, error) {
+						panic("no soup for you")
+					},
+				},
+				{
+					ServiceName: "SleepService",
+					Name:        "Headers",
+					NewInput:    func() services.StructPointer { return &sleepRequest{} },
+					Timeout:     50 * time.Millisecond,
+					Handler: func(ctx context.Context, req any) (any, error) {
+						metadata.SetResponseHeader(ctx, "X-Early", "yes")
+						time.Sleep(req.(*sleepRequest).Sleep)
+						metadata.SetResponseHeader(ctx, "X-Late", "yes")
+						close(lateHeaders)
+						return req, nil
+					},
+				},
+			},
+		}),
+		services.OnPanic(func(err error, stack []byte) {
+			panicStacks <- string(stack)
+		}),
+	)
+
+	_, err := server.Invoke(context.Background(), "SleepService", "Sleep", &sleepRequest{Sleep: 5 * time.Millisecond})
+	suite.Require().NoError(err, "Handlers that finish in time should succeed")
+
+	start := time.Now()
+	_, err = server.Invoke(context.Background(), "SleepService", "Sleep", &sleepRequest{Sleep: time.Second})
+	suite.Require().Error(err)
+	suite.True(fail.IsTimeout(err), "Slow handlers should fail w/ a 408")
+	suite.Less(time.Since(start), 500*time.Millisecond, "We shouldn't wait for the handler to finish")
+
+	// The caller's deadline is shorter than the endpoint's TIMEOUT, so it should win.
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	_, err = server.Invoke(ctx, "SleepService", "Sleep", &sleepRequest{Sleep: 30 * time.Millisecond})
+	suite.Require().Error(err)
+	suite.True(fail.IsTimeout(err), "Handlers that exceed the caller's deadline should fail w/ a 408")
+	suite.Less(time.Since(start), 25*time.Millisecond, "The caller's deadline should win")
+
+	_, err = server.Invoke(context.Background(), "SleepService", "Panic", &sleepRequest{})
+	suite.Require().Error(err)
+	suite.Equal(500, fail.Status(err), "Panics should still be recovered when there's a timeout")
+	suite.Equal("no soup for you", err.Error())
+	suite.Contains(<-panicStacks, "server_test.go", "OnPanic should get the stack of the handler that panicked")
+
+	// Once the call times out, the handler that's still running shouldn't be able to change the headers.
+	ctx, responseHeaders := metadata.TrackResponseHeaders(context.Background())
+	_, err = server.Invoke(ctx, "SleepService", "Headers", &sleepRequest{Sleep: 100 * time.Millisecond})
+	suite.Require().Error(err)
+	suite.True(fail.IsTimeout(err))
+	<-lateHeaders
+	suite.Equal("yes", responseHeaders().Get("X-Early"))
+	suite.Equal("", responseHeaders().Get("X-Late"))
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"io"
+	"time"
 )
 
 // ContentGetter provides a way for your service response to indicate that you want to return a
@@ -75,22 +76,82 @@ type ContentFileNameSetter interface {
 	SetContentFileName(string)
 }
 
+// ContentModTimeGetter is used by raw response streams to indicate when the underlying resource was
+// last modified. The API gateway sends this as the Last-Modified header, which also lets callers
+// safely resume downloads using the If-Range header.
+type ContentModTimeGetter interface {
+	// ContentModTime returns the time that the stream's underlying resource last changed.
+	ContentModTime() time.Time
+}
+
+// ContentModTimeSetter recaptures the Last-Modified header from raw responses when using the
+// code-generated Go client for your services.
+type ContentModTimeSetter interface {
+	// SetContentModTime applies the time that the stream's underlying resource last changed.
+	SetContentModTime(time.Time)
+}
+
 // StreamRequest implements all of the ContentXxx and SetContentXxx methods that we support and look
-// at when we look at streaming/upload style requests.
+// at when we look at streaming/upload style requests. When your request embeds one of these, the API
+// gateway hands you the raw request body (or the file from a multipart/form-data upload) rather than
+// trying to decode it as JSON. Your other request fields are still bound from the path, query string,
+// and any form fields.
 //
 //	type FileUploadRequest struct {
 //		services.StreamRequest
+//		FolderID string
 //	}
 //
-//	func (res *ImageDownloadResponse) Init(file os.File, info fs.FileInfo) {
-//		res.SetContent(file)
-//		res.SetContentType("image/png")
-//		res.SetContentLength(info.Size())
+//	func (svc FileServiceHandler) Upload(ctx context.Context, req *FileUploadRequest) (*FileUploadResponse, error) {
+//		defer req.Content().Close()
+//		return svc.store.Save(ctx, req.FolderID, req.ContentFileName(), req.Content())
 //	}
 type StreamRequest struct {
-	content       io.ReadCloser
-	contentType   string
-	contentLength int
+	content         io.ReadCloser
+	contentType     string
+	contentLength   int
+	contentFileName string
+}
+
+// Content returns the raw byte stream that the caller uploaded.
+func (req *StreamRequest) Content() io.ReadCloser {
+	return req.content
+}
+
+// SetContent applies the raw byte stream that the caller is uploading.
+func (req *StreamRequest) SetContent(content io.ReadCloser) {
+	req.content = content
+}
+
+// ContentType returns the MIME content type string that describes the data in the stream.
+func (req *StreamRequest) ContentType() string {
+	return req.contentType
+}
+
+// SetContentType applies the MIME content type that describes the data in the stream.
+func (req *StreamRequest) SetContentType(contentType string) {
+	req.contentType = contentType
+}
+
+// ContentLength returns the number of bytes you can read from the content stream. This
+// is zero when the caller didn't tell us how big the upload is.
+func (req *StreamRequest) ContentLength() int {
+	return req.contentLength
+}
+
+// SetContentLength sets the number of bytes the server should read from the content stream.
+func (req *StreamRequest) SetContentLength(contentLength int) {
+	req.contentLength = contentLength
+}
+
+// ContentFileName returns the name of the file that the caller uploaded, if they supplied one.
+func (req *StreamRequest) ContentFileName() string {
+	return req.contentFileName
+}
+
+// SetContentFileName sets the name of the file that the caller is uploading.
+func (req *StreamRequest) SetContentFileName(contentFileName string) {
+	req.contentFileName = contentFileName
 }
 
 // StreamResponse implements all of the ContentXxx and SetContentXxx methods that we support. You
@@ -114,6 +175,7 @@ type StreamResponse struct {
 	contentRangeEnd   int
 	contentRangeSize  int
 	contentFileName   string
+	contentModTime    time.Time
 }
 
 // Content returns the raw byte stream representing the data returned by the endpoint.
@@ -168,6 +230,16 @@ func (res *StreamResponse) SetContentFileName(contentFileName string) {
 	res.contentFileName = contentFileName
 }
 
+// ContentModTime returns the time that the stream's underlying resource last changed.
+func (res *StreamResponse) ContentModTime() time.Time {
+	return res.contentModTime
+}
+
+// SetContentModTime sets the time that the stream's underlying resource last changed.
+func (res *StreamResponse) SetContentModTime(modTime time.Time) {
+	res.contentModTime = modTime
+}
+
 // Redirector provides a way to tell gateways that the response value doesn't contain the
 // raw byte stream we want to deliver. Instead, you should redirect to that URI to fetch
 // the response data.
@@ -204,3 +276,15 @@ type RedirectorPermanent interface {
 	// we want this endpoint to return.
 	RedirectPermanent() string
 }
+
+// ETagGetter lets your response tell gateways which version of the underlying resource it contains. The API
+// gateway sends this as the "ETag" header and responds to "If-None-Match" requests for the same version w/ a
+// 304, so callers can skip downloading data they already have. Endpoints w/ the "CACHE xxx" doc option get an
+// ETag based on the hash of the response body when you don't implement this.
+//
+// GATEWAY COMPATABILITY: This currently only works with the API gateway. Other gateways ignore it.
+type ETagGetter interface {
+	// ETag returns the version identifier (e.g. a hash or revision number) of the response's data. You don't
+	// need to quote it; we'll do that for you. Returning "" means that the response doesn't have one.
+	ETag() string
+}
@@ -6,6 +6,7 @@ import (
 	"bytes"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/monadicstack/abide/services"
 	"github.com/stretchr/testify/require"
@@ -152,6 +153,16 @@ func TestStreamResponse_RangedWithLength(t *testing.T) {
 	assert.Equal(2048, size)
 }
 
+func TestStreamResponse_ContentModTime(t *testing.T) {
+	assert := require.New(t)
+	stream := services.StreamResponse{}
+	assert.True(stream.ContentModTime().IsZero())
+
+	modTime := time.Date(1998, time.March, 6, 12, 0, 0, 0, time.UTC)
+	stream.SetContentModTime(modTime)
+	assert.Equal(modTime, stream.ContentModTime())
+}
+
 func newTextStream(value string) io.ReadCloser {
 	return io.NopCloser(bytes.NewBufferString(value))
 }